@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// configFlagName is the name of the flag setting the config file, which cannot be set in the file itself.
+const configFlagName = "config"
+
+// loadConfigFile returns the flag values set in the YAML config file of the given path, keyed by the flag names
+// (e.g. "ttl-seconds: 300"). A list value is joined by commas, e.g. of '--namespace-allowlist'.
+func loadConfigFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, val := range raw {
+		switch typed := val.(type) {
+		case map[string]interface{}:
+			return nil, fmt.Errorf("invalid value of %q in config file %s: a map is not supported", key, path)
+		case []interface{}:
+			items := make([]string, 0, len(typed))
+			for _, item := range typed {
+				items = append(items, formatConfigValue(item))
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = formatConfigValue(typed)
+		}
+	}
+
+	return values, nil
+}
+
+// formatConfigValue returns the given scalar value of a config file as a flag value. Numbers are parsed as float64
+// from YAML, so they are formatted without an exponent, e.g. 1000000 rather than 1e+06.
+func formatConfigValue(val interface{}) string {
+	switch typed := val.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	default:
+		return fmt.Sprint(typed)
+	}
+}
+
+// setFlagsFromConfig sets the flags of the given flag set not set on the command line (or from their environment
+// variables) from the given values of a config file. So a flag takes precedence over its environment variable, which
+// takes precedence over the config file, which takes precedence over its default value. A key not naming any flag is
+// rejected, e.g. misspelled.
+func setFlagsFromConfig(flagSet *flag.FlagSet, values map[string]string) error {
+	setFlags := make(map[string]bool)
+	flagSet.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	// set in order so that the error returned is deterministic
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == configFlagName || flagSet.Lookup(key) == nil {
+			return fmt.Errorf("unknown flag %q in the config file", key)
+		}
+		if setFlags[key] {
+			continue
+		}
+		if err := flagSet.Set(key, values[key]); err != nil {
+			return fmt.Errorf("invalid value %q of %q in the config file: %v", values[key], key, err)
+		}
+	}
+
+	return nil
+}
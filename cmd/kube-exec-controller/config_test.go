@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestSetFlagsFromConfig tests flags being set from a config file only if absent from the command line
+func TestSetFlagsFromConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	config := `
+ttl-seconds: 300
+namespace-allowlist:
+  - kube-system
+  - monitoring
+exempt-all: true
+port: 9443
+history-size: 1000000
+stale-interaction-after: 1h
+`
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	values, err := loadConfigFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	ttlSeconds := flagSet.Int("ttl-seconds", 600, "")
+	namespaceAllowlist := flagSet.String("namespace-allowlist", "", "")
+	exemptAll := flagSet.Bool("exempt-all", false, "")
+	port := flagSet.Int("port", 8443, "")
+	historySize := flagSet.Int("history-size", 100, "")
+	staleInteractionAfter := flagSet.Duration("stale-interaction-after", 0, "")
+	logLevel := flagSet.String("log-level", "info", "")
+	flagSet.String(configFlagName, "", "")
+	if err := flagSet.Parse([]string{"--port=10443"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := setFlagsFromConfig(flagSet, values); err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the config file is honored for the flags absent from the command line
+	if *ttlSeconds != 300 {
+		t.Errorf("expected --ttl-seconds set to 300 from the config file, got %d", *ttlSeconds)
+	}
+	if *namespaceAllowlist != "kube-system,monitoring" {
+		t.Errorf("expected --namespace-allowlist joined from the list in the config file, got %q", *namespaceAllowlist)
+	}
+	if !*exemptAll {
+		t.Error("expected --exempt-all set to true from the config file, got false")
+	}
+	if *historySize != 1000000 {
+		t.Errorf("expected --history-size set to 1000000 from the config file, got %d", *historySize)
+	}
+	if *staleInteractionAfter != time.Hour {
+		t.Errorf("expected --stale-interaction-after set to 1h from the config file, got %s", *staleInteractionAfter)
+	}
+
+	// verify the command line takes precedence over the config file, and the default over nothing
+	if *port != 10443 {
+		t.Errorf("expected --port kept as 10443 from the command line, got %d", *port)
+	}
+	if *logLevel != "info" {
+		t.Errorf("expected --log-level kept as its default, got %q", *logLevel)
+	}
+
+	// verify an unknown key, the config flag itself or an invalid value is rejected
+	for _, invalidValues := range []map[string]string{
+		{"ttl-second": "300"},
+		{configFlagName: "other.yaml"},
+		{"ttl-seconds": "ten"},
+	} {
+		flagSet = flag.NewFlagSet("test", flag.ContinueOnError)
+		flagSet.Int("ttl-seconds", 600, "")
+		flagSet.String(configFlagName, "", "")
+		if err := setFlagsFromConfig(flagSet, invalidValues); err == nil {
+			t.Errorf("expected an error setting flags from %v, got nil", invalidValues)
+		}
+	}
+
+	// verify a missing or malformed config file is rejected
+	if _, err := loadConfigFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error loading a missing config file, got nil")
+	}
+	if err := os.WriteFile(configPath, []byte("ttl-seconds: [300"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfigFile(configPath); err == nil {
+		t.Error("expected an error loading a malformed config file, got nil")
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newDebugMux returns an http.ServeMux serving the net/http/pprof handlers under /debug/pprof/, registered explicitly
+// rather than on http.DefaultServeMux so that they are never exposed by any other server.
+func newDebugMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return mux
+}
+
+// runDebugServer serves the debug handlers over plain HTTP at the given address (e.g. "localhost:6060"), separately
+// from the TLS webhook port. No write timeout is set as profiling takes as long as requested (30s by default).
+func runDebugServer(addr string) {
+	debugServer := &http.Server{
+		Addr:              addr,
+		Handler:           newDebugMux(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	zap.L().Info("Serving pprof handlers on the debug listener.", zap.String("debug_addr", addr))
+	if err := debugServer.ListenAndServe(); err != nil {
+		zap.L().Error("Debug server exited with an error.", zap.Error(err))
+	}
+}
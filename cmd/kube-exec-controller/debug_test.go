@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDebugMux tests the debug mux serving the pprof handlers only
+func TestDebugMux(t *testing.T) {
+	server := httptest.NewServer(newDebugMux())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/debug/pprof/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "goroutine") {
+		t.Errorf("expected the pprof index listing the goroutine profile, got status %d: %s", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected no other handler served by the debug mux, got status %d", resp.StatusCode)
+	}
+}
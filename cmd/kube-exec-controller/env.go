@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// envPrefix is the prefix of the environment variables setting the flags, e.g. KEC_TTL_SECONDS for '--ttl-seconds'.
+const envPrefix = "KEC_"
+
+// setFlagsFromEnv sets the flags of the given flag set not set on the command line from their environment variables
+// looked up by the given func, if present. So a flag takes precedence over its environment variable, which takes
+// precedence over its default value.
+func setFlagsFromEnv(flagSet *flag.FlagSet, lookupEnv func(string) (string, bool)) error {
+	setFlags := make(map[string]bool)
+	flagSet.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	var err error
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if err != nil || setFlags[f.Name] {
+			return
+		}
+
+		envName := getFlagEnvName(f.Name)
+		val, present := lookupEnv(envName)
+		if !present {
+			return
+		}
+		if setErr := flagSet.Set(f.Name, val); setErr != nil {
+			err = fmt.Errorf("invalid value %q of environment variable %s: %v", val, envName, setErr)
+		}
+	})
+
+	return err
+}
+
+// getFlagEnvName returns the name of the environment variable setting the flag of the given name.
+func getFlagEnvName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+// TestSetFlagsFromEnv tests flags being set from their environment variables only if absent from the command line
+func TestSetFlagsFromEnv(t *testing.T) {
+	env := map[string]string{
+		"KEC_TTL_SECONDS":         "300",
+		"KEC_NAMESPACE_ALLOWLIST": "kube-system,monitoring",
+		"KEC_EXEMPT_ALL":          "true",
+		"KEC_PORT":                "9443",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		val, present := env[name]
+		return val, present
+	}
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	ttlSeconds := flagSet.Int("ttl-seconds", 600, "")
+	namespaceAllowlist := flagSet.String("namespace-allowlist", "", "")
+	exemptAll := flagSet.Bool("exempt-all", false, "")
+	port := flagSet.Int("port", 8443, "")
+	logLevel := flagSet.String("log-level", "info", "")
+	if err := flagSet.Parse([]string{"--port=10443"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := setFlagsFromEnv(flagSet, lookupEnv); err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the environment variables are honored for the flags absent from the command line
+	if *ttlSeconds != 300 {
+		t.Errorf("expected --ttl-seconds set to 300 from the environment, got %d", *ttlSeconds)
+	}
+	if *namespaceAllowlist != "kube-system,monitoring" {
+		t.Errorf("expected --namespace-allowlist set from the environment, got %q", *namespaceAllowlist)
+	}
+	if !*exemptAll {
+		t.Error("expected --exempt-all set to true from the environment, got false")
+	}
+
+	// verify the command line takes precedence over the environment, and the default over nothing
+	if *port != 10443 {
+		t.Errorf("expected --port kept as 10443 from the command line, got %d", *port)
+	}
+	if *logLevel != "info" {
+		t.Errorf("expected --log-level kept as its default, got %q", *logLevel)
+	}
+
+	// verify an invalid value of an environment variable is rejected
+	env["KEC_TTL_SECONDS"] = "ten"
+	flagSet = flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.Int("ttl-seconds", 600, "")
+	if err := setFlagsFromEnv(flagSet, lookupEnv); err == nil {
+		t.Error("expected an error setting --ttl-seconds from an invalid environment variable, got nil")
+	}
+}
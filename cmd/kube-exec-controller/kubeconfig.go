@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// loadKubeConfig returns the K8s client config loaded from the given kubeconfig path if set, or else the in-cluster
+// config returned by the given func if available, or else the kubeconfig found by the default loading rules of
+// kubectl, i.e. $KUBECONFIG or ~/.kube/config, so the controller can run out of the cluster for development.
+func loadKubeConfig(kubeconfigPath string, inClusterConfig func() (*rest.Config, error)) (*rest.Config, error) {
+	if kubeconfigPath != "" {
+		loadingRules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath}
+		return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	}
+
+	config, inClusterErr := inClusterConfig()
+	if inClusterErr == nil {
+		return config, nil
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("not running in-cluster (%v) and no kubeconfig loaded: %v", inClusterErr, err)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+// TestLoadKubeConfig tests the K8s client config loaded from the kubeconfig flag, the in-cluster config and the
+// default kubeconfig in that order of precedence
+func TestLoadKubeConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeKubeconfig := func(name, server string) string {
+		path := filepath.Join(dir, name)
+		content := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: test-cluster
+  cluster:
+    server: %s
+contexts:
+- name: test-context
+  context:
+    cluster: test-cluster
+current-context: test-context
+`, server)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	flagPath := writeKubeconfig("flag", "https://flag.example.com")
+	envPath := writeKubeconfig("env", "https://env.example.com")
+
+	// the default loading rules read $KUBECONFIG before ~/.kube/config
+	oldKubeconfig, present := os.LookupEnv("KUBECONFIG")
+	os.Setenv("KUBECONFIG", envPath)
+	defer func() {
+		if present {
+			os.Setenv("KUBECONFIG", oldKubeconfig)
+		} else {
+			os.Unsetenv("KUBECONFIG")
+		}
+	}()
+
+	inCluster := func() (*rest.Config, error) {
+		return &rest.Config{Host: "https://in-cluster.example.com"}, nil
+	}
+	outOfCluster := func() (*rest.Config, error) {
+		return nil, rest.ErrNotInCluster
+	}
+
+	for _, testCase := range []struct {
+		kubeconfigPath  string
+		inClusterConfig func() (*rest.Config, error)
+		expectedHost    string
+	}{
+		{flagPath, inCluster, "https://flag.example.com"},
+		{"", inCluster, "https://in-cluster.example.com"},
+		{"", outOfCluster, "https://env.example.com"},
+	} {
+		config, err := loadKubeConfig(testCase.kubeconfigPath, testCase.inClusterConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Host != testCase.expectedHost {
+			t.Errorf("expected the config of host %s, got %s", testCase.expectedHost, config.Host)
+		}
+	}
+
+	// verify a missing kubeconfig is reported rather than falling back
+	if _, err := loadKubeConfig(filepath.Join(dir, "missing"), inCluster); err == nil {
+		t.Error("expected an error loading a missing kubeconfig, got nil")
+	}
+	os.Setenv("KUBECONFIG", filepath.Join(dir, "missing"))
+	if _, err := loadKubeConfig("", outOfCluster); err == nil {
+		t.Error("expected an error out of the cluster with no kubeconfig, got nil")
+	}
+}
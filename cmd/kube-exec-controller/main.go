@@ -1,19 +1,36 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
-	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	// embed the timezone database for '--eviction-window-timezone', which the container image does not have
+	_ "time/tzdata"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
 	"github.com/box/kube-exec-controller/pkg/controller"
+	"github.com/box/kube-exec-controller/pkg/policy"
 	"github.com/box/kube-exec-controller/pkg/webhook"
 )
 
+// admitTestCmd is a subcommand to admit a recorded AdmissionReview JSON file offline, e.g.
+// "kube-exec-controller --namespace-allowlist=kube-system admit-test review.json".
+const admitTestCmd = "admit-test"
+
+// staleInteractionCheckInterval is how often the controller checks for stale Pod interactions.
+const staleInteractionCheckInterval = time.Minute
+
 func main() {
 	certPath := flag.String("cert-path", "",
 		"Path to the PEM-encoded TLS certificate",
@@ -27,23 +44,262 @@ func main() {
 	port := flag.Int("port", 8443,
 		"Port for the app to listen on",
 	)
+	healthPort := flag.Int("health-port", 0,
+		"Separate port serving /health/liveness and /health/readiness over plain HTTP, on top of the TLS port, 0 means no separate port",
+	)
 	apiServerURL := flag.String("api-server", "",
 		"URL to K8s api-server, required if kube-proxy is not set up",
 	)
+	kubeconfigPath := flag.String("kubeconfig", "",
+		"Path to a kubeconfig file to run out of the cluster with, empty means the in-cluster config or else $KUBECONFIG or ~/.kube/config",
+	)
+	exemptAll := flag.Bool("exempt-all", false,
+		"Allow all requests without tracking any Pod interaction (monitor-only), taking precedence over any allowlist",
+	)
 	namespaceAllowlistRaw := flag.String("namespace-allowlist", "",
-		"Comma separated list of namespaces that allow interaction without evicting their Pods",
+		"Comma separated list of namespaces that allow interaction without evicting their Pods, including globs (e.g. 'team-*-sandbox') and regular expressions prefixed with 'regex:'",
+	)
+	userAllowlistRaw := flag.String("user-allowlist", "",
+		"Comma separated list of users that are allowed to interact with Pods without evicting them, in any namespace",
+	)
+	groupAllowlistRaw := flag.String("group-allowlist", "",
+		"Comma separated list of groups whose users are allowed to interact with Pods without evicting them, in any namespace",
+	)
+	commandAllowlistRaw := flag.String("command-allowlist", "",
+		"Comma separated list of read-only commands (e.g. cat,ls,ps) that are allowed to be run in Pods without evicting them",
 	)
 	podInteractChanSize := flag.Int("interact-chan-size", 500,
 		"Buffer size of the channel for handling Pod interaction",
 	)
+	podInteractWorkers := flag.Int("interact-workers", 1,
+		"Number of workers handling Pod interactions concurrently, the interactions of the same Pod are handled in order by the same worker",
+	)
+	interactionDedupWindow := flag.Duration("interaction-dedup-window", 10*time.Second,
+		"Window after handling an interaction of a Pod in which its repeated interactions are coalesced and counted at once, 0 means handling every interaction",
+	)
 	podExtendChanSize := flag.Int("extend-chan-size", 500,
 		"Buffer size of the channel for handling Pod extension",
 	)
+	drainDeadline := flag.Duration("drain-deadline", 20*time.Second,
+		"Max time to handle the Pod interactions and extensions left in the channels on shutdown, remaining ones are dead-lettered, and then to publish the buffered stream records, notifications and history records",
+	)
+	shutdownTimeout := flag.Duration("shutdown-timeout", 5*time.Second,
+		"Max time to wait for the admission requests in progress on shutdown, before draining the channels",
+	)
+	maxCommandArgs := flag.Int("max-command-args", 100,
+		"Max number of command args kept from a Pod interaction before getting truncated, 0 means unlimited",
+	)
+	maxCommandLength := flag.Int("max-command-length", 4096,
+		"Max number of characters kept from a Pod interaction's command before getting truncated, 0 means unlimited",
+	)
+	redactPatterns := append([]string{}, controller.DefaultRedactPatterns...)
+	flag.Func("redact-command-pattern",
+		"Regex redacting the matched segments of a Pod interaction's command args with '***' (keeping its first capture group), can be repeated to add to the default ones matching common password flags",
+		func(pattern string) error {
+			redactPatterns = append(redactPatterns, pattern)
+			return nil
+		},
+	)
+	maxExtension := flag.Duration("max-extension", 0,
+		"Max total extension an interacted Pod can be given on top of its TTL, exceeding ones are denied by the webhook or capped by the controller, 0 means unlimited",
+	)
+	maxHold := flag.Duration("max-hold", 24*time.Hour,
+		"Max duration an interacted Pod held in use by 'kubectl pi hold' is kept running past its eviction time, after which it is evicted even if not released, 0 means unbounded",
+	)
+	staleInteractionAfter := flag.Duration("stale-interaction-after", 0,
+		"Clear interaction labels/annotations of Pods still running this long after their eviction time, 0 means never, only with '--audit-only' or '--statefulset-exempt'",
+	)
+	auditOnly := flag.Bool("audit-only", false,
+		"Only track and annotate interacted Pods, submitting an event instead of evicting them once due",
+	)
+	pluginWarning := flag.String("plugin-warning", webhook.DefaultPluginWarning,
+		"Admission warning shown to users on every tracked interaction to advise 'kubectl pi', empty means no warning",
+	)
+	controllerUsername := flag.String("controller-username", "",
+		"Username of the controller (e.g. system:serviceaccount:<namespace>:<name>), whose Pod updates are always allowed and never sent back to the controller",
+	)
+	justificationNamespacesRaw := flag.String("justification-namespaces", "",
+		"Comma separated list of namespaces whose Pods must be justified by 'kubectl pi justify' before being interacted",
+	)
+	justificationMaxAge := flag.Duration("justification-max-age", time.Hour,
+		"Max age of a justification to be considered recent when its Pod gets interacted, 0 means any age",
+	)
+	unjustifiedTTLSeconds := flag.Int("unjustified-ttl-seconds", 60,
+		"TTL (time-to-live) of Pods interacted without a recent justification under the justification namespaces",
+	)
+	privilegedTTL := flag.Duration("privileged-ttl", 0,
+		"TTL (time-to-live) of interacted Pods running a privileged container or using hostNetwork or hostPID, if shorter than their TTL, 0 means no reduction",
+	)
+	maxTrackedPodsPerUser := flag.Int("max-tracked-pods-per-user", 0,
+		"Max number of interacted Pods tracked per user, a new interaction exceeding it evicts (or shortens the TTL of) the user's oldest tracked Pod, 0 means unlimited",
+	)
+	trackedPodsPolicy := flag.String("tracked-pods-policy", string(controller.TrackedPodsPolicyEvict),
+		"How to enforce '--max-tracked-pods-per-user' on the user's oldest tracked Pod: evict (right away) or shorten-ttl (to be evicted in 5 minutes)",
+	)
+	warnBefore := flag.Duration("warn-before", 0,
+		"Lead time to warn interacted Pods with an event before evicting them, overridden by their namespace's 'box.com/evictionWarningLeadTime' annotation, 0 means no warning",
+	)
+	evictionGracePeriod := flag.Duration("eviction-grace-period", 0,
+		"Deprecated: use '--warn-before' instead, which takes precedence if both are set (for the grace period of evictions, use '--evict-grace-period')",
+	)
+	evictionLeaseIdentity := flag.String("eviction-lease-identity", "",
+		"Unique identity of this replica (e.g. its Pod name) to acquire a per-Pod Lease before evicting, so multiple replicas evict each Pod once",
+	)
+	enableLeaderElection := flag.Bool("enable-leader-election", false,
+		"Elect a leader among controller replicas, which is the only one setting termination timers and evicting Pods, while all replicas keep serving the webhook",
+	)
+	leaderElectionNamespace := flag.String("leader-election-namespace", "default",
+		"Namespace of the 'kube-exec-controller-leader' Lease elected by controller replicas with '--enable-leader-election'",
+	)
+	evictionWindow := flag.String("eviction-window", "",
+		"Daily time window (e.g. 09:00-18:00) in which Pods are evicted, evictions outside it are deferred to its next opening, empty means any time",
+	)
+	evictionWindowTimezone := flag.String("eviction-window-timezone", "UTC",
+		"IANA timezone (e.g. America/Los_Angeles) of the time window set in '--eviction-window'",
+	)
+	statefulSetExempt := flag.Bool("statefulset-exempt", false,
+		"Exempt interacted Pods owned by a StatefulSet from eviction, advising to restart them manually in order",
+	)
+	statefulSetGracePeriod := flag.Duration("statefulset-grace-period", 0,
+		"Grace period to evict interacted Pods owned by a StatefulSet with, 0 means the Pod's own termination grace period",
+	)
+	annotateEvictedPodOwners := flag.Bool("annotate-evicted-pod-owners", false,
+		"Annotate the owning workload (e.g. Deployment) of evicted Pods with the time and reason of their last eviction, so recreated Pods can be correlated to it",
+	)
+	deleteDebugJobs := flag.Bool("delete-debug-jobs", false,
+		"Delete the owning Job of interacted Pods labeled 'box.com/debugJob: \"true\"' instead of evicting them, so the Job does not recreate them",
+	)
+	suppressTerminatingPodEvents := flag.Bool("suppress-terminating-pod-events", false,
+		"Suppress the K8s events to interacted Pods already terminating (being deleted, Succeeded or Failed), which are still tracked",
+	)
+	interactionEventType := flag.String("interaction-event-type", "Warning",
+		"Type of the K8s events about Pod interactions: Normal or Warning",
+	)
+	interactionEventReason := flag.String("interaction-event-reason", "PodInteraction",
+		"Reason of the K8s events about Pod interactions, except the ones of a specific reason (e.g. PrivilegedPodInteraction)",
+	)
+	extensionEventType := flag.String("extension-event-type", "Warning",
+		"Type of the K8s events about Pod extensions (and holds): Normal or Warning",
+	)
+	extensionEventReason := flag.String("extension-event-reason", "PodInteraction",
+		"Reason of the K8s events about Pod extensions (and holds), except the ones of a specific reason (e.g. ForeignPodExtension)",
+	)
+	evictionEventType := flag.String("eviction-event-type", "Warning",
+		"Type of the K8s events about Pod evictions: Normal or Warning",
+	)
+	evictionEventReason := flag.String("eviction-event-reason", "PodInteraction",
+		"Reason of the K8s events about Pod evictions, except the ones of a specific reason (e.g. PodEvictionWarning)",
+	)
+	podExemptSelectorRaw := flag.String("pod-exempt-selector", "",
+		"Label selector (e.g. 'app in (ci-runner, sandbox)') of Pods that are never evicted after being interacted, empty means none",
+	)
+	readinessGate := flag.Bool("readiness-gate", true,
+		"Fail the readiness probe until the controller's caches are synced and previously interacted Pods are checked",
+	)
+	configNamespace := flag.String("config-namespace", "",
+		"Namespace of the 'kube-exec-controller-config' ConfigMap to watch, e.g. setting its 'disabled: \"true\"' pauses all evictions",
+	)
+	watchTrackedPods := flag.Bool("watch-tracked-pods", false,
+		"Read newly interacted Pods labeled by the '/mutate-pod' webhook from an informer cache, rather than getting them from the K8s API",
+	)
+	policyName := flag.String("policy-name", "",
+		"Name of the cluster-scoped ExecTrackingPolicy object to watch, its values take precedence over the flags",
+	)
+	namespacedPolicy := flag.Bool("namespaced-policy", false,
+		"Also watch the NamespacedExecTrackingPolicy objects named '--policy-name' in all namespaces, their values take precedence over the ExecTrackingPolicy for the Pods in their namespace",
+	)
+	policySyncTimeout := flag.Duration("policy-sync-timeout", time.Minute,
+		"Max duration to sync the ExecTrackingPolicy object at startup, after which the controller exits (e.g. its CRD is not installed)",
+	)
+	replayAuditLog := flag.String("replay-audit-log", "",
+		"Path to a K8s API audit log (JSON) whose exec/attach requests are replayed at startup to track Pods interacted while the webhook was unavailable",
+	)
+	interactionMetadata := flag.String("interaction-metadata", string(controller.InteractionMetadataLabels),
+		"Type of metadata storing the interaction timestamp, interactor and TTL of interacted Pods: labels or annotations, the latter for clusters restricting labels",
+	)
+	keyPrefix := flag.String("key-prefix", controller.DefaultKeyPrefix,
+		"Prefix of all labels/annotations the controller sets or reads (e.g. 'box.com/podTTLDuration'), which 'kubectl pi --key-prefix' must match",
+	)
+	previousKeyPrefix := flag.String("previous-key-prefix", "",
+		"Prefix the labels/annotations were named under before --key-prefix, also recognized while transitioning to it: Pods tracked under it are migrated to --key-prefix once observed",
+	)
+	terminationMode := flag.String("termination-mode", string(controller.TerminationModeEvict),
+		"How to terminate interacted Pods once due: evict (through the Eviction API, respecting PodDisruptionBudgets) or delete, overridden by their namespace's 'box.com/terminationMode' annotation",
+	)
+	evictGracePeriod := flag.Duration("evict-grace-period", 0,
+		"Grace period to evict interacted Pods with in the 'evict' termination mode, 0 means the Pod's own termination grace period",
+	)
+	pdbBlockedAction := flag.String("pdb-blocked-action", string(controller.PDBBlockedActionLog),
+		"What to do with an interacted Pod whose eviction is still refused by a PodDisruptionBudget after retrying it: log (leaving it alive) or delete",
+	)
+	pdbBlockedRetries := flag.Int("pdb-blocked-retries", 3,
+		"Max number of retries of an eviction refused by a PodDisruptionBudget, with exponential backoff from --pdb-blocked-retry-interval",
+	)
+	pdbBlockedRetryInterval := flag.Duration("pdb-blocked-retry-interval", 10*time.Second,
+		"Initial interval between the retries of an eviction refused by a PodDisruptionBudget",
+	)
+	kubeAPITimeout := flag.Duration("kube-api-timeout", 30*time.Second,
+		"Timeout of each K8s API call getting, patching, evicting or deleting interacted Pods, failed calls are retried with backoff, 0 means no timeout",
+	)
+	retryMaxElapsedTime := flag.Duration("retry-max-elapsed-time", 15*time.Minute,
+		"Max time to retry handling a Pod interaction or extension with exponential backoff, before giving up with a RetryGivenUp event to the Pod",
+	)
+	retryMaxInterval := flag.Duration("retry-max-interval", time.Minute,
+		"Max interval between the retries of handling a Pod interaction or extension with exponential backoff",
+	)
+	deleteGracePeriod := flag.Duration("delete-grace-period", 0,
+		"Grace period to delete interacted Pods with in the 'delete' termination mode, 0 means the Pod's own termination grace period",
+	)
+	ttlMode := flag.String("ttl-mode", string(controller.TTLModeFixed),
+		"How the TTL of interacted Pods counts: fixed (from their initial interaction) or idle (from their latest interaction, evicting them once idle for their TTL, requires --controller-username)",
+	)
+	auditLogPath := flag.String("audit-log-path", "",
+		"Path to a file the webhook appends a JSON line to for every admitted Pod interaction, including the exempted ones, empty means no audit log",
+	)
+	auditFormat := flag.String("audit-format", "",
+		"Format of the audit record printed to stdout for every new Pod interaction: json, cef or leef, empty means no audit record",
+	)
+	streamURL := flag.String("stream-url", "",
+		"URL to post a JSON record of every Pod interaction, extension and eviction to (e.g. of a Kafka REST proxy topic), empty means no streaming",
+	)
+	streamBufferSize := flag.Int("stream-buffer-size", 1000,
+		"Max number of records buffered for '--stream-url', records are dropped once it is full",
+	)
+	notifyWebhookURL := flag.String("notify-webhook-url", "",
+		"URL (e.g. of a Slack incoming webhook) to post a JSON notification of every new Pod interaction to, empty means no notification",
+	)
+	notifyTimeout := flag.Duration("notify-timeout", 10*time.Second,
+		"Timeout of posting a notification to '--notify-webhook-url'",
+	)
+	historyNamespace := flag.String("history-namespace", "",
+		"Namespace of the 'kube-exec-controller-history' ConfigMap keeping the most recent Pod interaction, extension and eviction records for 'kubectl pi history', empty means no history",
+	)
+	historySize := flag.Int("history-size", 100,
+		"Max number of records kept in the history ConfigMap (at most 2000), the oldest ones are overwritten once full",
+	)
+	debugAddr := flag.String("debug-addr", "",
+		"Address (e.g. localhost:6060) of a separate plain HTTP listener serving the pprof handlers under /debug/pprof/, empty means no debug listener",
+	)
 	logLevel := flag.String("log-level", "info",
 		"Log level. `debug`, `info`, `warn`, `error` are currently supported",
 	)
+	configPath := flag.String(configFlagName, "",
+		"Path to a YAML config file setting flags by their names (e.g. 'ttl-seconds: 300'), which the command line and environment variables take precedence over",
+	)
 
 	flag.Parse()
+	if err := setFlagsFromEnv(flag.CommandLine, os.LookupEnv); err != nil {
+		log.Fatalf("Cannot set flags from the environment. Error: %v", err)
+	}
+	if *configPath != "" {
+		values, err := loadConfigFile(*configPath)
+		if err != nil {
+			log.Fatalf("Cannot load the config file. Error: %v", err)
+		}
+		if err := setFlagsFromConfig(flag.CommandLine, values); err != nil {
+			log.Fatalf("Cannot set flags from the config file. Error: %v", err)
+		}
+	}
 
 	// set up zap logging
 	loggerCfg := zap.NewProductionConfig()
@@ -58,50 +314,380 @@ func main() {
 	zap.ReplaceGlobals(zapLogger)
 	defer zapLogger.Sync()
 
+	commandRedactions, err := controller.ParseRedactPatterns(redactPatterns)
+	if err != nil {
+		zap.L().Fatal("Invalid command redact pattern.", zap.Error(err))
+	}
+
+	if err := controller.SetKeyPrefix(*keyPrefix); err != nil {
+		zap.L().Fatal("Invalid key prefix.", zap.Error(err))
+	}
+	if err := controller.SetPreviousKeyPrefix(*previousKeyPrefix); err != nil {
+		zap.L().Fatal("Invalid previous key prefix.", zap.Error(err))
+	}
+
+	if flag.Arg(0) == admitTestCmd {
+		if flag.NArg() != 2 {
+			zap.L().Fatal("Subcommand 'admit-test' expects exactly one path to a recorded AdmissionReview JSON file.")
+		}
+
+		offlineServer := webhook.NewOfflineServer(*namespaceAllowlistRaw)
+		offlineServer.ExemptAll = *exemptAll
+		offlineServer.AllowedUsers = webhook.ParseAllowlist(*userAllowlistRaw)
+		offlineServer.AllowedGroups = webhook.ParseAllowlist(*groupAllowlistRaw)
+		offlineServer.AllowedCommands = webhook.ParseAllowlist(*commandAllowlistRaw)
+		offlineServer.MaxCommandArgs = *maxCommandArgs
+		offlineServer.MaxCommandLength = *maxCommandLength
+		offlineServer.CommandRedactions = commandRedactions
+		offlineServer.MaxExtension = *maxExtension
+		offlineServer.PluginWarning = *pluginWarning
+		offlineServer.EvictionWarning = true
+		if err := offlineServer.ReviewFile(flag.Arg(1), os.Stdout); err != nil {
+			zap.L().Fatal("Cannot admit the recorded AdmissionReview.", zap.Error(err))
+		}
+		return
+	}
+
+	if *maxCommandArgs < 0 || *maxCommandLength < 0 {
+		zap.L().Fatal("Flag '--max-command-args' or '--max-command-length' cannot be set to a negative value.")
+	}
+
+	if *maxExtension < 0 {
+		zap.L().Fatal("Flag '--max-extension' cannot be set to a negative value.")
+	}
+
+	if *maxHold < 0 {
+		zap.L().Fatal("Flag '--max-hold' cannot be set to a negative value.")
+	}
+
 	if *ttlSeconds < 0 {
 		zap.L().Fatal("Flag '--ttl-seconds' cannot be set to a negative value.")
 	}
+	if *privilegedTTL < 0 {
+		zap.L().Fatal("Flag '--privileged-ttl' cannot be set to a negative value.")
+	}
+	if *maxTrackedPodsPerUser < 0 {
+		zap.L().Fatal("Flag '--max-tracked-pods-per-user' cannot be set to a negative value.")
+	}
+	if *podInteractWorkers < 1 {
+		zap.L().Fatal("Flag '--interact-workers' must be set to at least 1.")
+	}
 
 	if *certPath == "" || *keyPath == "" {
 		zap.L().Fatal("Flag '--cert-path' or '--key-path' is not set or set to an empty value.")
 	}
 
-	kubeClient, err := initKubeClient(*apiServerURL)
+	kubeConfig, err := initKubeConfig(*apiServerURL, *kubeconfigPath)
+	if err != nil {
+		zap.L().Fatal("Cannot initialize Kube client config.", zap.Error(err))
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
 	if err != nil {
 		zap.L().Fatal("Cannot initialize Kube client.", zap.Error(err))
 	}
 
+	// watch the ExecTrackingPolicy object if specified (nil policy store falls back to the flag values)
+	var policyStore *policy.Store
+	if *policyName != "" {
+		dynamicClient, err := dynamic.NewForConfig(kubeConfig)
+		if err != nil {
+			zap.L().Fatal("Cannot initialize Kube dynamic client.", zap.Error(err))
+		}
+
+		policyStore = policy.NewStore()
+		if err := policyStore.Watch(dynamicClient, *policyName, *policySyncTimeout, make(chan struct{})); err != nil {
+			zap.L().Fatal("Cannot sync the ExecTrackingPolicy, check its CRD is installed and readable by the controller.",
+				zap.Error(err))
+		}
+		if *namespacedPolicy {
+			if err := policyStore.WatchNamespaced(dynamicClient, *policyName, *policySyncTimeout, make(chan struct{})); err != nil {
+				zap.L().Fatal("Cannot sync the NamespacedExecTrackingPolicy objects, check its CRD is installed and readable by the controller.",
+					zap.Error(err))
+			}
+		}
+	} else if *namespacedPolicy {
+		zap.L().Fatal("Flag '--namespaced-policy' requires '--policy-name' to be set.")
+	}
+
 	// initialize controller service to handle Pod interaction and extension update
 	controller.PodInteractionCh = make(chan controller.PodInteraction, *podInteractChanSize)
 	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate, *podExtendChanSize)
-	contr := controller.NewController(kubeClient, *ttlSeconds)
+	metadata, err := controller.ParseInteractionMetadata(*interactionMetadata)
+	if err != nil {
+		zap.L().Fatal("Invalid interaction metadata.", zap.Error(err))
+	}
+	mode, err := controller.ParseTerminationMode(*terminationMode)
+	if err != nil {
+		zap.L().Fatal("Invalid termination mode.", zap.Error(err))
+	}
+	pdbBlockedActionValue, err := controller.ParsePDBBlockedAction(*pdbBlockedAction)
+	if err != nil {
+		zap.L().Fatal("Invalid PDB blocked action.", zap.Error(err))
+	}
+	ttlModeValue, err := controller.ParseTTLMode(*ttlMode)
+	if err != nil {
+		zap.L().Fatal("Invalid TTL mode.", zap.Error(err))
+	}
+	// the webhook denies recording the latest interaction of a Pod to anyone but the controller
+	if ttlModeValue == controller.TTLModeIdle && *controllerUsername == "" {
+		zap.L().Fatal("Flag '--controller-username' must be set in the idle TTL mode.")
+	}
+	if *staleInteractionAfter > 0 {
+		if !*auditOnly && !*statefulSetExempt {
+			zap.L().Fatal("Flag '--stale-interaction-after' must be set with '--audit-only' or '--statefulset-exempt', as interacted Pods are evicted otherwise.")
+		}
+		// the webhook denies clearing the interaction labels of a Pod to anyone but the controller
+		if *controllerUsername == "" {
+			zap.L().Fatal("Flag '--controller-username' must be set with '--stale-interaction-after'.")
+		}
+	}
+	controllerOpts := []controller.Option{
+		controller.WithPolicyStore(policyStore),
+		controller.WithStaleInteractionCleanup(*staleInteractionAfter),
+		controller.WithMaxExtension(*maxExtension),
+		controller.WithMaxHold(*maxHold),
+		controller.WithInteractionMetadata(metadata),
+		controller.WithCommandRedaction(commandRedactions),
+		controller.WithTerminationMode(mode, *deleteGracePeriod),
+		controller.WithEvictionGracePeriod(*evictGracePeriod),
+		controller.WithPDBBlockedAction(pdbBlockedActionValue, *pdbBlockedRetries, *pdbBlockedRetryInterval),
+		controller.WithTTLMode(ttlModeValue),
+		controller.WithInteractionDedup(*interactionDedupWindow),
+		controller.WithKubeAPITimeout(*kubeAPITimeout),
+		controller.WithRetryBackoff(*retryMaxElapsedTime, *retryMaxInterval),
+	}
+	if *auditFormat != "" {
+		format, err := controller.ParseAuditFormat(*auditFormat)
+		if err != nil {
+			zap.L().Fatal("Invalid audit format.", zap.Error(err))
+		}
+		controllerOpts = append(controllerOpts, controller.WithAuditOutput(os.Stdout, format))
+	}
+	if *historyNamespace != "" {
+		if *historySize <= 0 || *historySize > controller.MaxHistorySize {
+			zap.L().Fatal("Flag '--history-size' must be set between 1 and 2000.")
+		}
+		controllerOpts = append(controllerOpts, controller.WithHistoryConfigMap(*historyNamespace, *historySize))
+	}
+	if *streamURL != "" {
+		controllerOpts = append(controllerOpts, controller.WithStreamProducer(controller.NewHTTPProducer(*streamURL), *streamBufferSize))
+	}
+	if *notifyWebhookURL != "" {
+		if *notifyTimeout <= 0 {
+			zap.L().Fatal("Flag '--notify-timeout' must be set to a positive value.")
+		}
+		controllerOpts = append(controllerOpts, controller.WithInteractionNotification(*notifyWebhookURL, *notifyTimeout))
+	}
+	if *justificationNamespacesRaw != "" {
+		controllerOpts = append(controllerOpts, controller.WithJustificationRequirement(
+			strings.Split(*justificationNamespacesRaw, ","),
+			*justificationMaxAge,
+			time.Duration(*unjustifiedTTLSeconds)*time.Second,
+		))
+	}
+	if *privilegedTTL > 0 {
+		controllerOpts = append(controllerOpts, controller.WithPrivilegedTTL(*privilegedTTL))
+	}
+	if *maxTrackedPodsPerUser > 0 {
+		podsPolicy, err := controller.ParseTrackedPodsPolicy(*trackedPodsPolicy)
+		if err != nil {
+			zap.L().Fatal("Invalid tracked pods policy.", zap.Error(err))
+		}
+		controllerOpts = append(controllerOpts, controller.WithMaxTrackedPodsPerUser(*maxTrackedPodsPerUser, podsPolicy))
+	}
+	if *podInteractWorkers > 1 {
+		controllerOpts = append(controllerOpts, controller.WithInteractionWorkers(*podInteractWorkers))
+	}
+	if *evictionLeaseIdentity != "" {
+		controllerOpts = append(controllerOpts, controller.WithEvictionLease(*evictionLeaseIdentity))
+	}
+	if *enableLeaderElection {
+		controllerOpts = append(controllerOpts, controller.WithLeaderElection())
+	}
+	if *evictionWindow != "" {
+		window, err := controller.ParseEvictionWindow(*evictionWindow, *evictionWindowTimezone)
+		if err != nil {
+			zap.L().Fatal("Invalid eviction window.", zap.Error(err))
+		}
+		controllerOpts = append(controllerOpts, controller.WithEvictionWindow(window))
+	}
+	if *auditOnly {
+		controllerOpts = append(controllerOpts, controller.WithAuditOnly())
+	}
+	if *statefulSetExempt || *statefulSetGracePeriod > 0 {
+		controllerOpts = append(controllerOpts, controller.WithStatefulSetHandling(*statefulSetExempt, *statefulSetGracePeriod))
+	}
+	if *evictionGracePeriod != 0 {
+		zap.L().Warn("Flag '--eviction-grace-period' is deprecated, use '--warn-before' instead, or '--evict-grace-period' for the grace period of evictions.")
+	}
+	if *warnBefore == 0 {
+		*warnBefore = *evictionGracePeriod
+	}
+	if *warnBefore > 0 {
+		controllerOpts = append(controllerOpts, controller.WithEvictionWarning(*warnBefore))
+	}
+	podExemptSelector, err := controller.ParsePodExemptSelector(*podExemptSelectorRaw)
+	if err != nil {
+		zap.L().Fatal("Invalid pod exempt selector.", zap.Error(err))
+	}
+	if podExemptSelector != nil {
+		controllerOpts = append(controllerOpts, controller.WithPodExemptSelector(podExemptSelector))
+	}
+	if *annotateEvictedPodOwners {
+		controllerOpts = append(controllerOpts, controller.WithEvictedPodOwnerAnnotation())
+	}
+	if *deleteDebugJobs {
+		controllerOpts = append(controllerOpts, controller.WithDebugJobDeletion())
+	}
+	if *suppressTerminatingPodEvents {
+		controllerOpts = append(controllerOpts, controller.WithTerminatingPodEventSuppression())
+	}
+	for _, event := range []struct {
+		category  controller.EventCategory
+		eventType string
+		reason    string
+	}{
+		{controller.EventCategoryInteraction, *interactionEventType, *interactionEventReason},
+		{controller.EventCategoryExtension, *extensionEventType, *extensionEventReason},
+		{controller.EventCategoryEviction, *evictionEventType, *evictionEventReason},
+	} {
+		eventType, err := controller.ParseEventType(event.eventType)
+		if err != nil {
+			zap.L().Fatal("Invalid event type.", zap.String("category", string(event.category)), zap.Error(err))
+		}
+		controllerOpts = append(controllerOpts, controller.WithEventConfig(event.category, eventType, event.reason))
+	}
+	contr := controller.NewController(kubeClient, *ttlSeconds, controllerOpts...)
+	if *configNamespace != "" {
+		contr.WatchKillSwitch(*configNamespace, make(chan struct{}))
+	}
 
-	go func() {
-		defer close(controller.PodInteractionCh)
+	if *enableLeaderElection {
+		identity, err := os.Hostname()
+		if err != nil {
+			zap.L().Fatal("Cannot get the identity of this replica for leader election.", zap.Error(err))
+		}
+		go func() {
+			if err := contr.RunLeaderElection(*leaderElectionNamespace, identity, make(chan struct{})); err != nil {
+				zap.L().Fatal("Cannot run leader election.", zap.Error(err))
+			}
+		}()
+	}
 
-		contr.CheckPodInteraction()
-	}()
+	contr.WatchInteractedPods(make(chan struct{}))
+	if *watchTrackedPods {
+		contr.WatchTrackedPods(make(chan struct{}))
+	}
 
-	go func() {
-		defer close(controller.PodExtensionUpdateCh)
+	go contr.RunStaleInteractionCleanup(staleInteractionCheckInterval, make(chan struct{}))
 
-		contr.CheckPodExtensionUpdate()
-	}()
+	// the publishers are stopped once the controller is drained on shutdown, flushing what is buffered by then
+	publishStopCh := make(chan struct{})
+	var publishers sync.WaitGroup
+	for _, run := range []func(<-chan struct{}){contr.RunStreamPublisher, contr.RunInteractionNotifier, contr.RunHistoryAppender} {
+		publishers.Add(1)
+		go func(run func(<-chan struct{})) {
+			defer publishers.Done()
+			run(publishStopCh)
+		}(run)
+	}
+
+	go contr.CheckPodInteraction()
+	go contr.CheckPodExtensionUpdate()
+
+	// the debug listener is off by default, and never served on the webhook port
+	if *debugAddr != "" {
+		go runDebugServer(*debugAddr)
+	}
 
 	// initialize webhook server and start admitting incoming requests
 	webhookServer, err := webhook.NewServer(*port, *certPath, *keyPath, *namespaceAllowlistRaw)
 	if err != nil {
 		zap.L().Fatal("Cannot initialize webhook server.", zap.Error(err))
 	}
+	webhookServer.ExemptAll = *exemptAll
+	webhookServer.AllowedUsers = webhook.ParseAllowlist(*userAllowlistRaw)
+	webhookServer.AllowedGroups = webhook.ParseAllowlist(*groupAllowlistRaw)
+	webhookServer.AllowedCommands = webhook.ParseAllowlist(*commandAllowlistRaw)
+	webhookServer.Policy = policyStore
+	webhookServer.MaxCommandArgs = *maxCommandArgs
+	webhookServer.MaxCommandLength = *maxCommandLength
+	webhookServer.CommandRedactions = commandRedactions
+	webhookServer.MaxExtension = *maxExtension
+	webhookServer.ControllerUsername = *controllerUsername
+	webhookServer.ShutdownTimeout = *shutdownTimeout
+	webhookServer.PluginWarning = *pluginWarning
+	webhookServer.EvictionWarning = true
+	webhookServer.Recorder = controller.NewEventRecorder(kubeClient)
+	webhookServer.HealthPort = *healthPort
+	webhookServer.PodExemptSelector = podExemptSelector
+	if *readinessGate {
+		webhookServer.Ready = contr.Ready
+	}
+	if *auditLogPath != "" {
+		auditLogger, err := webhook.NewFileAuditLogger(*auditLogPath)
+		if err != nil {
+			zap.L().Fatal("Cannot open the audit log.", zap.Error(err))
+		}
+		defer auditLogger.Close()
+		webhookServer.AuditLogger = auditLogger
+	}
+	if err := webhookServer.WarnMissingNamespaces(kubeClient); err != nil {
+		zap.L().Warn("Cannot check existence of namespaces in the namespace allowlist", zap.Error(err))
+	}
+
+	// shut down gracefully on SIGTERM (e.g. on rollout), letting the requests and the channel items in progress finish
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if *replayAuditLog != "" {
+		// replay in background as the controller may take a while to handle every interaction sent to the channel
+		go func() {
+			replayed, err := webhookServer.ReplayAuditLog(*replayAuditLog, ctx.Done())
+			if err != nil {
+				zap.L().Error("Cannot replay Pod interactions from the audit log.", zap.Error(err))
+				return
+			}
+			zap.L().Info("Replayed Pod interactions from the audit log.", zap.Int("interactions", replayed))
+		}()
+	}
 
-	err = webhookServer.Run()
-	if err != nil && err != http.ErrServerClosed {
+	err = webhookServer.Run(ctx.Done())
+	if ctx.Err() == nil {
 		zap.L().Fatal("Webhook server exited with an error.", zap.Error(err))
 	}
+	zap.L().Info("Shutting down.")
+	if err != nil {
+		zap.L().Warn("Stopped the webhook server before the requests in progress are completed.", zap.Error(err))
+	}
+
+	// drain the channels once nothing more is sent to them, giving up the sends still blocked past the timeout
+	webhookServer.CloseControllerChannels(*shutdownTimeout)
+	if !contr.Drain(*drainDeadline) {
+		zap.L().Warn("Dead-lettered the items left unhandled at the drain deadline.",
+			zap.String("drain_deadline", drainDeadline.String()),
+		)
+	}
+
+	// flush the stream records, notifications and history records buffered so far, as nothing more is buffered once drained
+	close(publishStopCh)
+	flushed := make(chan struct{})
+	go func() {
+		publishers.Wait()
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+	case <-time.After(*drainDeadline):
+		zap.L().Warn("Gave up flushing the buffered stream records, notifications and history records at the drain deadline.",
+			zap.String("drain_deadline", drainDeadline.String()),
+		)
+	}
 }
 
-func initKubeClient(apiServerURL string) (kubernetes.Interface, error) {
-	config, err := rest.InClusterConfig()
+func initKubeConfig(apiServerURL, kubeconfigPath string) (*rest.Config, error) {
+	config, err := loadKubeConfig(kubeconfigPath, rest.InClusterConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -111,5 +697,5 @@ func initKubeClient(apiServerURL string) (kubernetes.Interface, error) {
 		config.Host = apiServerURL
 	}
 
-	return kubernetes.NewForConfig(config)
+	return config, nil
 }
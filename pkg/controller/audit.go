@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AuditFormat is the format of the audit record written for every new Pod interaction.
+type AuditFormat string
+
+// These are the supported audit formats, CEF and LEEF are for ingesting into SIEMs like ArcSight and QRadar.
+const (
+	AuditFormatJSON AuditFormat = "json"
+	AuditFormatCEF  AuditFormat = "cef"
+	AuditFormatLEEF AuditFormat = "leef"
+)
+
+// These are set in the header of CEF and LEEF audit records.
+const (
+	auditVendor    = "Box"
+	auditProduct   = "kube-exec-controller"
+	auditEventID   = "PodInteraction"
+	auditEventName = "Pod interaction"
+	auditSeverity  = 5
+)
+
+// auditRecord contains the fields of a Pod interaction written in a JSON audit record.
+type auditRecord struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Username      string    `json:"username"`
+	PodNamespace  string    `json:"pod_namespace"`
+	PodName       string    `json:"pod_name"`
+	ContainerName string    `json:"container_name"`
+	Commands      []string  `json:"commands"`
+}
+
+// ParseAuditFormat returns the AuditFormat of the given name, or an error if it is not supported.
+func ParseAuditFormat(name string) (AuditFormat, error) {
+	switch format := AuditFormat(strings.ToLower(name)); format {
+	case AuditFormatJSON, AuditFormatCEF, AuditFormatLEEF:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported audit format %q, expected one of: json, cef, leef", name)
+	}
+}
+
+// WithAuditOutput writes an audit record in the given format to the given writer for every new Pod interaction.
+func WithAuditOutput(out io.Writer, format AuditFormat) Option {
+	return func(c *Controller) {
+		c.auditOut = out
+		c.auditFormat = format
+		c.auditMu = &sync.Mutex{}
+	}
+}
+
+// AuditRecord returns a single line audit record of the Pod interaction in the given format.
+func (pi *PodInteraction) AuditRecord(format AuditFormat) (string, error) {
+	switch format {
+	case AuditFormatJSON:
+		record, err := json.Marshal(auditRecord{
+			Timestamp:     pi.InitTime,
+			Username:      pi.Username,
+			PodNamespace:  pi.PodNamespace,
+			PodName:       pi.PodName,
+			ContainerName: pi.ContainerName,
+			Commands:      pi.Commands,
+		})
+		return string(record), err
+	case AuditFormatCEF:
+		header := strings.Join([]string{
+			"CEF:0",
+			escapeCEFHeader(auditVendor),
+			escapeCEFHeader(auditProduct),
+			"",
+			escapeCEFHeader(auditEventID),
+			escapeCEFHeader(auditEventName),
+			strconv.Itoa(auditSeverity),
+		}, "|")
+		extension := strings.Join([]string{
+			"rt=" + strconv.FormatInt(pi.InitTime.UnixNano()/int64(time.Millisecond), 10),
+			"suser=" + escapeCEFExtension(pi.Username),
+			"cs1Label=podNamespace cs1=" + escapeCEFExtension(pi.PodNamespace),
+			"cs2Label=podName cs2=" + escapeCEFExtension(pi.PodName),
+			"cs3Label=containerName cs3=" + escapeCEFExtension(pi.ContainerName),
+			"cs4Label=commands cs4=" + escapeCEFExtension(strings.Join(pi.Commands, " ")),
+		}, " ")
+		return header + "|" + extension, nil
+	case AuditFormatLEEF:
+		header := strings.Join([]string{
+			"LEEF:1.0",
+			escapeLEEFHeader(auditVendor),
+			escapeLEEFHeader(auditProduct),
+			"",
+			escapeLEEFHeader(auditEventID),
+		}, "|")
+		attributes := strings.Join([]string{
+			"devTime=" + strconv.FormatInt(pi.InitTime.UnixNano()/int64(time.Millisecond), 10),
+			"devTimeFormat=" + "epoch",
+			"sev=" + strconv.Itoa(auditSeverity),
+			"usrName=" + escapeLEEFAttribute(pi.Username),
+			"podNamespace=" + escapeLEEFAttribute(pi.PodNamespace),
+			"podName=" + escapeLEEFAttribute(pi.PodName),
+			"containerName=" + escapeLEEFAttribute(pi.ContainerName),
+			"commands=" + escapeLEEFAttribute(strings.Join(pi.Commands, " ")),
+		}, "\t")
+		return header + "|" + attributes, nil
+	default:
+		return "", fmt.Errorf("unsupported audit format %q", format)
+	}
+}
+
+// writeAuditRecord writes an audit record of the given Pod interaction if an audit output is set.
+func (c *Controller) writeAuditRecord(pi PodInteraction) {
+	if c.auditOut == nil {
+		return
+	}
+
+	record, err := pi.AuditRecord(c.auditFormat)
+	if err == nil {
+		c.auditMu.Lock()
+		_, err = fmt.Fprintln(c.auditOut, record)
+		c.auditMu.Unlock()
+	}
+	if err != nil {
+		zap.L().Error("Error in writing an audit record of a Pod interaction",
+			zap.Object("pod_interaction", &pi),
+			zap.Error(err),
+		)
+	}
+}
+
+// escapeCEFHeader escapes backslashes and pipes in a CEF header field.
+func escapeCEFHeader(val string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`).Replace(val)
+}
+
+// escapeCEFExtension escapes backslashes, equal signs and line breaks in a CEF extension value.
+func escapeCEFExtension(val string) string {
+	return strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`).Replace(val)
+}
+
+// escapeLEEFHeader escapes backslashes and pipes in a LEEF header field.
+func escapeLEEFHeader(val string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`).Replace(val)
+}
+
+// escapeLEEFAttribute escapes the tab delimiter and line breaks in a LEEF attribute value.
+func escapeLEEFAttribute(val string) string {
+	return strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\r", `\r`, "\n", `\n`).Replace(val)
+}
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// WithAuditOnly makes the controller only track and annotate interacted Pods, submitting an event instead of evicting
+// them once due, e.g. to audit interactions before enforcing evictions.
+func WithAuditOnly() Option {
+	return func(c *Controller) {
+		c.auditOnly = true
+	}
+}
+
+// adviseAuditOnly submits an event to the given Pod due in the audit-only mode set by WithAuditOnly, telling it would
+// be evicted otherwise, and returns true if in that mode.
+func (c *Controller) adviseAuditOnly(pod corev1.Pod) bool {
+	if !c.auditOnly {
+		return false
+	}
+
+	message := "Pod would be evicted now, but the controller only audits interactions"
+	// the Pod is left running regardless of failing to submit the event, which is logged in submitEvent
+	_ = c.submitEvent(&pod, EventCategoryEviction, message)
+	zap.L().Info("Skipped evicting a Pod in the audit-only mode",
+		zap.String("pod_name", pod.Name),
+		zap.String("pod_namespace", pod.Namespace),
+	)
+
+	return true
+}
+
+// leavesPodsRunning returns true if interacted Pods are left running past their termination time, i.e. in the
+// audit-only mode or if Pods owned by a StatefulSet are exempt by WithStatefulSetHandling, so their interaction
+// metadata can get stale.
+func (c *Controller) leavesPodsRunning() bool {
+	return c.auditOnly || (c.statefulSet != nil && c.statefulSet.exempt)
+}
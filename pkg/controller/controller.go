@@ -1,20 +1,28 @@
 package controller
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/record"
+
+	"github.com/box/kube-exec-controller/pkg/metrics"
+	"github.com/box/kube-exec-controller/pkg/policy"
 )
 
 // Channels for handling new Pod interactions and their extension updates.
@@ -23,6 +31,16 @@ var (
 	PodExtensionUpdateCh chan PodExtensionUpdate
 )
 
+// InteractionVerb is the kind of a Pod interaction, i.e. the kubectl command interacting with the Pod.
+type InteractionVerb string
+
+// These are the verbs of the interactions tracked by the controller.
+const (
+	InteractionVerbExec        InteractionVerb = "exec"
+	InteractionVerbAttach      InteractionVerb = "attach"
+	InteractionVerbPortForward InteractionVerb = "port-forward"
+)
+
 // PodInteraction contains information about a Pod interaction occurrence.
 type PodInteraction struct {
 	PodName       string
@@ -31,6 +49,22 @@ type PodInteraction struct {
 	Username      string
 	Commands      []string
 	InitTime      time.Time
+	// Verb is the kind of the interaction, e.g. InteractionVerbExec (empty if unknown)
+	Verb InteractionVerb
+	// Interactive is true if no command is run but attached to the running process of a container,
+	// e.g. by "kubectl attach"
+	Interactive bool
+	// EphemeralContainer is true if ContainerName is an ephemeral container of the Pod, e.g. added by "kubectl debug"
+	// to attach to, which is only known once the controller gets the Pod
+	EphemeralContainer bool
+	// Ports are the forwarded ports of the Pod if interacted by "kubectl port-forward" (empty otherwise)
+	Ports []int32
+	// ClientInfo contains metadata of the client sending the interaction request, e.g. the node a service account
+	// token is used from as set by the authenticator (empty if none available)
+	ClientInfo map[string]string
+	// Replayed is true if the interaction is replayed from a K8s API audit log rather than admitted by the webhook,
+	// which may have been recorded on its Pod by a previous replay already
+	Replayed bool
 }
 
 // MarshalLogObject makes PodInteraction struct loggable.
@@ -39,8 +73,23 @@ func (pi *PodInteraction) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddString("pod_namespace", pi.PodNamespace)
 	enc.AddString("container_name", pi.ContainerName)
 	enc.AddString("username", pi.Username)
+	enc.AddString("verb", string(pi.Verb))
 	enc.AddString("command_list", strings.Join(pi.Commands, ","))
+	enc.AddBool("interactive_session", pi.Interactive)
+	if pi.EphemeralContainer {
+		enc.AddBool("ephemeral_container", true)
+	}
+	if len(pi.Ports) > 0 {
+		if err := enc.AddReflected("ports", pi.Ports); err != nil {
+			return err
+		}
+	}
 	enc.AddTime("interacted_time", pi.InitTime)
+	if len(pi.ClientInfo) > 0 {
+		if err := enc.AddReflected("client_info", pi.ClientInfo); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -49,59 +98,127 @@ func (pi *PodInteraction) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 type PodExtensionUpdate struct {
 	Pod      corev1.Pod
 	Username string
+	// InUseToggled is true if the update only holds the Pod in use or releases it (by "kubectl pi hold/release"),
+	// without changing its extension
+	InUseToggled bool
 }
 
 // Controller ensures that interacted Pods are in the desired state.
 type Controller struct {
 	kubeClient           kubernetes.Interface
+	kubeAPITimeout       time.Duration
+	retryBackoff         retryBackoff
 	recorder             record.EventRecorder
 	podTTLDuration       time.Duration
+	interactionMetadata  metadataType
+	maxExtension         time.Duration
+	maxHold              time.Duration
 	terminationTimersMap map[types.UID]*time.Timer
+	terminationTimersMu  *sync.Mutex // guards terminationTimersMap, heldTimers and terminationTimes accessed from multiple goroutines
+	heldTimers           map[types.UID]bool
+	terminationTimes     map[types.UID]time.Time // the termination time each timer is set for, to reconcile Pod updates
+	policy               *policy.Store
+	killSwitch           *killSwitch
+	evictionAPI          *evictionAPI
+	evictionLease        *evictionLease
+	evictionWindow       *EvictionWindow
+	evictionWarning      *evictionWarning
+	statefulSet          *statefulSetHandling
+	debugJobDeletion     bool
+	podExemptSelector    labels.Selector
+	auditOut             io.Writer
+	auditFormat          AuditFormat
+	auditMu              *sync.Mutex // serializes the audit records written by multiple interaction workers
+	commandRedactions    []*regexp.Regexp
+	streamPublisher      *streamPublisher
+	notifier             *interactionNotifier
+	eventConfigs         map[EventCategory]eventConfig
+	history              *historyRingBuffer
+	justification        *justificationRequirement
+	privilegedTTL        time.Duration
+	idleTTL              bool
+	trackedPodsLimit     *trackedPodsLimit
+	leader               *leaderState
+	syncState            *syncState
+	drainState           *drainState
+
+	staleInteractionAfter        time.Duration
+	auditOnly                    bool
+	suppressTerminatingPodEvents bool
+	annotateEvictedPodOwner      bool
+	interactionWorkers           int
+	interactionDedup             *interactionDedup
+	trackedPods                  corelisters.PodLister
+}
+
+// Option configures an optional setting of the Controller.
+type Option func(*Controller)
+
+// WithPolicyStore sets a policy.Store whose ExecTrackingPolicy takes precedence over the flag values.
+func WithPolicyStore(store *policy.Store) Option {
+	return func(c *Controller) {
+		c.policy = store
+	}
 }
 
 // NewController creates a new Controller with all required components set.
-func NewController(kubeClient kubernetes.Interface, ttlSeconds int) Controller {
-	return Controller{
+func NewController(kubeClient kubernetes.Interface, ttlSeconds int, opts ...Option) Controller {
+	c := Controller{
 		kubeClient:           kubeClient,
-		recorder:             initEventRecorder(kubeClient),
+		recorder:             NewEventRecorder(kubeClient),
 		podTTLDuration:       time.Duration(ttlSeconds) * time.Second,
+		interactionMetadata:  typeLabels,
 		terminationTimersMap: make(map[types.UID]*time.Timer),
+		terminationTimersMu:  &sync.Mutex{},
+		heldTimers:           make(map[types.UID]bool),
+		terminationTimes:     make(map[types.UID]time.Time),
+		killSwitch:           newKillSwitch(),
+		evictionAPI:          &evictionAPI{},
+		evictionWarning:      newEvictionWarning(),
+		syncState:            &syncState{},
+		drainState:           &drainState{},
+		commandRedactions:    defaultRedactions,
 	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+	// the termination mode is resolved from the namespace of a Pod or the policy once its termination timer fires
+	c.evictionAPI.namespaceMode = c.getNamespaceTerminationMode
+	c.evictionAPI.policyMode = c.getPolicyTerminationMode
+
+	return c
 }
 
 // CheckPodInteraction checks both previously existed Pod interactions at startup
-// and all new interactions received from the channel with exponential backoff.
+// and all new interactions received from the channel with exponential backoff,
+// in as many workers as set by WithInteractionWorkers.
 func (c *Controller) CheckPodInteraction() {
-	ebo := backoff.NewExponentialBackOff()
-	retryNotifier := func(err error, t time.Duration) {
-		zap.L().Warn(
-			fmt.Sprintf("Failed to handle a Pod interaction, will retry in %s", t.String()),
-			zap.Error(err),
-		)
-	}
+	c.drainState.workers.Add(1)
+	defer c.drainState.workers.Done()
 
 	// check previous Pod interactions (exist before controller restarts)
-	if err := backoff.RetryNotify(c.handlePreviousInteraction, ebo, retryNotifier); err != nil {
+	ebo := c.newRetryBackOff()
+	if err := backoff.RetryNotify(c.handlePreviousInteraction, ebo, notifyInteractionRetry); err != nil {
 		zap.L().Error("Error in retrying to check previous Pod interactions, giving up!", zap.Error(err))
+		metrics.GiveUpsTotal.WithLabelValues(retryKindPreviousInteractions).Inc()
 	}
-	ebo.Reset()
+	c.setPreviousInteractionsChecked()
 
 	// check new Pod interactions received from the channel
-	for newInteraction := range PodInteractionCh {
-		retryOperation := func() error { return c.handleNewInteraction(newInteraction) }
-		if err := backoff.RetryNotify(retryOperation, ebo, retryNotifier); err != nil {
-			zap.L().Error("Error in retrying to check a new Pod interaction, giving up!",
-				zap.Object("pod_interaction", &newInteraction),
-				zap.Error(err),
-			)
-		}
-		ebo.Reset()
+	if c.interactionWorkers > 1 {
+		c.fanOutInteractions()
+		return
 	}
+	c.handleInteractions(0, PodInteractionCh)
 }
 
 // CheckPodExtensionUpdate checks Pod extension update received from the channel.
 func (c *Controller) CheckPodExtensionUpdate() {
-	ebo := backoff.NewExponentialBackOff()
+	c.drainState.workers.Add(1)
+	defer c.drainState.workers.Done()
+
+	ebo := c.newRetryBackOff()
 	retryNotifier := func(err error, t time.Duration) {
 		zap.L().Warn(
 			fmt.Sprintf("Failed to handle a Pod extension update, will retry in %s", t.String()),
@@ -110,6 +227,7 @@ func (c *Controller) CheckPodExtensionUpdate() {
 	}
 
 	for podUpdate := range PodExtensionUpdateCh {
+		c.setExtensionUpdateInProgress(&podUpdate)
 		retryOperation := func() error { return c.handlePodExtensionUpdate(podUpdate) }
 		if err := backoff.RetryNotify(retryOperation, ebo, retryNotifier); err != nil {
 			zap.L().Error("Error in retrying to check a pod extension update, giving up!",
@@ -118,7 +236,10 @@ func (c *Controller) CheckPodExtensionUpdate() {
 				zap.String("requester", podUpdate.Username),
 				zap.Error(err),
 			)
+			c.giveUpRetrying(retryKindExtensionUpdate, EventCategoryExtension, podUpdate.Pod.Namespace,
+				podUpdate.Pod.Name, err)
 		}
+		c.setExtensionUpdateInProgress(nil)
 		ebo.Reset()
 	}
 }
@@ -126,14 +247,27 @@ func (c *Controller) CheckPodExtensionUpdate() {
 // handlePodExtensionUpdate resets termination time of the Pod and annotates username who requested the extension.
 // It also submits a K8s event with all updated info to the target Pod.
 func (c *Controller) handlePodExtensionUpdate(pd PodExtensionUpdate) error {
-	// skip if no termination timer exists for the target Pod (could be expired or stopped)
+	// check if the extension can be honored if no termination timer exists for the target Pod (could be expired or
+	// stopped), only the leader keeps timers and other replicas patch the Pod for the leader to reconcile
 	pod := pd.Pod
-	if _, present := c.terminationTimersMap[pod.UID]; !present {
-		zap.L().Warn("Failed to get the termination timer of an extension updated Pod, ignoring",
-			zap.String("pod_name", pod.Name),
-			zap.String("pod_namespace", pod.Namespace),
-		)
-		return nil
+	c.terminationTimersMu.Lock()
+	_, present := c.terminationTimersMap[pod.UID]
+	c.terminationTimersMu.Unlock()
+	if !present && c.isLeading() {
+		if honored, err := c.checkUntrackedExtension(pd); err != nil || !honored {
+			return err
+		}
+	}
+
+	// pause or resume the timer if the update only holds the Pod in use or releases it
+	if pd.InUseToggled {
+		return c.handlePodInUseToggle(pod, pd.Username)
+	}
+
+	// floor a reduction once, so that the termination time stays fixed however often it is calculated
+	pod, err := c.floorReducedExtension(pod)
+	if err != nil {
+		return err
 	}
 
 	// reset the timer based on current termination metadata attached in the target Pod
@@ -141,11 +275,20 @@ func (c *Controller) handlePodExtensionUpdate(pd PodExtensionUpdate) error {
 		return err
 	}
 
-	// annotate extension requester to the target Pod
+	if err := c.adviseCappedExtension(pod, pd.Username); err != nil {
+		return err
+	}
+
+	// annotate extension requester and the updated extension history to the target Pod
+	extensionHistory, err := getExtensionHistory(pod, pd.Username)
+	if err != nil {
+		return err
+	}
 	annotationPatchMap := map[string]string{
-		PodExtendRequesterAnnotate: pd.Username,
+		PodExtendRequesterAnnotate:  pd.Username,
+		PodExtensionHistoryAnnotate: extensionHistory,
 	}
-	patchedPod, err := patch(pod, typeAnnotations, annotationPatchMap, c.kubeClient)
+	patchedPod, err := patch(pod, typeAnnotations, annotationPatchMap, c.kubeClient, c.kubeAPITimeout)
 	if err != nil {
 		return err
 	}
@@ -156,10 +299,33 @@ func (c *Controller) handlePodExtensionUpdate(pd PodExtensionUpdate) error {
 	message := fmt.Sprintf(
 		"Pod eviction time has been extended by '%s', as requested from user '%s'. New eviction time: %s",
 		newExtension, pd.Username, newTerminationTime)
-	if err := submitEvent(patchedPod, message, c.recorder); err != nil {
+	if reduction := strings.TrimPrefix(newExtension, "-"); reduction != newExtension {
+		message = fmt.Sprintf(
+			"Pod eviction time has been reduced by '%s', as requested from user '%s'. New eviction time: %s",
+			reduction, pd.Username, newTerminationTime)
+	}
+	if err := c.submitEvent(patchedPod, EventCategoryExtension, message); err != nil {
 		return err
 	}
 
+	// flag an extension requested by someone other than the original interactor for accountability
+	// the interactor is sanitized if stored as a label
+	interactor, present := GetInteractionMetadata(pod, PodInteractorLabel)
+	if present && !IsInteractor(pod, pd.Username) {
+		if err := c.flagForeignExtension(patchedPod, interactor, pd.Username); err != nil {
+			return err
+		}
+	}
+
+	metrics.ExtensionsTotal.WithLabelValues(pod.Namespace).Inc()
+	c.recordLifecycleEvent(StreamRecord{
+		Type:         StreamRecordExtension,
+		Timestamp:    time.Now(),
+		Username:     pd.Username,
+		PodNamespace: pod.Namespace,
+		PodName:      pod.Name,
+		Extension:    newExtension,
+	})
 	zap.L().Info("Updated termination time of an interacted Pod with a new extension",
 		zap.String("pod_name", pod.Name),
 		zap.String("pod_namespace", pod.Namespace),
@@ -171,16 +337,101 @@ func (c *Controller) handlePodExtensionUpdate(pd PodExtensionUpdate) error {
 	return nil
 }
 
+// checkUntrackedExtension checks the given extension update of a Pod without a termination timer (e.g. its timer has
+// expired but its eviction failed), returning true if the Pod still exists and its extended termination time is in
+// the future, so that its timer is recreated by setTermination. Otherwise, it submits a K8s event to the Pod
+// explaining why the extension is ignored, unless the Pod no longer exists.
+func (c *Controller) checkUntrackedExtension(pd PodExtensionUpdate) (bool, error) {
+	pod := pd.Pod
+	currentPod, err := getPod(c.kubeClient, pod.Namespace, pod.Name, c.kubeAPITimeout)
+	if apierrors.IsNotFound(err) || (err == nil && currentPod.UID != pod.UID) {
+		zap.L().Warn("Pod of an extension update no longer exists, ignoring",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+		)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	terminationTime, err := getTerminationTime(pod, c.getMaxExtension(pod.Namespace), c.idleTTL)
+	if err != nil {
+		zap.L().Warn("Failed to get the termination time of an extension updated Pod without a termination timer, ignoring",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+			zap.Error(err),
+		)
+		return false, nil
+	}
+	if terminationTime.After(time.Now()) {
+		zap.L().Info("Recreating the termination timer of an extension updated Pod",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+			zap.String("new_termination_time", terminationTime.String()),
+		)
+		return true, nil
+	}
+
+	message := fmt.Sprintf(
+		"Pod eviction time extension requested by user '%s' is ignored, as the extended eviction time %s has passed already",
+		pd.Username, terminationTime.String())
+	if err := c.submitEvent(currentPod, EventCategoryExtension, message); err != nil {
+		return false, err
+	}
+	zap.L().Warn("Ignored an extension of a Pod whose extended termination time has passed",
+		zap.String("pod_name", pod.Name),
+		zap.String("pod_namespace", pod.Namespace),
+		zap.String("requester_username", pd.Username),
+		zap.String("new_termination_time", terminationTime.String()),
+	)
+
+	return false, nil
+}
+
+// flagForeignExtension submits a distinct K8s event and records a metric for an extension requested by a user
+// other than the original interactor of the Pod (e.g. someone extending another person's debug Pod).
+func (c *Controller) flagForeignExtension(pod *corev1.Pod, interactor, requester string) error {
+	message := fmt.Sprintf(
+		"Pod eviction time was extended by user '%s', who is not the original interactor '%s' of the Pod",
+		requester, interactor)
+	if err := c.submitEventWithReason(pod, EventCategoryExtension, foreignPodExtensionEventReason, message); err != nil {
+		return err
+	}
+	metrics.ForeignExtensionsTotal.WithLabelValues(pod.Namespace).Inc()
+
+	zap.L().Warn("A Pod is extended by a user other than its original interactor",
+		zap.String("pod_name", pod.Name),
+		zap.String("pod_namespace", pod.Namespace),
+		zap.String("interactor_username", interactor),
+		zap.String("requester_username", requester),
+	)
+
+	return nil
+}
+
 // handlePreviousInteraction lists all running Pods that were previously interacted
-// and sets termination to them based on their current metadata.
+// and sets termination to them based on their current metadata. Any change afterwards is reconciled by
+// WatchInteractedPods.
 func (c *Controller) handlePreviousInteraction() error {
-	options := metav1.ListOptions{LabelSelector: PodInteractionTimestampLabel}
-	podList, err := c.kubeClient.CoreV1().Pods(corev1.NamespaceAll).List(context.TODO(), options)
+	// the leader checks them once elected instead
+	if !c.isLeading() {
+		return nil
+	}
+
+	pods, err := c.listInteractedPods()
 	if err != nil {
 		return err
 	}
 
-	for _, pod := range podList.Items {
+	for _, pod := range pods {
+		// skip the Pods already reconciled by WatchInteractedPods
+		if terminationTime, err := getTerminationTime(pod, c.getMaxExtension(pod.Namespace), c.idleTTL); err == nil {
+			if _, consistent := c.checkTerminationTimer(pod, terminationTime); consistent {
+				continue
+			}
+		}
+
 		if err := c.setTermination(pod); err != nil {
 			zap.L().Error("Error in setting termination timer to a previously interacted Pod, skipping.",
 				zap.String("pod_name", pod.Name),
@@ -194,94 +445,383 @@ func (c *Controller) handlePreviousInteraction() error {
 }
 
 // handleNewInteraction updates the target Pod and creates a timer to evict it later.
-// It skips if the target Pod already has an interacted timestamp label set.
+// It skips if the target Pod no longer exists, and coalesces the interaction if its Pod is interacted within the
+// window set by WithInteractionDedup.
 func (c *Controller) handleNewInteraction(pi PodInteraction) error {
-	// locate the Pod in cluster from the given PodInteraction
-	pod, err := c.kubeClient.CoreV1().Pods(pi.PodNamespace).Get(context.TODO(), pi.PodName, metav1.GetOptions{})
+	if c.coalesceInteraction(pi) {
+		return nil
+	}
+
+	// read the Pod labeled by the mutating webhook from the cache if watched, sparing a round trip on the hot path
+	// a stale cached Pod (e.g. just interacted) fails its labeling guarded by the resource version before any other
+	// effect of handling it, so the interaction is retried once the cache catches up
+	pod, cached := c.getTrackedPod(pi.PodNamespace, pi.PodName)
+	if !cached {
+		// locate the Pod in cluster from the given PodInteraction
+		var err error
+		pod, err = getPod(c.kubeClient, pi.PodNamespace, pi.PodName, c.kubeAPITimeout)
+		if apierrors.IsNotFound(err) {
+			// the Pod may have been deleted since, e.g. when replaying an interaction from the audit log
+			zap.L().Info("Pod no longer exists, ignored.",
+				zap.String("pod_name", pi.PodName),
+				zap.String("pod_namespace", pi.PodNamespace),
+			)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.handleInteractedPod(pod, pi, 1)
+}
+
+// handleInteractedPod handles the given interaction of the given Pod, which is the latest of the given number of
+// its interactions. It only counts them if the Pod already has an interacted timestamp label set, and only submits
+// events if the Pod is exempt by WithPodExemptSelector. A replayed interaction is skipped if recorded already.
+func (c *Controller) handleInteractedPod(pod *corev1.Pod, pi PodInteraction, count int) error {
+	// a Pod tracked under the previous key prefix is migrated first, so that its interaction metadata is recognized
+	pod, err := migratePreviousKeys(*pod, c.kubeClient, c.kubeAPITimeout)
 	if err != nil {
 		return err
 	}
 
-	// ignore the Pod with an existing termination label (has been checked already)
-	if val, present := pod.Labels[PodInteractionTimestampLabel]; present {
-		zap.L().Debug("Pod has already been labeled with the interaction info, ignored.",
+	if pi.Replayed {
+		if reason, skip := c.getReplaySkipReason(*pod, pi); skip {
+			zap.L().Info("Skipped a replayed Pod interaction.",
+				zap.String("reason", reason),
+				zap.Object("pod_interaction", &pi),
+			)
+			return nil
+		}
+	}
+
+	// only count the interactions of the Pod with an existing termination label (has been checked already), and
+	// reset its TTL if it counts from its latest interaction
+	// the count is added last from the Pod patched so far, so that a failure retrying the interactions never counts
+	// them twice
+	if val, present := GetInteractionMetadata(*pod, PodInteractionTimestampLabel); present {
+		if c.idleTTL {
+			if pod, err = c.resetIdleTTL(*pod, pi); err != nil {
+				return err
+			}
+		}
+		if err := c.addInteractionCount(*pod, pi, count); err != nil {
+			return err
+		}
+		c.startInteractionWindow(*pod)
+		zap.L().Debug("Pod has already been labeled with the interaction info, counted.",
 			zap.String("pod_name", pi.PodName),
 			zap.String("pod_namespace", pi.PodNamespace),
 			zap.String("pod_interaction_timestamp", val),
+			zap.Int("interaction_count", count),
 		)
 		return nil
 	}
 
+	// the webhook cannot tell an ephemeral container (e.g. added by "kubectl debug" to attach to) without the Pod
+	pi.EphemeralContainer = isEphemeralContainer(*pod, pi.ContainerName)
+
 	// submit a K8s event to the target Pod
+	verb := string(pi.Verb)
+	if verb == "" {
+		verb = "exec/attach"
+	}
 	message := fmt.Sprintf(
-		"Pod was interacted with 'kubectl exec/attach' command by a user '%s' initially at time %s",
+		"Pod was interacted with 'kubectl %s' command by a user '%s' initially at time %s",
+		verb,
 		pi.Username,
 		pi.InitTime.String(),
 	)
-	if err := submitEvent(pod, message, c.recorder); err != nil {
-		return err
+	annotations := make(map[string]string)
+	if len(pi.Commands) > 0 {
+		message += fmt.Sprintf(", running command '%s'", getEventCommand(pi.Commands, maxEventCommandLength))
+		annotations[EventInteractionCommandAnnotate] = getEventCommand(pi.Commands, maxEventCommandAnnotationLength)
+	}
+	if pi.ContainerName != "" {
+		if pi.EphemeralContainer {
+			message += fmt.Sprintf(", in the ephemeral container '%s'", pi.ContainerName)
+		} else {
+			message += fmt.Sprintf(", in the container '%s'", pi.ContainerName)
+		}
+		annotations[EventInteractionContainerAnnotate] = pi.ContainerName
+	}
+	if len(pi.Ports) > 0 {
+		message += fmt.Sprintf(", forwarding port(s) '%s'", strings.Trim(fmt.Sprint(pi.Ports), "[]"))
+	}
+	eventReason := c.getEventConfig(EventCategoryInteraction).reason
+
+	// skip tracking the Pod if it is exempt from eviction by its annotation or labels
+	if reason, exempt := c.getPodExemption(*pod); exempt {
+		if err := c.submitAnnotatedEvent(pod, EventCategoryInteraction, eventReason, message, annotations); err != nil {
+			return err
+		}
+		message := fmt.Sprintf("%s, it will not be evicted", reason)
+		if err := c.submitEvent(pod, EventCategoryInteraction, message); err != nil {
+			return err
+		}
+
+		c.writeAuditRecord(pi)
+		c.recordLifecycleEvent(pi.streamRecord())
+		c.notifyInteraction(pi, nil)
+		zap.L().Info("A new interaction of an exempt Pod is detected, skipped its eviction.",
+			zap.Object("pod_interaction", &pi),
+		)
+		return nil
 	}
 
 	// set interaction related metadata to the target Pod
-	updatedPod, err := c.setInteractionLabels(*pod, pi)
+	ttl, err := c.getInteractionTTL(*pod, pi)
 	if err != nil {
 		return err
 	}
+	// the Pod is labeled first, so that nothing else is done for a Pod changed since read
+	updatedPod, err := c.setInteractionLabels(*pod, pi, ttl, count)
+	if err != nil {
+		return err
+	}
+	if err := c.submitAnnotatedEvent(updatedPod, EventCategoryInteraction, eventReason, message,
+		annotations); err != nil {
+		return err
+	}
+	if updatedPod, err = c.setInteractionAnnotations(*updatedPod, pi, count); err != nil {
+		return err
+	}
 
 	// set termination timer based on the above metadata
 	if err := c.setTermination(*updatedPod); err != nil {
 		return err
 	}
+	// the interaction is handled already, so it is not retried for failing to enforce the limit
+	if err := c.enforceMaxTrackedPods(pi.Username, updatedPod.UID); err != nil {
+		zap.L().Error("Failed to enforce the max number of tracked Pods of a user.",
+			zap.String("interactor", pi.Username),
+			zap.Error(err),
+		)
+	}
 
+	c.startInteractionWindow(*updatedPod)
+	c.writeAuditRecord(pi)
+	c.recordLifecycleEvent(pi.streamRecord())
+	c.notifyInteraction(pi, updatedPod)
 	zap.L().Info("A new Pod interaction is detected and handled.", zap.Object("pod_interaction", &pi))
 
 	return nil
 }
 
-// setInteractionLabels patches interaction related info as labels to the target Pod.
-func (c *Controller) setInteractionLabels(pod corev1.Pod, pi PodInteraction) (*corev1.Pod, error) {
+// setInteractionLabels patches interaction related info and the given TTL as labels (or annotations if set by
+// WithInteractionMetadata) to the target Pod, unless it has changed since read (e.g. it has been interacted in the
+// meantime).
+func (c *Controller) setInteractionLabels(pod corev1.Pod, pi PodInteraction, ttl time.Duration, count int) (
+	*corev1.Pod, error) {
 	timestamp := strconv.FormatInt(pi.InitTime.Unix(), 10)
 	labelsPatchMap := map[string]string{
 		PodInteractionTimestampLabel: timestamp,
 		PodInteractorLabel:           pi.Username,
-		PodTTLDurationLabel:          c.podTTLDuration.String(),
+		PodTTLDurationLabel:          ttl.String(),
+	}
+	return patchIfUnchanged(pod, c.interactionMetadata, labelsPatchMap, c.kubeClient, c.kubeAPITimeout)
+}
+
+// setInteractionAnnotations patches the given number of interactions, the user of the interaction and its client
+// metadata if any as annotations to the target Pod, in a single patch.
+func (c *Controller) setInteractionAnnotations(pod corev1.Pod, pi PodInteraction, count int) (*corev1.Pod, error) {
+	annotationPatchMap := map[string]string{
+		PodInteractionCountAnnotate: strconv.Itoa(count),
+		PodLastInteractorAnnotate:   pi.Username,
+	}
+	if len(pi.ClientInfo) > 0 {
+		clientInfo, err := json.Marshal(pi.ClientInfo)
+		if err != nil {
+			return nil, err
+		}
+		annotationPatchMap[PodInteractorClientAnnotate] = string(clientInfo)
 	}
-	return patch(pod, typeLabels, labelsPatchMap, c.kubeClient)
+	return patch(pod, typeAnnotations, annotationPatchMap, c.kubeClient, c.kubeAPITimeout)
 }
 
 // setTermination patches termination time as annotation to the target Pod and sets a timer
 // in controller to evict the Pod. It calculates the termination time from Pod's metadata.
 func (c *Controller) setTermination(pod corev1.Pod) error {
-	terminationTime, err := getTerminationTime(pod)
+	_, err := c.updateTermination(pod)
+	return err
+}
+
+// updateTermination sets the termination of the target Pod like setTermination, returning the patched Pod.
+func (c *Controller) updateTermination(pod corev1.Pod) (*corev1.Pod, error) {
+	terminationTime, err := getTerminationTime(pod, c.getMaxExtension(pod.Namespace), c.idleTTL)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	annotationPatchMap := map[string]string{
 		PodTerminationTimeAnnotate: terminationTime.String(),
 	}
-	if _, err := patch(pod, typeAnnotations, annotationPatchMap, c.kubeClient); err != nil {
-		return err
+	updatedPod, err := patch(pod, typeAnnotations, annotationPatchMap, c.kubeClient, c.kubeAPITimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the leader sets timers, reconciling the Pod patched by other replicas
+	if !c.isLeading() {
+		return updatedPod, nil
+	}
+	c.trackedPodsLimit.track(pod)
+
+	// pause the timer while the Pod is held in use, at most until its max hold expires
+	if heldUntil, held := c.isPodHeld(pod); held {
+		c.holdTerminationTimer(pod, heldUntil)
+		c.stopEvictionWarning(pod.UID)
+		c.setTerminationTimeRecord(pod.UID, terminationTime)
+		return updatedPod, nil
 	}
 
 	// create or reset a timer to evict the target Pod with currently remaining duration
 	remainDuration := time.Until(terminationTime)
-	if timer, present := c.terminationTimersMap[pod.UID]; present {
-		if success := timer.Reset(remainDuration); !success {
-			zap.L().Warn("Failed to reset termination timer in a Pod (either expired or stopped)",
-				zap.String("pod_name", pod.Name),
-				zap.String("pod_namespace", pod.Namespace),
-			)
-			return nil
-		}
-	} else {
-		newTimer := time.AfterFunc(remainDuration, evictPodFunc(pod.Name, pod.Namespace, c.kubeClient))
-		c.terminationTimersMap[pod.UID] = newTimer
+	if success := c.setTerminationTimer(pod, remainDuration); !success {
+		zap.L().Warn("Failed to reset termination timer in a Pod (either expired or stopped)",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+		)
+		return updatedPod, nil
 	}
+	c.setTerminationTimeRecord(pod.UID, terminationTime)
+	c.setEvictionWarning(pod, terminationTime)
 
 	// submit a K8s event to the Pod with its termination time
 	message := fmt.Sprintf("Pod will be evicted at time %s (in about %s)",
 		terminationTime.String(),
 		remainDuration.Round(time.Second).String(),
 	)
-	return submitEvent(&pod, message, c.recorder)
+	if err := c.submitEvent(&pod, EventCategoryEviction, message); err != nil {
+		return nil, err
+	}
+
+	return updatedPod, nil
+}
+
+// setTerminationTimer creates a timer to evict the given Pod after the given duration, or resets the existing one.
+// It returns false if the existing timer cannot be reset as it has expired or been stopped, unless held in use or
+// deferred by the kill switch.
+func (c *Controller) setTerminationTimer(pod corev1.Pod, duration time.Duration) bool {
+	c.terminationTimersMu.Lock()
+	defer c.terminationTimersMu.Unlock()
+
+	// the timer paused by holdTerminationTimer or expired while the kill switch is on cannot be reset, so it is replaced
+	if deferred := c.killSwitch.drop(pod.UID); c.heldTimers[pod.UID] || deferred {
+		delete(c.heldTimers, pod.UID)
+		if timer, present := c.terminationTimersMap[pod.UID]; present {
+			timer.Stop()
+		}
+		c.terminationTimersMap[pod.UID] = c.newTerminationTimer(pod, duration)
+		return true
+	}
+	if timer, present := c.terminationTimersMap[pod.UID]; present {
+		return timer.Reset(duration)
+	}
+	c.terminationTimersMap[pod.UID] = c.newTerminationTimer(pod, duration)
+	metrics.TerminationTimers.Set(float64(len(c.terminationTimersMap)))
+
+	return true
+}
+
+// replaceTerminationTimer sets a new timer to evict the given Pod after the given duration, replacing the existing one.
+func (c *Controller) replaceTerminationTimer(pod corev1.Pod, duration time.Duration) {
+	c.terminationTimersMu.Lock()
+	defer c.terminationTimersMu.Unlock()
+
+	c.killSwitch.drop(pod.UID)
+	c.terminationTimersMap[pod.UID] = c.newTerminationTimer(pod, duration)
+	metrics.TerminationTimers.Set(float64(len(c.terminationTimersMap)))
+}
+
+// newTerminationTimer returns a timer to evict the given Pod after the given duration. It must be called with
+// terminationTimersMu held, and the timer stored in terminationTimersMap before releasing it.
+func (c *Controller) newTerminationTimer(pod corev1.Pod, duration time.Duration) *time.Timer {
+	var timer *time.Timer
+	// the timer is only read by the fired function with terminationTimersMu held, so it is assigned by then
+	timer = time.AfterFunc(duration, c.terminatePodFunc(pod, func() *time.Timer { return timer }))
+	return timer
+}
+
+// terminatePodFunc returns a function to evict the given Pod, which is deferred while the kill switch is on.
+// The given func returns the timer firing it, which is removed once the Pod is evicted, or re-armed with backoff
+// if the eviction fails, unless replaced meanwhile.
+// A Pod owned by a StatefulSet is evicted gracefully or exempt, if set by WithStatefulSetHandling.
+// A Pod is left running in the audit-only mode, if set by WithAuditOnly.
+// The owner of an evicted Pod is annotated with the eviction, if set by WithEvictedPodOwnerAnnotation.
+// The owning Job of a debug Job's Pod is deleted instead, if set by WithDebugJobDeletion.
+func (c *Controller) terminatePodFunc(pod corev1.Pod, firedTimer func() *time.Timer) func() {
+	evictPod := evictPodFunc(pod, c.kubeClient, c.evictionAPI, c.evictionLease, c.statefulSet.getGracePeriod(pod))
+	if job := getDebugJobOwner(pod); c.debugJobDeletion && job != "" {
+		// evicting the Pod alone would make its Job recreate it
+		evictPod = c.deleteJobFunc(pod, job)
+	}
+	failures := 0
+	evict := func() {
+		if !evictPod() {
+			// the eviction may succeed later, e.g. once permitted by RBAC or a PodDisruptionBudget
+			failures++
+			c.retryTermination(pod, firedTimer, failures)
+			return
+		}
+
+		evictedTime := time.Now()
+		c.recordLifecycleEvent(StreamRecord{
+			Type:         StreamRecordEviction,
+			Timestamp:    evictedTime,
+			PodNamespace: pod.Namespace,
+			PodName:      pod.Name,
+		})
+		if c.annotateEvictedPodOwner {
+			if err := c.annotateWorkloadOwner(pod, evictedTime); err != nil {
+				zap.L().Warn("Failed to annotate the owner of an evicted Pod.",
+					zap.String("pod_name", pod.Name),
+					zap.String("pod_namespace", pod.Namespace),
+					zap.Error(err),
+				)
+			}
+		}
+		// the timer has fired, so it is no longer needed
+		c.deleteFiredTerminationTimer(pod.UID, firedTimer)
+	}
+
+	return func() {
+		// the leadership may be lost while the timer is firing
+		if !c.isLeading() {
+			return
+		}
+
+		// other replicas may have extended the Pod without this replica's timer being reset
+		if c.evictionLease != nil && c.postponeIfExtended(pod) {
+			return
+		}
+
+		if c.evictionWindow != nil && c.deferToEvictionWindow(pod) {
+			return
+		}
+
+		if c.adviseStatefulSetPod(pod) {
+			zap.L().Info("Skipped evicting a Pod as it is owned by a StatefulSet",
+				zap.String("pod_name", pod.Name),
+				zap.String("pod_namespace", pod.Namespace),
+			)
+			c.deleteFiredTerminationTimer(pod.UID, firedTimer)
+			return
+		}
+
+		if c.adviseAuditOnly(pod) {
+			c.deleteFiredTerminationTimer(pod.UID, firedTimer)
+			return
+		}
+
+		if c.killSwitch.deferIfDisabled(pod) {
+			zap.L().Warn("Deferred evicting a Pod as the kill switch is on",
+				zap.String("pod_name", pod.Name),
+				zap.String("pod_namespace", pod.Namespace),
+			)
+			return
+		}
+
+		evict()
+	}
 }
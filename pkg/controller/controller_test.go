@@ -2,19 +2,47 @@ package controller_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/box/kube-exec-controller/pkg/controller"
+	"github.com/box/kube-exec-controller/pkg/metrics"
+	"github.com/box/kube-exec-controller/pkg/policy"
 )
 
 // TestCheckPodInteraction tests controller checking both previously and newly interacted pods
@@ -28,6 +56,8 @@ func TestCheckPodInteraction(t *testing.T) {
 	// create a previously (e.g. controller is restarted) interacted pod by setting related labels to it
 	previousInteractedPodName := "test-pod-previous"
 	previousInteractedPod := getPodObject(namespace, previousInteractedPodName)
+	// UID is used for setting termination timer by the controller, so each pod needs its own one
+	previousInteractedPod.SetUID(types.UID(previousInteractedPodName))
 	previousInteractedPod.SetLabels(map[string]string{
 		controller.PodInteractionTimestampLabel: strconv.FormatInt(interactedTime.Unix(), 10),
 		controller.PodTTLDurationLabel:          ttlDuration.String(),
@@ -38,6 +68,7 @@ func TestCheckPodInteraction(t *testing.T) {
 	interactedUsername := "test-user"
 	mockPodInteraction(namespace, newInteractedPodName, interactedUsername, interactedTime)
 	newInteractedPod := getPodObject(namespace, newInteractedPodName)
+	newInteractedPod.SetUID(types.UID(newInteractedPodName))
 
 	fakeClient := fake.NewSimpleClientset(previousInteractedPod, newInteractedPod)
 	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()))
@@ -59,6 +90,8 @@ func TestCheckPodInteraction(t *testing.T) {
 		controller.PodTerminationTimeAnnotate: terminationTime.String(),
 	}
 	checkDeepEquals(t, expectedAnnotations, previousInteractedPod.GetAnnotations())
+	expectedAnnotations[controller.PodLastInteractorAnnotate] = interactedUsername
+	expectedAnnotations[controller.PodInteractionCountAnnotate] = "1"
 	checkDeepEquals(t, expectedAnnotations, newInteractedPod.GetAnnotations())
 
 	// verify labels (the newly interacted pod should have its labels updated)
@@ -69,12 +102,9 @@ func TestCheckPodInteraction(t *testing.T) {
 	}
 	checkDeepEquals(t, expectedLabels, newInteractedPod.GetLabels())
 
-	// verify both interacted pods are evicted by the controller (kube client should return errors)
-	time.Sleep(ttlDuration)
-	pods, err := fakeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err == nil {
-		t.Fatal("expected an error accessting to evicted, but got", pods)
-	}
+	// verify both interacted pods are evicted by the controller
+	waitForEviction(t, fakeClient, previousInteractedPodName)
+	waitForEviction(t, fakeClient, newInteractedPodName)
 }
 
 // TestCheckPodInteraction tests controller checking an extension of interacted pod
@@ -128,51 +158,4233 @@ func TestCheckPodExtension(t *testing.T) {
 	// verify the pod's annotation contains extension info set by the controller
 	terminationTime := interactedTime.Add(ttlDuration).Add(extendDuration).Truncate(time.Second)
 	expectedAnnotaitons := map[string]string{
-		controller.PodTerminationTimeAnnotate: terminationTime.String(),
-		controller.PodExtendRequesterAnnotate: extendRequester,
+		controller.PodTerminationTimeAnnotate:  terminationTime.String(),
+		controller.PodExtendRequesterAnnotate:  extendRequester,
+		controller.PodLastInteractorAnnotate:   "",
+		controller.PodInteractionCountAnnotate: "1",
+		controller.PodExtensionHistoryAnnotate: getExtensionHistory(t, *extendedTestPod, controller.ExtensionRecord{
+			Requester: extendRequester,
+			Duration:  extendDuration.String(),
+		}),
 	}
 	checkDeepEquals(t, expectedAnnotaitons, extendedTestPod.GetAnnotations())
 }
 
-/*
-  Helper functions used by the testings above.
-*/
+// TestCheckPodExtensionWithoutTimer tests controller checking extensions of still existing pods whose termination
+// timers were stopped, honoring the one extending a pod into the future and explaining the ignored one
+func TestCheckPodExtensionWithoutTimer(t *testing.T) {
+	setupZapLogging(t)
 
-// setupZapLogging gives better visibility when running a test
-func setupZapLogging(t *testing.T) {
-	logger := zaptest.NewLogger(t)
-	zap.ReplaceGlobals(logger)
+	namespace := "test-namespace-untracked-extension"
+	ttlDuration := time.Hour
+	interactedTime := time.Now().Add(-2 * ttlDuration)
+	getExtendedPod := func(podName string, extendDuration time.Duration) *corev1.Pod {
+		podObj := getPodObject(namespace, podName)
+		podObj.SetUID(types.UID(podName))
+		podObj.SetLabels(map[string]string{
+			controller.PodInteractionTimestampLabel: strconv.FormatInt(interactedTime.Unix(), 10),
+			controller.PodInteractorLabel:           "test-user",
+			controller.PodTTLDurationLabel:          ttlDuration.String(),
+		})
+		podObj.SetAnnotations(map[string]string{
+			controller.PodExtendDurationAnnotate: extendDuration.String(),
+		})
+		return podObj
+	}
+	// the termination time of the honored pod is extended into the future, the ignored one's remains in the past
+	honoredPod := getExtendedPod("test-pod-honored", 2*ttlDuration)
+	ignoredPod := getExtendedPod("test-pod-ignored", ttlDuration/2)
+
+	// the controller keeps no termination timer of the pods, as if their timers were stopped
+	fakeClient := fake.NewSimpleClientset(honoredPod, ignoredPod)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()), controller.WithEventRecorder(fakeRecorder))
+
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate, 2)
+	for _, pod := range []*corev1.Pod{honoredPod, ignoredPod} {
+		controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: *pod, Username: "test-requester"}
+	}
+	close(controller.PodExtensionUpdateCh)
+	contr.CheckPodExtensionUpdate()
+
+	// verify the extension of the honored pod recreates its timer at the extended termination time
+	if !contr.HasTerminationTimer(honoredPod.UID) {
+		t.Error("expected a termination timer recreated for the honored pod")
+	}
+	pod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), honoredPod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	terminationTime := interactedTime.Add(3 * ttlDuration).Truncate(time.Second)
+	checkDeepEquals(t, terminationTime.String(), pod.Annotations[controller.PodTerminationTimeAnnotate])
+	checkDeepEquals(t, "test-requester", pod.Annotations[controller.PodExtendRequesterAnnotate])
+
+	// verify the extension of the ignored pod is explained by an event, without recreating its timer
+	if contr.HasTerminationTimer(ignoredPod.UID) {
+		t.Error("expected no termination timer recreated for the ignored pod")
+	}
+	checkEventSubmitted(t, fakeRecorder, "Pod eviction time extension requested by user 'test-requester' is ignored")
 }
 
-// mockPodInteraction sends a new PodInteraction with the given namespace and pod name to PodInteractionCh
-func mockPodInteraction(namespace, podName, interactor string, interactedTime time.Time) {
-	podInteraction := controller.PodInteraction{
-		PodNamespace: namespace,
-		PodName:      podName,
-		InitTime:     interactedTime,
-		Username:     interactor,
+// TestCheckPodInteractionWithPolicy tests controller applying an ExecTrackingPolicy over its flag values
+func TestCheckPodInteractionWithPolicy(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	interactedTime := time.Now()
+	flagTTLDuration := time.Duration(2) * time.Second
+	policyTTLDuration := time.Duration(1) * time.Hour
+	policyMaxExtension := time.Duration(30) * time.Minute
+
+	store := policy.NewStore()
+	store.Set(&policy.ExecTrackingPolicy{
+		Spec: policy.ExecTrackingPolicySpec{
+			TTL:          policyTTLDuration.String(),
+			MaxExtension: policyMaxExtension.String(),
+		},
+	})
+
+	mockPodInteraction(namespace, podName, "test-user", interactedTime)
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	contr := controller.NewController(fakeClient, int(flagTTLDuration.Seconds()), controller.WithPolicyStore(store))
+	contr.CheckPodInteraction()
+
+	// verify the pod is labeled with the TTL from policy and not evicted after the flag TTL
+	time.Sleep(flagTTLDuration)
+	interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("expected the pod still exists, but failed to get it with err:", err)
 	}
+	checkDeepEquals(t, policyTTLDuration.String(), interactedPod.Labels[controller.PodTTLDurationLabel])
+	terminationTime := interactedTime.Add(policyTTLDuration).Truncate(time.Second)
+	checkDeepEquals(t, terminationTime.String(), interactedPod.Annotations[controller.PodTerminationTimeAnnotate])
 
-	controller.PodInteractionCh = make(chan controller.PodInteraction)
+	// verify a requested extension is capped to the max extension from policy
+	interactedPod.Annotations[controller.PodExtendDurationAnnotate] = (2 * policyMaxExtension).String()
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate)
 	go func() {
-		defer close(controller.PodInteractionCh)
+		defer close(controller.PodExtensionUpdateCh)
 
-		controller.PodInteractionCh <- podInteraction
+		controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: *interactedPod, Username: "test-user"}
 	}()
+	contr.CheckPodExtensionUpdate()
+
+	extendedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	terminationTime = interactedTime.Add(policyTTLDuration).Add(policyMaxExtension).Truncate(time.Second)
+	checkDeepEquals(t, terminationTime.String(), extendedPod.Annotations[controller.PodTerminationTimeAnnotate])
 }
 
-// getPodObject returns a new corev1.Pod object with tbe given namespace and pod name
-func getPodObject(namespace, podName string) *corev1.Pod {
-	return &corev1.Pod{
+// TestKillSwitch tests controller pausing evictions while the kill switch is on and resuming them once cleared
+func TestKillSwitch(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	configNamespace := "test-config-namespace"
+	podName := "test-pod"
+	ttlDuration := time.Duration(1) * time.Second
+
+	killSwitchConfigMap := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      podName,
-			Namespace: namespace,
+			Name:      controller.KillSwitchConfigMapName,
+			Namespace: configNamespace,
 		},
+		Data: map[string]string{"disabled": "true"},
+	}
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj, killSwitchConfigMap)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()))
+	contr.WatchKillSwitch(configNamespace, stopCh)
+	contr.CheckPodInteraction()
+
+	// verify the pod is not evicted after its TTL while the kill switch is on
+	time.Sleep(2 * ttlDuration)
+	if evictions := getEvictedPodNames(fakeClient); len(evictions) != 0 {
+		t.Fatal("expected no eviction while the kill switch is on, but got", evictions)
+	}
+	checkDeepEquals(t, float64(1), testutil.ToFloat64(metrics.EvictionDisabled))
+
+	// verify the deferred eviction is resumed once the kill switch is cleared
+	killSwitchConfigMap.Data["disabled"] = "false"
+	_, err := fakeClient.CoreV1().ConfigMaps(configNamespace).Update(context.TODO(), killSwitchConfigMap, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
 	}
+	waitForEviction(t, fakeClient, podName)
+	checkDeepEquals(t, float64(0), testutil.ToFloat64(metrics.EvictionDisabled))
 }
 
-func checkDeepEquals(t *testing.T, expected, actual interface{}) {
-	if !reflect.DeepEqual(expected, actual) {
-		t.Errorf("expected: %s, got: %s", expected, actual)
+// TestKillSwitchExtendedPod tests controller not resuming the deferred eviction of a pod extended while the kill switch
+// is on, re-arming its timer with the extended termination time instead
+func TestKillSwitchExtendedPod(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-kill-switch-extension"
+	configNamespace := "test-config-namespace"
+	podName := "test-pod"
+	ttlDuration := time.Duration(1) * time.Second
+
+	killSwitchConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controller.KillSwitchConfigMapName,
+			Namespace: configNamespace,
+		},
+		Data: map[string]string{"disabled": "true"},
+	}
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj, killSwitchConfigMap)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()))
+	contr.WatchKillSwitch(configNamespace, stopCh)
+	contr.CheckPodInteraction()
+
+	// extend the pod after its eviction is deferred by the kill switch
+	time.Sleep(2 * ttlDuration)
+	interactedTestPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interactedTestPod.Annotations[controller.PodExtendDurationAnnotate] = "1h"
+	// the update admitted by the webhook is persisted before the controller handles it
+	interactedTestPod, err = fakeClient.CoreV1().Pods(namespace).Update(context.TODO(), interactedTestPod,
+		metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate, 1)
+	controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: *interactedTestPod, Username: "test-user"}
+	close(controller.PodExtensionUpdateCh)
+	contr.CheckPodExtensionUpdate()
+
+	// verify the extended pod is not evicted once the kill switch is cleared, while its timer is kept
+	killSwitchConfigMap.Data["disabled"] = "false"
+	_, err = fakeClient.CoreV1().ConfigMaps(configNamespace).Update(context.TODO(), killSwitchConfigMap, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(ttlDuration)
+	if evictions := getEvictedPodNames(fakeClient); len(evictions) != 0 {
+		t.Fatal("expected no eviction of the extended pod once the kill switch is cleared, but got", evictions)
+	}
+	if !contr.HasTerminationTimer(podObj.UID) {
+		t.Error("expected the termination timer of the extended pod kept, but got none")
+	}
+}
+
+// TestEvictionWindow tests controller deferring an eviction outside the eviction window to the window start
+func TestEvictionWindow(t *testing.T) {
+	setupZapLogging(t)
+
+	// verify invalid eviction windows are rejected
+	for _, window := range []string{"", "09:00", "9am-6pm", "09:00-24:30", "09:00-09:00"} {
+		if _, err := controller.ParseEvictionWindow(window, "UTC"); err == nil {
+			t.Errorf("expected an error parsing eviction window %q, got nil", window)
+		}
+	}
+	if _, err := controller.ParseEvictionWindow("09:00-18:00", "Invalid/Timezone"); err == nil {
+		t.Error("expected an error parsing an invalid timezone, got nil")
+	}
+
+	// set an eviction window opening in 2 hours, so the current time is out of it
+	windowStart := time.Now().UTC().Add(2 * time.Hour).Truncate(time.Minute)
+	windowEnd := windowStart.Add(time.Hour)
+	window, err := controller.ParseEvictionWindow(
+		fmt.Sprintf("%s-%s", windowStart.Format("15:04"), windowEnd.Format("15:04")),
+		"UTC",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	ttlDuration := time.Duration(1) * time.Second
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithEvictionWindow(window),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+	contr.CheckPodInteraction()
+
+	// verify the pod is not evicted after its TTL, but deferred to the window start
+	time.Sleep(2 * ttlDuration)
+	if evictions := getEvictedPodNames(fakeClient); len(evictions) != 0 {
+		t.Fatal("expected no eviction outside the eviction window, but got", evictions)
+	}
+
+	expectedMessage := fmt.Sprintf("Pod eviction is deferred to %s as it is outside the eviction window %s",
+		windowStart.String(), window.String())
+	checkEventSubmitted(t, fakeRecorder, expectedMessage)
+}
+
+// TestEvictionAPIVersion tests controller evicting pods with the Eviction API version served by the cluster
+func TestEvictionAPIVersion(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	ttlDuration := time.Duration(1) * time.Second
+	for _, version := range []string{"v1", "v1beta1"} {
+		mockPodInteraction(namespace, podName, "test-user", time.Now())
+		podObj := getPodObject(namespace, podName)
+		podObj.SetUID(types.UID(podName))
+		fakeClient := fake.NewSimpleClientset(podObj)
+		fakeClient.Resources = []*metav1.APIResourceList{
+			{
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{
+					{Name: "pods", Kind: "Pod"},
+					{Name: "pods/eviction", Kind: "Eviction", Group: "policy", Version: version},
+				},
+			},
+		}
+		contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()))
+		contr.CheckPodInteraction()
+		waitForEviction(t, fakeClient, podName)
+
+		// verify the eviction is issued against the served API version
+		for _, action := range fakeClient.Actions() {
+			if action.GetVerb() != "create" || action.GetSubresource() != "eviction" {
+				continue
+			}
+
+			eviction := action.(k8stesting.CreateAction).GetObject()
+			switch eviction.(type) {
+			case *policyv1.Eviction:
+				checkDeepEquals(t, "v1", version)
+			case *policyv1beta1.Eviction:
+				checkDeepEquals(t, "v1beta1", version)
+			default:
+				t.Errorf("unexpected eviction object %T", eviction)
+			}
+		}
+	}
+}
+
+// TestEvictionAPIVersionFallback tests controller detecting the Eviction API version again once failed to, and falling
+// back to policy/v1beta1 if policy/v1 is not found meanwhile
+func TestEvictionAPIVersionFallback(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podNames := []string{"test-pod-1", "test-pod-2"}
+	var podObjs []runtime.Object
+	for _, podName := range podNames {
+		podObj := getPodObject(namespace, podName)
+		podObj.SetUID(types.UID(podName))
+		podObjs = append(podObjs, podObj)
+	}
+	// the discovery fails as no resources are served, while only policy/v1beta1 evicts the pods
+	fakeClient := fake.NewSimpleClientset(podObjs...)
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		if _, v1 := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction); v1 {
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Group: "policy", Resource: "evictions"}, "")
+		}
+		return true, nil, nil
+	})
+	contr := controller.NewController(fakeClient, 1)
+
+	// verify the first pod is evicted with policy/v1beta1 once policy/v1 is not found
+	mockPodInteraction(namespace, podNames[0], "test-user", time.Now())
+	contr.CheckPodInteraction()
+	waitForTimerRemoval(t, &contr, types.UID(podNames[0]))
+	getEvictionVersions := func(podName string) []string {
+		var versions []string
+		for _, action := range fakeClient.Actions() {
+			if action.GetVerb() != "create" || action.GetSubresource() != "eviction" {
+				continue
+			}
+			eviction := action.(k8stesting.CreateAction).GetObject()
+			if eviction.(metav1.Object).GetName() != podName {
+				continue
+			}
+			if _, v1 := eviction.(*policyv1.Eviction); v1 {
+				versions = append(versions, "v1")
+			} else {
+				versions = append(versions, "v1beta1")
+			}
+		}
+		return versions
+	}
+	checkDeepEquals(t, []string{"v1", "v1beta1"}, getEvictionVersions(podNames[0]))
+
+	// verify the next pod is evicted with policy/v1beta1 right away
+	mockPodInteraction(namespace, podNames[1], "test-user", time.Now())
+	contr.CheckPodInteraction()
+	waitForTimerRemoval(t, &contr, types.UID(podNames[1]))
+	checkDeepEquals(t, []string{"v1beta1"}, getEvictionVersions(podNames[1]))
+}
+
+// TestTerminationMode tests controller evicting or deleting pods in either termination mode
+func TestTerminationMode(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	ttlDuration := time.Duration(1) * time.Second
+	for _, name := range []string{"evict", "delete"} {
+		mode, err := controller.ParseTerminationMode(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mockPodInteraction(namespace, podName, "test-user", time.Now())
+		podObj := getPodObject(namespace, podName)
+		podObj.SetUID(types.UID(podName))
+		fakeClient := fake.NewSimpleClientset(podObj)
+		contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+			controller.WithTerminationMode(mode, 30*time.Second),
+		)
+		contr.CheckPodInteraction()
+		waitForTermination(t, fakeClient, podName, mode)
+		waitForTimerRemoval(t, &contr, podObj.UID)
+
+		// verify the pod is terminated through the API of the mode only, exactly once
+		if mode == controller.TerminationModeDelete {
+			checkDeepEquals(t, []string{podName}, getDeletedPodNames(fakeClient))
+			checkDeepEquals(t, 0, len(getEvictedPodNames(fakeClient)))
+		} else {
+			checkDeepEquals(t, 0, len(getDeletedPodNames(fakeClient)))
+			checkDeepEquals(t, []string{podName}, getEvictedPodNames(fakeClient))
+		}
+	}
+
+	if _, err := controller.ParseTerminationMode("kill"); err == nil {
+		t.Error("expected an error parsing an unsupported termination mode, but got none")
+	}
+}
+
+// TestPolicyTerminationAction tests controller terminating pods by the termination action of the ExecTrackingPolicy
+// over the termination mode flag, unless overridden by the namespace annotation
+func TestPolicyTerminationAction(t *testing.T) {
+	setupZapLogging(t)
+
+	ttlDuration := time.Duration(1) * time.Second
+	store := policy.NewStore()
+	store.Set(&policy.ExecTrackingPolicy{
+		Spec: policy.ExecTrackingPolicySpec{TerminationAction: policy.TerminationActionDelete},
+	})
+
+	policyNamespace := "test-namespace-policy"
+	annotatedNamespace := "test-namespace-annotated"
+	annotatedNsObj := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        annotatedNamespace,
+		Annotations: map[string]string{controller.NamespaceTerminationModeAnnotate: "evict"},
+	}}
+	var objects []runtime.Object
+	for _, namespace := range []string{policyNamespace, annotatedNamespace} {
+		podObj := getPodObject(namespace, "test-pod")
+		podObj.SetUID(types.UID(namespace))
+		podObj.SetLabels(map[string]string{
+			controller.PodInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10),
+			controller.PodTTLDurationLabel:          ttlDuration.String(),
+		})
+		objects = append(objects, podObj)
+	}
+	fakeClient := fake.NewSimpleClientset(append(objects, annotatedNsObj)...)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithTerminationMode(controller.TerminationModeEvict, 0),
+		controller.WithPolicyStore(store),
+	)
+	controller.PodInteractionCh = make(chan controller.PodInteraction)
+	close(controller.PodInteractionCh)
+	contr.CheckPodInteraction()
+	waitForTimerRemoval(t, &contr, types.UID(policyNamespace))
+	waitForTimerRemoval(t, &contr, types.UID(annotatedNamespace))
+
+	// verify only the pod in the namespace not annotated is deleted by the policy rather than evicted
+	var deletedNamespaces, evictedNamespaces []string
+	for _, action := range fakeClient.Actions() {
+		if action.GetVerb() == "delete" && action.GetResource().Resource == "pods" {
+			deletedNamespaces = append(deletedNamespaces, action.GetNamespace())
+		}
+		if action.GetVerb() == "create" && action.GetSubresource() == "eviction" {
+			evictedNamespaces = append(evictedNamespaces, action.GetNamespace())
+		}
+	}
+	checkDeepEquals(t, []string{policyNamespace}, deletedNamespaces)
+	checkDeepEquals(t, []string{annotatedNamespace}, evictedNamespaces)
+}
+
+// TestPolicyChangeTrackedPods tests a change of the ExecTrackingPolicy, here of the NamespacedExecTrackingPolicy of
+// a namespace, applying to the pods already tracked in it by the time they are terminated
+func TestPolicyChangeTrackedPods(t *testing.T) {
+	setupZapLogging(t)
+
+	ttlDuration := time.Duration(1) * time.Second
+	store := policy.NewStore()
+	policyNamespace := "test-namespace-policy-change"
+	otherNamespace := "test-namespace-policy-unchanged"
+	var objects []runtime.Object
+	for _, namespace := range []string{policyNamespace, otherNamespace} {
+		podObj := getPodObject(namespace, "test-pod")
+		podObj.SetUID(types.UID(namespace))
+		podObj.SetLabels(map[string]string{
+			controller.PodInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10),
+			controller.PodTTLDurationLabel:          ttlDuration.String(),
+		})
+		objects = append(objects, podObj)
+	}
+	fakeClient := fake.NewSimpleClientset(objects...)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithTerminationMode(controller.TerminationModeEvict, 0),
+		controller.WithPolicyStore(store),
+	)
+	controller.PodInteractionCh = make(chan controller.PodInteraction)
+	close(controller.PodInteractionCh)
+	contr.CheckPodInteraction()
+	if !contr.HasTerminationTimer(types.UID(policyNamespace)) || !contr.HasTerminationTimer(types.UID(otherNamespace)) {
+		t.Fatal("expected the pods tracked before changing the policy")
+	}
+
+	// change the termination action of one namespace after its pod is tracked
+	store.Set(&policy.ExecTrackingPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-policy", Namespace: policyNamespace},
+		Spec:       policy.ExecTrackingPolicySpec{TerminationAction: policy.TerminationActionDelete},
+	})
+	waitForTimerRemoval(t, &contr, types.UID(policyNamespace))
+	waitForTimerRemoval(t, &contr, types.UID(otherNamespace))
+
+	// verify the tracked pod in the namespace of the changed policy is deleted rather than evicted
+	var deletedNamespaces, evictedNamespaces []string
+	for _, action := range fakeClient.Actions() {
+		if action.GetVerb() == "delete" && action.GetResource().Resource == "pods" {
+			deletedNamespaces = append(deletedNamespaces, action.GetNamespace())
+		}
+		if action.GetVerb() == "create" && action.GetSubresource() == "eviction" {
+			evictedNamespaces = append(evictedNamespaces, action.GetNamespace())
+		}
+	}
+	checkDeepEquals(t, []string{policyNamespace}, deletedNamespaces)
+	checkDeepEquals(t, []string{otherNamespace}, evictedNamespaces)
+}
+
+// TestNamespaceTerminationMode tests controller terminating pods by the termination mode annotated to their namespace
+// over the default one
+func TestNamespaceTerminationMode(t *testing.T) {
+	setupZapLogging(t)
+
+	ttlDuration := time.Duration(1) * time.Second
+	namespaceModes := map[string]string{
+		"test-namespace-delete":  "delete",
+		"test-namespace-invalid": "kill",
+		"test-namespace-default": "",
+	}
+	var objects []runtime.Object
+	var pods []*corev1.Pod
+	for namespace, mode := range namespaceModes {
+		nsObj := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+		podObj := getPodObject(namespace, "test-pod")
+		podObj.SetUID(types.UID(namespace))
+		// the pods in the annotated namespaces are previously interacted, while the other one is newly interacted
+		if mode != "" {
+			nsObj.SetAnnotations(map[string]string{controller.NamespaceTerminationModeAnnotate: mode})
+			podObj.SetLabels(map[string]string{
+				controller.PodInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10),
+				controller.PodTTLDurationLabel:          ttlDuration.String(),
+			})
+		}
+		objects = append(objects, nsObj, podObj)
+		pods = append(pods, podObj)
+	}
+
+	mockPodInteraction("test-namespace-default", "test-pod", "test-user", time.Now())
+	fakeClient := fake.NewSimpleClientset(objects...)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithTerminationMode(controller.TerminationModeEvict, 0),
+	)
+	contr.CheckPodInteraction()
+	for _, pod := range pods {
+		waitForTimerRemoval(t, &contr, pod.UID)
+	}
+
+	// verify only the pod in the namespace annotated with the delete mode is deleted rather than evicted
+	var deletedNamespaces []string
+	for _, action := range fakeClient.Actions() {
+		if action.GetVerb() == "delete" && action.GetResource().Resource == "pods" {
+			deletedNamespaces = append(deletedNamespaces, action.GetNamespace())
+		}
+	}
+	var evictedNamespaces []string
+	for _, action := range fakeClient.Actions() {
+		if action.GetVerb() == "create" && action.GetSubresource() == "eviction" {
+			evictedNamespaces = append(evictedNamespaces, action.GetNamespace())
+		}
+	}
+	sort.Strings(evictedNamespaces)
+	checkDeepEquals(t, []string{"test-namespace-delete"}, deletedNamespaces)
+	checkDeepEquals(t, []string{"test-namespace-default", "test-namespace-invalid"}, evictedNamespaces)
+}
+
+// TestForbiddenEviction tests controller diagnosing evictions forbidden by missing RBAC once and being unready until
+// evicting a pod again
+func TestForbiddenEviction(t *testing.T) {
+	observedCore, observedLogs := observer.New(zapcore.ErrorLevel)
+	zap.ReplaceGlobals(zap.New(observedCore))
+
+	namespace := "test-namespace"
+	podNames := []string{"test-pod-1", "test-pod-2", "test-pod-3"}
+	var podObjs []runtime.Object
+	for _, podName := range podNames {
+		podObj := getPodObject(namespace, podName)
+		podObj.SetUID(types.UID(podName))
+		podObjs = append(podObjs, podObj)
+	}
+	fakeClient := fake.NewSimpleClientset(podObjs...)
+	var forbidden int32 = 1
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" || atomic.LoadInt32(&forbidden) == 0 {
+			return false, nil, nil
+		}
+
+		name := action.(k8stesting.CreateAction).GetObject().(metav1.Object).GetName()
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "pods/eviction"}, name,
+			fmt.Errorf("cannot create resource \"pods/eviction\""))
+	})
+	contr := controller.NewController(fakeClient, 1)
+	defer controller.SetTerminationRetryInterval(100*time.Millisecond, 100*time.Millisecond)()
+
+	// verify evicting pods forbidden is diagnosed only once, while the pods are still tracked
+	for _, podName := range podNames[:2] {
+		mockPodInteraction(namespace, podName, "test-user", time.Now())
+		contr.CheckPodInteraction()
+		waitForEviction(t, fakeClient, podName)
+		if !contr.HasTerminationTimer(types.UID(podName)) {
+			t.Fatalf("expected the pod %s still tracked once forbidden to evict it", podName)
+		}
+	}
+	diagnostics := observedLogs.FilterMessageSnippet("Forbidden to evict interacted Pods").All()
+	checkDeepEquals(t, 1, len(diagnostics))
+	checkDeepEquals(t, `{apiGroups: [""], resources: ["pods/eviction"], verbs: ["create"]}`, diagnostics[0].ContextMap()["rbac_rule"])
+	checkDeepEquals(t, float64(1), testutil.ToFloat64(metrics.EvictionForbidden))
+	if !contr.Ready() {
+		t.Fatal("expected the controller ready while evicting pods is forbidden, reported by the metric instead")
+	}
+
+	// verify the forbidden evictions are no longer reported once evicting a pod is permitted
+	atomic.StoreInt32(&forbidden, 0)
+	mockPodInteraction(namespace, podNames[2], "test-user", time.Now())
+	contr.CheckPodInteraction()
+	for _, podName := range podNames {
+		waitForTimerRemoval(t, &contr, types.UID(podName))
+	}
+	checkDeepEquals(t, float64(0), testutil.ToFloat64(metrics.EvictionForbidden))
+}
+
+// TestIdleTTL tests controller evicting pods idle for their TTL only in the idle TTL mode
+func TestIdleTTL(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	idlePodName := "test-pod-idle"
+	activePodName := "test-pod-active"
+	ttlDuration := time.Duration(3) * time.Second
+
+	idlePodObj := getPodObject(namespace, idlePodName)
+	idlePodObj.SetUID(types.UID(idlePodName))
+	activePodObj := getPodObject(namespace, activePodName)
+	activePodObj.SetUID(types.UID(activePodName))
+	fakeClient := fake.NewSimpleClientset(idlePodObj, activePodObj)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithTTLMode(controller.TTLModeIdle),
+	)
+
+	interactedTime := time.Now()
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 3)
+	for _, podName := range []string{idlePodName, activePodName} {
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: namespace,
+			PodName:      podName,
+			InitTime:     interactedTime,
+			Username:     "test-user",
+		}
+	}
+	go contr.CheckPodInteraction()
+
+	// interact the active pod again before its TTL
+	time.Sleep(2 * time.Second)
+	controller.PodInteractionCh <- controller.PodInteraction{
+		PodNamespace: namespace,
+		PodName:      activePodName,
+		InitTime:     time.Now(),
+		Username:     "test-user",
+	}
+	close(controller.PodInteractionCh)
+
+	// verify the idle pod is evicted after its TTL, but the active one survives it
+	waitForEviction(t, fakeClient, idlePodName)
+	checkDeepEquals(t, []string{idlePodName}, getEvictedPodNames(fakeClient))
+	activePod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), activePodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, present := activePod.Annotations[controller.PodLastInteractionTimestampAnnotate]; !present {
+		t.Errorf("expected the latest interaction of the active pod annotated, got: %v", activePod.Annotations)
 	}
+
+	// verify the active pod is evicted once idle for its TTL
+	waitForEviction(t, fakeClient, activePodName)
+	waitForTimerRemoval(t, &contr, activePodObj.UID)
+
+	if _, err := controller.ParseTTLMode("sliding"); err == nil {
+		t.Error("expected an error parsing an unsupported TTL mode, but got none")
+	}
+}
+
+// TestFixedTTLIgnoresLastInteraction tests controller counting the TTL of a pod from its initial interaction in the
+// fixed TTL mode, regardless of a latest interaction annotated on it
+func TestFixedTTLIgnoresLastInteraction(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-fixed-ttl"
+	podName := "test-pod"
+	interactedTime := time.Now().Add(-time.Minute)
+	ttlDuration := time.Hour
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	podObj.SetLabels(map[string]string{
+		controller.PodInteractionTimestampLabel: strconv.FormatInt(interactedTime.Unix(), 10),
+		controller.PodTTLDurationLabel:          ttlDuration.String(),
+	})
+	podObj.SetAnnotations(map[string]string{
+		controller.PodLastInteractionTimestampAnnotate: strconv.FormatInt(time.Now().Unix(), 10),
+	})
+	fakeClient := fake.NewSimpleClientset(podObj)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()))
+	controller.PodInteractionCh = make(chan controller.PodInteraction)
+	close(controller.PodInteractionCh)
+	contr.CheckPodInteraction()
+
+	pod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, interactedTime.Add(ttlDuration).Truncate(time.Second).String(),
+		pod.Annotations[controller.PodTerminationTimeAnnotate])
+}
+
+// TestWatchPodDeletions tests controller removing the termination timers of deleted and evicted pods
+func TestWatchPodDeletions(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-deletion"
+	podName := "test-pod"
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	contr := controller.NewController(fakeClient, 3600)
+	contr.WatchInteractedPods(stopCh)
+	contr.CheckPodInteraction()
+	if !contr.HasTerminationTimer(podObj.UID) {
+		t.Fatal("expected a termination timer of the interacted pod, but got none")
+	}
+
+	// verify the timer is removed once the pod is deleted out-of-band
+	if err := fakeClient.CoreV1().Pods(namespace).Delete(context.TODO(), podName, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForTimerRemoval(t, &contr, podObj.UID)
+
+	// verify the timer is removed once it fires and evicts the pod
+	ttlDuration := time.Duration(1) * time.Second
+	evictionsCount := testutil.ToFloat64(metrics.EvictionsTotal.WithLabelValues(namespace))
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	fakeClient = fake.NewSimpleClientset(podObj)
+	contr = controller.NewController(fakeClient, int(ttlDuration.Seconds()))
+	contr.CheckPodInteraction()
+	waitForEviction(t, fakeClient, podName)
+	waitForTimerRemoval(t, &contr, podObj.UID)
+	checkDeepEquals(t, evictionsCount+1, testutil.ToFloat64(metrics.EvictionsTotal.WithLabelValues(namespace)))
+}
+
+// TestWatchInteractedPods tests controller reconciling the termination of pods interacted or extended without it
+func TestWatchInteractedPods(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-reconcile"
+	fakeClient := fake.NewSimpleClientset()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	contr := controller.NewController(fakeClient, 3600)
+	contr.WatchInteractedPods(stopCh)
+
+	getInteractedPod := func(podName, ttl string) *corev1.Pod {
+		podObj := getPodObject(namespace, podName)
+		podObj.SetUID(types.UID(podName))
+		podObj.SetLabels(map[string]string{
+			controller.PodInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10),
+			controller.PodInteractorLabel:           "test-user",
+			controller.PodTTLDurationLabel:          ttl,
+		})
+		return podObj
+	}
+	waitForTerminationTime := func(podName string, terminationTime time.Time) {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			pod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+			if err == nil && pod.Annotations[controller.PodTerminationTimeAnnotate] == terminationTime.String() {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("expected pod %s to be terminated at %s, but it is not", podName, terminationTime)
+	}
+
+	// verify a pod appearing after startup with the interaction labels gets evicted after its TTL
+	appearedPod := getInteractedPod("test-pod-appeared", "1s")
+	if _, err := fakeClient.CoreV1().Pods(namespace).Create(context.TODO(), appearedPod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForEviction(t, fakeClient, appearedPod.Name)
+	waitForTimerRemoval(t, &contr, appearedPod.UID)
+
+	// verify the termination of a pod is reset once extended without the controller being notified
+	extendedPod := getInteractedPod("test-pod-extended", "1h")
+	if _, err := fakeClient.CoreV1().Pods(namespace).Create(context.TODO(), extendedPod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	interactedTime, _ := strconv.ParseInt(extendedPod.Labels[controller.PodInteractionTimestampLabel], 10, 64)
+	waitForTerminationTime(extendedPod.Name, time.Unix(interactedTime, 0).Add(time.Hour))
+
+	currentPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), extendedPod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentPod.Annotations[controller.PodExtendDurationAnnotate] = "30m"
+	if _, err := fakeClient.CoreV1().Pods(namespace).Update(context.TODO(), currentPod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForTerminationTime(extendedPod.Name, time.Unix(interactedTime, 0).Add(90*time.Minute))
+	if !contr.HasTerminationTimer(extendedPod.UID) {
+		t.Fatal("expected the termination timer of the extended pod kept, but got none")
+	}
+	checkDeepEquals(t, []string{appearedPod.Name}, getEvictedPodNames(fakeClient))
+}
+
+// TestStatefulSetPodEviction tests controller evicting a StatefulSet-owned pod gracefully or exempting it
+func TestStatefulSetPodEviction(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod-0"
+	ttlDuration := time.Duration(1) * time.Second
+	isController := true
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	podObj.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: "apps/v1",
+		Kind:       "StatefulSet",
+		Name:       "test-statefulset",
+		UID:        "test-statefulset-uid",
+		Controller: &isController,
+	}})
+
+	// verify the pod is evicted with the configured grace period and an advising event
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithStatefulSetHandling(false, 5*time.Minute),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+	contr.CheckPodInteraction()
+	waitForEviction(t, fakeClient, podName)
+	// the timer goroutine refers to contr, which must be done before reassigning it
+	waitForTimerRemoval(t, &contr, podObj.UID)
+
+	for _, action := range fakeClient.Actions() {
+		if action.GetVerb() == "create" && action.GetSubresource() == "eviction" {
+			eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+			if eviction.DeleteOptions == nil || eviction.DeleteOptions.GracePeriodSeconds == nil {
+				t.Fatal("expected the eviction to have a grace period, but got none")
+			}
+			checkDeepEquals(t, int64(300), *eviction.DeleteOptions.GracePeriodSeconds)
+		}
+	}
+	checkEventSubmitted(t, fakeRecorder, "Pod is owned by the StatefulSet 'test-statefulset' and evicted with a grace period of 5m0s")
+
+	// verify the pod is not evicted if exempt, but still gets an advising event
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	fakeClient = fake.NewSimpleClientset(podObj)
+	fakeRecorder = record.NewFakeRecorder(100)
+	contr = controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithStatefulSetHandling(true, 0),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+	contr.CheckPodInteraction()
+	time.Sleep(2 * ttlDuration)
+	if evictions := getEvictedPodNames(fakeClient); len(evictions) != 0 {
+		t.Fatal("expected no eviction of an exempt StatefulSet pod, but got", evictions)
+	}
+	checkEventSubmitted(t, fakeRecorder, "Pod is owned by the StatefulSet 'test-statefulset' and exempt from eviction")
+}
+
+// TestAuditOnly tests controller submitting an event to a due pod instead of evicting it in the audit-only mode
+func TestAuditOnly(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	ttlDuration := time.Duration(1) * time.Second
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithAuditOnly(),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+	contr.CheckPodInteraction()
+
+	// verify the pod is not evicted once due, but gets an event and its timer is removed
+	waitForTimerRemoval(t, &contr, podObj.UID)
+	if evictions := getEvictedPodNames(fakeClient); len(evictions) != 0 {
+		t.Fatal("expected no eviction in the audit-only mode, but got", evictions)
+	}
+	checkEventSubmitted(t, fakeRecorder, "Pod would be evicted now, but the controller only audits interactions")
+}
+
+// TestReplayedInteraction tests controller skipping the interactions replayed from an audit log if recorded already,
+// too old or made to a previous pod of the same name
+func TestReplayedInteraction(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	ttlDuration := time.Hour
+	createdTime := time.Now().Add(-3 * ttlDuration)
+	replay := func(fakeClient *fake.Clientset, interactedTime time.Time) map[string]string {
+		controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: namespace,
+			PodName:      podName,
+			InitTime:     interactedTime,
+			Username:     "test-user",
+			Replayed:     true,
+		}
+		close(controller.PodInteractionCh)
+		contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()))
+		contr.CheckPodInteraction()
+
+		pod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pod.GetLabels()
+	}
+	newFakeClient := func() *fake.Clientset {
+		podObj := getPodObject(namespace, podName)
+		podObj.SetCreationTimestamp(metav1.NewTime(createdTime))
+		return fake.NewSimpleClientset(podObj)
+	}
+
+	// verify an interaction before the pod is created or older than its TTL is skipped
+	if labels := replay(newFakeClient(), createdTime.Add(-time.Minute)); len(labels) != 0 {
+		t.Error("expected an interaction before the pod is created to be skipped, but got labels", labels)
+	}
+	if labels := replay(newFakeClient(), time.Now().Add(-2*ttlDuration)); len(labels) != 0 {
+		t.Error("expected an interaction older than the TTL to be skipped, but got labels", labels)
+	}
+
+	// verify a recent interaction is tracked, but neither counted nor tracked again once replayed again
+	fakeClient := newFakeClient()
+	interactedTime := time.Now().Add(-time.Minute)
+	labels := replay(fakeClient, interactedTime)
+	checkDeepEquals(t, strconv.FormatInt(interactedTime.Unix(), 10), labels[controller.PodInteractionTimestampLabel])
+	checkDeepEquals(t, labels, replay(fakeClient, interactedTime))
+	checkDeepEquals(t, labels, replay(fakeClient, interactedTime.Add(time.Second)))
+}
+
+// TestEvictionGracePeriod tests controller evicting pods with the configured grace period, or else their own one
+func TestEvictionGracePeriod(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-eviction-grace-period"
+	podName := "test-pod"
+	ttlDuration := time.Duration(1) * time.Second
+	getEvictionGracePeriod := func(opts ...controller.Option) *int64 {
+		podObj := getPodObject(namespace, podName)
+		podObj.SetUID(types.UID(podName))
+		mockPodInteraction(namespace, podName, "test-user", time.Now())
+		fakeClient := fake.NewSimpleClientset(podObj)
+		contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()), opts...)
+		contr.CheckPodInteraction()
+		waitForEviction(t, fakeClient, podName)
+		waitForTimerRemoval(t, &contr, podObj.UID)
+
+		for _, action := range fakeClient.Actions() {
+			if action.GetVerb() == "create" && action.GetSubresource() == "eviction" {
+				eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+				if eviction.DeleteOptions == nil {
+					return nil
+				}
+				return eviction.DeleteOptions.GracePeriodSeconds
+			}
+		}
+		return nil
+	}
+
+	// verify the pod is evicted with its own grace period by default or if set to zero or a negative value
+	if gracePeriod := getEvictionGracePeriod(); gracePeriod != nil {
+		t.Errorf("expected the eviction without a grace period by default, got %d", *gracePeriod)
+	}
+	for _, unset := range []time.Duration{0, -time.Second} {
+		if gracePeriod := getEvictionGracePeriod(controller.WithEvictionGracePeriod(unset)); gracePeriod != nil {
+			t.Errorf("expected the eviction without a grace period if set to %s, got %d", unset, *gracePeriod)
+		}
+	}
+
+	// verify the configured grace period propagates to the eviction
+	gracePeriod := getEvictionGracePeriod(controller.WithEvictionGracePeriod(30 * time.Second))
+	if gracePeriod == nil {
+		t.Fatal("expected the eviction with a grace period of 30s, got none")
+	}
+	checkDeepEquals(t, int64(30), *gracePeriod)
+}
+
+// TestPDBBlockedEviction tests controller retrying an eviction refused by a PodDisruptionBudget, and deleting the pod
+// once the retries are exhausted if set
+func TestPDBBlockedEviction(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-pdb-blocked"
+	podName := "test-pod"
+	pdbRefusal := apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+	evictPodRefused := func(refusal error, refusals int32, opts ...controller.Option) (*fake.Clientset,
+		*controller.Controller) {
+		podObj := getPodObject(namespace, podName)
+		podObj.SetUID(types.UID(podName))
+		fakeClient := fake.NewSimpleClientset(podObj)
+		fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetSubresource() != "eviction" || atomic.AddInt32(&refusals, -1) < 0 {
+				return false, nil, nil
+			}
+
+			return true, nil, refusal
+		})
+		mockPodInteraction(namespace, podName, "test-user", time.Now())
+		contr := controller.NewController(fakeClient, 1, opts...)
+		contr.CheckPodInteraction()
+		return fakeClient, &contr
+	}
+	evictPod := func(refusals int32, opts ...controller.Option) (*fake.Clientset, *controller.Controller) {
+		return evictPodRefused(pdbRefusal, refusals, opts...)
+	}
+	waitForEvictions := func(fakeClient *fake.Clientset, count int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for len(getEvictedPodNames(fakeClient)) < count {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d evictions of the pod, got: %v", count, getEvictedPodNames(fakeClient))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// verify the eviction refused once is retried and succeeds
+	fakeClient, contr := evictPod(1, controller.WithPDBBlockedAction(controller.PDBBlockedActionLog, 3, time.Millisecond))
+	waitForTimerRemoval(t, contr, types.UID(podName))
+	checkDeepEquals(t, []string{podName, podName}, getEvictedPodNames(fakeClient))
+	checkDeepEquals(t, 0, len(getDeletedPodNames(fakeClient)))
+
+	// verify the eviction is not retried by default, but the pod is still tracked and evicted later
+	restoreRetryInterval := controller.SetTerminationRetryInterval(10*time.Millisecond, 10*time.Millisecond)
+	fakeClient, contr = evictPod(1)
+	waitForTimerRemoval(t, contr, types.UID(podName))
+	checkDeepEquals(t, []string{podName, podName}, getEvictedPodNames(fakeClient))
+	restoreRetryInterval()
+
+	// verify the pod is left alive and tracked or deleted once the retries are exhausted, as set
+	fakeClient, contr = evictPod(10, controller.WithPDBBlockedAction(controller.PDBBlockedActionLog, 2, time.Millisecond))
+	waitForEvictions(fakeClient, 3)
+	checkDeepEquals(t, []string{podName, podName, podName}, getEvictedPodNames(fakeClient))
+	checkDeepEquals(t, 0, len(getDeletedPodNames(fakeClient)))
+	checkDeepEquals(t, true, contr.HasTerminationTimer(types.UID(podName)))
+	fakeClient, contr = evictPod(10, controller.WithPDBBlockedAction(controller.PDBBlockedActionDelete, 2, time.Millisecond))
+	waitForTimerRemoval(t, contr, types.UID(podName))
+	checkDeepEquals(t, []string{podName, podName, podName}, getEvictedPodNames(fakeClient))
+	checkDeepEquals(t, []string{podName}, getDeletedPodNames(fakeClient))
+
+	// verify an eviction refused by its DisruptionBudget cause is retried likewise
+	causedRefusal := &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusTooManyRequests,
+		Reason:  metav1.StatusReasonTooManyRequests,
+		Message: "Cannot evict pod",
+		Details: &metav1.StatusDetails{Causes: []metav1.StatusCause{{Type: policyv1.DisruptionBudgetCause}}},
+	}}
+	fakeClient, contr = evictPodRefused(causedRefusal, 1,
+		controller.WithPDBBlockedAction(controller.PDBBlockedActionLog, 3, time.Millisecond))
+	waitForTimerRemoval(t, contr, types.UID(podName))
+	checkDeepEquals(t, []string{podName, podName}, getEvictedPodNames(fakeClient))
+
+	// verify an eviction throttled by the API server is neither retried nor deleted as blocked, but evicted later
+	restoreRetryInterval = controller.SetTerminationRetryInterval(100*time.Millisecond, 100*time.Millisecond)
+	throttled := apierrors.NewTooManyRequests("Too many requests, please try again later.", 0)
+	fakeClient, contr = evictPodRefused(throttled, 1,
+		controller.WithPDBBlockedAction(controller.PDBBlockedActionDelete, 2, time.Millisecond))
+	waitForEvictions(fakeClient, 1)
+	time.Sleep(50 * time.Millisecond)
+	checkDeepEquals(t, []string{podName}, getEvictedPodNames(fakeClient))
+	checkDeepEquals(t, 0, len(getDeletedPodNames(fakeClient)))
+	waitForTimerRemoval(t, contr, types.UID(podName))
+	checkDeepEquals(t, []string{podName, podName}, getEvictedPodNames(fakeClient))
+	checkDeepEquals(t, 0, len(getDeletedPodNames(fakeClient)))
+	restoreRetryInterval()
+}
+
+// TestDebugJobDeletion tests controller deleting the owning Job of a labeled debug Job's pod instead of evicting it
+func TestDebugJobDeletion(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-debug-job-abcde"
+	jobName := "test-debug-job"
+	ttlDuration := time.Duration(1) * time.Second
+	isController := true
+	jobObj := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: jobName, Namespace: namespace}}
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	podObj.SetLabels(map[string]string{controller.DebugJobLabel: "true"})
+	podObj.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: "batch/v1",
+		Kind:       "Job",
+		Name:       jobName,
+		UID:        "test-debug-job-uid",
+		Controller: &isController,
+	}})
+
+	// verify the Job is deleted instead of evicting the pod
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	fakeClient := fake.NewSimpleClientset(podObj, jobObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithDebugJobDeletion(),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+	contr.CheckPodInteraction()
+	waitForTimerRemoval(t, &contr, podObj.UID)
+
+	if _, err := fakeClient.BatchV1().Jobs(namespace).Get(context.TODO(), jobName, metav1.GetOptions{}); err == nil {
+		t.Fatal("expected the debug Job to be deleted, but it still exists")
+	}
+	if evictions := getEvictedPodNames(fakeClient); len(evictions) != 0 {
+		t.Fatal("expected no eviction of a debug Job's pod, but got", evictions)
+	}
+	checkEventSubmitted(t, fakeRecorder, "Pod's debug Job 'test-debug-job' has been deleted instead of evicting the Pod")
+
+	// verify the pod is evicted as usual if the debug Job deletion is not enabled
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	fakeClient = fake.NewSimpleClientset(podObj, jobObj)
+	contr = controller.NewController(fakeClient, int(ttlDuration.Seconds()))
+	contr.CheckPodInteraction()
+	waitForEviction(t, fakeClient, podName)
+	waitForTimerRemoval(t, &contr, podObj.UID)
+	if _, err := fakeClient.BatchV1().Jobs(namespace).Get(context.TODO(), jobName, metav1.GetOptions{}); err != nil {
+		t.Fatal("expected the Job to be kept, got error:", err)
+	}
+}
+
+// TestEvictedPodOwnerAnnotation tests controller annotating the owning Deployment of an evicted pod
+// with the eviction
+func TestEvictedPodOwnerAnnotation(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-web-abcde-12345"
+	replicaSetName := "test-web-abcde"
+	deploymentName := "test-web"
+	ttlDuration := time.Duration(1) * time.Second
+	isController := true
+	deploymentObj := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: deploymentName, Namespace: namespace}}
+	replicaSetObj := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name:      replicaSetName,
+		Namespace: namespace,
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       deploymentName,
+			UID:        "test-web-uid",
+			Controller: &isController,
+		}},
+	}}
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	podObj.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: "apps/v1",
+		Kind:       "ReplicaSet",
+		Name:       replicaSetName,
+		UID:        "test-web-abcde-uid",
+		Controller: &isController,
+	}})
+
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	fakeClient := fake.NewSimpleClientset(podObj, replicaSetObj, deploymentObj)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithEvictedPodOwnerAnnotation(),
+	)
+	contr.CheckPodInteraction()
+	waitForEviction(t, fakeClient, podName)
+	waitForTimerRemoval(t, &contr, podObj.UID)
+
+	// verify the Deployment rather than its ReplicaSet is annotated with the eviction
+	deployment, err := fakeClient.AppsV1().Deployments(namespace).Get(context.TODO(), deploymentName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := time.Parse(time.RFC3339, deployment.Annotations[controller.OwnerLastEvictionTimeAnnotate]); err != nil {
+		t.Errorf("expected the Deployment annotated with the eviction time, got error: %v", err)
+	}
+	checkDeepEquals(t, "Pod 'test-web-abcde-12345' was evicted after being interacted by a user 'test-user'",
+		deployment.Annotations[controller.OwnerLastEvictionReasonAnnotate])
+
+	replicaSet, err := fakeClient.AppsV1().ReplicaSets(namespace).Get(context.TODO(), replicaSetName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, 0, len(replicaSet.Annotations))
+}
+
+// TestPodExemptSelector tests controller skipping the eviction of interacted pods matching the exempt selector
+func TestPodExemptSelector(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	matchingPod := getPodObject(namespace, "test-pod-matching")
+	matchingPod.SetLabels(map[string]string{"app": "ci-runner"})
+	nonMatchingPod := getPodObject(namespace, "test-pod-non-matching")
+	nonMatchingPod.SetLabels(map[string]string{"app": "web"})
+	pods := []*corev1.Pod{matchingPod, nonMatchingPod}
+
+	checkExempt := func(selector labels.Selector, expectedExempt map[string]bool) {
+		controller.PodInteractionCh = make(chan controller.PodInteraction, len(pods))
+		for _, pod := range pods {
+			pod.SetUID(types.UID(pod.Name))
+			controller.PodInteractionCh <- controller.PodInteraction{
+				PodNamespace: namespace,
+				PodName:      pod.Name,
+				Username:     "test-user",
+				InitTime:     time.Now(),
+			}
+		}
+		close(controller.PodInteractionCh)
+
+		fakeClient := fake.NewSimpleClientset(matchingPod, nonMatchingPod)
+		fakeRecorder := record.NewFakeRecorder(100)
+		contr := controller.NewController(fakeClient, 600,
+			controller.WithPodExemptSelector(selector),
+			controller.WithEventRecorder(fakeRecorder),
+		)
+		contr.CheckPodInteraction()
+
+		for _, pod := range pods {
+			interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, labeled := interactedPod.Labels[controller.PodInteractionTimestampLabel]
+			checkDeepEquals(t, !expectedExempt[pod.Name], labeled)
+			checkDeepEquals(t, !expectedExempt[pod.Name], contr.HasTerminationTimer(pod.UID))
+		}
+		if len(expectedExempt) > 0 {
+			checkEventSubmitted(t, fakeRecorder, "Pod matches the exempt selector 'app in (ci-runner,sandbox)'")
+		}
+	}
+
+	// verify only the matching pod is exempt
+	selector, err := controller.ParsePodExemptSelector("app in (ci-runner, sandbox)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExempt(selector, map[string]bool{matchingPod.Name: true})
+
+	// verify no pod is exempt by an empty selector, rather than all of them
+	selector, err = controller.ParsePodExemptSelector("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExempt(selector, map[string]bool{})
+	checkExempt(labels.Everything(), map[string]bool{})
+
+	if _, err := controller.ParsePodExemptSelector("app in ci-runner"); err == nil {
+		t.Error("expected an error parsing an invalid selector, but got none")
+	}
+}
+
+// TestPodDisableEvictionAnnotation tests controller never setting a termination timer to a pod annotated to disable
+// its eviction, even if it does not match the exempt selector
+func TestPodDisableEvictionAnnotation(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	pinnedPod := getPodObject(namespace, "test-pod-pinned")
+	pinnedPod.SetUID(types.UID(pinnedPod.Name))
+	pinnedPod.SetLabels(map[string]string{"app": "web"})
+	pinnedPod.SetAnnotations(map[string]string{controller.PodDisableEvictionAnnotate: "true"})
+	// only the "true" value disables the eviction
+	falsePod := getPodObject(namespace, "test-pod-false")
+	falsePod.SetUID(types.UID(falsePod.Name))
+	falsePod.SetAnnotations(map[string]string{controller.PodDisableEvictionAnnotate: "false"})
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 2)
+	for _, pod := range []*corev1.Pod{pinnedPod, falsePod} {
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: namespace,
+			PodName:      pod.Name,
+			Username:     "test-user",
+			InitTime:     time.Now(),
+		}
+	}
+	close(controller.PodInteractionCh)
+
+	selector, err := controller.ParsePodExemptSelector("app=ci-runner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeClient := fake.NewSimpleClientset(pinnedPod, falsePod)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, 600,
+		controller.WithPodExemptSelector(selector),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+	contr.CheckPodInteraction()
+
+	// verify the pinned pod is neither labeled nor given a termination timer, while informed by an event
+	interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), pinnedPod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, labeled := interactedPod.Labels[controller.PodInteractionTimestampLabel]
+	checkDeepEquals(t, false, labeled)
+	checkDeepEquals(t, false, contr.HasTerminationTimer(pinnedPod.UID))
+	checkDeepEquals(t, true, contr.HasTerminationTimer(falsePod.UID))
+	checkEventSubmitted(t, fakeRecorder, "Pod is annotated with 'box.com/disableExecEviction: \"true\"', it will not be evicted")
+}
+
+// TestPodAgeAtEvictionMetric tests controller observing the age of evicted pods by whether they were extended
+func TestPodAgeAtEvictionMetric(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-age"
+	interactedTime := time.Now().Add(-time.Minute)
+	extendedPod := getPodObject(namespace, "test-pod-extended")
+	extendedPod.SetUID(types.UID(extendedPod.Name))
+	extendedPod.SetAnnotations(map[string]string{controller.PodExtendDurationAnnotate: "1s"})
+	regularPod := getPodObject(namespace, "test-pod-regular")
+	regularPod.SetUID(types.UID(regularPod.Name))
+	pods := []*corev1.Pod{extendedPod, regularPod}
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction, len(pods))
+	for _, pod := range pods {
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: pod.Namespace,
+			PodName:      pod.Name,
+			Username:     "test-user",
+			InitTime:     interactedTime,
+		}
+	}
+	close(controller.PodInteractionCh)
+
+	// both pods are evicted right away as they were interacted before their TTL
+	fakeClient := fake.NewSimpleClientset(extendedPod, regularPod)
+	contr := controller.NewController(fakeClient, 1)
+	contr.CheckPodInteraction()
+	for _, pod := range pods {
+		waitForEviction(t, fakeClient, pod.Name)
+		waitForTimerRemoval(t, &contr, pod.UID)
+	}
+
+	// verify each eviction is observed once with the age since the interaction
+	for _, extended := range []string{"true", "false"} {
+		histogram := &dto.Metric{}
+		observer := metrics.PodAgeAtEvictionSeconds.WithLabelValues(namespace, extended)
+		if err := observer.(prometheus.Histogram).Write(histogram); err != nil {
+			t.Fatal(err)
+		}
+		checkDeepEquals(t, uint64(1), histogram.GetHistogram().GetSampleCount())
+		if age := histogram.GetHistogram().GetSampleSum(); age < time.Minute.Seconds() {
+			t.Errorf("expected the age of the evicted pod (extended: %s) at least a minute, got: %fs", extended, age)
+		}
+	}
+}
+
+// TestDrainDeadline tests controller dead-lettering a slow item and the ones left behind it at the drain deadline
+func TestDrainDeadline(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-drain"
+	slowPod := getPodObject(namespace, "test-pod-slow")
+	queuedPod := getPodObject(namespace, "test-pod-queued")
+	fakeClient := fake.NewSimpleClientset(slowPod, queuedPod)
+	contr := controller.NewController(fakeClient, 600)
+
+	// verify draining returns right away once the closed channels are consumed
+	controller.PodInteractionCh = make(chan controller.PodInteraction)
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate)
+	close(controller.PodInteractionCh)
+	close(controller.PodExtensionUpdateCh)
+	contr.CheckPodInteraction()
+	contr.CheckPodExtensionUpdate()
+	if !contr.Drain(time.Second) {
+		t.Fatal("expected the channels drained without dead-lettering any item")
+	}
+
+	// mock getting the slow pod to block until released
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	var handlingOnce sync.Once
+	fakeClient.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		handlingOnce.Do(func() { close(handling) })
+		<-release
+		return false, nil, nil
+	})
+
+	// mock the items left in the channels on shutdown, the extension update is never consumed
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 2)
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate, 1)
+	for _, pod := range []*corev1.Pod{slowPod, queuedPod} {
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: pod.Namespace,
+			PodName:      pod.Name,
+			Username:     "test-user",
+			InitTime:     time.Now(),
+		}
+	}
+	controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: *slowPod, Username: "test-user"}
+	close(controller.PodInteractionCh)
+	close(controller.PodExtensionUpdateCh)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		contr.CheckPodInteraction()
+	}()
+	<-handling
+
+	// verify the slow item in progress and the ones left in the channels are dead-lettered at the deadline
+	if contr.Drain(100 * time.Millisecond) {
+		t.Fatal("expected the items unhandled at the drain deadline to be dead-lettered")
+	}
+	checkDeepEquals(t, float64(2), testutil.ToFloat64(metrics.DeadLetteredItemsTotal.WithLabelValues("pod_interaction")))
+	checkDeepEquals(t, float64(1), testutil.ToFloat64(metrics.DeadLetteredItemsTotal.WithLabelValues("pod_extension_update")))
+
+	// let the slow item finish so that nothing is logged after the test
+	close(release)
+	<-done
+}
+
+// TestCheckPodInteractionCommandRedaction tests controller redacting passwords in the commands of a pod interaction
+func TestCheckPodInteractionCommandRedaction(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-redaction"
+	podName := "test-pod"
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+	controller.PodInteractionCh <- controller.PodInteraction{
+		PodNamespace: namespace,
+		PodName:      podName,
+		Username:     "test-user",
+		Commands:     []string{"mysql", "-psecret", "--host=db", "test-db"},
+		InitTime:     time.Now(),
+	}
+	close(controller.PodInteractionCh)
+
+	fakeClient := fake.NewSimpleClientset(getPodObject(namespace, podName))
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, 600, controller.WithEventRecorder(fakeRecorder))
+	contr.CheckPodInteraction()
+
+	// verify the password is redacted from the event by the default patterns, leaving the other args as is
+	checkEventSubmitted(t, fakeRecorder, "running command 'mysql -p*** --host=db test-db'")
+
+	// verify more patterns can be added to the default ones
+	redactions, err := controller.ParseRedactPatterns(append(controller.DefaultRedactPatterns, `(--host=)\S+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	redacted := controller.RedactCommands([]string{
+		"sh", "-c", "PGPASSWORD=secret psql postgres://user:secret@db/test-db --host=db",
+	}, redactions)
+	checkDeepEquals(t, []string{"sh", "-c", "PGPASSWORD=*** psql postgres://user:***@db/test-db --host=***"}, redacted)
+
+	// verify the value of a flag passed as a separate arg is redacted, while "-p" of other commands is kept
+	redactions, err = controller.ParseRedactPatterns(controller.DefaultRedactPatterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		commands []string
+		redacted []string
+	}{
+		{[]string{"psql", "--password", "secret", "db"}, []string{"psql", "--password", "***", "db"}},
+		{[]string{"/usr/bin/mysql", "-u", "root", "-p", "secret"}, []string{"/usr/bin/mysql", "-u", "root", "-p", "***"}},
+		{[]string{"mysql", "-psecret", "db"}, []string{"mysql", "-p***", "db"}},
+		{[]string{"sh", "-c", "mysqldump -p secret db"}, []string{"sh", "-c", "mysqldump -p *** db"}},
+		{[]string{"find", ".", "-name", "*.log", "-print"}, []string{"find", ".", "-name", "*.log", "-print"}},
+		{[]string{"ls", "-p", "dir"}, []string{"ls", "-p", "dir"}},
+	}
+	for _, tc := range testCases {
+		checkDeepEquals(t, tc.redacted, controller.RedactCommands(tc.commands, redactions))
+	}
+
+	if _, err := controller.ParseRedactPatterns([]string{"(unclosed"}); err == nil {
+		t.Error("expected an error parsing an invalid redact pattern")
+	}
+}
+
+// TestCleanupStaleInteractions tests controller clearing interaction metadata of pods not evicted long after their TTL
+func TestCleanupStaleInteractions(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	ttlDuration := time.Minute
+	staleAfter := time.Hour
+
+	// create a stale pod which is still running long after its termination time, and a recently interacted pod
+	stalePodName := "test-pod-stale"
+	stalePod := getPodObject(namespace, stalePodName)
+	stalePod.SetLabels(map[string]string{
+		controller.PodInteractionTimestampLabel: strconv.FormatInt(time.Now().Add(-2*staleAfter).Unix(), 10),
+		controller.PodInteractorLabel:           "test-user",
+		controller.PodTTLDurationLabel:          ttlDuration.String(),
+		"app":                                   "test-app",
+	})
+	stalePod.SetAnnotations(map[string]string{
+		controller.PodTerminationTimeAnnotate: time.Now().Add(-2 * staleAfter).String(),
+	})
+
+	recentPodName := "test-pod-recent"
+	recentLabels := map[string]string{
+		controller.PodInteractionTimestampLabel: strconv.FormatInt(time.Now().Add(-2*ttlDuration).Unix(), 10),
+		controller.PodInteractorLabel:           "test-user",
+		controller.PodTTLDurationLabel:          ttlDuration.String(),
+	}
+	recentPod := getPodObject(namespace, recentPodName)
+	recentPod.SetLabels(recentLabels)
+
+	fakeClient := fake.NewSimpleClientset(stalePod, recentPod)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()), controller.WithStaleInteractionCleanup(staleAfter))
+	if err := contr.CleanupStaleInteractions(); err != nil {
+		t.Fatal(err)
+	}
+
+	// verify only the interaction metadata of the stale pod is cleared
+	stalePod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), stalePodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, map[string]string{"app": "test-app"}, stalePod.GetLabels())
+	checkDeepEquals(t, 0, len(stalePod.GetAnnotations()))
+
+	recentPod, err = fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), recentPodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, recentLabels, recentPod.GetLabels())
+}
+
+// TestRunStaleInteractionCleanup tests controller clearing stale interactions periodically only if pods are left running
+func TestRunStaleInteractionCleanup(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod-stale"
+	staleAfter := time.Hour
+	runCleanup := func(opts ...controller.Option) map[string]string {
+		podObj := getPodObject(namespace, podName)
+		podObj.SetLabels(map[string]string{
+			controller.PodInteractionTimestampLabel: strconv.FormatInt(time.Now().Add(-2*staleAfter).Unix(), 10),
+			controller.PodInteractorLabel:           "test-user",
+			controller.PodTTLDurationLabel:          time.Minute.String(),
+		})
+		podObj.SetAnnotations(map[string]string{
+			controller.PodTerminationTimeAnnotate: time.Now().Add(-2 * staleAfter).String(),
+		})
+		fakeClient := fake.NewSimpleClientset(podObj)
+		contr := controller.NewController(fakeClient, 60, append(opts, controller.WithStaleInteractionCleanup(staleAfter))...)
+
+		stopCh := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			contr.RunStaleInteractionCleanup(10*time.Millisecond, stopCh)
+			close(done)
+		}()
+		time.Sleep(100 * time.Millisecond)
+		close(stopCh)
+		<-done
+
+		pod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pod.GetLabels()
+	}
+
+	// verify the stale interaction is left as is if interacted pods are evicted once due
+	if labels := runCleanup(); len(labels) != 3 {
+		t.Error("expected the stale interaction to be kept if pods are evicted, but got labels", labels)
+	}
+
+	// verify it is cleared in the audit-only mode or if StatefulSet pods are exempt
+	if labels := runCleanup(controller.WithAuditOnly()); len(labels) != 0 {
+		t.Error("expected the stale interaction to be cleared in the audit-only mode, but got labels", labels)
+	}
+	if labels := runCleanup(controller.WithStatefulSetHandling(true, 0)); len(labels) != 0 {
+		t.Error("expected the stale interaction to be cleared if StatefulSet pods are exempt, but got labels", labels)
+	}
+}
+
+// TestEvictionLease tests two controller replicas tracking the same pod while only one of them evicts it
+func TestEvictionLease(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	ttlDuration := time.Duration(1) * time.Second
+
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	// keep the pod object as is on eviction, as the fake client otherwise replaces it with the Eviction object
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return action.GetSubresource() == "eviction", nil, nil
+	})
+
+	// the first replica handles the new interaction, the second one picks it up as a previous interaction
+	contrA := controller.NewController(fakeClient, int(ttlDuration.Seconds()), controller.WithEvictionLease("replica-a"))
+	contrA.CheckPodInteraction()
+	contrB := controller.NewController(fakeClient, int(ttlDuration.Seconds()), controller.WithEvictionLease("replica-b"))
+	contrB.CheckPodInteraction()
+
+	// verify the pod is evicted exactly once
+	waitForEviction(t, fakeClient, podName)
+	time.Sleep(ttlDuration)
+	checkDeepEquals(t, []string{podName}, getEvictedPodNames(fakeClient))
+}
+
+// TestLeaderElection tests only the elected replica setting termination timers, which are removed once it stops leading
+func TestLeaderElection(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	previousPodName := "test-pod-previous"
+	newPodName := "test-pod-new"
+	previousPodObj := getPodObject(namespace, previousPodName)
+	previousPodObj.SetUID(types.UID(previousPodName))
+	previousPodObj.SetLabels(map[string]string{
+		controller.PodInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10),
+		controller.PodInteractorLabel:           "test-user",
+		controller.PodTTLDurationLabel:          "1h",
+	})
+	newPodObj := getPodObject(namespace, newPodName)
+	newPodObj.SetUID(types.UID(newPodName))
+	fakeClient := fake.NewSimpleClientset(previousPodObj, newPodObj)
+	contr := controller.NewController(fakeClient, 3600, controller.WithLeaderElection())
+
+	// verify no timer is set before being elected, while a new interaction is still labeled
+	mockPodInteraction(namespace, newPodName, "test-user", time.Now())
+	contr.CheckPodInteraction()
+	for _, podObj := range []*corev1.Pod{previousPodObj, newPodObj} {
+		if contr.HasTerminationTimer(podObj.UID) {
+			t.Fatalf("expected no termination timer of pod %s before being elected, but got one", podObj.Name)
+		}
+	}
+	newPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), newPodName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, present := newPod.Labels[controller.PodInteractionTimestampLabel]; !present {
+		t.Errorf("expected the new interaction labeled before being elected, got: %v", newPod.Labels)
+	}
+
+	// verify timers are set to all interacted pods once elected
+	stopCh := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- contr.RunLeaderElection(namespace, "test-replica", stopCh)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !contr.HasTerminationTimer(previousPodObj.UID) || !contr.HasTerminationTimer(newPodObj.UID) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected termination timers of all interacted pods once elected, but got none")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	lease, err := fakeClient.CoordinationV1().Leases(namespace).Get(context.TODO(), "kube-exec-controller-leader", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, "test-replica", *lease.Spec.HolderIdentity)
+
+	// verify timers are removed once it stops leading
+	close(stopCh)
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	waitForTimerRemoval(t, &contr, previousPodObj.UID)
+	waitForTimerRemoval(t, &contr, newPodObj.UID)
+}
+
+// TestAuditRecord tests formatting a pod interaction as JSON, CEF and LEEF audit records
+func TestAuditRecord(t *testing.T) {
+	podInteraction := controller.PodInteraction{
+		PodName:       "test-pod",
+		PodNamespace:  "test-namespace",
+		ContainerName: "test-container",
+		Username:      "test-user",
+		Commands:      []string{"sh", "-c", "echo a=b|c"},
+		InitTime:      time.Unix(1634400000, 0).UTC(),
+	}
+
+	testCases := []struct {
+		name           string
+		format         string
+		expectedRecord string
+	}{
+		{
+			name:           "Test-1 JSON audit record",
+			format:         "json",
+			expectedRecord: `{"timestamp":"2021-10-16T16:00:00Z","username":"test-user","pod_namespace":"test-namespace","pod_name":"test-pod","container_name":"test-container","commands":["sh","-c","echo a=b|c"]}`,
+		},
+		{
+			name:   "Test-2 CEF audit record",
+			format: "cef",
+			expectedRecord: `CEF:0|Box|kube-exec-controller||PodInteraction|Pod interaction|5|rt=1634400000000 suser=test-user ` +
+				`cs1Label=podNamespace cs1=test-namespace cs2Label=podName cs2=test-pod ` +
+				`cs3Label=containerName cs3=test-container cs4Label=commands cs4=sh -c echo a\=b|c`,
+		},
+		{
+			name:   "Test-3 LEEF audit record",
+			format: "LEEF",
+			expectedRecord: "LEEF:1.0|Box|kube-exec-controller||PodInteraction|devTime=1634400000000\tdevTimeFormat=epoch\tsev=5\t" +
+				"usrName=test-user\tpodNamespace=test-namespace\tpodName=test-pod\tcontainerName=test-container\t" +
+				"commands=sh -c echo a=b|c",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			format, err := controller.ParseAuditFormat(testCase.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			record, err := podInteraction.AuditRecord(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkDeepEquals(t, testCase.expectedRecord, record)
+		})
+	}
+
+	if _, err := controller.ParseAuditFormat("syslog"); err == nil {
+		t.Error("expected an error parsing an unsupported audit format, got nil")
+	}
+}
+
+// TestCheckPodInteractionClientInfo tests controller annotating the client metadata of a new pod interaction
+func TestCheckPodInteractionClientInfo(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+	controller.PodInteractionCh <- controller.PodInteraction{
+		PodNamespace: namespace,
+		PodName:      podName,
+		Username:     "test-user",
+		InitTime:     time.Now(),
+		ClientInfo:   map[string]string{"uid": "test-user-uid"},
+	}
+	close(controller.PodInteractionCh)
+
+	fakeClient := fake.NewSimpleClientset(getPodObject(namespace, podName))
+	contr := controller.NewController(fakeClient, 600)
+	contr.CheckPodInteraction()
+
+	// verify the client metadata is annotated along with the termination time
+	interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, `{"uid":"test-user-uid"}`, interactedPod.Annotations[controller.PodInteractorClientAnnotate])
+	if _, present := interactedPod.Annotations[controller.PodTerminationTimeAnnotate]; !present {
+		t.Error("expected the termination time annotated, got:", interactedPod.Annotations)
+	}
+}
+
+// TestCheckPodInteractionJustification tests controller reducing TTL of unjustified pod interactions in sensitive namespaces
+func TestCheckPodInteractionJustification(t *testing.T) {
+	setupZapLogging(t)
+
+	sensitiveNamespace := "test-namespace-sensitive"
+	regularNamespace := "test-namespace-regular"
+	interactedTime := time.Now()
+	ttlDuration := time.Hour
+	unjustifiedTTL := time.Minute
+
+	justifiedPod := getPodObject(sensitiveNamespace, "test-pod-justified")
+	justifiedPod.SetAnnotations(map[string]string{
+		controller.PodExecJustificationAnnotate:     "debugging an incident",
+		controller.PodExecJustificationTimeAnnotate: strconv.FormatInt(interactedTime.Add(-time.Minute).Unix(), 10),
+	})
+	outdatedPod := getPodObject(sensitiveNamespace, "test-pod-outdated")
+	outdatedPod.SetAnnotations(map[string]string{
+		controller.PodExecJustificationAnnotate:     "debugging an earlier incident",
+		controller.PodExecJustificationTimeAnnotate: strconv.FormatInt(interactedTime.Add(-2*time.Hour).Unix(), 10),
+	})
+	unjustifiedPod := getPodObject(sensitiveNamespace, "test-pod-unjustified")
+	regularPod := getPodObject(regularNamespace, "test-pod-regular")
+	pods := []*corev1.Pod{justifiedPod, outdatedPod, unjustifiedPod, regularPod}
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction, len(pods))
+	for _, pod := range pods {
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: pod.Namespace,
+			PodName:      pod.Name,
+			Username:     "test-user",
+			InitTime:     interactedTime,
+		}
+	}
+	close(controller.PodInteractionCh)
+
+	fakeClient := fake.NewSimpleClientset(justifiedPod, outdatedPod, unjustifiedPod, regularPod)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithJustificationRequirement([]string{sensitiveNamespace}, time.Hour, unjustifiedTTL),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+	contr.CheckPodInteraction()
+
+	// verify only the pods without a recent justification under the sensitive namespace get the reduced TTL
+	expectedTTLs := map[string]time.Duration{
+		justifiedPod.Name:   ttlDuration,
+		outdatedPod.Name:    unjustifiedTTL,
+		unjustifiedPod.Name: unjustifiedTTL,
+		regularPod.Name:     ttlDuration,
+	}
+	for _, pod := range pods {
+		interactedPod, err := fakeClient.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkDeepEquals(t, expectedTTLs[pod.Name].String(), interactedPod.Labels[controller.PodTTLDurationLabel])
+	}
+
+	// verify a flagged event is submitted to each unjustified pod
+	unjustifiedEvents := 0
+	for len(fakeRecorder.Events) > 0 {
+		if event := <-fakeRecorder.Events; strings.Contains(event, "UnjustifiedPodInteraction") {
+			unjustifiedEvents++
+		}
+	}
+	checkDeepEquals(t, 2, unjustifiedEvents)
+}
+
+// TestCheckPodInteractionFutureJustification tests controller treating a pod justified at a future time beyond the
+// clock skew as unjustified, as it would be justified forever otherwise
+func TestCheckPodInteractionFutureJustification(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-sensitive"
+	interactedTime := time.Now()
+	ttlDuration := time.Hour
+	unjustifiedTTL := time.Minute
+	justifiedTimes := map[string]time.Time{
+		"test-pod-skewed": interactedTime.Add(30 * time.Second),
+		"test-pod-future": interactedTime.Add(24 * time.Hour),
+	}
+
+	var podObjs []runtime.Object
+	controller.PodInteractionCh = make(chan controller.PodInteraction, len(justifiedTimes))
+	for podName, justifiedTime := range justifiedTimes {
+		pod := getPodObject(namespace, podName)
+		pod.SetAnnotations(map[string]string{
+			controller.PodExecJustificationAnnotate:     "debugging an incident",
+			controller.PodExecJustificationTimeAnnotate: strconv.FormatInt(justifiedTime.Unix(), 10),
+		})
+		podObjs = append(podObjs, pod)
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: namespace,
+			PodName:      podName,
+			Username:     "test-user",
+			InitTime:     interactedTime,
+		}
+	}
+	close(controller.PodInteractionCh)
+
+	fakeClient := fake.NewSimpleClientset(podObjs...)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithJustificationRequirement([]string{namespace}, time.Hour, unjustifiedTTL),
+	)
+	contr.CheckPodInteraction()
+
+	// verify only the pod justified beyond the clock skew gets the reduced TTL
+	expectedTTLs := map[string]time.Duration{
+		"test-pod-skewed": ttlDuration,
+		"test-pod-future": unjustifiedTTL,
+	}
+	for podName, expectedTTL := range expectedTTLs {
+		interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkDeepEquals(t, expectedTTL.String(), interactedPod.Labels[controller.PodTTLDurationLabel])
+	}
+}
+
+// TestCheckPodInteractionPrivilegedTTL tests controller reducing the TTL of interacted privileged pods
+func TestCheckPodInteractionPrivilegedTTL(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	interactedTime := time.Now()
+	ttlDuration := time.Hour
+	privilegedTTL := 5 * time.Minute
+
+	privileged := true
+	privilegedPod := getPodObject(namespace, "test-pod-privileged")
+	privilegedPod.Spec.Containers = []corev1.Container{
+		{Name: "test-container", SecurityContext: &corev1.SecurityContext{Privileged: &privileged}},
+	}
+	hostNetworkPod := getPodObject(namespace, "test-pod-host-network")
+	hostNetworkPod.Spec.HostNetwork = true
+	hostPIDPod := getPodObject(namespace, "test-pod-host-pid")
+	hostPIDPod.Spec.HostPID = true
+	regularPod := getPodObject(namespace, "test-pod-regular")
+	regularPod.Spec.Containers = []corev1.Container{{Name: "test-container"}}
+	pods := []*corev1.Pod{privilegedPod, hostNetworkPod, hostPIDPod, regularPod}
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction, len(pods))
+	for _, pod := range pods {
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: pod.Namespace,
+			PodName:      pod.Name,
+			Username:     "test-user",
+			InitTime:     interactedTime,
+		}
+	}
+	close(controller.PodInteractionCh)
+
+	fakeClient := fake.NewSimpleClientset(privilegedPod, hostNetworkPod, hostPIDPod, regularPod)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithPrivilegedTTL(privilegedTTL),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+	contr.CheckPodInteraction()
+
+	// verify only the privileged pods get the reduced TTL
+	expectedTTLs := map[string]time.Duration{
+		privilegedPod.Name:  privilegedTTL,
+		hostNetworkPod.Name: privilegedTTL,
+		hostPIDPod.Name:     privilegedTTL,
+		regularPod.Name:     ttlDuration,
+	}
+	for _, pod := range pods {
+		interactedPod, err := fakeClient.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkDeepEquals(t, expectedTTLs[pod.Name].String(), interactedPod.Labels[controller.PodTTLDurationLabel])
+	}
+
+	// verify a flagged event naming the privileged access is submitted to each privileged pod
+	var privilegedEvents []string
+	for len(fakeRecorder.Events) > 0 {
+		if event := <-fakeRecorder.Events; strings.Contains(event, "PrivilegedPodInteraction") {
+			privilegedEvents = append(privilegedEvents, event)
+		}
+	}
+	checkDeepEquals(t, 3, len(privilegedEvents))
+	checkDeepEquals(t, true, strings.Contains(strings.Join(privilegedEvents, "\n"), "privileged container 'test-container'"))
+}
+
+// TestMaxTrackedPodsPerUser tests controller evicting (or shortening the TTL of) the oldest tracked pod of a user
+// interacting with more pods than the max number
+func TestMaxTrackedPodsPerUser(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	ttlDuration := time.Hour
+	for _, name := range []string{"evict", "shorten-ttl"} {
+		podsPolicy, err := controller.ParseTrackedPodsPolicy(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var pods []*corev1.Pod
+		var podObjs []runtime.Object
+		for _, podName := range []string{"test-pod-1", "test-pod-2", "test-pod-3", "test-pod-other"} {
+			pod := getPodObject(namespace, podName)
+			pod.SetUID(types.UID(podName))
+			pods = append(pods, pod)
+			podObjs = append(podObjs, pod)
+		}
+		oldestPod, otherUserPod := pods[0], pods[3]
+		fakeClient := fake.NewSimpleClientset(podObjs...)
+		fakeRecorder := record.NewFakeRecorder(100)
+		contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+			controller.WithMaxTrackedPodsPerUser(2, podsPolicy),
+			controller.WithEventRecorder(fakeRecorder),
+		)
+
+		// interact the pods one by one, the oldest first and the last one by another user
+		interactedTime := time.Now().Add(-10 * time.Minute)
+		for i, pod := range pods {
+			interactor := "test-user"
+			if pod == otherUserPod {
+				interactor = "test-user-other"
+			}
+			mockPodInteraction(namespace, pod.Name, interactor, interactedTime.Add(time.Duration(i)*time.Minute))
+			contr.CheckPodInteraction()
+		}
+		checkEventSubmitted(t, fakeRecorder, "User 'test-user' is tracked in more than 2 interacted Pods")
+
+		if podsPolicy == controller.TrackedPodsPolicyEvict {
+			// verify only the oldest pod of the user exceeding the limit is evicted
+			waitForEviction(t, fakeClient, oldestPod.Name)
+			checkDeepEquals(t, []string{oldestPod.Name}, getEvictedPodNames(fakeClient))
+			continue
+		}
+
+		// verify only the oldest pod of the user exceeding the limit has its TTL shortened, to be evicted shortly
+		for _, pod := range pods {
+			trackedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ttl, err := time.ParseDuration(trackedPod.Labels[controller.PodTTLDurationLabel])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pod != oldestPod {
+				checkDeepEquals(t, ttlDuration, ttl)
+				continue
+			}
+			if ttl < 14*time.Minute || ttl > 16*time.Minute {
+				t.Errorf("expected the TTL of the oldest pod shortened to about 15m, got %s", ttl)
+			}
+		}
+		checkDeepEquals(t, 0, len(getEvictedPodNames(fakeClient)))
+	}
+
+	if _, err := controller.ParseTrackedPodsPolicy("kill"); err == nil {
+		t.Error("expected an error parsing an unsupported tracked pods policy, but got none")
+	}
+}
+
+// TestMaxTrackedPodsPerServiceAccount tests controller enforcing the max number of tracked pods of a service account,
+// whose name is sanitized in the interactor label, passing over its oldest pod held in use for the next oldest one
+func TestMaxTrackedPodsPerServiceAccount(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	interactor := "system:serviceaccount:test-namespace:test-sa"
+	var pods []*corev1.Pod
+	var podObjs []runtime.Object
+	for _, podName := range []string{"test-pod-held", "test-pod-2", "test-pod-3"} {
+		pod := getPodObject(namespace, podName)
+		pod.SetUID(types.UID(podName))
+		pods = append(pods, pod)
+		podObjs = append(podObjs, pod)
+	}
+	heldPod, nextOldestPod := pods[0], pods[1]
+	heldPod.SetAnnotations(map[string]string{controller.PodInUseAnnotate: "true"})
+	fakeClient := fake.NewSimpleClientset(podObjs...)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(time.Hour.Seconds()),
+		controller.WithMaxTrackedPodsPerUser(2, controller.TrackedPodsPolicyEvict),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+
+	// interact the pods one by one, the oldest first
+	interactedTime := time.Now().Add(-10 * time.Minute)
+	for i, pod := range pods {
+		mockPodInteraction(namespace, pod.Name, interactor, interactedTime.Add(time.Duration(i)*time.Minute))
+		contr.CheckPodInteraction()
+	}
+	checkEventSubmitted(t, fakeRecorder, fmt.Sprintf("User '%s' is tracked in more than 2 interacted Pods", interactor))
+
+	// verify the next oldest pod is evicted instead of the held one
+	waitForEviction(t, fakeClient, nextOldestPod.Name)
+	checkDeepEquals(t, []string{nextOldestPod.Name}, getEvictedPodNames(fakeClient))
+}
+
+// TestCheckPodInteractionEventCommand tests controller recording the container and the (truncated) command of a pod
+// interaction in its event message, and up to a larger limit in the event annotations
+func TestCheckPodInteractionEventCommand(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-event-command"
+	podNames := []string{"test-pod", "test-pod-long-command"}
+	longArg := strings.Repeat("é", 5000)
+	controller.PodInteractionCh = make(chan controller.PodInteraction, len(podNames))
+	controller.PodInteractionCh <- controller.PodInteraction{
+		PodNamespace:  namespace,
+		PodName:       podNames[0],
+		ContainerName: "app",
+		Username:      "test-user",
+		Commands:      []string{"mysql", "-psecret", "--host=db"},
+		InitTime:      time.Now(),
+	}
+	controller.PodInteractionCh <- controller.PodInteraction{
+		PodNamespace: namespace,
+		PodName:      podNames[1],
+		Username:     "test-user",
+		Commands:     []string{"echo", longArg},
+		InitTime:     time.Now(),
+	}
+	close(controller.PodInteractionCh)
+
+	fakeClient := fake.NewSimpleClientset(getPodObject(namespace, podNames[0]), getPodObject(namespace, podNames[1]))
+	recorder := &annotatedEventRecorder{}
+	contr := controller.NewController(fakeClient, 600, controller.WithEventRecorder(recorder))
+	contr.CheckPodInteraction()
+
+	// verify the event message names the container and the redacted command, which the annotations keep as well
+	var events []annotatedEvent
+	for _, event := range recorder.getEvents() {
+		// other events are submitted to the pods as well, e.g. of their eviction time
+		if strings.Contains(event.message, "was interacted with") {
+			events = append(events, event)
+		}
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected an interaction event to each pod, got: %v", events)
+	}
+	if !strings.Contains(events[0].message, "running command 'mysql -p*** --host=db', in the container 'app'") {
+		t.Errorf("expected the event message naming the container and command, got: %s", events[0].message)
+	}
+	checkDeepEquals(t, map[string]string{
+		controller.EventInteractionContainerAnnotate: "app",
+		controller.EventInteractionCommandAnnotate:   "mysql -p*** --host=db",
+	}, events[0].annotations)
+
+	// verify a long command is truncated by characters in the event message, and up to a larger limit in the annotation
+	if !strings.Contains(events[1].message, "'echo "+strings.Repeat("é", 252)+" ...(truncated)'") {
+		t.Errorf("expected the long command truncated to 256 characters in the event message, got: %s", events[1].message)
+	}
+	if !utf8.ValidString(events[1].message) {
+		t.Errorf("expected the event message to be valid UTF-8, got: %q", events[1].message)
+	}
+	checkDeepEquals(t, map[string]string{
+		controller.EventInteractionCommandAnnotate: "echo " + strings.Repeat("é", 4092) + " " + controller.CommandTruncatedMarker,
+	}, events[1].annotations)
+}
+
+// TestKubeAPITimeout tests controller timing out a call to a hung K8s API server, retrying it with backoff
+func TestKubeAPITimeout(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-api-timeout"
+	podName := "test-pod"
+	// the pod has been interacted already, so that the server needs no patch applied to return it
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	podObj.SetLabels(map[string]string{
+		controller.PodInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10),
+		controller.PodInteractorLabel:           "test-user",
+		controller.PodTTLDurationLabel:          time.Hour.String(),
+	})
+	podPath := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s", namespace, podName)
+
+	// the first call getting the pod hangs, as if the API server did not respond
+	var podGets, podPatches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// no pod was interacted previously
+		if r.URL.Path == "/api/v1/pods" {
+			json.NewEncoder(w).Encode(corev1.PodList{})
+			return
+		}
+		if r.URL.Path != podPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet && atomic.AddInt32(&podGets, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		if r.Method == http.MethodPatch {
+			atomic.AddInt32(&podPatches, 1)
+		}
+		json.NewEncoder(w).Encode(podObj)
+	}))
+	defer server.Close()
+
+	kubeClient, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	contr := controller.NewController(kubeClient, 600,
+		controller.WithKubeAPITimeout(100*time.Millisecond),
+		controller.WithEventRecorder(record.NewFakeRecorder(100)),
+	)
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+	controller.PodInteractionCh <- controller.PodInteraction{
+		PodNamespace: namespace,
+		PodName:      podName,
+		Username:     "test-user",
+		InitTime:     time.Now(),
+	}
+	close(controller.PodInteractionCh)
+
+	// verify the hung call times out rather than blocking the interaction, which is retried to patch the pod
+	start := time.Now()
+	contr.CheckPodInteraction()
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("expected the hung call timed out, but handling the interaction took %s", elapsed)
+	}
+	if gets := atomic.LoadInt32(&podGets); gets < 2 {
+		t.Errorf("expected getting the pod retried after timing out, got %d call(s)", gets)
+	}
+	if atomic.LoadInt32(&podPatches) == 0 {
+		t.Error("expected the pod patched after retrying the interaction")
+	}
+}
+
+// TestRetryGivenUp tests controller giving up retrying to handle an interaction failing repeatedly, once the max
+// elapsed time of its backoff has passed, with an event to the pod and a metric counting it
+func TestRetryGivenUp(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-retry-given-up"
+	podName := "test-pod"
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	// every patch of the pod fails, so the interaction can never be handled
+	var patches int32
+	fakeClient.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt32(&patches, 1)
+		return true, nil, apierrors.NewServiceUnavailable("test-unavailable")
+	})
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, 600,
+		controller.WithRetryBackoff(time.Second, 100*time.Millisecond),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+
+	giveUps := metrics.GiveUpsTotal.WithLabelValues("pod_interaction")
+	giveUpsBefore := testutil.ToFloat64(giveUps)
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+	controller.PodInteractionCh <- controller.PodInteraction{
+		PodNamespace: namespace,
+		PodName:      podName,
+		Username:     "test-user",
+		InitTime:     time.Now(),
+	}
+	close(controller.PodInteractionCh)
+
+	// verify the retries are given up after the max elapsed time, rather than the default 15 minutes
+	start := time.Now()
+	contr.CheckPodInteraction()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the retries given up after the max elapsed time, but took %s", elapsed)
+	}
+	if retried := atomic.LoadInt32(&patches); retried < 3 {
+		t.Errorf("expected the interaction retried at most 100ms apart before giving up, got %d attempt(s)", retried)
+	}
+
+	// verify the give-up is submitted as an event to the still reachable pod and counted
+	checkEventSubmitted(t, fakeRecorder, "RetryGivenUp Gave up retrying to handle the interaction of the Pod")
+	checkDeepEquals(t, giveUpsBefore+1, testutil.ToFloat64(giveUps))
+	if contr.HasTerminationTimer(podObj.UID) {
+		t.Error("expected no termination timer set to the pod whose interaction is lost")
+	}
+}
+
+// TestCheckPodInteractionEventVerb tests controller naming the verb of an interaction in its event message
+func TestCheckPodInteractionEventVerb(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-event-verb"
+	verbs := map[string]controller.InteractionVerb{
+		"test-pod-exec":         controller.InteractionVerbExec,
+		"test-pod-attach":       controller.InteractionVerbAttach,
+		"test-pod-port-forward": controller.InteractionVerbPortForward,
+		"test-pod-unknown":      "",
+	}
+	var pods []runtime.Object
+	controller.PodInteractionCh = make(chan controller.PodInteraction, len(verbs))
+	for podName, verb := range verbs {
+		pods = append(pods, getPodObject(namespace, podName))
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: namespace,
+			PodName:      podName,
+			Username:     "test-user",
+			InitTime:     time.Now(),
+			Verb:         verb,
+		}
+	}
+	close(controller.PodInteractionCh)
+
+	fakeClient := fake.NewSimpleClientset(pods...)
+	recorder := &annotatedEventRecorder{}
+	contr := controller.NewController(fakeClient, 600, controller.WithEventRecorder(recorder))
+	contr.CheckPodInteraction()
+
+	// verify the event message of each interaction names its verb, or both exec and attach if unknown
+	var messages []string
+	for _, event := range recorder.getEvents() {
+		// other events are submitted to the pods as well, e.g. of their eviction time
+		if strings.Contains(event.message, "was interacted with") {
+			messages = append(messages, event.message)
+		}
+	}
+	if len(messages) != len(verbs) {
+		t.Fatalf("expected an interaction event to each pod, got: %v", messages)
+	}
+	for _, command := range []string{"'kubectl exec'", "'kubectl attach'", "'kubectl port-forward'", "'kubectl exec/attach'"} {
+		found := false
+		for _, message := range messages {
+			found = found || strings.Contains(message, command)
+		}
+		if !found {
+			t.Errorf("expected an interaction event naming %s, got: %v", command, messages)
+		}
+	}
+}
+
+// TestCheckPodInteractionEphemeralContainer tests controller recording an interaction with an ephemeral container
+// (e.g. by "kubectl debug") and still labeling its pod with the TTL
+func TestCheckPodInteractionEphemeralContainer(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod-distroless"
+	ttlDuration := time.Hour
+
+	podObj := getPodObject(namespace, podName)
+	podObj.Spec.Containers = []corev1.Container{{Name: "app"}}
+	podObj.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+		{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger-x7k2p"}, TargetContainerName: "app"},
+	}
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+	controller.PodInteractionCh <- controller.PodInteraction{
+		PodNamespace:  namespace,
+		PodName:       podName,
+		ContainerName: "debugger-x7k2p",
+		Username:      "test-user",
+		InitTime:      time.Now(),
+		Interactive:   true,
+	}
+	close(controller.PodInteractionCh)
+
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()), controller.WithEventRecorder(fakeRecorder))
+	contr.CheckPodInteraction()
+
+	interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, ttlDuration.String(), interactedPod.Labels[controller.PodTTLDurationLabel])
+	checkEventSubmitted(t, fakeRecorder, "in the ephemeral container 'debugger-x7k2p'")
+}
+
+// TestTerminatingPodEventSuppression tests controller tracking a terminating pod without submitting events to it
+func TestTerminatingPodEventSuppression(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-terminating"
+	podName := "test-pod-terminating"
+	ttlDuration := time.Hour
+
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	deletionTimestamp := metav1.Now()
+	podObj.SetDeletionTimestamp(&deletionTimestamp)
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	suppressedCount := testutil.ToFloat64(metrics.SuppressedEventsTotal.WithLabelValues(namespace, "PodInteraction"))
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithEventRecorder(fakeRecorder),
+		controller.WithTerminatingPodEventSuppression(),
+	)
+	contr.CheckPodInteraction()
+
+	// verify the pod is still tracked, but no event is submitted to it
+	interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, "test-user", interactedPod.Labels[controller.PodInteractorLabel])
+	if !contr.HasTerminationTimer(podObj.UID) {
+		t.Error("expected a termination timer of the terminating pod, but got none")
+	}
+	if len(fakeRecorder.Events) > 0 {
+		t.Errorf("expected no event submitted to the terminating pod, got: %s", <-fakeRecorder.Events)
+	}
+	if testutil.ToFloat64(metrics.SuppressedEventsTotal.WithLabelValues(namespace, "PodInteraction")) <= suppressedCount {
+		t.Error("expected the suppressed events counted, but got none")
+	}
+}
+
+// TestEventConfig tests controller submitting events of the type and reason configured for their category
+func TestEventConfig(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-event-config"
+	podName := "test-pod"
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, 1,
+		controller.WithEventRecorder(fakeRecorder),
+		controller.WithEventConfig(controller.EventCategoryInteraction, corev1.EventTypeNormal, "PodExec"),
+		controller.WithEventConfig(controller.EventCategoryEviction, corev1.EventTypeWarning, "PodEvictionScheduled"),
+	)
+	contr.CheckPodInteraction()
+	waitForEviction(t, fakeClient, podName)
+
+	// verify the interaction and eviction events are submitted as configured
+	var events []string
+	for len(fakeRecorder.Events) > 0 {
+		events = append(events, <-fakeRecorder.Events)
+	}
+	expectedPrefixes := []string{
+		"Normal PodExec Pod was interacted with",
+		"Warning PodEvictionScheduled Pod will be evicted at time",
+	}
+	for _, prefix := range expectedPrefixes {
+		submitted := false
+		for _, event := range events {
+			if strings.HasPrefix(event, prefix) {
+				submitted = true
+			}
+		}
+		if !submitted {
+			t.Errorf("expected an event starting with %q, got: %v", prefix, events)
+		}
+	}
+
+	// verify an unsupported event type is rejected
+	if _, err := controller.ParseEventType("Error"); err == nil {
+		t.Error("expected an error parsing an unsupported event type, got nil")
+	}
+}
+
+// TestCheckPodInteractionNamespaceTTL tests controller overriding the TTL of pods by their namespace annotation
+func TestCheckPodInteractionNamespaceTTL(t *testing.T) {
+	setupZapLogging(t)
+
+	interactedTime := time.Now()
+	ttlDuration := time.Hour
+	namespaceTTL := 2 * time.Hour
+
+	overriddenNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "test-namespace-overridden",
+		Annotations: map[string]string{controller.NamespaceTTLDurationAnnotate: namespaceTTL.String()},
+	}}
+	absentNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: "test-namespace-absent",
+	}}
+	malformedNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "test-namespace-malformed",
+		Annotations: map[string]string{controller.NamespaceTTLDurationAnnotate: "two hours"},
+	}}
+	overriddenPod := getPodObject(overriddenNamespace.Name, "test-pod-overridden")
+	absentPod := getPodObject(absentNamespace.Name, "test-pod-absent")
+	malformedPod := getPodObject(malformedNamespace.Name, "test-pod-malformed")
+	pods := []*corev1.Pod{overriddenPod, absentPod, malformedPod}
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction, len(pods))
+	for _, pod := range pods {
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: pod.Namespace,
+			PodName:      pod.Name,
+			Username:     "test-user",
+			InitTime:     interactedTime,
+		}
+	}
+	close(controller.PodInteractionCh)
+
+	fakeClient := fake.NewSimpleClientset(overriddenNamespace, absentNamespace, malformedNamespace,
+		overriddenPod, absentPod, malformedPod)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()))
+	contr.CheckPodInteraction()
+
+	// verify only the pod under the namespace with a valid annotation gets the overridden TTL
+	expectedTTLs := map[string]time.Duration{
+		overriddenPod.Name: namespaceTTL,
+		absentPod.Name:     ttlDuration,
+		malformedPod.Name:  ttlDuration,
+	}
+	for _, pod := range pods {
+		interactedPod, err := fakeClient.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkDeepEquals(t, expectedTTLs[pod.Name].String(), interactedPod.Labels[controller.PodTTLDurationLabel])
+		terminationTime := interactedTime.Add(expectedTTLs[pod.Name]).Truncate(time.Second)
+		checkDeepEquals(t, terminationTime.String(), interactedPod.Annotations[controller.PodTerminationTimeAnnotate])
+	}
+}
+
+// TestEvictionWarningNamespaceLeadTime tests controller warning pods before eviction with the lead time of their namespace
+func TestEvictionWarningNamespaceLeadTime(t *testing.T) {
+	setupZapLogging(t)
+
+	// the termination time is truncated to seconds, so the TTL is kept well above the lead times
+	ttlDuration := time.Duration(4) * time.Second
+	defaultNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: "test-namespace-default",
+	}}
+	overriddenNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "test-namespace-overridden",
+		Annotations: map[string]string{controller.NamespaceEvictionWarningAnnotate: "2s"},
+	}}
+	exceedingNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "test-namespace-exceeding",
+		Annotations: map[string]string{controller.NamespaceEvictionWarningAnnotate: "1h"},
+	}}
+	defaultPod := getPodObject(defaultNamespace.Name, "test-pod-default")
+	overriddenPod := getPodObject(overriddenNamespace.Name, "test-pod-overridden")
+	exceedingPod := getPodObject(exceedingNamespace.Name, "test-pod-exceeding")
+	pods := []*corev1.Pod{defaultPod, overriddenPod, exceedingPod}
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction, len(pods))
+	for _, pod := range pods {
+		pod.SetUID(types.UID(pod.Name))
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: pod.Namespace,
+			PodName:      pod.Name,
+			Username:     "test-user",
+			InitTime:     time.Now(),
+		}
+	}
+	close(controller.PodInteractionCh)
+
+	fakeClient := fake.NewSimpleClientset(defaultNamespace, overriddenNamespace, exceedingNamespace,
+		defaultPod, overriddenPod, exceedingPod)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithEvictionWarning(time.Second),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+	contr.CheckPodInteraction()
+	for _, pod := range pods {
+		waitForEviction(t, fakeClient, pod.Name)
+		waitForTimerRemoval(t, &contr, pod.UID)
+	}
+
+	// verify the pods are warned with the lead time of their namespace, unless exceeding their TTL
+	var warnings []string
+	for len(fakeRecorder.Events) > 0 {
+		if event := <-fakeRecorder.Events; strings.Contains(event, "PodEvictionWarning") {
+			warnings = append(warnings, event)
+		}
+	}
+	if len(warnings) != 2 {
+		t.Fatal("expected 2 eviction warnings, but got", warnings)
+	}
+	for _, leadTime := range []string{"in about 1s", "in about 2s"} {
+		if !strings.Contains(strings.Join(warnings, "\n"), leadTime) {
+			t.Errorf("expected an eviction warning %q, but got %v", leadTime, warnings)
+		}
+	}
+}
+
+// TestEvictionWarningBeforeEviction tests controller recording the eviction warning of a pod before evicting it
+func TestEvictionWarningBeforeEviction(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	ttlDuration := time.Duration(3) * time.Second
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+
+	// capture the events recorded by the time the pod gets evicted
+	var mu sync.Mutex
+	var eventsBeforeEviction []string
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() == "eviction" {
+			mu.Lock()
+			defer mu.Unlock()
+			for len(fakeRecorder.Events) > 0 {
+				eventsBeforeEviction = append(eventsBeforeEviction, <-fakeRecorder.Events)
+			}
+		}
+		return false, nil, nil
+	})
+
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithEvictionWarning(time.Second),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+	contr.CheckPodInteraction()
+	waitForEviction(t, fakeClient, podName)
+	waitForTimerRemoval(t, &contr, podObj.UID)
+
+	mu.Lock()
+	defer mu.Unlock()
+	warned := false
+	for _, event := range eventsBeforeEviction {
+		if strings.Contains(event, "PodEvictionWarning") && strings.Contains(event, "Pod will be evicted in about 1s") {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Error("expected an eviction warning recorded before the eviction, but got", eventsBeforeEviction)
+	}
+}
+
+// TestCheckPodExtensionMaxExtension tests controller capping an extension exceeding the max extension
+func TestCheckPodExtensionMaxExtension(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	interactedTime := time.Now()
+	ttlDuration := time.Hour
+	maxExtension := 30 * time.Minute
+
+	mockPodInteraction(namespace, podName, "test-user", interactedTime)
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithMaxExtension(maxExtension),
+		controller.WithEventRecorder(fakeRecorder),
+	)
+	contr.CheckPodInteraction()
+
+	// mock an extension request exceeding the max extension
+	interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interactedPod.SetAnnotations(map[string]string{
+		controller.PodExtendDurationAnnotate: (2 * time.Hour).String(),
+	})
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate)
+	go func() {
+		defer close(controller.PodExtensionUpdateCh)
+
+		controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: *interactedPod, Username: "test-user"}
+	}()
+	contr.CheckPodExtensionUpdate()
+
+	// verify the termination time is capped to the max extension with an event explaining it
+	extendedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	terminationTime := interactedTime.Add(ttlDuration).Add(maxExtension).Truncate(time.Second)
+	checkDeepEquals(t, terminationTime.String(), extendedPod.Annotations[controller.PodTerminationTimeAnnotate])
+	checkEventSubmitted(t, fakeRecorder, "exceeds the max extension of interacted Pods, capped to '30m0s'")
+}
+
+// TestStreamProducer tests controller publishing records of a pod's interaction, extension and eviction
+func TestStreamProducer(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	ttlDuration := time.Duration(1) * time.Second
+
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	producer := &fakeStreamProducer{}
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithStreamProducer(producer, 10),
+	)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go contr.RunStreamPublisher(stopCh)
+	contr.CheckPodInteraction()
+
+	// mock an extension request by another user
+	interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interactedPod.Annotations[controller.PodExtendDurationAnnotate] = "1s"
+	// the update admitted by the webhook is persisted before the controller handles it
+	interactedPod, err = fakeClient.CoreV1().Pods(namespace).Update(context.TODO(), interactedPod, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate)
+	go func() {
+		defer close(controller.PodExtensionUpdateCh)
+
+		controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: *interactedPod, Username: "test-user-2"}
+	}()
+	contr.CheckPodExtensionUpdate()
+	waitForEviction(t, fakeClient, podName)
+	waitForTimerRemoval(t, &contr, podObj.UID)
+
+	// verify the records are published in order, in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for len(producer.getRecords()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	records := producer.getRecords()
+	if len(records) != 3 {
+		t.Fatal("expected 3 published records, but got", records)
+	}
+	checkDeepEquals(t, controller.StreamRecordInteraction, records[0].Type)
+	checkDeepEquals(t, "test-user", records[0].Username)
+	checkDeepEquals(t, controller.StreamRecordExtension, records[1].Type)
+	checkDeepEquals(t, "test-user-2", records[1].Username)
+	checkDeepEquals(t, "1s", records[1].Extension)
+	checkDeepEquals(t, controller.StreamRecordEviction, records[2].Type)
+	for _, record := range records {
+		checkDeepEquals(t, namespace, record.PodNamespace)
+		checkDeepEquals(t, podName, record.PodName)
+	}
+}
+
+// TestStreamPublisherStop tests controller publishing the records still buffered once its stream publisher is stopped
+func TestStreamPublisherStop(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	fakeClient := fake.NewSimpleClientset(getPodObject(namespace, podName))
+	producer := &fakeStreamProducer{}
+	contr := controller.NewController(fakeClient, 600, controller.WithStreamProducer(producer, 10))
+
+	// verify the record buffered before running the publisher is published once stopped, before it returns
+	contr.CheckPodInteraction()
+	stopCh := make(chan struct{})
+	close(stopCh)
+	contr.RunStreamPublisher(stopCh)
+	records := producer.getRecords()
+	if len(records) != 1 {
+		t.Fatal("expected 1 published record, but got", records)
+	}
+	checkDeepEquals(t, controller.StreamRecordInteraction, records[0].Type)
+
+	// verify running without a producer returns right away
+	unsetContr := controller.NewController(fakeClient, 600)
+	unsetContr.RunStreamPublisher(make(chan struct{}))
+}
+
+// TestHTTPProducer tests publishing a record as JSON to an HTTP endpoint
+func TestHTTPProducer(t *testing.T) {
+	var received controller.StreamRecord
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	producer := controller.NewHTTPProducer(server.URL)
+	record := controller.StreamRecord{
+		Type:         controller.StreamRecordEviction,
+		Timestamp:    time.Now().UTC().Truncate(time.Second),
+		PodNamespace: "test-namespace",
+		PodName:      "test-pod",
+	}
+	if err := producer.Publish(record); err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, record, received)
+
+	// verify a non-2xx response fails the publishing
+	status = http.StatusServiceUnavailable
+	if err := producer.Publish(record); err == nil {
+		t.Error("expected an error publishing to an unavailable endpoint, but got none")
+	}
+}
+
+// TestInteractionNotification tests controller posting a notification of a new interaction to a webhook
+func TestInteractionNotification(t *testing.T) {
+	setupZapLogging(t)
+
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	namespace := "test-namespace-notification"
+	pod := getPodObject(namespace, "test-pod")
+	pod.SetUID("test-pod-uid")
+	fakeClient := fake.NewSimpleClientset(pod)
+	contr := controller.NewController(fakeClient, 600, controller.WithInteractionNotification(server.URL, time.Second))
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go contr.RunInteractionNotifier(stopCh)
+
+	interactedTime := time.Now().UTC().Truncate(time.Second)
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+	controller.PodInteractionCh <- controller.PodInteraction{
+		PodNamespace:  namespace,
+		PodName:       "test-pod",
+		ContainerName: "test-container",
+		Username:      "test-user",
+		Commands:      []string{"mysql", "-psecret"},
+		InitTime:      interactedTime,
+	}
+	close(controller.PodInteractionCh)
+	contr.CheckPodInteraction()
+
+	// verify the payload names the interaction with its redacted command and the eviction time
+	select {
+	case payload := <-received:
+		evictionTime := interactedTime.Add(10 * time.Minute).Format(time.RFC3339)
+		checkDeepEquals(t, map[string]interface{}{
+			"text": fmt.Sprintf("User 'test-user' interacted with Pod '%s/test-pod', running command 'mysql -p***', "+
+				"it will be evicted at %s", namespace, evictionTime),
+			"username":        "test-user",
+			"pod_namespace":   namespace,
+			"pod_name":        "test-pod",
+			"container_name":  "test-container",
+			"commands":        []interface{}{"mysql", "-p***"},
+			"interacted_time": interactedTime.Format(time.RFC3339),
+			"eviction_time":   evictionTime,
+		}, payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a notification posted, but got none")
+	}
+}
+
+// TestInteractionNotifierStop tests controller posting the notifications still buffered once its notifier is stopped
+func TestInteractionNotifierStop(t *testing.T) {
+	setupZapLogging(t)
+
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	namespace := "test-namespace-notification-stop"
+	mockPodInteraction(namespace, "test-pod", "test-user", time.Now())
+	fakeClient := fake.NewSimpleClientset(getPodObject(namespace, "test-pod"))
+	contr := controller.NewController(fakeClient, 600, controller.WithInteractionNotification(server.URL, time.Second))
+
+	// verify the notification buffered before running the notifier is posted once stopped, before it returns
+	contr.CheckPodInteraction()
+	stopCh := make(chan struct{})
+	close(stopCh)
+	contr.RunInteractionNotifier(stopCh)
+	select {
+	case payload := <-received:
+		checkDeepEquals(t, "test-user", payload["username"])
+	default:
+		t.Fatal("expected a notification posted, but got none")
+	}
+}
+
+// TestHistoryConfigMapRingBuffer tests the history ConfigMap keeping the most recent records, wrapping at its capacity
+func TestHistoryConfigMapRingBuffer(t *testing.T) {
+	historyNamespace := "test-history-namespace"
+	fakeClient := fake.NewSimpleClientset()
+	contr := controller.NewController(fakeClient, 60, controller.WithHistoryConfigMap(historyNamespace, 3))
+
+	getRecord := func(i int) controller.StreamRecord {
+		return controller.StreamRecord{
+			Type:         controller.StreamRecordInteraction,
+			Timestamp:    time.Now().UTC().Truncate(time.Second),
+			Username:     "test-user",
+			PodNamespace: "test-namespace",
+			PodName:      fmt.Sprintf("test-pod-%d", i),
+		}
+	}
+	getSlots := func() map[string]string {
+		configMap, err := fakeClient.CoreV1().ConfigMaps(historyNamespace).Get(context.TODO(), controller.HistoryConfigMapName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return configMap.Data
+	}
+	getSlotValue := func(record controller.StreamRecord) string {
+		value, err := json.Marshal(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(value)
+	}
+
+	// append 5 records to a ring buffer of 3, so the first 2 are overwritten
+	records := make([]controller.StreamRecord, 5)
+	for i := range records {
+		records[i] = getRecord(i)
+		if err := contr.AppendHistory(records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkDeepEquals(t, map[string]string{
+		"0":                           getSlotValue(records[3]),
+		"1":                           getSlotValue(records[4]),
+		"2":                           getSlotValue(records[2]),
+		controller.HistoryNextSlotKey: "2",
+	}, getSlots())
+
+	// verify lowering the capacity removes the slots beyond it and restarts from the first slot
+	contr = controller.NewController(fakeClient, 60, controller.WithHistoryConfigMap(historyNamespace, 2))
+	lastRecord := getRecord(5)
+	if err := contr.AppendHistory(lastRecord); err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, map[string]string{
+		"0":                           getSlotValue(lastRecord),
+		"1":                           getSlotValue(records[4]),
+		controller.HistoryNextSlotKey: "1",
+	}, getSlots())
+}
+
+// TestHistoryConfigMapAppender tests the history records appended in the background, capped in size and kept with
+// the default capacity if set to zero
+func TestHistoryConfigMapAppender(t *testing.T) {
+	historyNamespace := "test-history-namespace-appender"
+	namespace := "test-namespace-history-appender"
+	mockPodInteraction(namespace, "test-pod", "test-user", time.Now())
+	fakeClient := fake.NewSimpleClientset(getPodObject(namespace, "test-pod"))
+	contr := controller.NewController(fakeClient, 600, controller.WithHistoryConfigMap(historyNamespace, 0))
+
+	// verify the record buffered before running the appender is appended once stopped, before it returns
+	contr.CheckPodInteraction()
+	if _, err := fakeClient.CoreV1().ConfigMaps(historyNamespace).Get(context.TODO(), controller.HistoryConfigMapName, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected no history ConfigMap before running the appender, but got error: %v", err)
+	}
+	stopCh := make(chan struct{})
+	close(stopCh)
+	contr.RunHistoryAppender(stopCh)
+
+	configMap, err := fakeClient.CoreV1().ConfigMaps(historyNamespace).Get(context.TODO(), controller.HistoryConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record controller.StreamRecord
+	if err := json.Unmarshal([]byte(configMap.Data["0"]), &record); err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, "test-pod", record.PodName)
+	checkDeepEquals(t, "1", configMap.Data[controller.HistoryNextSlotKey])
+
+	// verify the commands of a record exceeding its share of the max data size are truncated, written to the next slot
+	cappedContr := controller.NewController(fakeClient, 600, controller.WithHistoryConfigMap(historyNamespace, controller.MaxHistorySize))
+	oversized := controller.StreamRecord{
+		Type:         controller.StreamRecordInteraction,
+		PodNamespace: namespace,
+		PodName:      "test-pod-oversized",
+		Commands:     []string{strings.Repeat("x", 1024)},
+	}
+	if err := cappedContr.AppendHistory(oversized); err != nil {
+		t.Fatal(err)
+	}
+	configMap, err = fakeClient.CoreV1().ConfigMaps(historyNamespace).Get(context.TODO(), controller.HistoryConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cappedRecord controller.StreamRecord
+	if err := json.Unmarshal([]byte(configMap.Data["1"]), &cappedRecord); err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, "test-pod-oversized", cappedRecord.PodName)
+	checkDeepEquals(t, []string{"...(truncated)"}, cappedRecord.Commands)
+}
+
+// TestCheckPodInUseHold tests controller pausing the eviction of a pod held in use and resuming it once released
+func TestCheckPodInUseHold(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	ttlDuration := time.Duration(1) * time.Second
+
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()), controller.WithEventRecorder(fakeRecorder))
+	contr.CheckPodInteraction()
+
+	interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendInUseToggle := func(pod corev1.Pod) {
+		controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate)
+		go func() {
+			defer close(controller.PodExtensionUpdateCh)
+
+			controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: pod, Username: "test-user", InUseToggled: true}
+		}()
+		contr.CheckPodExtensionUpdate()
+	}
+
+	// verify the pod is not evicted after its TTL while held in use
+	heldPod := interactedPod.DeepCopy()
+	heldPod.Annotations[controller.PodInUseAnnotate] = "true"
+	sendInUseToggle(*heldPod)
+	checkEventSubmitted(t, fakeRecorder, "Pod is held in use by user 'test-user'")
+	time.Sleep(2 * ttlDuration)
+	checkDeepEquals(t, 0, len(getEvictedPodNames(fakeClient)))
+	if !contr.HasTerminationTimer(podObj.UID) {
+		t.Fatal("expected the termination timer of the held pod kept, but got none")
+	}
+
+	// verify the pod is evicted once released as its termination time has passed
+	sendInUseToggle(*interactedPod)
+	checkEventSubmitted(t, fakeRecorder, "Pod is released by user 'test-user'")
+	waitForEviction(t, fakeClient, podName)
+	waitForTimerRemoval(t, &contr, podObj.UID)
+}
+
+// TestCheckPodInUseMaxHold tests controller evicting a pod held in use once its max hold has expired
+func TestCheckPodInUseMaxHold(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-max-hold"
+	podName := "test-pod"
+	ttlDuration := time.Duration(1) * time.Second
+	maxHold := time.Duration(1) * time.Second
+
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithEventRecorder(fakeRecorder),
+		controller.WithMaxHold(maxHold),
+	)
+	contr.CheckPodInteraction()
+
+	interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	heldPod := interactedPod.DeepCopy()
+	heldPod.Annotations[controller.PodInUseAnnotate] = "true"
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate)
+	go func() {
+		defer close(controller.PodExtensionUpdateCh)
+
+		controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: *heldPod, Username: "test-user", InUseToggled: true}
+	}()
+	contr.CheckPodExtensionUpdate()
+	checkEventSubmitted(t, fakeRecorder, "Pod is held in use by user 'test-user', its eviction is paused until released by 'kubectl pi release' or at most until")
+
+	// verify the pod is not evicted after its TTL, but once its max hold expires without being released
+	time.Sleep(ttlDuration + maxHold/2)
+	checkDeepEquals(t, 0, len(getEvictedPodNames(fakeClient)))
+	waitForEviction(t, fakeClient, podName)
+	waitForTimerRemoval(t, &contr, podObj.UID)
+}
+
+// TestCheckPodInteractionAnnotationMetadata tests controller storing and honoring the interaction metadata in annotations
+func TestCheckPodInteractionAnnotationMetadata(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-annotation-metadata"
+	interactedTime := time.Now()
+	ttlDuration := time.Duration(2) * time.Second
+
+	// create a previously interacted pod with its interaction metadata stored in annotations
+	previousInteractedPod := getPodObject(namespace, "test-pod-previous")
+	previousInteractedPod.SetUID(types.UID(previousInteractedPod.Name))
+	previousInteractedPod.SetAnnotations(map[string]string{
+		controller.PodInteractionTimestampLabel: strconv.FormatInt(interactedTime.Unix(), 10),
+		controller.PodTTLDurationLabel:          ttlDuration.String(),
+	})
+
+	// create a newly interacted pod by a user whose name is not a valid label value
+	newInteractedPodName := "test-pod-new"
+	interactedUsername := "system:serviceaccount:test:interactor"
+	mockPodInteraction(namespace, newInteractedPodName, interactedUsername, interactedTime)
+	newInteractedPod := getPodObject(namespace, newInteractedPodName)
+	newInteractedPod.SetUID(types.UID(newInteractedPodName))
+
+	fakeClient := fake.NewSimpleClientset(previousInteractedPod, newInteractedPod)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithInteractionMetadata(controller.InteractionMetadataAnnotations))
+	contr.CheckPodInteraction()
+
+	previousInteractedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), previousInteractedPod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newInteractedPod, err = fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), newInteractedPod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the interaction metadata is set as annotations (with the username unsanitized) and not as labels
+	terminationTime := interactedTime.Add(ttlDuration).Truncate(time.Second)
+	checkDeepEquals(t, terminationTime.String(), previousInteractedPod.Annotations[controller.PodTerminationTimeAnnotate])
+	expectedAnnotations := map[string]string{
+		controller.PodInteractionTimestampLabel: strconv.FormatInt(interactedTime.Unix(), 10),
+		controller.PodTTLDurationLabel:          ttlDuration.String(),
+		controller.PodInteractorLabel:           interactedUsername,
+		controller.PodInteractionCountAnnotate:  "1",
+		controller.PodTerminationTimeAnnotate:   terminationTime.String(),
+		controller.PodLastInteractorAnnotate:    interactedUsername,
+	}
+	checkDeepEquals(t, expectedAnnotations, newInteractedPod.GetAnnotations())
+	if len(newInteractedPod.GetLabels()) != 0 {
+		t.Fatal("expected no interaction labels, got", newInteractedPod.GetLabels())
+	}
+
+	// verify both interacted pods are evicted by the controller at the TTL read from their annotations
+	waitForEviction(t, fakeClient, previousInteractedPod.Name)
+	waitForEviction(t, fakeClient, newInteractedPod.Name)
+}
+
+// TestCustomKeyPrefix tests controller setting and parsing the interaction labels/annotations under a custom prefix
+func TestCustomKeyPrefix(t *testing.T) {
+	setupZapLogging(t)
+
+	if err := controller.SetKeyPrefix("example.com"); err != nil {
+		t.Fatal(err)
+	}
+	defer controller.SetKeyPrefix(controller.DefaultKeyPrefix)
+
+	namespace := "test-namespace-key-prefix"
+	interactedTime := time.Now()
+	ttlDuration := time.Hour
+
+	// create previously interacted pods labeled under the custom and the default prefix
+	previousInteractedPod := getPodObject(namespace, "test-pod-previous")
+	previousInteractedPod.SetUID(types.UID(previousInteractedPod.Name))
+	previousInteractedPod.SetLabels(map[string]string{
+		"example.com/podInitialInteractionTimestamp": strconv.FormatInt(interactedTime.Unix(), 10),
+		"example.com/podTTLDuration":                 ttlDuration.String(),
+	})
+	defaultPrefixedPod := getPodObject(namespace, "test-pod-default-prefix")
+	defaultPrefixedPod.SetUID(types.UID(defaultPrefixedPod.Name))
+	defaultPrefixedPod.SetLabels(map[string]string{
+		"box.com/podInitialInteractionTimestamp": strconv.FormatInt(interactedTime.Unix(), 10),
+		"box.com/podTTLDuration":                 ttlDuration.String(),
+	})
+
+	// create a newly interacted pod by mocking a new pod interaction
+	newInteractedPodName := "test-pod-new"
+	mockPodInteraction(namespace, newInteractedPodName, "test-user", interactedTime)
+	newInteractedPod := getPodObject(namespace, newInteractedPodName)
+	newInteractedPod.SetUID(types.UID(newInteractedPodName))
+
+	fakeClient := fake.NewSimpleClientset(previousInteractedPod, defaultPrefixedPod, newInteractedPod)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()))
+	contr.CheckPodInteraction()
+
+	getPod := func(name string) *corev1.Pod {
+		pod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pod
+	}
+
+	// verify the newly interacted pod is patched under the custom prefix
+	terminationTime := interactedTime.Add(ttlDuration).Truncate(time.Second)
+	newInteractedPod = getPod(newInteractedPodName)
+	checkDeepEquals(t, map[string]string{
+		"example.com/podInitialInteractionTimestamp": strconv.FormatInt(interactedTime.Unix(), 10),
+		"example.com/podTTLDuration":                 ttlDuration.String(),
+		"example.com/podInteractorUsername":          "test-user",
+	}, newInteractedPod.GetLabels())
+	checkDeepEquals(t, map[string]string{
+		"example.com/podTerminationTime":        terminationTime.String(),
+		"example.com/podLastInteractorUsername": "test-user",
+		"example.com/podInteractionCount":       "1",
+	}, newInteractedPod.GetAnnotations())
+
+	// verify only the previously interacted pod labeled under the custom prefix is parsed
+	checkDeepEquals(t, map[string]string{
+		"example.com/podTerminationTime": terminationTime.String(),
+	}, getPod(previousInteractedPod.Name).GetAnnotations())
+	checkDeepEquals(t, 0, len(getPod(defaultPrefixedPod.Name).GetAnnotations()))
+
+	// verify the interaction metadata under the custom prefix is cleared once stale
+	staleController := controller.NewController(fakeClient, int(ttlDuration.Seconds()),
+		controller.WithStaleInteractionCleanup(-2*ttlDuration))
+	if err := staleController.CleanupStaleInteractions(); err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, 0, len(getPod(newInteractedPodName).GetLabels()))
+	checkDeepEquals(t, 0, len(getPod(newInteractedPodName).GetAnnotations()))
+
+	// verify an invalid prefix is rejected
+	if err := controller.SetKeyPrefix("Example_com"); err == nil {
+		t.Error("expected an error setting an invalid key prefix, got nil")
+	}
+}
+
+// TestPreviousKeyPrefix tests controller recognizing the labels/annotations named under the previous key prefix while
+// transitioning to a new one, migrating the Pods tracked under it
+func TestPreviousKeyPrefix(t *testing.T) {
+	setupZapLogging(t)
+
+	if err := controller.SetKeyPrefix("example.com"); err != nil {
+		t.Fatal(err)
+	}
+	defer controller.SetKeyPrefix(controller.DefaultKeyPrefix)
+	if err := controller.SetPreviousKeyPrefix("box.com"); err != nil {
+		t.Fatal(err)
+	}
+	defer controller.SetPreviousKeyPrefix("")
+
+	interactedTime := time.Now()
+	ttlDuration := time.Hour
+	namespaceTTL := 2 * time.Hour
+
+	// the namespace overrides the TTL under the previous prefix
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "test-namespace-previous-key-prefix",
+		Annotations: map[string]string{"box.com/podTTLDuration": namespaceTTL.String()},
+	}}
+
+	// create a previously interacted pod labeled under the previous prefix
+	previousPrefixedPod := getPodObject(namespace.Name, "test-pod-previous-prefix")
+	previousPrefixedPod.SetUID(types.UID(previousPrefixedPod.Name))
+	previousPrefixedPod.SetLabels(map[string]string{
+		"box.com/podInitialInteractionTimestamp": strconv.FormatInt(interactedTime.Unix(), 10),
+		"box.com/podTTLDuration":                 ttlDuration.String(),
+		"box.com/podInteractionCount":            "2",
+	})
+
+	// create a newly interacted pod by mocking a new pod interaction
+	newInteractedPodName := "test-pod-new"
+	mockPodInteraction(namespace.Name, newInteractedPodName, "test-user", interactedTime)
+	newInteractedPod := getPodObject(namespace.Name, newInteractedPodName)
+	newInteractedPod.SetUID(types.UID(newInteractedPodName))
+
+	fakeClient := fake.NewSimpleClientset(namespace, previousPrefixedPod, newInteractedPod)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()))
+	contr.CheckPodInteraction()
+
+	getPod := func(name string) *corev1.Pod {
+		pod, err := fakeClient.CoreV1().Pods(namespace.Name).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pod
+	}
+
+	// verify the pod labeled under the previous prefix is migrated to the current one and tracked
+	checkDeepEquals(t, map[string]string{
+		"example.com/podInitialInteractionTimestamp": strconv.FormatInt(interactedTime.Unix(), 10),
+		"example.com/podTTLDuration":                 ttlDuration.String(),
+		"example.com/podInteractionCount":            "2",
+	}, getPod(previousPrefixedPod.Name).GetLabels())
+	checkDeepEquals(t, map[string]string{
+		"example.com/podTerminationTime": interactedTime.Add(ttlDuration).Truncate(time.Second).String(),
+	}, getPod(previousPrefixedPod.Name).GetAnnotations())
+
+	// verify the newly interacted pod gets the TTL of the namespace annotated under the previous prefix
+	checkDeepEquals(t, namespaceTTL.String(), getPod(newInteractedPodName).GetLabels()["example.com/podTTLDuration"])
+
+	// verify an interaction of a pod still labeled under the previous prefix is counted as a repeated one, moving its
+	// count label to the annotation
+	repeatedPod := getPod(previousPrefixedPod.Name).DeepCopy()
+	repeatedPod.SetLabels(map[string]string{
+		"box.com/podInitialInteractionTimestamp": strconv.FormatInt(interactedTime.Unix(), 10),
+		"box.com/podTTLDuration":                 ttlDuration.String(),
+		"box.com/podInteractionCount":            "2",
+	})
+	if _, err := fakeClient.CoreV1().Pods(namespace.Name).Update(context.TODO(), repeatedPod,
+		metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	mockPodInteraction(namespace.Name, repeatedPod.Name, "test-user", interactedTime.Add(time.Minute))
+	contr.CheckPodInteraction()
+	labels := getPod(repeatedPod.Name).GetLabels()
+	checkDeepEquals(t, strconv.FormatInt(interactedTime.Unix(), 10), labels["example.com/podInitialInteractionTimestamp"])
+	checkDeepEquals(t, "3", getPod(repeatedPod.Name).GetAnnotations()["example.com/podInteractionCount"])
+	checkDeepEquals(t, "", labels["example.com/podInteractionCount"])
+	checkDeepEquals(t, "", labels["box.com/podInteractionCount"])
+
+	// verify an invalid previous prefix or the current one is rejected
+	if err := controller.SetPreviousKeyPrefix("Box_com"); err == nil {
+		t.Error("expected an error setting an invalid previous key prefix, got nil")
+	}
+	if err := controller.SetPreviousKeyPrefix("example.com"); err == nil {
+		t.Error("expected an error setting the current key prefix as the previous one, got nil")
+	}
+}
+
+// TestCheckPodExtensionDays tests controller honoring an extension requested in days
+func TestCheckPodExtensionDays(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-extension-days"
+	podName := "test-pod"
+	interactedTime := time.Now()
+	ttlDuration := time.Hour
+
+	mockPodInteraction(namespace, podName, "test-user", interactedTime)
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()))
+	contr.CheckPodInteraction()
+
+	// mock an extension request of a day, as accepted by the webhook and the plugin
+	interactedTestPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interactedTestPod.Annotations[controller.PodExtendDurationAnnotate] = "1d"
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate, 1)
+	controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: *interactedTestPod, Username: "test-user"}
+	close(controller.PodExtensionUpdateCh)
+	contr.CheckPodExtensionUpdate()
+
+	// verify the termination time is extended by 24 hours
+	extendedTestPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	terminationTime := interactedTime.Add(ttlDuration).Add(24 * time.Hour).Truncate(time.Second)
+	checkDeepEquals(t, terminationTime.String(), extendedTestPod.Annotations[controller.PodTerminationTimeAnnotate])
+}
+
+// TestCheckPodExtensionReduction tests controller moving the termination time of a pod earlier by a negative
+// extension, but never before now
+func TestCheckPodExtensionReduction(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-extension-reduction"
+	podName := "test-pod"
+	interactedTime := time.Now()
+	ttlDuration := 2 * time.Hour
+
+	mockPodInteraction(namespace, podName, "test-user", interactedTime)
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()), controller.WithEventRecorder(fakeRecorder))
+	contr.CheckPodInteraction()
+
+	reduce := func(extension string) time.Time {
+		interactedTestPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		interactedTestPod.Annotations[controller.PodExtendDurationAnnotate] = extension
+		// the update admitted by the webhook is persisted before the controller handles it
+		interactedTestPod, err = fakeClient.CoreV1().Pods(namespace).Update(context.TODO(), interactedTestPod,
+			metav1.UpdateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate, 1)
+		controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: *interactedTestPod, Username: "test-user"}
+		close(controller.PodExtensionUpdateCh)
+		contr.CheckPodExtensionUpdate()
+
+		reducedTestPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal("expected the pod still exists, but failed to get it with err:", err)
+		}
+		terminationTime, err := time.Parse("2006-01-02 15:04:05 -0700 MST",
+			reducedTestPod.Annotations[controller.PodTerminationTimeAnnotate])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return terminationTime
+	}
+
+	// verify the termination time moves earlier by a reduction within the TTL
+	terminationTime := reduce("-1h")
+	expectedTerminationTime := interactedTime.Add(ttlDuration).Add(-time.Hour).Truncate(time.Second)
+	if !terminationTime.Equal(expectedTerminationTime) {
+		t.Errorf("expected the termination time reduced to %s, got %s", expectedTerminationTime, terminationTime)
+	}
+	checkEventSubmitted(t, fakeRecorder, "Pod eviction time has been reduced by '1h'")
+
+	// verify the termination time is not before now by a reduction beyond the TTL, evicting the pod soon instead
+	reducedTime := time.Now()
+	terminationTime = reduce("-3h")
+	if terminationTime.Before(reducedTime.Truncate(time.Second)) || terminationTime.After(reducedTime.Add(time.Minute)) {
+		t.Errorf("expected a termination time shortly after %s, got %s", reducedTime, terminationTime)
+	}
+	if !contr.HasTerminationTimer(podObj.UID) {
+		t.Error("expected the termination timer of the pod kept until its reduced termination time")
+	}
+}
+
+// TestCheckPodExtensionReductionWithEvictionLease tests a pod reduced beyond its TTL being evicted soon while the
+// eviction lease re-reads its termination time before evicting it
+func TestCheckPodExtensionReductionWithEvictionLease(t *testing.T) {
+	setupZapLogging(t)
+	defer controller.SetMinReducedRemainingDuration(time.Second)()
+
+	namespace := "test-namespace-extension-reduction-lease"
+	podName := "test-pod"
+	ttlDuration := 2 * time.Hour
+
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	// keep the pod object as is on eviction, as the fake client otherwise replaces it with the Eviction object
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return action.GetSubresource() == "eviction", nil, nil
+	})
+	contr := controller.NewController(fakeClient, int(ttlDuration.Seconds()), controller.WithEvictionLease("replica-a"))
+	contr.CheckPodInteraction()
+
+	interactedTestPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interactedTestPod.Annotations[controller.PodExtendDurationAnnotate] = "-3h"
+	// the update admitted by the webhook is persisted before the controller handles it
+	interactedTestPod, err = fakeClient.CoreV1().Pods(namespace).Update(context.TODO(), interactedTestPod,
+		metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate, 1)
+	controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: *interactedTestPod, Username: "test-user"}
+	close(controller.PodExtensionUpdateCh)
+	contr.CheckPodExtensionUpdate()
+
+	// verify the floored reduction is stored, so the termination time does not move when calculated again
+	reducedTestPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extension := reducedTestPod.Annotations[controller.PodExtendDurationAnnotate]; extension == "-3h" {
+		t.Errorf("expected the reduction beyond the TTL floored, got the extension %s", extension)
+	}
+
+	// verify the pod is evicted rather than postponed once its reduced termination time comes
+	waitForEviction(t, fakeClient, podName)
+}
+
+// TestCheckPodExtensionByAnotherUser tests controller flagging an extension requested by someone other than the interactor
+func TestCheckPodExtensionByAnotherUser(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-foreign-extension"
+	podName := "test-pod"
+	interactor := "system:serviceaccount:test:interactor"
+	mockPodInteraction(namespace, podName, interactor, time.Now())
+
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, 600, controller.WithEventRecorder(fakeRecorder))
+	contr.CheckPodInteraction()
+
+	interactedTestPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interactedTestPod.Annotations[controller.PodExtendDurationAnnotate] = "1h"
+
+	// mock extension requests from the original interactor and another user
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate, 2)
+	controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: *interactedTestPod, Username: interactor}
+	controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{Pod: *interactedTestPod, Username: "test-requester"}
+	close(controller.PodExtensionUpdateCh)
+	contr.CheckPodExtensionUpdate()
+
+	// verify only the extension from another user is flagged
+	var foreignExtensionEvents []string
+	for len(fakeRecorder.Events) > 0 {
+		if event := <-fakeRecorder.Events; strings.Contains(event, "ForeignPodExtension") {
+			foreignExtensionEvents = append(foreignExtensionEvents, event)
+		}
+	}
+	if len(foreignExtensionEvents) != 1 || !strings.Contains(foreignExtensionEvents[0], "test-requester") {
+		t.Fatal("expected a single event flagging the extension from test-requester, got", foreignExtensionEvents)
+	}
+	checkDeepEquals(t, float64(1), testutil.ToFloat64(metrics.ForeignExtensionsTotal.WithLabelValues(namespace)))
+}
+
+// TestHasSynced tests controller being unready until its caches are synced and previous interactions are checked
+func TestHasSynced(t *testing.T) {
+	setupZapLogging(t)
+
+	fakeClient := fake.NewSimpleClientset()
+	contr := controller.NewController(fakeClient, 600)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	contr.WatchKillSwitch("test-config-namespace", stopCh)
+
+	// verify the controller is unready before checking previous interactions
+	if contr.HasSynced() {
+		t.Fatal("expected the controller unready before checking previous interactions")
+	}
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction)
+	close(controller.PodInteractionCh)
+	contr.CheckPodInteraction()
+
+	// verify the controller is ready after checking previous interactions
+	if !contr.HasSynced() {
+		t.Fatal("expected the controller ready after checking previous interactions")
+	}
+}
+
+// TestCheckPodExtensionHistory tests controller keeping a bounded history of the extensions requested to a pod
+func TestCheckPodExtensionHistory(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	contr := controller.NewController(fakeClient, 600)
+	contr.CheckPodInteraction()
+
+	// mock more extension requests than the history can keep, each based on the latest pod object
+	requestsCount := 12
+	for i := 1; i <= requestsCount; i++ {
+		interactedTestPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		interactedTestPod.Annotations[controller.PodExtendDurationAnnotate] = fmt.Sprintf("%dm", i)
+
+		controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate, 1)
+		controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{
+			Pod:      *interactedTestPod,
+			Username: fmt.Sprintf("test-requester-%d", i),
+		}
+		close(controller.PodExtensionUpdateCh)
+		contr.CheckPodExtensionUpdate()
+	}
+
+	// verify only the most recent 10 extensions are kept in order
+	extendedTestPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var history []controller.ExtensionRecord
+	if err := json.Unmarshal([]byte(extendedTestPod.Annotations[controller.PodExtensionHistoryAnnotate]), &history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 10 {
+		t.Fatalf("expected 10 extension records kept, got %d: %v", len(history), history)
+	}
+	for i, record := range history {
+		requestNum := requestsCount - len(history) + i + 1
+		checkDeepEquals(t, fmt.Sprintf("test-requester-%d", requestNum), record.Requester)
+		checkDeepEquals(t, fmt.Sprintf("%dm", requestNum), record.Duration)
+		if time.Since(record.Time) > time.Minute {
+			t.Errorf("expected a recent extension time, got %s", record.Time)
+		}
+	}
+}
+
+// TestCheckPodInteractionAndExtensionConcurrently tests controller handling new interactions and extensions
+// at the same time, which is expected to be run with "-race" to detect any data race
+func TestCheckPodInteractionAndExtensionConcurrently(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podsCount := 100
+	var pods []runtime.Object
+	for i := 0; i < podsCount; i++ {
+		// a previously interacted pod to be extended
+		interactedPod := getPodObject(namespace, fmt.Sprintf("interacted-pod-%d", i))
+		interactedPod.SetUID(types.UID(interactedPod.Name))
+		interactedPod.SetLabels(map[string]string{
+			controller.PodInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10),
+			controller.PodTTLDurationLabel:          time.Hour.String(),
+		})
+
+		// a new pod to be interacted
+		newPod := getPodObject(namespace, fmt.Sprintf("new-pod-%d", i))
+		newPod.SetUID(types.UID(newPod.Name))
+
+		pods = append(pods, interactedPod, newPod)
+	}
+	fakeClient := fake.NewSimpleClientset(pods...)
+	contr := controller.NewController(fakeClient, 3600)
+
+	// set termination timers of the previously interacted pods
+	controller.PodInteractionCh = make(chan controller.PodInteraction)
+	close(controller.PodInteractionCh)
+	contr.CheckPodInteraction()
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction, podsCount)
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate, podsCount)
+	for i := 0; i < podsCount; i++ {
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: namespace,
+			PodName:      fmt.Sprintf("new-pod-%d", i),
+			InitTime:     time.Now(),
+			Username:     "test-interactor",
+		}
+
+		interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), fmt.Sprintf("interacted-pod-%d", i), metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		interactedPod.Annotations[controller.PodExtendDurationAnnotate] = "30m"
+		controller.PodExtensionUpdateCh <- controller.PodExtensionUpdate{
+			Pod:      *interactedPod,
+			Username: "test-requester",
+		}
+	}
+	close(controller.PodInteractionCh)
+	close(controller.PodExtensionUpdateCh)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		contr.CheckPodInteraction()
+	}()
+	go func() {
+		defer wg.Done()
+		contr.CheckPodExtensionUpdate()
+	}()
+	wg.Wait()
+
+	// verify all pods are handled as expected
+	for i := 0; i < podsCount; i++ {
+		newPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), fmt.Sprintf("new-pod-%d", i), metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, present := newPod.Annotations[controller.PodTerminationTimeAnnotate]; !present {
+			t.Errorf("expected pod %s to have a termination time set, got annotations: %v", newPod.Name, newPod.Annotations)
+		}
+
+		extendedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), fmt.Sprintf("interacted-pod-%d", i), metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkDeepEquals(t, "test-requester", extendedPod.Annotations[controller.PodExtendRequesterAnnotate])
+	}
+}
+
+// TestCheckPodInteractionWorkers tests controller handling many new interactions concurrently by multiple workers,
+// which is expected to be run with "-race" to detect any data race
+func TestCheckPodInteractionWorkers(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-workers"
+	podsCount := 40
+	var pods []runtime.Object
+	for i := 0; i < podsCount; i++ {
+		pod := getPodObject(namespace, fmt.Sprintf("test-pod-%d", i))
+		pod.SetUID(types.UID(pod.Name))
+		pods = append(pods, pod)
+	}
+	// mock a slow API server receiving the events, recording the max number of them submitted at the same time
+	// (the fake client serializes its reactors, so they cannot mock it)
+	fakeRecorder := &slowEventRecorder{delay: 10 * time.Millisecond}
+	fakeClient := fake.NewSimpleClientset(pods...)
+	contr := controller.NewController(fakeClient, 3600,
+		controller.WithEventRecorder(fakeRecorder),
+		controller.WithInteractionWorkers(4),
+	)
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction, podsCount)
+	for i := 0; i < podsCount; i++ {
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: namespace,
+			PodName:      fmt.Sprintf("test-pod-%d", i),
+			InitTime:     time.Now(),
+			Username:     "test-interactor",
+		}
+	}
+	close(controller.PodInteractionCh)
+	contr.CheckPodInteraction()
+
+	// verify the interactions are handled concurrently and all pods are labeled
+	if max := fakeRecorder.getMaxInFlight(); max < 2 {
+		t.Errorf("expected the events submitted concurrently by multiple workers, got at most %d at a time", max)
+	}
+	for i := 0; i < podsCount; i++ {
+		pod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), fmt.Sprintf("test-pod-%d", i), metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkDeepEquals(t, "test-interactor", pod.Labels[controller.PodInteractorLabel])
+		if !contr.HasTerminationTimer(pod.UID) {
+			t.Errorf("expected a termination timer set for pod %s", pod.Name)
+		}
+	}
+}
+
+// TestInteractionDedup tests controller coalescing repeated interactions of a pod within the dedup window, labeling
+// the pod once and counting all of them
+func TestInteractionDedup(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-dedup"
+	podName := "test-pod"
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, 3600,
+		controller.WithEventRecorder(fakeRecorder),
+		controller.WithTTLMode(controller.TTLModeIdle),
+		controller.WithInteractionDedup(100*time.Millisecond),
+	)
+
+	// fire repeated interactions of the pod, the latest one last
+	interactionsCount := 5
+	interactedTime := time.Now().Truncate(time.Second)
+	controller.PodInteractionCh = make(chan controller.PodInteraction, interactionsCount)
+	for i := 0; i < interactionsCount; i++ {
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: namespace,
+			PodName:      podName,
+			InitTime:     interactedTime.Add(time.Duration(i) * time.Second),
+			Username:     "test-user",
+		}
+	}
+	close(controller.PodInteractionCh)
+	contr.CheckPodInteraction()
+
+	// verify the pod is labeled and its interaction is submitted as an event only once
+	getPod := func() *corev1.Pod {
+		pod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pod
+	}
+	checkDeepEquals(t, map[string]string{
+		controller.PodInteractionTimestampLabel: strconv.FormatInt(interactedTime.Unix(), 10),
+		controller.PodInteractorLabel:           "test-user",
+		controller.PodTTLDurationLabel:          time.Hour.String(),
+	}, getPod().GetLabels())
+	checkDeepEquals(t, "1", getPod().Annotations[controller.PodInteractionCountAnnotate])
+	interactionEvents := 0
+	for len(fakeRecorder.Events) > 0 {
+		if strings.Contains(<-fakeRecorder.Events, "Pod was interacted with") {
+			interactionEvents++
+		}
+	}
+	checkDeepEquals(t, 1, interactionEvents)
+
+	// verify the coalesced interactions are counted at the end of the window, resetting the TTL from the latest one
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && getPod().Annotations[controller.PodInteractionCountAnnotate] == "1" {
+		time.Sleep(10 * time.Millisecond)
+	}
+	pod := getPod()
+	checkDeepEquals(t, strconv.Itoa(interactionsCount), pod.Annotations[controller.PodInteractionCountAnnotate])
+	latestTime := interactedTime.Add(time.Duration(interactionsCount-1) * time.Second)
+	checkDeepEquals(t, strconv.FormatInt(latestTime.Unix(), 10),
+		pod.Annotations[controller.PodLastInteractionTimestampAnnotate])
+	checkDeepEquals(t, latestTime.Add(time.Hour).String(), pod.Annotations[controller.PodTerminationTimeAnnotate])
+}
+
+// TestDrainInteractionDedup tests controller draining the repeated interactions of a pod coalesced within the dedup
+// window right away rather than at the end of the window
+func TestDrainInteractionDedup(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-dedup-drain"
+	podName := "test-pod"
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	contr := controller.NewController(fakeClient, 3600, controller.WithInteractionDedup(time.Hour))
+
+	interactionsCount := 3
+	controller.PodInteractionCh = make(chan controller.PodInteraction, interactionsCount)
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate)
+	for i := 0; i < interactionsCount; i++ {
+		controller.PodInteractionCh <- controller.PodInteraction{
+			PodNamespace: namespace,
+			PodName:      podName,
+			InitTime:     time.Now(),
+			Username:     "test-user",
+		}
+	}
+	close(controller.PodInteractionCh)
+	close(controller.PodExtensionUpdateCh)
+	contr.CheckPodInteraction()
+	contr.CheckPodExtensionUpdate()
+
+	// verify the coalesced interactions are counted once drained, long before the end of the window
+	if !contr.Drain(5 * time.Second) {
+		t.Fatal("expected the coalesced interactions drained without dead-lettering any of them")
+	}
+	pod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, strconv.Itoa(interactionsCount), pod.Annotations[controller.PodInteractionCountAnnotate])
+}
+
+// TestInteractionDedupRecreatedPod tests controller never coalescing an interaction of a pod recreated under the same
+// name within the dedup window with the ones of its previous pod, telling them apart by their UIDs in the cache
+func TestInteractionDedupRecreatedPod(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-dedup-recreated"
+	podName := "test-pod"
+	getTrackedPodObject := func(uid string) *corev1.Pod {
+		podObj := getPodObject(namespace, podName)
+		podObj.SetUID(types.UID(uid))
+		podObj.SetLabels(map[string]string{controller.PodTrackingLabel: "true"})
+		return podObj
+	}
+	fakeClient := fake.NewSimpleClientset(getTrackedPodObject("test-uid-previous"))
+	contr := controller.NewController(fakeClient, 3600, controller.WithInteractionDedup(time.Hour))
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	contr.WatchTrackedPods(stopCh)
+	waitForCache := func(cached bool) {
+		deadline := time.Now().Add(5 * time.Second)
+		for contr.IsPodCached(namespace, podName) != cached {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected the pod cached: %t, got the opposite", cached)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitForCache(true)
+
+	// interact with the pod, opening its window, then recreate it under the same name
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	contr.CheckPodInteraction()
+	if err := fakeClient.CoreV1().Pods(namespace).Delete(context.TODO(), podName, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForCache(false)
+	_, err := fakeClient.CoreV1().Pods(namespace).Create(context.TODO(), getTrackedPodObject("test-uid-recreated"),
+		metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForCache(true)
+
+	// verify an interaction of the recreated pod is handled right away rather than at the end of the window
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	contr.CheckPodInteraction()
+	pod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDeepEquals(t, "1", pod.Annotations[controller.PodInteractionCountAnnotate])
+	if !contr.HasTerminationTimer(pod.UID) {
+		t.Error("expected the recreated pod tracked with a termination timer, but it is not")
+	}
+}
+
+// TestWatchTrackedPods tests controller reading a newly interacted pod labeled by the mutating webhook from the cache
+// rather than getting it, while handling it from the latest pod if the cache is stale
+func TestWatchTrackedPods(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace-tracked"
+	podName := "test-pod"
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	podObj.SetLabels(map[string]string{controller.PodTrackingLabel: "true"})
+	podObj.SetResourceVersion("1")
+	staleName := "test-pod-stale"
+	stalePodObj := getPodObject(namespace, staleName)
+	stalePodObj.SetUID(types.UID(staleName))
+	stalePodObj.SetLabels(map[string]string{controller.PodTrackingLabel: "true"})
+	stalePodObj.SetResourceVersion("1")
+	fakeClient := fake.NewSimpleClientset(podObj, stalePodObj)
+
+	// the fake client does not bump the resource version, so the labeling of the cached stale pod is refused once
+	var staleOnce sync.Once
+	fakeClient.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(k8stesting.PatchAction)
+		refused := false
+		if patchAction.GetName() == staleName && strings.Contains(string(patchAction.GetPatch()), `"op":"test"`) {
+			staleOnce.Do(func() { refused = true })
+		}
+		if refused {
+			return true, nil, fmt.Errorf("the pod %s has changed since cached", staleName)
+		}
+		return false, nil, nil
+	})
+	fakeRecorder := record.NewFakeRecorder(100)
+	contr := controller.NewController(fakeClient, 3600, controller.WithEventRecorder(fakeRecorder))
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	contr.WatchTrackedPods(stopCh)
+	if !contr.IsPodCached(namespace, podName) || !contr.IsPodCached(namespace, staleName) {
+		t.Fatal("expected the tracked pods cached once watched, but got none")
+	}
+
+	getPodActions := func() int {
+		count := 0
+		for _, action := range fakeClient.Actions() {
+			if action.GetVerb() == "get" && action.GetResource().Resource == "pods" {
+				count++
+			}
+		}
+		return count
+	}
+
+	// verify the pod is labeled with the interaction without getting it
+	mockPodInteraction(namespace, podName, "test-user", time.Now())
+	contr.CheckPodInteraction()
+	interactedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, present := interactedPod.Labels[controller.PodInteractionTimestampLabel]; !present {
+		t.Fatalf("expected the tracked pod labeled with the interaction, got: %v", interactedPod.Labels)
+	}
+	checkDeepEquals(t, 1, getPodActions())
+
+	// verify an interaction of a pod changed since cached is retried from the cache, handling it only once
+	for len(fakeRecorder.Events) > 0 {
+		<-fakeRecorder.Events
+	}
+	mockPodInteraction(namespace, staleName, "test-user", time.Now())
+	contr.CheckPodInteraction()
+	stalePod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), staleName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, present := stalePod.Labels[controller.PodInteractionTimestampLabel]; !present {
+		t.Fatalf("expected the stale pod labeled with the interaction, got: %v", stalePod.Labels)
+	}
+	checkDeepEquals(t, 2, getPodActions())
+	interactionEvents := 0
+	for len(fakeRecorder.Events) > 0 {
+		if strings.Contains(<-fakeRecorder.Events, "Pod was interacted") {
+			interactionEvents++
+		}
+	}
+	checkDeepEquals(t, 1, interactionEvents)
+}
+
+// TestLastInteractor tests controller counting the interactions of a pod by different users and annotating the
+// latest interactor, while keeping the initial interactor and timestamp
+func TestLastInteractor(t *testing.T) {
+	setupZapLogging(t)
+
+	namespace := "test-namespace"
+	podName := "test-pod"
+	podObj := getPodObject(namespace, podName)
+	podObj.SetUID(types.UID(podName))
+	fakeClient := fake.NewSimpleClientset(podObj)
+	contr := controller.NewController(fakeClient, 3600)
+	getPod := func() *corev1.Pod {
+		pod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pod
+	}
+
+	// exec into the pod by a user, then by another one later
+	interactedTime := time.Now().Truncate(time.Second)
+	mockPodInteraction(namespace, podName, "test-user-1", interactedTime)
+	contr.CheckPodInteraction()
+	pod := getPod()
+	checkDeepEquals(t, "1", pod.Annotations[controller.PodInteractionCountAnnotate])
+	checkDeepEquals(t, "test-user-1", pod.Annotations[controller.PodLastInteractorAnnotate])
+
+	mockPodInteraction(namespace, podName, "test-user-2", interactedTime.Add(time.Minute))
+	contr.CheckPodInteraction()
+	pod = getPod()
+	checkDeepEquals(t, "2", pod.Annotations[controller.PodInteractionCountAnnotate])
+	checkDeepEquals(t, "test-user-2", pod.Annotations[controller.PodLastInteractorAnnotate])
+	checkDeepEquals(t, "test-user-1", pod.Labels[controller.PodInteractorLabel])
+	checkDeepEquals(t, strconv.FormatInt(interactedTime.Unix(), 10), pod.Labels[controller.PodInteractionTimestampLabel])
+	checkDeepEquals(t, interactedTime.Add(time.Hour).String(), pod.Annotations[controller.PodTerminationTimeAnnotate])
+}
+
+/*
+  Helper functions used by the testings above.
+*/
+
+// setupZapLogging gives better visibility when running a test
+func setupZapLogging(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	zap.ReplaceGlobals(logger)
+}
+
+// mockPodInteraction sends a new PodInteraction with the given namespace and pod name to PodInteractionCh
+func mockPodInteraction(namespace, podName, interactor string, interactedTime time.Time) {
+	podInteraction := controller.PodInteraction{
+		PodNamespace: namespace,
+		PodName:      podName,
+		InitTime:     interactedTime,
+		Username:     interactor,
+	}
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction)
+	go func() {
+		defer close(controller.PodInteractionCh)
+
+		controller.PodInteractionCh <- podInteraction
+	}()
+}
+
+// annotatedEvent is a K8s event captured by annotatedEventRecorder
+type annotatedEvent struct {
+	message     string
+	annotations map[string]string
+}
+
+// annotatedEventRecorder captures the submitted events along with their annotations, which record.FakeRecorder drops
+type annotatedEventRecorder struct {
+	mu     sync.Mutex
+	events []annotatedEvent
+}
+
+func (r *annotatedEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, "%s", message)
+}
+
+func (r *annotatedEventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, messageFmt, args...)
+}
+
+func (r *annotatedEventRecorder) AnnotatedEventf(_ runtime.Object, annotations map[string]string, _, _, messageFmt string,
+	args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, annotatedEvent{message: fmt.Sprintf(messageFmt, args...), annotations: annotations})
+}
+
+func (r *annotatedEventRecorder) getEvents() []annotatedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]annotatedEvent{}, r.events...)
+}
+
+// slowEventRecorder delays every submitted event, recording the max number of events submitted at the same time
+type slowEventRecorder struct {
+	delay time.Duration
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (r *slowEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, "%s", message)
+}
+
+func (r *slowEventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, messageFmt, args...)
+}
+
+func (r *slowEventRecorder) AnnotatedEventf(runtime.Object, map[string]string, string, string, string, ...interface{}) {
+	r.mu.Lock()
+	r.inFlight++
+	if r.inFlight > r.maxInFlight {
+		r.maxInFlight = r.inFlight
+	}
+	r.mu.Unlock()
+
+	time.Sleep(r.delay)
+
+	r.mu.Lock()
+	r.inFlight--
+	r.mu.Unlock()
+}
+
+func (r *slowEventRecorder) getMaxInFlight() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.maxInFlight
+}
+
+// fakeStreamProducer captures the published records
+type fakeStreamProducer struct {
+	mu      sync.Mutex
+	records []controller.StreamRecord
+}
+
+func (p *fakeStreamProducer) Publish(record controller.StreamRecord) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.records = append(p.records, record)
+	return nil
+}
+
+func (p *fakeStreamProducer) getRecords() []controller.StreamRecord {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]controller.StreamRecord(nil), p.records...)
+}
+
+// getPodObject returns a new corev1.Pod object with tbe given namespace and pod name
+func getPodObject(namespace, podName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: namespace,
+		},
+	}
+}
+
+func checkDeepEquals(t *testing.T, expected, actual interface{}) {
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %s, got: %s", expected, actual)
+	}
+}
+
+// getEvictedPodNames returns names of the pods evicted through the given fake client
+func getEvictedPodNames(fakeClient *fake.Clientset) []string {
+	var podNames []string
+	for _, action := range fakeClient.Actions() {
+		if action.GetVerb() == "create" && action.GetSubresource() == "eviction" {
+			createAction := action.(k8stesting.CreateAction)
+			podNames = append(podNames, createAction.GetObject().(metav1.Object).GetName())
+		}
+	}
+
+	return podNames
+}
+
+// getDeletedPodNames returns the names of the pods deleted directly (rather than evicted) through the fake client
+func getDeletedPodNames(fakeClient *fake.Clientset) []string {
+	var podNames []string
+	for _, action := range fakeClient.Actions() {
+		if action.GetVerb() == "delete" && action.GetResource().Resource == "pods" && action.GetSubresource() == "" {
+			podNames = append(podNames, action.(k8stesting.DeleteAction).GetName())
+		}
+	}
+
+	return podNames
+}
+
+// checkEventSubmitted checks if an event containing the given message is submitted to the given fake recorder
+func checkEventSubmitted(t *testing.T, fakeRecorder *record.FakeRecorder, message string) {
+	submitted := false
+	for len(fakeRecorder.Events) > 0 {
+		if strings.Contains(<-fakeRecorder.Events, message) {
+			submitted = true
+		}
+	}
+
+	if !submitted {
+		t.Errorf("expected an event with message %q, but got none", message)
+	}
+}
+
+// getExtensionHistory returns the extension history annotation expected of the given pod with the given records,
+// which are requested recently at the time recorded in its actual one
+func getExtensionHistory(t *testing.T, pod corev1.Pod, expected ...controller.ExtensionRecord) string {
+	var history []controller.ExtensionRecord
+	if err := json.Unmarshal([]byte(pod.Annotations[controller.PodExtensionHistoryAnnotate]), &history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != len(expected) {
+		t.Fatalf("expected %d extension records, got %d: %v", len(expected), len(history), history)
+	}
+	for i, record := range history {
+		if time.Since(record.Time) > time.Minute {
+			t.Errorf("expected a recent extension time, got %s", record.Time)
+		}
+		expected[i].Time = record.Time
+	}
+
+	historyJSON, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(historyJSON)
+}
+
+// waitForTimerRemoval waits until the given controller removes the termination timer of the given pod UID
+func waitForTimerRemoval(t *testing.T, contr *controller.Controller, uid types.UID) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if !contr.HasTerminationTimer(uid) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("expected the termination timer of pod %s to be removed, but it still exists", uid)
+}
+
+// waitForEviction waits until the pod of the given name is evicted through the given fake client
+func waitForEviction(t *testing.T, fakeClient *fake.Clientset, podName string) {
+	waitForTermination(t, fakeClient, podName, controller.TerminationModeEvict)
+}
+
+// waitForTermination waits until the given pod is terminated through the API of the given termination mode, which
+// is asserted from the actions recorded by the fake client rather than the pod being gone, as the fake client does
+// not delete an evicted pod
+func waitForTermination(t *testing.T, fakeClient *fake.Clientset, podName string, mode controller.TerminationMode) {
+	getTerminatedPodNames := getEvictedPodNames
+	if mode == controller.TerminationModeDelete {
+		getTerminatedPodNames = getDeletedPodNames
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, terminatedPodName := range getTerminatedPodNames(fakeClient) {
+			if terminatedPodName == podName {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("expected pod %s to be terminated in the %s mode, got: %v", podName, mode, getTerminatedPodNames(fakeClient))
 }
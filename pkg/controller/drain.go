@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/box/kube-exec-controller/pkg/metrics"
+)
+
+// drainState tracks the workers consuming PodInteractionCh and PodExtensionUpdateCh (as well as the windows of
+// coalescing repeated interactions) and the items they are handling, so that Drain can dead-letter the items in
+// progress if they are still unhandled at the drain deadline.
+type drainState struct {
+	workers sync.WaitGroup
+
+	mu                sync.Mutex
+	interactions      map[int]PodInteraction // keyed by the interaction worker handling it
+	interactionShards []chan PodInteraction  // set if the interactions are fanned out to multiple workers
+	update            *PodExtensionUpdate
+}
+
+// Drain waits for the items left in PodInteractionCh and PodExtensionUpdateCh to be handled by CheckPodInteraction
+// and CheckPodExtensionUpdate, which return once the channels are closed and empty. It must be called after closing
+// both channels, e.g. on shutdown once no more items are sent. The repeated interactions coalesced by
+// WithInteractionDedup are handled right away rather than at the end of their windows. Items still unhandled at the
+// given deadline, including the ones in progress, are dead-lettered (logged and counted by
+// metrics.DeadLetteredItemsTotal) so that a single slow item cannot block the shutdown. It returns false if any item
+// is dead-lettered.
+func (c *Controller) Drain(deadline time.Duration) bool {
+	c.endInteractionWindows()
+
+	drained := make(chan struct{})
+	go func() {
+		c.drainState.workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return true
+	case <-time.After(deadline):
+	}
+
+	c.drainState.mu.Lock()
+	for _, pi := range c.drainState.interactions {
+		deadLetterInteraction(pi)
+	}
+	if c.drainState.update != nil {
+		deadLetterExtensionUpdate(*c.drainState.update)
+	}
+	shards := c.drainState.interactionShards
+	c.drainState.mu.Unlock()
+	c.deadLetterCoalescedInteractions()
+
+	// the channels are closed, so the remaining items are received without blocking
+	for pi := range PodInteractionCh {
+		deadLetterInteraction(pi)
+	}
+	for pd := range PodExtensionUpdateCh {
+		deadLetterExtensionUpdate(pd)
+	}
+	// the shards are only closed once all interactions are fanned out, so take what is left in them without blocking
+	for _, shard := range shards {
+		deadLetterShard(shard)
+	}
+
+	return false
+}
+
+// setInteractionInProgress sets the Pod interaction being handled by the given worker, nil once it is handled.
+func (c *Controller) setInteractionInProgress(worker int, pi *PodInteraction) {
+	c.drainState.mu.Lock()
+	defer c.drainState.mu.Unlock()
+
+	if pi == nil {
+		delete(c.drainState.interactions, worker)
+		return
+	}
+	if c.drainState.interactions == nil {
+		c.drainState.interactions = make(map[int]PodInteraction)
+	}
+	c.drainState.interactions[worker] = *pi
+}
+
+// setInteractionShards sets the channels the Pod interactions are fanned out to, one per interaction worker.
+func (c *Controller) setInteractionShards(shards []chan PodInteraction) {
+	c.drainState.mu.Lock()
+	defer c.drainState.mu.Unlock()
+
+	c.drainState.interactionShards = shards
+}
+
+// deadLetterShard dead-letters the Pod interactions buffered in the given shard until it is empty or closed.
+func deadLetterShard(shard <-chan PodInteraction) {
+	for {
+		select {
+		case pi, ok := <-shard:
+			if !ok {
+				return
+			}
+			deadLetterInteraction(pi)
+		default:
+			return
+		}
+	}
+}
+
+// setExtensionUpdateInProgress sets the Pod extension update being handled, nil once it is handled.
+func (c *Controller) setExtensionUpdateInProgress(pd *PodExtensionUpdate) {
+	c.drainState.mu.Lock()
+	defer c.drainState.mu.Unlock()
+
+	c.drainState.update = pd
+}
+
+// deadLetterInteraction logs and counts a Pod interaction left unhandled at the drain deadline.
+func deadLetterInteraction(pi PodInteraction) {
+	zap.L().Error("Dead-lettered a Pod interaction unhandled at the drain deadline.",
+		zap.Object("pod_interaction", &pi),
+	)
+	metrics.DeadLetteredItemsTotal.WithLabelValues("pod_interaction").Inc()
+}
+
+// deadLetterExtensionUpdate logs and counts a Pod extension update left unhandled at the drain deadline.
+func deadLetterExtensionUpdate(pd PodExtensionUpdate) {
+	zap.L().Error("Dead-lettered a Pod extension update unhandled at the drain deadline.",
+		zap.String("pod_name", pd.Pod.Name),
+		zap.String("pod_namespace", pd.Pod.Namespace),
+		zap.String("requester", pd.Username),
+	)
+	metrics.DeadLetteredItemsTotal.WithLabelValues("pod_extension_update").Inc()
+}
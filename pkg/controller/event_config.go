@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// EventCategory is the category of a K8s event submitted to an interacted Pod, whose type and reason can be set
+// by WithEventConfig.
+type EventCategory string
+
+// These are the categories of K8s events submitted to interacted Pods.
+const (
+	// EventCategoryInteraction is of the events about an interaction, e.g. its Pod getting tracked or exempt
+	EventCategoryInteraction EventCategory = "interaction"
+	// EventCategoryExtension is of the events about an extension of the eviction time
+	EventCategoryExtension EventCategory = "extension"
+	// EventCategoryEviction is of the events about an eviction, e.g. its time being set or deferred
+	EventCategoryEviction EventCategory = "eviction"
+)
+
+// eventConfig is the type and reason of the K8s events of a category.
+type eventConfig struct {
+	eventType string
+	reason    string
+}
+
+// defaultEventConfig is the type and reason of the K8s events of every category, unless set by WithEventConfig.
+var defaultEventConfig = eventConfig{eventType: corev1.EventTypeWarning, reason: podInteractionEventReason}
+
+// ParseEventType returns the K8s event type of the given name, or an error if it is not supported.
+func ParseEventType(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "normal":
+		return corev1.EventTypeNormal, nil
+	case "warning":
+		return corev1.EventTypeWarning, nil
+	default:
+		return "", fmt.Errorf("unsupported event type %q, expected one of: Normal, Warning", name)
+	}
+}
+
+// WithEventConfig sets the type (Normal or Warning) of all K8s events of the given category, e.g. Normal for routine
+// interactions to avoid alerting on Warning events. The given reason is set to the events of the category without
+// a specific reason (e.g. PodEvictionWarning), empty keeps the default one.
+func WithEventConfig(category EventCategory, eventType, reason string) Option {
+	return func(c *Controller) {
+		config := eventConfig{eventType: eventType, reason: reason}
+		if config.reason == "" {
+			config.reason = defaultEventConfig.reason
+		}
+		if c.eventConfigs == nil {
+			c.eventConfigs = make(map[EventCategory]eventConfig)
+		}
+		c.eventConfigs[category] = config
+	}
+}
+
+// getEventConfig returns the type and reason of the K8s events of the given category.
+func (c *Controller) getEventConfig(category EventCategory) eventConfig {
+	if config, present := c.eventConfigs[category]; present {
+		return config
+	}
+
+	return defaultEventConfig
+}
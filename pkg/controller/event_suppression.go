@@ -0,0 +1,17 @@
+package controller
+
+import corev1 "k8s.io/api/core/v1"
+
+// WithTerminatingPodEventSuppression suppresses the K8s events to interacted Pods already terminating, i.e. being
+// deleted or in a terminal phase, which are noise as the Pods are gone soon anyway. Such Pods are still tracked and
+// the suppressed events are counted by metrics.SuppressedEventsTotal.
+func WithTerminatingPodEventSuppression() Option {
+	return func(c *Controller) {
+		c.suppressTerminatingPodEvents = true
+	}
+}
+
+// isPodTerminating returns true if the given Pod is being deleted or in a terminal phase (Succeeded or Failed).
+func isPodTerminating(pod corev1.Pod) bool {
+	return pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
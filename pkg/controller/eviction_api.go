@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+)
+
+// These are the versions of the Eviction API, policy/v1beta1 is removed since K8s 1.22
+// while policy/v1 is only served since K8s 1.21.
+const (
+	evictionVersionV1      = "v1"
+	evictionVersionV1beta1 = "v1beta1"
+)
+
+// evictionAPI evicts Pods with the version of the Eviction API served by the cluster, which is detected
+// on the first eviction, or again on the next ones until detected. It deletes them directly instead in the
+// TerminationModeDelete mode, either set globally or annotated to their namespace.
+type evictionAPI struct {
+	versionMu sync.Mutex
+	version   string // empty until detected
+
+	// evictGracePeriod is the grace period of the Evictions, unless one is given for the Pod (nil means the Pod's own)
+	evictGracePeriod  *time.Duration
+	deletePods        bool
+	deleteGracePeriod time.Duration
+	// namespaceMode returns the TerminationMode annotated to the given namespace, if any
+	namespaceMode func(namespace string) (TerminationMode, bool)
+	// policyMode returns the TerminationMode of the given namespace set by the termination action of the
+	// ExecTrackingPolicy, if any
+	policyMode func(namespace string) (TerminationMode, bool)
+	// pdbBlocked is how the evictions refused by a PodDisruptionBudget are retried and handled once exhausted
+	pdbBlocked pdbBlockedConfig
+	// timeout bounds each call evicting or deleting a Pod, as set by WithKubeAPITimeout (zero means no timeout)
+	timeout time.Duration
+
+	permission evictionPermission
+}
+
+// WithEvictionGracePeriod sets the grace period of the Evictions of interacted Pods, unless a grace period is set for
+// their owner, i.e. by WithStatefulSetHandling. Zero or less means the Pod's own termination grace period, as by
+// default and like the grace period set by WithTerminationMode, which applies to Pods deleted in the
+// TerminationModeDelete mode instead.
+func WithEvictionGracePeriod(gracePeriod time.Duration) Option {
+	return func(c *Controller) {
+		if gracePeriod > 0 {
+			c.evictionAPI.evictGracePeriod = &gracePeriod
+		}
+	}
+}
+
+// evict evicts the Pod of the given name and namespace, with the given grace period to terminate it if positive
+// or else the one set by WithEvictionGracePeriod, if any, or the Pod's own termination grace period otherwise.
+func (ea *evictionAPI) evict(kubeClient kubernetes.Interface, name, namespace string, gracePeriod time.Duration) error {
+	if ea.deletesPodsIn(namespace) {
+		if gracePeriod <= 0 {
+			gracePeriod = ea.deleteGracePeriod
+		}
+		return deletePod(kubeClient, name, namespace, gracePeriod, ea.timeout)
+	}
+
+	version, detected := ea.getVersion(kubeClient.Discovery())
+	err := ea.evictWithVersion(kubeClient, version, name, namespace, gracePeriod)
+	// an undetected version is assumed to be policy/v1, unless it is not served (e.g. by a cluster before K8s 1.21)
+	if !detected && apierrors.IsNotFound(err) {
+		if ea.evictWithVersion(kubeClient, evictionVersionV1beta1, name, namespace, gracePeriod) == nil {
+			zap.L().Info("Using the deprecated policy/v1beta1 Eviction API as policy/v1 is not found")
+			ea.setVersion(evictionVersionV1beta1)
+			return nil
+		}
+	}
+
+	return err
+}
+
+// getVersion returns the version of the Eviction API served by the cluster, detecting it if not yet, or policy/v1
+// and false if it cannot be detected.
+func (ea *evictionAPI) getVersion(discoveryClient discovery.DiscoveryInterface) (string, bool) {
+	ea.versionMu.Lock()
+	defer ea.versionMu.Unlock()
+
+	if ea.version == "" {
+		if version, detected := detectEvictionVersion(discoveryClient); detected {
+			ea.version = version
+		} else {
+			return evictionVersionV1, false
+		}
+	}
+
+	return ea.version, true
+}
+
+// setVersion sets the version of the Eviction API served by the cluster.
+func (ea *evictionAPI) setVersion(version string) {
+	ea.versionMu.Lock()
+	defer ea.versionMu.Unlock()
+
+	ea.version = version
+}
+
+// evictWithVersion evicts the Pod of the given name and namespace with the given version of the Eviction API, with
+// the given grace period as evict does.
+func (ea *evictionAPI) evictWithVersion(kubeClient kubernetes.Interface, version, name, namespace string,
+	gracePeriod time.Duration) error {
+	objectMeta := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+	}
+	var deleteOptions *metav1.DeleteOptions
+	if gracePeriod > 0 {
+		gracePeriodSeconds := int64(gracePeriod.Seconds())
+		deleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriodSeconds}
+	} else if ea.evictGracePeriod != nil {
+		gracePeriodSeconds := int64(ea.evictGracePeriod.Seconds())
+		deleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriodSeconds}
+	}
+	ctx, cancel := newKubeAPIContext(ea.timeout)
+	defer cancel()
+	if version == evictionVersionV1beta1 {
+		return kubeClient.PolicyV1beta1().Evictions(namespace).Evict(ctx, &policyv1beta1.Eviction{
+			ObjectMeta:    objectMeta,
+			DeleteOptions: deleteOptions,
+		})
+	}
+
+	return kubeClient.PolicyV1().Evictions(namespace).Evict(ctx, &policyv1.Eviction{
+		ObjectMeta:    objectMeta,
+		DeleteOptions: deleteOptions,
+	})
+}
+
+// deletesPodsIn returns true if the Pods in the given namespace are deleted rather than evicted, by the TerminationMode
+// annotated to the namespace, or else the termination action of the ExecTrackingPolicy, or else the one set by
+// WithTerminationMode.
+func (ea *evictionAPI) deletesPodsIn(namespace string) bool {
+	if ea.namespaceMode != nil {
+		if mode, present := ea.namespaceMode(namespace); present {
+			return mode == TerminationModeDelete
+		}
+	}
+	if ea.policyMode != nil {
+		if mode, present := ea.policyMode(namespace); present {
+			return mode == TerminationModeDelete
+		}
+	}
+
+	return ea.deletePods
+}
+
+// deletePod deletes the Pod of the given name and namespace, bypassing its PodDisruptionBudgets, with the given grace
+// period to terminate it if positive or the Pod's own termination grace period otherwise. The call times out after
+// the given timeout if positive.
+func deletePod(kubeClient kubernetes.Interface, name, namespace string, gracePeriod, timeout time.Duration) error {
+	deleteOptions := metav1.DeleteOptions{}
+	if gracePeriod > 0 {
+		gracePeriodSeconds := int64(gracePeriod.Seconds())
+		deleteOptions.GracePeriodSeconds = &gracePeriodSeconds
+	}
+
+	ctx, cancel := newKubeAPIContext(timeout)
+	defer cancel()
+	return kubeClient.CoreV1().Pods(namespace).Delete(ctx, name, deleteOptions)
+}
+
+// detectEvictionVersion returns the version of the Eviction API served as the "pods/eviction" subresource,
+// or false if it cannot be detected.
+func detectEvictionVersion(discoveryClient discovery.DiscoveryInterface) (string, bool) {
+	resources, err := discoveryClient.ServerResourcesForGroupVersion("v1")
+	if err != nil {
+		zap.L().Warn("Failed to discover the Eviction API version, trying policy/v1", zap.Error(err))
+		return "", false
+	}
+
+	for _, resource := range resources.APIResources {
+		if resource.Name != "pods/eviction" || resource.Kind != "Eviction" {
+			continue
+		}
+
+		// the subresource version is not set by old clusters, which only serve policy/v1beta1 if not policy/v1
+		version := resource.Version
+		if version == "" {
+			version = evictionVersionV1beta1
+			if _, err := discoveryClient.ServerResourcesForGroupVersion("policy/v1"); err == nil {
+				version = evictionVersionV1
+			}
+		}
+		if version == evictionVersionV1beta1 {
+			zap.L().Info("Using the deprecated policy/v1beta1 Eviction API served by the cluster")
+			return evictionVersionV1beta1, true
+		}
+
+		return evictionVersionV1, true
+	}
+
+	zap.L().Warn("Failed to find the Eviction API served by the cluster, trying policy/v1")
+	return "", false
+}
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// evictionLeasePrefix is the name prefix of the Lease created for evicting a Pod, followed by the Pod's UID.
+const evictionLeasePrefix = "kube-exec-controller-eviction-"
+
+// evictionLeaseDuration is how long a Lease holder is given to evict a Pod before another replica can take over.
+const evictionLeaseDuration = time.Minute
+
+// evictionLease coordinates multiple controller replicas to evict each Pod exactly once, by acquiring
+// a coordination/v1 Lease per Pod before evicting it. The Lease is owned by the Pod and garbage collected with it.
+type evictionLease struct {
+	kubeClient kubernetes.Interface
+	identity   string
+}
+
+// WithEvictionLease makes the Controller acquire a per-Pod Lease as the given identity before evicting a Pod,
+// so all replicas can set termination timers while only one of them evicts each Pod.
+func WithEvictionLease(identity string) Option {
+	return func(c *Controller) {
+		c.evictionLease = &evictionLease{
+			kubeClient: c.kubeClient,
+			identity:   identity,
+		}
+	}
+}
+
+// acquireForEviction returns true if the given Pod can be evicted by this replica, i.e. no evictionLease is used
+// or the Lease of the Pod is acquired, with each call to the K8s API timing out after the given timeout if positive.
+// Failing to acquire it is logged.
+func (l *evictionLease) acquireForEviction(pod corev1.Pod, timeout time.Duration) bool {
+	if l == nil {
+		return true
+	}
+
+	acquired, err := l.tryAcquire(pod, timeout)
+	if err != nil {
+		zap.L().Error("Error in acquiring the eviction lease of a Pod!",
+			zap.String("pod_name", pod.Name),
+			zap.String("namespace", pod.Namespace),
+			zap.Error(err),
+		)
+		return false
+	}
+	if !acquired {
+		zap.L().Info("Skipped evicting a Pod as its eviction lease is held by another replica",
+			zap.String("pod_name", pod.Name),
+			zap.String("namespace", pod.Namespace),
+		)
+		return false
+	}
+
+	return true
+}
+
+// tryAcquire returns true if the Lease of the given Pod is acquired (or already held) by this replica.
+// It returns false if the Lease is held by another replica and not expired yet.
+func (l *evictionLease) tryAcquire(pod corev1.Pod, timeout time.Duration) (bool, error) {
+	leaseClient := l.kubeClient.CoordinationV1().Leases(pod.Namespace)
+	leaseName := evictionLeasePrefix + string(pod.UID)
+	now := metav1.NewMicroTime(time.Now())
+	durationSeconds := int32(evictionLeaseDuration.Seconds())
+
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      leaseName,
+			Namespace: pod.Namespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       pod.Name,
+				UID:        pod.UID,
+			}},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &l.identity,
+			LeaseDurationSeconds: &durationSeconds,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	}
+	createCtx, cancelCreate := newKubeAPIContext(timeout)
+	defer cancelCreate()
+	_, err := leaseClient.Create(createCtx, lease, metav1.CreateOptions{})
+	if err == nil {
+		return true, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return false, err
+	}
+
+	// the Lease exists, take it over only if its holder did not evict the Pod in time
+	getCtx, cancelGet := newKubeAPIContext(timeout)
+	defer cancelGet()
+	existingLease, err := leaseClient.Get(getCtx, leaseName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	holder := existingLease.Spec.HolderIdentity
+	if holder != nil && *holder == l.identity {
+		return true, nil
+	}
+	renewTime := existingLease.Spec.RenewTime
+	if renewTime != nil && time.Since(renewTime.Time) < evictionLeaseDuration {
+		return false, nil
+	}
+
+	existingLease.Spec = lease.Spec
+	updateCtx, cancelUpdate := newKubeAPIContext(timeout)
+	defer cancelUpdate()
+	if _, err := leaseClient.Update(updateCtx, existingLease, metav1.UpdateOptions{}); err != nil {
+		if apierrors.IsConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// postponeIfExtended re-reads the given Pod and resets its termination timer if its termination time has been
+// extended since the timer was set, e.g. by an extension request handled in another replica. The timer is paused
+// instead if the Pod has been held in use, unless its max hold has expired.
+func (c *Controller) postponeIfExtended(pod corev1.Pod) bool {
+	latestPod, err := getPod(c.kubeClient, pod.Namespace, pod.Name, c.kubeAPITimeout)
+	if err != nil {
+		return false
+	}
+
+	if heldUntil, held := c.isPodHeld(*latestPod); held {
+		c.holdTerminationTimer(*latestPod, heldUntil)
+		zap.L().Info("Paused evicting a Pod as it has been held in use",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+		)
+		return true
+	}
+
+	terminationTime, err := getTerminationTime(*latestPod, c.getMaxExtension(latestPod.Namespace), c.idleTTL)
+	if err != nil || !time.Now().Before(terminationTime) {
+		return false
+	}
+
+	c.replaceTerminationTimer(*latestPod, time.Until(terminationTime))
+	zap.L().Info("Postponed evicting a Pod as its termination time has been extended",
+		zap.String("pod_name", pod.Name),
+		zap.String("pod_namespace", pod.Namespace),
+		zap.String("termination_time", terminationTime.String()),
+	)
+
+	return true
+}
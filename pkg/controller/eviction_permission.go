@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"sync"
+
+	"github.com/box/kube-exec-controller/pkg/metrics"
+)
+
+// These are the RBAC rules granting the controller's ServiceAccount to terminate Pods in either termination mode.
+const (
+	evictionRBACRule = `{apiGroups: [""], resources: ["pods/eviction"], verbs: ["create"]}`
+	deletionRBACRule = `{apiGroups: [""], resources: ["pods"], verbs: ["delete"]}`
+)
+
+// evictionPermission tracks whether the Controller is forbidden to terminate Pods by missing RBAC, as observed
+// from its latest attempt, so that it is diagnosed once rather than on every timer firing. It is reported by the
+// metrics.EvictionForbidden gauge.
+type evictionPermission struct {
+	mu        sync.Mutex
+	forbidden bool
+}
+
+// setForbidden records whether terminating Pods is forbidden, returning true if it differs from the previous record.
+func (ep *evictionPermission) setForbidden(forbidden bool) bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	changed := ep.forbidden != forbidden
+	ep.forbidden = forbidden
+	if forbidden {
+		metrics.EvictionForbidden.Set(1)
+	} else {
+		metrics.EvictionForbidden.Set(0)
+	}
+
+	return changed
+}
+
+// requiredRBACRule returns the RBAC rule the controller needs to terminate Pods in the given namespace through
+// the evictionAPI.
+func (ea *evictionAPI) requiredRBACRule(namespace string) string {
+	if ea.deletesPodsIn(namespace) {
+		return deletionRBACRule
+	}
+
+	return evictionRBACRule
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// NamespaceEvictionWarningAnnotate is set to a Namespace object (e.g. "15m") to override the lead time of warning
+// Pods interacted in it before evicting them.
+var NamespaceEvictionWarningAnnotate = "box.com/evictionWarningLeadTime"
+
+// evictionWarning submits a warning event to interacted Pods a lead time before their termination time.
+type evictionWarning struct {
+	leadTime time.Duration
+
+	mu     sync.Mutex
+	timers map[types.UID]*time.Timer
+}
+
+func newEvictionWarning() *evictionWarning {
+	return &evictionWarning{timers: make(map[types.UID]*time.Timer)}
+}
+
+// WithEvictionWarning sets the lead time to warn interacted Pods with an event before evicting them, which is
+// overridden by NamespaceEvictionWarningAnnotate of their namespace if set. Zero means no warning unless annotated.
+func WithEvictionWarning(leadTime time.Duration) Option {
+	return func(c *Controller) {
+		c.evictionWarning.leadTime = leadTime
+	}
+}
+
+// setEvictionWarning sets a timer to warn the given Pod ahead of the given termination time, replacing the existing
+// one. No warning is set if the lead time is zero or the termination time is already within it, as the Pod has just
+// been notified of its termination time then.
+func (c *Controller) setEvictionWarning(pod corev1.Pod, terminationTime time.Time) {
+	leadTime := c.getNamespaceDuration(pod.Namespace, NamespaceEvictionWarningAnnotate, c.evictionWarning.leadTime)
+
+	c.stopEvictionWarning(pod.UID)
+	warnIn := time.Until(terminationTime) - leadTime
+	if leadTime <= 0 || warnIn <= 0 {
+		return
+	}
+
+	ew := c.evictionWarning
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+
+	var timer *time.Timer
+	timer = time.AfterFunc(warnIn, func() {
+		message := fmt.Sprintf("Pod will be evicted in about %s at time %s, extend it by 'kubectl pi extend' if still needed",
+			leadTime.String(),
+			terminationTime.String(),
+		)
+		// the warning is best effort, failing to submit it is logged in submitEventWithReason
+		_ = c.submitEventWithReason(&pod, EventCategoryEviction, evictionWarningEventReason, message)
+
+		ew.mu.Lock()
+		defer ew.mu.Unlock()
+		// the timer may have been replaced meanwhile
+		if ew.timers[pod.UID] == timer {
+			delete(ew.timers, pod.UID)
+		}
+	})
+	ew.timers[pod.UID] = timer
+}
+
+// stopEvictionWarning stops and removes the warning timer of the Pod with the given UID, if any.
+func (c *Controller) stopEvictionWarning(uid types.UID) {
+	ew := c.evictionWarning
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+
+	if timer, present := ew.timers[uid]; present {
+		timer.Stop()
+		delete(ew.timers, uid)
+	}
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// EvictionWindow is the daily time window (e.g. "09:00-18:00" in a timezone) in which Pods are allowed to be evicted.
+// A window whose end is earlier than its start spans midnight, e.g. "22:00-06:00".
+type EvictionWindow struct {
+	// start and end are the minutes since midnight
+	start, end int
+	location   *time.Location
+}
+
+// ParseEvictionWindow returns the EvictionWindow of the given "HH:MM-HH:MM" range in the given IANA timezone
+// (e.g. "America/Los_Angeles"), or an error if either of them is invalid.
+func ParseEvictionWindow(window, timezone string) (*EvictionWindow, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid eviction window timezone %q: %v", timezone, err)
+	}
+
+	bounds := strings.Split(window, "-")
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("invalid eviction window %q, expected a range like 09:00-18:00", window)
+	}
+
+	var minutes [2]int
+	for i, bound := range bounds {
+		t, err := time.Parse("15:04", strings.TrimSpace(bound))
+		if err != nil {
+			return nil, fmt.Errorf("invalid eviction window %q, expected a range like 09:00-18:00", window)
+		}
+		minutes[i] = t.Hour()*60 + t.Minute()
+	}
+	if minutes[0] == minutes[1] {
+		return nil, fmt.Errorf("invalid eviction window %q, its start and end must be different", window)
+	}
+
+	return &EvictionWindow{
+		start:    minutes[0],
+		end:      minutes[1],
+		location: location,
+	}, nil
+}
+
+// WithEvictionWindow defers the evictions of Pods whose termination time is outside the given window
+// until the window opens next.
+func WithEvictionWindow(window *EvictionWindow) Option {
+	return func(c *Controller) {
+		c.evictionWindow = window
+	}
+}
+
+// String returns the window in the "HH:MM-HH:MM <timezone>" format.
+func (w *EvictionWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d %s", w.start/60, w.start%60, w.end/60, w.end%60, w.location.String())
+}
+
+// evictionTime returns the time at which a Pod terminating at the given time gets evicted, which is
+// the given time itself if it is in the window (or no window is set), or the next opening of the window otherwise.
+func (w *EvictionWindow) evictionTime(terminationTime time.Time) time.Time {
+	if w == nil {
+		return terminationTime
+	}
+
+	t := terminationTime.In(w.location)
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end && minute >= w.start && minute < w.end {
+		return terminationTime
+	}
+	if w.start > w.end && (minute >= w.start || minute < w.end) {
+		return terminationTime
+	}
+
+	opening := time.Date(t.Year(), t.Month(), t.Day(), w.start/60, w.start%60, 0, 0, w.location)
+	if !opening.After(t) {
+		opening = opening.AddDate(0, 0, 1)
+	}
+
+	return opening
+}
+
+// deferToEvictionWindow resets the termination timer of the given Pod to the next opening of the eviction window
+// and returns true, if it is currently outside the window.
+func (c *Controller) deferToEvictionWindow(pod corev1.Pod) bool {
+	now := time.Now()
+	evictionTime := c.evictionWindow.evictionTime(now)
+	if !evictionTime.After(now) {
+		return false
+	}
+
+	c.replaceTerminationTimer(pod, time.Until(evictionTime))
+
+	message := fmt.Sprintf("Pod eviction is deferred to %s as it is outside the eviction window %s",
+		evictionTime.String(),
+		c.evictionWindow.String(),
+	)
+	// the eviction is deferred regardless of failing to submit the event, which is logged in submitEvent
+	_ = c.submitEvent(&pod, EventCategoryEviction, message)
+
+	zap.L().Info("Deferred evicting a Pod as it is outside the eviction window",
+		zap.String("pod_name", pod.Name),
+		zap.String("pod_namespace", pod.Namespace),
+		zap.String("eviction_window", c.evictionWindow.String()),
+		zap.String("eviction_time", evictionTime.String()),
+	)
+
+	return true
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// HasTerminationTimer returns if the Controller keeps a termination timer of the Pod with the given UID.
+func (c *Controller) HasTerminationTimer(uid types.UID) bool {
+	c.terminationTimersMu.Lock()
+	defer c.terminationTimersMu.Unlock()
+
+	_, present := c.terminationTimersMap[uid]
+	return present
+}
+
+// AppendHistory appends the given record to the history ConfigMap set by WithHistoryConfigMap.
+func (c *Controller) AppendHistory(record StreamRecord) error {
+	return c.history.append(record, c.kubeAPITimeout)
+}
+
+// SetMinReducedRemainingDuration sets the least remaining duration of a reduced Pod, returning a func restoring it.
+func SetMinReducedRemainingDuration(remaining time.Duration) func() {
+	previous := minReducedRemainingDuration
+	minReducedRemainingDuration = remaining
+	return func() {
+		minReducedRemainingDuration = previous
+	}
+}
+
+// IsPodCached returns if the Pod of the given namespace and name is in the cache of WatchTrackedPods.
+func (c *Controller) IsPodCached(namespace, name string) bool {
+	_, cached := c.getTrackedPod(namespace, name)
+	return cached
+}
+
+// SetTerminationRetryInterval sets the initial and max interval re-arming a failed eviction, returning a func
+// restoring them.
+func SetTerminationRetryInterval(initial, max time.Duration) func() {
+	previousInitial, previousMax := terminationRetryInitialInterval, terminationRetryMaxInterval
+	terminationRetryInitialInterval, terminationRetryMaxInterval = initial, max
+	return func() {
+		terminationRetryInitialInterval, terminationRetryMaxInterval = previousInitial, previousMax
+	}
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodExtensionHistoryAnnotate is set to a JSON array of ExtensionRecord of all extensions requested to a Pod.
+var PodExtensionHistoryAnnotate = "box.com/podExtensionHistory"
+
+// maxExtensionHistory is the max number of the most recent extensions kept in PodExtensionHistoryAnnotate.
+const maxExtensionHistory = 10
+
+// ExtensionRecord contains who requested an extension of how long to a Pod and when.
+type ExtensionRecord struct {
+	Requester string    `json:"requester"`
+	Duration  string    `json:"duration"`
+	Time      time.Time `json:"time"`
+}
+
+// getExtensionHistory returns the extension history of the given Pod with a new record of the given requester
+// appended, in JSON. Only the most recent maxExtensionHistory records are kept.
+func getExtensionHistory(pod corev1.Pod, requester string) (string, error) {
+	var history []ExtensionRecord
+	if historyRaw, present := pod.Annotations[PodExtensionHistoryAnnotate]; present {
+		if err := json.Unmarshal([]byte(historyRaw), &history); err != nil {
+			zap.L().Warn("Failed to parse the extension history of a Pod, starting a new one",
+				zap.String("pod_name", pod.Name),
+				zap.String("pod_namespace", pod.Namespace),
+				zap.Error(err),
+			)
+			history = nil
+		}
+	}
+
+	history = append(history, ExtensionRecord{
+		Requester: requester,
+		Duration:  pod.Annotations[PodExtendDurationAnnotate],
+		Time:      time.Now().UTC().Truncate(time.Second),
+	})
+	if len(history) > maxExtensionHistory {
+		history = history[len(history)-maxExtensionHistory:]
+	}
+
+	historyJSON, err := json.Marshal(history)
+	return string(historyJSON), err
+}
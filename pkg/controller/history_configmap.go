@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HistoryConfigMapName is the name of the ConfigMap keeping the most recent records of Pod interactions, extensions
+// and evictions as a ring buffer, which is read by "kubectl pi history".
+const HistoryConfigMapName = "kube-exec-controller-history"
+
+// HistoryNextSlotKey is the key of the history ConfigMap containing the slot to write the next record to, i.e.
+// the slot of the oldest record once the ring buffer is full. The records are kept under the slot numbers as keys.
+const HistoryNextSlotKey = "next"
+
+// MaxHistorySize is the max number of records kept in the history ConfigMap, so that each of them can take up to
+// maxHistoryDataSize / MaxHistorySize bytes.
+const MaxHistorySize = 2000
+
+// defaultHistorySize is the number of records kept in the history ConfigMap, unless set by WithHistoryConfigMap.
+const defaultHistorySize = 100
+
+// maxHistoryDataSize is the max total size of the records kept in the history ConfigMap, leaving room for its
+// metadata below the 1MiB limit of a K8s object.
+const maxHistoryDataSize = 900 * 1024
+
+// historyTruncatedCommands replaces the commands of a record exceeding its share of maxHistoryDataSize.
+var historyTruncatedCommands = []string{CommandTruncatedMarker}
+
+// historyBufferSize is the number of records buffered to be appended to the history ConfigMap.
+const historyBufferSize = 100
+
+// historyUpdateAttempts is the max number of attempts to update the history ConfigMap on conflicts, e.g. with
+// another controller replica.
+const historyUpdateAttempts = 3
+
+// historyRingBuffer writes the records of Pod lifecycle events to a bounded ring buffer in a ConfigMap,
+// overwriting the oldest record once full.
+type historyRingBuffer struct {
+	kubeClient kubernetes.Interface
+	namespace  string
+	capacity   int
+	records    chan StreamRecord
+
+	mu sync.Mutex // serializes the updates of the ConfigMap from multiple goroutines
+}
+
+// WithHistoryConfigMap keeps the given number of most recent records of Pod interactions, extensions and evictions
+// in the HistoryConfigMapName ConfigMap under the given namespace, as a simple audit trail for teams without a SIEM.
+// A non-positive capacity keeps defaultHistorySize records, and one above MaxHistorySize keeps MaxHistorySize records.
+// The records are appended in the background by RunHistoryAppender.
+func WithHistoryConfigMap(namespace string, capacity int) Option {
+	if capacity <= 0 {
+		capacity = defaultHistorySize
+	} else if capacity > MaxHistorySize {
+		capacity = MaxHistorySize
+	}
+	return func(c *Controller) {
+		c.history = &historyRingBuffer{
+			kubeClient: c.kubeClient,
+			namespace:  namespace,
+			capacity:   capacity,
+			records:    make(chan StreamRecord, historyBufferSize),
+		}
+	}
+}
+
+// RunHistoryAppender appends the records buffered by recordLifecycleEvent to the history ConfigMap until the given
+// channel is closed, then appends the ones left in the buffer before returning. It returns right away if no history
+// ConfigMap is set.
+func (c *Controller) RunHistoryAppender(stopCh <-chan struct{}) {
+	if c.history == nil {
+		return
+	}
+
+	for {
+		select {
+		case record := <-c.history.records:
+			c.appendHistory(record)
+		case <-stopCh:
+			for {
+				select {
+				case record := <-c.history.records:
+					c.appendHistory(record)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// appendHistory appends the given record to the history ConfigMap. Failing to append it is logged only, as it must
+// not fail handling the event.
+func (c *Controller) appendHistory(record StreamRecord) {
+	if err := c.history.append(record, c.kubeAPITimeout); err != nil {
+		zap.L().Error("Error in appending a record to the history ConfigMap",
+			zap.String("type", string(record.Type)),
+			zap.String("pod_name", record.PodName),
+			zap.String("pod_namespace", record.PodNamespace),
+			zap.Error(err),
+		)
+	}
+}
+
+// append writes the given record to the next slot of the ring buffer, creating the ConfigMap if absent. Each call to
+// the K8s API times out after the given timeout if positive.
+func (hb *historyRingBuffer) append(record StreamRecord, timeout time.Duration) error {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+
+	value, err := hb.marshal(record)
+	if err != nil {
+		return err
+	}
+
+	configMaps := hb.kubeClient.CoreV1().ConfigMaps(hb.namespace)
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := newKubeAPIContext(timeout)
+		configMap, err := configMaps.Get(ctx, HistoryConfigMapName, metav1.GetOptions{})
+		cancel()
+		if apierrors.IsNotFound(err) {
+			configMap = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Name:      HistoryConfigMapName,
+				Namespace: hb.namespace,
+			}}
+			hb.writeSlot(configMap, string(value))
+			ctx, cancel := newKubeAPIContext(timeout)
+			_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{FieldManager: FieldManager})
+			cancel()
+		} else if err == nil {
+			hb.writeSlot(configMap, string(value))
+			ctx, cancel := newKubeAPIContext(timeout)
+			_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{FieldManager: FieldManager})
+			cancel()
+		}
+
+		if (apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)) && attempt < historyUpdateAttempts {
+			continue
+		}
+		return err
+	}
+}
+
+// marshal returns the JSON of the given record, with its commands truncated if it exceeds its share of
+// maxHistoryDataSize, so the ring buffer never grows the ConfigMap beyond the object size limit once full.
+func (hb *historyRingBuffer) marshal(record StreamRecord) ([]byte, error) {
+	maxSize := maxHistoryDataSize / hb.capacity
+	value, err := json.Marshal(record)
+	if err != nil || len(value) <= maxSize {
+		return value, err
+	}
+
+	record.Commands = historyTruncatedCommands
+	if value, err = json.Marshal(record); err != nil || len(value) <= maxSize {
+		return value, err
+	}
+	return nil, fmt.Errorf("record of %d bytes exceeds the max size of %d bytes", len(value), maxSize)
+}
+
+// writeSlot sets the given value to the next slot of the given ConfigMap and advances it, wrapping at the capacity.
+// Slots beyond the capacity (e.g. after lowering it) are removed.
+func (hb *historyRingBuffer) writeSlot(configMap *corev1.ConfigMap, value string) {
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+
+	next, err := strconv.Atoi(configMap.Data[HistoryNextSlotKey])
+	if err != nil || next < 0 || next >= hb.capacity {
+		next = 0
+	}
+	configMap.Data[strconv.Itoa(next)] = value
+	configMap.Data[HistoryNextSlotKey] = strconv.Itoa((next + 1) % hb.capacity)
+
+	for key := range configMap.Data {
+		if slot, err := strconv.Atoi(key); err == nil && slot >= hb.capacity {
+			delete(configMap.Data, key)
+		}
+	}
+}
+
+// recordLifecycleEvent publishes the given record of a Pod lifecycle event to the StreamProducer and buffers it to be
+// appended to the history ConfigMap, if set, without blocking. Either is dropped with a warning once its buffer is full.
+func (c *Controller) recordLifecycleEvent(record StreamRecord) {
+	c.publishStreamRecord(record)
+
+	if c.history == nil {
+		return
+	}
+	select {
+	case c.history.records <- record:
+	default:
+		zap.L().Warn("Dropped a history record as the buffer is full",
+			zap.String("type", string(record.Type)),
+			zap.String("pod_name", record.PodName),
+			zap.String("pod_namespace", record.PodNamespace),
+		)
+	}
+}
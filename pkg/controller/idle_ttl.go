@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodLastInteractionTimestampAnnotate is set to the Unix time of the latest interaction of a Pod in the TTLModeIdle
+// mode, from which its TTL counts instead of its initial interaction.
+var PodLastInteractionTimestampAnnotate = "box.com/podLastInteractionTimestamp"
+
+// TTLMode is how the TTL of interacted Pods counts.
+type TTLMode string
+
+// These are the supported TTL modes.
+const (
+	// TTLModeFixed counts the TTL from the initial interaction, regardless of any later one
+	TTLModeFixed TTLMode = "fixed"
+	// TTLModeIdle counts the TTL from the latest interaction, so Pods are only evicted once idle for their TTL
+	TTLModeIdle TTLMode = "idle"
+)
+
+// ParseTTLMode returns the TTLMode of the given name, or an error if it is not supported.
+func ParseTTLMode(name string) (TTLMode, error) {
+	switch mode := TTLMode(strings.ToLower(name)); mode {
+	case TTLModeFixed, TTLModeIdle:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported TTL mode %q, expected one of: fixed, idle", name)
+	}
+}
+
+// WithTTLMode sets how the TTL of interacted Pods counts, TTLModeFixed by default.
+func WithTTLMode(mode TTLMode) Option {
+	return func(c *Controller) {
+		c.idleTTL = mode == TTLModeIdle
+	}
+}
+
+// resetIdleTTL records the given interaction of an already interacted Pod as its latest one and resets its
+// termination timer accordingly, returning the patched Pod. Interactions older than the recorded one (e.g. replayed
+// ones) are ignored.
+func (c *Controller) resetIdleTTL(pod corev1.Pod, pi PodInteraction) (*corev1.Pod, error) {
+	if lastTime, err := parseUnixTime(pod.Annotations[PodLastInteractionTimestampAnnotate]); err == nil &&
+		!pi.InitTime.After(lastTime) {
+		return &pod, nil
+	}
+
+	annotationPatchMap := map[string]string{
+		PodLastInteractionTimestampAnnotate: strconv.FormatInt(pi.InitTime.Unix(), 10),
+	}
+	updatedPod, err := patch(pod, typeAnnotations, annotationPatchMap, c.kubeClient, c.kubeAPITimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("A repeated Pod interaction is detected, reset its idle TTL.", zap.Object("pod_interaction", &pi))
+	return c.updateTermination(*updatedPod)
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/box/kube-exec-controller/pkg/metrics"
+)
+
+// WithMaxHold bounds how long past its termination time an interacted Pod can be held in use by PodInUseAnnotate,
+// after which it is evicted even if not released. Zero means unbounded.
+func WithMaxHold(maxHold time.Duration) Option {
+	return func(c *Controller) {
+		c.maxHold = maxHold
+	}
+}
+
+// isPodInUse returns if the given Pod is annotated in use by PodInUseAnnotate.
+func isPodInUse(pod corev1.Pod) bool {
+	return pod.Annotations[PodInUseAnnotate] == "true"
+}
+
+// isPodHeld returns if the given Pod is held in use, along with the time its hold expires at by the max hold (zero
+// if unbounded). A Pod in use is no longer held once its hold has expired.
+func (c *Controller) isPodHeld(pod corev1.Pod) (time.Time, bool) {
+	if !isPodInUse(pod) {
+		return time.Time{}, false
+	}
+	if c.maxHold <= 0 {
+		return time.Time{}, true
+	}
+
+	terminationTime, err := getTerminationTime(pod, c.getMaxExtension(pod.Namespace), c.idleTTL)
+	if err != nil {
+		return time.Time{}, false
+	}
+	heldUntil := terminationTime.Add(c.maxHold)
+	return heldUntil, time.Now().Before(heldUntil)
+}
+
+// holdTerminationTimer pauses the termination timer of the given Pod until the given time (or indefinitely if zero),
+// or until it gets replaced by setTerminationTimer once the Pod is released. A timer is created if none exists, so
+// the Pod's updates are still handled.
+func (c *Controller) holdTerminationTimer(pod corev1.Pod, heldUntil time.Time) {
+	c.terminationTimersMu.Lock()
+	defer c.terminationTimersMu.Unlock()
+
+	duration := time.Duration(math.MaxInt64)
+	if !heldUntil.IsZero() {
+		duration = time.Until(heldUntil)
+	}
+	if timer, present := c.terminationTimersMap[pod.UID]; present {
+		timer.Stop()
+	}
+	timer := c.newTerminationTimer(pod, duration)
+	if heldUntil.IsZero() {
+		timer.Stop()
+	}
+	c.terminationTimersMap[pod.UID] = timer
+	metrics.TerminationTimers.Set(float64(len(c.terminationTimersMap)))
+	c.killSwitch.drop(pod.UID)
+	c.heldTimers[pod.UID] = true
+}
+
+// handlePodInUseToggle pauses or resumes evicting the given Pod as it is held in use or released by the given user.
+func (c *Controller) handlePodInUseToggle(pod corev1.Pod, requester string) error {
+	if err := c.setTermination(pod); err != nil {
+		return err
+	}
+
+	inUse := isPodInUse(pod)
+	message := fmt.Sprintf("Pod is released by user '%s', its eviction is resumed", requester)
+	if heldUntil, held := c.isPodHeld(pod); held && heldUntil.IsZero() {
+		message = fmt.Sprintf("Pod is held in use by user '%s', its eviction is paused until released by 'kubectl pi release'",
+			requester)
+	} else if held {
+		message = fmt.Sprintf("Pod is held in use by user '%s', its eviction is paused until released by 'kubectl pi release' or at most until %s",
+			requester, heldUntil.String())
+	} else if inUse {
+		message = fmt.Sprintf("Pod is held in use by user '%s', but its max hold has already expired", requester)
+	}
+	if err := c.submitEvent(&pod, EventCategoryExtension, message); err != nil {
+		return err
+	}
+
+	zap.L().Info("Toggled the in-use hold of an interacted Pod",
+		zap.String("pod_name", pod.Name),
+		zap.String("pod_namespace", pod.Namespace),
+		zap.String("requester_username", requester),
+		zap.Bool("in_use", inUse),
+	)
+
+	return nil
+}
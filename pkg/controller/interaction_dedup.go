@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PodInteractionCountAnnotate is set to the number of interactions of a Pod, including its initial one. It is an
+// annotation regardless of WithInteractionMetadata, so that counting interactions never changes the Pod's labels.
+var PodInteractionCountAnnotate = "box.com/podInteractionCount"
+
+// interactionDedup coalesces the repeated interactions of an interacted Pod within a window after the one handled,
+// so that a Pod interacted many times in a row is got and patched once per window instead of once per interaction.
+// Its entries are keyed by the UIDs of the Pods, so that the interactions of a Pod recreated under the same name are
+// never coalesced with the ones of its previous Pod.
+type interactionDedup struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	entries map[types.UID]*coalescedInteractions
+	// uids are the UIDs of the Pods whose windows are open by their namespace and name, as the UID of an interacted
+	// Pod is unknown until it is got (or read from the cache of WatchTrackedPods)
+	uids     map[string]types.UID
+	draining bool // set by Drain, ending the windows right away
+}
+
+// coalescedInteractions are the repeated interactions of a Pod coalesced within a window.
+type coalescedInteractions struct {
+	uid       types.UID
+	namespace string
+	name      string
+	count     int
+	latest    PodInteraction
+	timer     *time.Timer // ends the window
+	handling  bool        // set while the coalesced interactions are handled
+}
+
+// WithInteractionDedup coalesces the repeated interactions of an interacted Pod within the given window after the
+// one handled, which are handled at once at the end of the window: they are added to the PodInteractionCountAnnotate
+// of the Pod, and its TTL counts from the latest one in the TTLModeIdle mode. Zero or less handles every interaction.
+func WithInteractionDedup(window time.Duration) Option {
+	return func(c *Controller) {
+		if window > 0 {
+			c.interactionDedup = &interactionDedup{
+				window:  window,
+				entries: make(map[types.UID]*coalescedInteractions),
+				uids:    make(map[string]types.UID),
+			}
+		}
+	}
+}
+
+// coalesceInteraction coalesces the given interaction if its Pod is interacted within the window, returning true
+// if so. The Pod is told by its UID if read from the cache, otherwise it is assumed to be the one whose window is open
+// under its namespace and name, and a Pod recreated since is told once got at the end of the window.
+func (c *Controller) coalesceInteraction(pi PodInteraction) bool {
+	d := c.interactionDedup
+	if d == nil {
+		return false
+	}
+
+	cachedPod, cached := c.getTrackedPod(pi.PodNamespace, pi.PodName)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	uid := d.uids[pi.PodNamespace+"/"+pi.PodName]
+	if cached {
+		uid = cachedPod.UID
+	}
+	entry, present := d.entries[uid]
+	if !present {
+		return false
+	}
+	if entry.count == 0 || pi.InitTime.After(entry.latest.InitTime) {
+		entry.latest = pi
+	}
+	entry.count++
+
+	zap.L().Debug("A repeated Pod interaction is coalesced.", zap.Object("pod_interaction", &pi))
+	return true
+}
+
+// startInteractionWindow starts coalescing the repeated interactions of the given interacted Pod, which are handled
+// at once at the end of the window.
+func (c *Controller) startInteractionWindow(pod corev1.Pod) {
+	d := c.interactionDedup
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// a Pod recreated under the same name takes over the namespace and name from the previous one
+	d.uids[pod.Namespace+"/"+pod.Name] = pod.UID
+	if _, present := d.entries[pod.UID]; present {
+		return
+	}
+	entry := &coalescedInteractions{uid: pod.UID, namespace: pod.Namespace, name: pod.Name}
+	d.entries[pod.UID] = entry
+
+	// the window is waited for by Drain like a worker, which ends it right away
+	window := d.window
+	if d.draining {
+		window = 0
+	}
+	c.drainState.workers.Add(1)
+	entry.timer = time.AfterFunc(window, func() { c.flushCoalescedInteractions(entry) })
+}
+
+// flushCoalescedInteractions handles the interactions coalesced in the given entry with exponential backoff, until
+// no more are coalesced while handling them.
+func (c *Controller) flushCoalescedInteractions(entry *coalescedInteractions) {
+	defer c.drainState.workers.Done()
+
+	d := c.interactionDedup
+	for {
+		d.mu.Lock()
+		entry.handling = false
+		if entry.count == 0 {
+			delete(d.entries, entry.uid)
+			if key := entry.namespace + "/" + entry.name; d.uids[key] == entry.uid {
+				delete(d.uids, key)
+			}
+			d.mu.Unlock()
+			return
+		}
+		coalesced := *entry
+		entry.count = 0
+		entry.handling = true
+		d.mu.Unlock()
+
+		retryOperation := func() error { return c.handleCoalescedInteractions(coalesced) }
+		if err := backoff.RetryNotify(retryOperation, c.newRetryBackOff(), notifyInteractionRetry); err != nil {
+			zap.L().Error("Error in retrying to handle coalesced Pod interactions, giving up!",
+				zap.Object("pod_interaction", &coalesced.latest),
+				zap.Int("coalesced_count", coalesced.count),
+				zap.Error(err),
+			)
+			c.giveUpRetrying(retryKindInteraction, EventCategoryInteraction, coalesced.latest.PodNamespace,
+				coalesced.latest.PodName, err)
+		}
+	}
+}
+
+// endInteractionWindows ends the windows of coalescing repeated interactions right away, flushing the interactions
+// coalesced so far, as well as the windows started afterwards.
+func (c *Controller) endInteractionWindows() {
+	d := c.interactionDedup
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.draining = true
+	for _, entry := range d.entries {
+		// a stopped timer is not flushed yet, otherwise it is already being flushed
+		if entry.timer.Stop() {
+			go c.flushCoalescedInteractions(entry)
+		}
+	}
+}
+
+// deadLetterCoalescedInteractions dead-letters the latest one of the interactions coalesced for each Pod that are
+// still unhandled, including the ones being handled.
+func (c *Controller) deadLetterCoalescedInteractions() {
+	d := c.interactionDedup
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, entry := range d.entries {
+		if entry.count > 0 || entry.handling {
+			deadLetterInteraction(entry.latest)
+		}
+	}
+}
+
+// handleCoalescedInteractions handles the given coalesced interactions as repeated ones of their Pod, unless it is
+// recreated since.
+func (c *Controller) handleCoalescedInteractions(coalesced coalescedInteractions) error {
+	pi := coalesced.latest
+	pod, err := getPod(c.kubeClient, pi.PodNamespace, pi.PodName, c.kubeAPITimeout)
+	if apierrors.IsNotFound(err) {
+		zap.L().Info("Pod no longer exists, ignored its coalesced interactions.",
+			zap.String("pod_name", pi.PodName),
+			zap.String("pod_namespace", pi.PodNamespace),
+		)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// only the latest interaction is known to be of a Pod recreated within the window (unless told by the cache)
+	if pod.UID != coalesced.uid {
+		return c.handleInteractedPod(pod, pi, 1)
+	}
+
+	return c.handleInteractedPod(pod, pi, coalesced.count)
+}
+
+// addInteractionCount adds the given number of repeated interactions to the PodInteractionCountAnnotate of the given
+// interacted Pod, and sets its PodLastInteractorAnnotate to the user of the given latest one in the same patch.
+// The count includes its initial interaction only if unset (e.g. interacted before it was introduced), and continues
+// from the label set by previous versions if any, which is removed. It fails if the Pod has changed since read, so that a count added
+// concurrently (e.g. by another replica) is never overwritten, and the interactions are retried from the Pod read
+// again.
+func (c *Controller) addInteractionCount(pod corev1.Pod, pi PodInteraction, count int) error {
+	previousCount := 1
+	val, present := pod.Annotations[PodInteractionCountAnnotate]
+	if !present {
+		val, present = pod.Labels[PodInteractionCountAnnotate]
+	}
+	if present {
+		if parsed, err := strconv.Atoi(val); err == nil {
+			previousCount = parsed
+		}
+	}
+
+	annotationPatchMap := map[string]string{
+		PodInteractionCountAnnotate: strconv.Itoa(previousCount + count),
+		PodLastInteractorAnnotate:   pi.Username,
+	}
+	patchStrs := getJSONPatchAddStrs(pod, typeAnnotations, annotationPatchMap)
+	if _, present := pod.Labels[PodInteractionCountAnnotate]; present {
+		patchStrs = append(patchStrs, getJSONPatchRemoveStr(typeLabels, PodInteractionCountAnnotate))
+	}
+	if pod.ResourceVersion != "" {
+		patchStrs = append([]string{getJSONPatchTestStr("resourceVersion", pod.ResourceVersion)}, patchStrs...)
+	}
+
+	_, err := applyJSONPatch(pod, patchStrs, c.kubeClient, c.kubeAPITimeout)
+	return err
+}
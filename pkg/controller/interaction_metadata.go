@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InteractionMetadata is the type of metadata storing the interaction timestamp, interactor and TTL of a Pod.
+type InteractionMetadata string
+
+// These are the supported types of interaction metadata, annotations are for clusters restricting labels.
+const (
+	InteractionMetadataLabels      InteractionMetadata = "labels"
+	InteractionMetadataAnnotations InteractionMetadata = "annotations"
+)
+
+// ParseInteractionMetadata returns the InteractionMetadata of the given name, or an error if it is not supported.
+func ParseInteractionMetadata(name string) (InteractionMetadata, error) {
+	switch metadata := InteractionMetadata(strings.ToLower(name)); metadata {
+	case InteractionMetadataLabels, InteractionMetadataAnnotations:
+		return metadata, nil
+	default:
+		return "", fmt.Errorf("unsupported interaction metadata %q, expected one of: labels, annotations", name)
+	}
+}
+
+// WithInteractionMetadata stores the interaction timestamp, interactor and TTL of interacted Pods in the given type
+// of metadata instead of labels. Both types are read regardless, so Pods tracked in labels before switching to
+// annotations are still handled.
+func WithInteractionMetadata(metadata InteractionMetadata) Option {
+	return func(c *Controller) {
+		c.interactionMetadata = metadataType(metadata)
+	}
+}
+
+// GetInteractionMetadata returns the value of the given interaction key (e.g. PodInteractionTimestampLabel)
+// of the given Pod, which is looked up in its labels first and then its annotations.
+func GetInteractionMetadata(pod corev1.Pod, key string) (string, bool) {
+	if val, present := pod.Labels[key]; present {
+		return val, true
+	}
+
+	val, present := pod.Annotations[key]
+	return val, present
+}
+
+// IsInteractor returns if the given user is the recorded interactor of the given Pod, which is sanitized if stored
+// as a label.
+func IsInteractor(pod corev1.Pod, username string) bool {
+	interactor, present := GetInteractionMetadata(pod, PodInteractorLabel)
+	return present && (interactor == username || interactor == sanitizeLabelValue(username))
+}
+
+// listInteractedPods returns all Pods with an interaction timestamp set. Pods are selected by the label if stored
+// as labels, otherwise all Pods are listed and filtered. The ones tracked under the previous key prefix set by
+// SetPreviousKeyPrefix are listed as well, and returned migrated to the current prefix.
+func (c *Controller) listInteractedPods() ([]corev1.Pod, error) {
+	// all Pods are listed once if the interaction timestamp is not stored as a label
+	selectors := []string{""}
+	if c.interactionMetadata == typeLabels {
+		selectors = []string{PodInteractionTimestampLabel}
+		if previousKeyPrefix != "" {
+			selectors = append(selectors, getPreviousKey(PodInteractionTimestampLabel, previousKeyPrefix))
+		}
+	}
+
+	var pods []corev1.Pod
+	for _, selector := range selectors {
+		ctx, cancel := c.kubeAPIContext()
+		podList, err := c.kubeClient.CoreV1().Pods(corev1.NamespaceAll).List(ctx,
+			metav1.ListOptions{LabelSelector: selector})
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pod := range podList.Items {
+			migratedPod, err := migratePreviousKeys(pod, c.kubeClient, c.kubeAPITimeout)
+			if err != nil {
+				return nil, err
+			}
+			if _, interacted := GetInteractionMetadata(*migratedPod, PodInteractionTimestampLabel); interacted {
+				pods = append(pods, *migratedPod)
+			}
+		}
+	}
+
+	return pods, nil
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"go.uber.org/zap"
+)
+
+// WithInteractionWorkers makes CheckPodInteraction handle the new Pod interactions in the given number of goroutines,
+// so that a burst of interactions is not serialized behind the retries of a slow API server. The interactions of the
+// same Pod are always handled by the same worker, in the order they are received. One or less means a single worker.
+func WithInteractionWorkers(workers int) Option {
+	return func(c *Controller) {
+		if workers > 1 {
+			c.interactionWorkers = workers
+		}
+	}
+}
+
+// handleInteractions handles the Pod interactions received from the given channel with exponential backoff, as the
+// given worker, until the channel is closed.
+func (c *Controller) handleInteractions(worker int, interactions <-chan PodInteraction) {
+	ebo := c.newRetryBackOff()
+	for newInteraction := range interactions {
+		// redact the commands before anything is logged, submitted as an event or written as an audit record
+		newInteraction.Commands = RedactCommands(newInteraction.Commands, c.commandRedactions)
+		c.setInteractionInProgress(worker, &newInteraction)
+		retryOperation := func() error { return c.handleNewInteraction(newInteraction) }
+		if err := backoff.RetryNotify(retryOperation, ebo, notifyInteractionRetry); err != nil {
+			zap.L().Error("Error in retrying to check a new Pod interaction, giving up!",
+				zap.Object("pod_interaction", &newInteraction),
+				zap.Error(err),
+			)
+			c.giveUpRetrying(retryKindInteraction, EventCategoryInteraction, newInteraction.PodNamespace,
+				newInteraction.PodName, err)
+		}
+		c.setInteractionInProgress(worker, nil)
+		ebo.Reset()
+	}
+}
+
+// fanOutInteractions distributes the Pod interactions received from PodInteractionCh to the interaction workers,
+// sharded by their Pods, and returns once all of them are handled after the channel is closed.
+func (c *Controller) fanOutInteractions() {
+	shards := make([]chan PodInteraction, c.interactionWorkers)
+	var wg sync.WaitGroup
+	for i := range shards {
+		shards[i] = make(chan PodInteraction, cap(PodInteractionCh)/len(shards)+1)
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			c.handleInteractions(worker, shards[worker])
+		}(i)
+	}
+	c.setInteractionShards(shards)
+
+	for pi := range PodInteractionCh {
+		shards[getInteractionShard(pi, len(shards))] <- pi
+	}
+	for _, shard := range shards {
+		close(shard)
+	}
+	wg.Wait()
+}
+
+// getInteractionShard returns the index of the worker to handle the given Pod interaction among the given number of
+// workers. It is keyed by the namespace and name of the Pod, as its UID is unknown until the Pod is got.
+func getInteractionShard(pi PodInteraction, workers int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(pi.PodNamespace + "/" + pi.PodName))
+	return int(h.Sum32() % uint32(workers))
+}
+
+// notifyInteractionRetry logs the error of handling a Pod interaction to be retried in the given duration.
+func notifyInteractionRetry(err error, t time.Duration) {
+	zap.L().Warn(
+		fmt.Sprintf("Failed to handle a Pod interaction, will retry in %s", t.String()),
+		zap.Error(err),
+	)
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/box/kube-exec-controller/pkg/metrics"
+)
+
+// DebugJobLabel marks a Pod (usually through the pod template of its Job) whose owning Job is deleted instead of
+// evicting the Pod, if enabled by WithDebugJobDeletion. Otherwise the Job would recreate the evicted Pod.
+var DebugJobLabel = "box.com/debugJob"
+
+// WithDebugJobDeletion makes the Controller delete the owning Job of an interacted Pod labeled with
+// DebugJobLabel set to "true" instead of evicting the Pod, along with all its Pods.
+func WithDebugJobDeletion() Option {
+	return func(c *Controller) {
+		c.debugJobDeletion = true
+	}
+}
+
+// getDebugJobOwner returns the name of the Job controlling the given Pod if it is labeled as a debug Job,
+// or an empty string otherwise.
+func getDebugJobOwner(pod corev1.Pod) string {
+	if pod.Labels[DebugJobLabel] != "true" {
+		return ""
+	}
+	if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "Job" {
+		return owner.Name
+	}
+
+	return ""
+}
+
+// deleteJobFunc returns a function to delete the given Job owning the given Pod, propagating the deletion to its
+// Pods in the background. If an evictionLease is used, the Job is deleted only after acquiring the Pod's Lease.
+// The function returns true if the Job is deleted.
+func (c *Controller) deleteJobFunc(pod corev1.Pod, job string) func() bool {
+	return func() bool {
+		if !c.evictionLease.acquireForEviction(pod, c.kubeAPITimeout) {
+			return false
+		}
+
+		// the Pod may have been extended since the timer was set, so its current metadata is observed if available
+		current, err := getPod(c.kubeClient, pod.Namespace, pod.Name, c.kubeAPITimeout)
+		if err != nil {
+			current = &pod
+		}
+
+		propagation := metav1.DeletePropagationBackground
+		ctx, cancel := c.kubeAPIContext()
+		defer cancel()
+		err = c.kubeClient.BatchV1().Jobs(pod.Namespace).Delete(ctx, job, metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		})
+		if err != nil {
+			zap.L().Error("Error in deleting the Job of a Pod!",
+				zap.String("job_name", job),
+				zap.String("pod_name", pod.Name),
+				zap.String("namespace", pod.Namespace),
+				zap.Error(err),
+			)
+			return false
+		}
+
+		metrics.EvictionsTotal.WithLabelValues(pod.Namespace).Inc()
+		observePodAgeAtEviction(*current)
+		message := fmt.Sprintf("Pod's debug Job '%s' has been deleted instead of evicting the Pod", job)
+		// the Job is deleted regardless of failing to submit the event, which is logged in submitEvent
+		_ = c.submitEvent(&pod, EventCategoryEviction, message)
+		zap.L().Info("Successfully deleted the debug Job of an interacted Pod.",
+			zap.String("job_name", job),
+			zap.String("pod_name", pod.Name),
+			zap.String("namespace", pod.Namespace),
+		)
+
+		return true
+	}
+}
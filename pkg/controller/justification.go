@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// MaxClockSkew is how far in the future the justification time of a Pod can be set, tolerating the skewed clocks of
+// the users setting it.
+const MaxClockSkew = time.Minute
+
+// justificationRequirement requires interactions with Pods in sensitive namespaces to be justified beforehand.
+type justificationRequirement struct {
+	namespaces     map[string]bool
+	maxAge         time.Duration
+	unjustifiedTTL time.Duration
+}
+
+// WithJustificationRequirement requires a Pod under any of the given namespaces to carry a justification
+// (set by "kubectl pi justify") no older than maxAge when it gets interacted, zero maxAge means any age.
+// Otherwise, the Pod's TTL is reduced to unjustifiedTTL and a flagged event is submitted to it.
+func WithJustificationRequirement(namespaces []string, maxAge, unjustifiedTTL time.Duration) Option {
+	return func(c *Controller) {
+		requirement := &justificationRequirement{
+			namespaces:     make(map[string]bool),
+			maxAge:         maxAge,
+			unjustifiedTTL: unjustifiedTTL,
+		}
+		for _, ns := range namespaces {
+			if ns = strings.TrimSpace(ns); ns != "" {
+				requirement.namespaces[ns] = true
+			}
+		}
+		c.justification = requirement
+	}
+}
+
+// WithEventRecorder sets the record.EventRecorder to submit K8s events, instead of a default one posting to the
+// API server.
+func WithEventRecorder(recorder record.EventRecorder) Option {
+	return func(c *Controller) {
+		c.recorder = recorder
+	}
+}
+
+// isUnjustified returns true if the given Pod requires a justification but carries no recent one
+// at the time of the given interaction.
+func (jr *justificationRequirement) isUnjustified(pod corev1.Pod, pi PodInteraction) bool {
+	if jr == nil || !jr.namespaces[pod.Namespace] {
+		return false
+	}
+
+	if pod.Annotations[PodExecJustificationAnnotate] == "" {
+		return true
+	}
+	if jr.maxAge == 0 {
+		return false
+	}
+
+	justifiedTime, err := parseUnixTime(pod.Annotations[PodExecJustificationTimeAnnotate])
+	if err != nil {
+		zap.L().Warn("Failed to parse the justification time of a Pod, treating it as unjustified",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+			zap.Error(err),
+		)
+		return true
+	}
+	// a justification time in the future would justify the Pod forever
+	if justifiedTime.After(pi.InitTime.Add(MaxClockSkew)) {
+		zap.L().Warn("The justification time of a Pod is in the future, treating it as unjustified",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+			zap.Time("justification_time", justifiedTime),
+		)
+		return true
+	}
+
+	return pi.InitTime.Sub(justifiedTime) > jr.maxAge
+}
+
+// getInteractionTTL returns the TTL of the given Pod interaction, which is overridden by its namespace if annotated
+// and reduced if the Pod is privileged or it is not justified.
+// It also submits a flagged event to the Pod in either case.
+func (c *Controller) getInteractionTTL(pod corev1.Pod, pi PodInteraction) (time.Duration, error) {
+	ttl, err := c.reducePrivilegedTTL(pod, pi, c.getNamespaceTTL(pod.Namespace, c.policy.TTL(pod.Namespace, c.podTTLDuration)))
+	if err != nil {
+		return 0, err
+	}
+	if !c.justification.isUnjustified(pod, pi) {
+		return ttl, nil
+	}
+
+	if c.justification.unjustifiedTTL < ttl {
+		ttl = c.justification.unjustifiedTTL
+	}
+	message := fmt.Sprintf(
+		"Pod was interacted by a user '%s' without a recent justification (set by 'kubectl pi justify'), its TTL is reduced to %s",
+		pi.Username,
+		ttl.String(),
+	)
+	if err := c.submitEventWithReason(&pod, EventCategoryInteraction, unjustifiedPodInteractionEventReason, message); err != nil {
+		return 0, err
+	}
+
+	zap.L().Warn("An unjustified Pod interaction is detected in a namespace requiring justification",
+		zap.Object("pod_interaction", &pi),
+		zap.String("ttl", ttl.String()),
+	)
+
+	return ttl, nil
+}
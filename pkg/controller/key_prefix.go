@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultKeyPrefix is the prefix of all labels/annotations the controller sets or reads, unless set by SetKeyPrefix.
+const DefaultKeyPrefix = "box.com"
+
+// previousKeyPrefix is the key prefix the controller also recognizes during a transition to the current one, if set
+// by SetPreviousKeyPrefix.
+var previousKeyPrefix string
+
+// prefixedKeys are all labels/annotations named under the key prefix, which SetKeyPrefix renames.
+var prefixedKeys = []*string{
+	&PodInteractionTimestampLabel,
+	&PodInteractorLabel,
+	&PodTTLDurationLabel,
+	&PodInteractionCountAnnotate,
+	&PodExtendDurationAnnotate,
+	&PodExtendRequesterAnnotate,
+	&PodTerminationTimeAnnotate,
+	&PodExecJustificationAnnotate,
+	&PodExecJustificationTimeAnnotate,
+	&PodInUseAnnotate,
+	&PodInteractorClientAnnotate,
+	&PodLastInteractorAnnotate,
+	&PodTrackingLabel,
+	&PodExtensionHistoryAnnotate,
+	&PodLastInteractionTimestampAnnotate,
+	&PodDisableEvictionAnnotate,
+	&EventInteractionContainerAnnotate,
+	&EventInteractionCommandAnnotate,
+	&NamespaceTTLDurationAnnotate,
+	&NamespaceEvictionWarningAnnotate,
+	&NamespaceTerminationModeAnnotate,
+	&DebugJobLabel,
+	&OwnerLastEvictionTimeAnnotate,
+	&OwnerLastEvictionReasonAnnotate,
+}
+
+// SetKeyPrefix sets the prefix (e.g. "example.com") of all labels/annotations the controller sets or reads, for
+// forks or installs using their own domain, or returns an error if it is not a valid DNS subdomain. It must be
+// called at startup, before any Controller or webhook server is created.
+func SetKeyPrefix(prefix string) error {
+	if errs := validation.IsDNS1123Subdomain(prefix); len(errs) > 0 {
+		return fmt.Errorf("invalid key prefix %q: %s", prefix, strings.Join(errs, ", "))
+	}
+
+	for _, key := range prefixedKeys {
+		*key = prefix + (*key)[strings.Index(*key, "/"):]
+	}
+
+	return nil
+}
+
+// SetPreviousKeyPrefix sets the prefix the labels/annotations of Pods were named under before the current one (e.g.
+// "box.com" once moved to "example.com"), which the controller also recognizes during the transition, or returns an
+// error if it is not a valid DNS subdomain or is the current prefix. Pods whose interaction metadata is named under
+// it get their keys renamed to the current prefix once observed, and Namespace annotations named under it are read
+// if absent under the current prefix. Empty means no previous prefix. It must be called at startup after
+// SetKeyPrefix, before any Controller is created.
+func SetPreviousKeyPrefix(prefix string) error {
+	if prefix == "" {
+		previousKeyPrefix = ""
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(prefix); len(errs) > 0 {
+		return fmt.Errorf("invalid previous key prefix %q: %s", prefix, strings.Join(errs, ", "))
+	}
+	if getPreviousKey(PodInteractionTimestampLabel, prefix) == PodInteractionTimestampLabel {
+		return fmt.Errorf("previous key prefix %q is the current key prefix", prefix)
+	}
+
+	previousKeyPrefix = prefix
+	return nil
+}
+
+// getPreviousKey returns the given label/annotation key named under the given prefix instead.
+func getPreviousKey(key, prefix string) string {
+	return prefix + key[strings.Index(key, "/"):]
+}
+
+// migratePreviousKeys renames the labels/annotations of the given Pod named under the previous key prefix set by
+// SetPreviousKeyPrefix to the current prefix in a single patch, keeping the value of any key named under both, and
+// returns the patched Pod. The Pod is returned as is if it has none of them.
+func migratePreviousKeys(pod corev1.Pod, kubeClient kubernetes.Interface, timeout time.Duration) (*corev1.Pod, error) {
+	if previousKeyPrefix == "" {
+		return &pod, nil
+	}
+
+	var patchStrs []string
+	// some keys are shared by the metadata of different objects, e.g. the TTL of both Pods and Namespaces
+	migratedKeys := map[string]bool{}
+	for _, key := range prefixedKeys {
+		if migratedKeys[*key] {
+			continue
+		}
+		migratedKeys[*key] = true
+
+		previousKey := getPreviousKey(*key, previousKeyPrefix)
+		for _, dataType := range []metadataType{typeLabels, typeAnnotations} {
+			data := pod.Labels
+			if dataType == typeAnnotations {
+				data = pod.Annotations
+			}
+
+			val, present := data[previousKey]
+			if !present {
+				continue
+			}
+			if _, migrated := data[*key]; !migrated {
+				patchStrs = append(patchStrs, getJSONPatchStr(dataType, *key, val))
+			}
+			patchStrs = append(patchStrs, getJSONPatchRemoveStr(dataType, previousKey))
+		}
+	}
+	if len(patchStrs) == 0 {
+		return &pod, nil
+	}
+
+	patchedPod, err := applyJSONPatch(pod, patchStrs, kubeClient, timeout)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("Renamed the labels/annotations of a Pod named under the previous key prefix",
+		zap.String("pod_name", pod.Name),
+		zap.String("pod_namespace", pod.Namespace),
+		zap.String("previous_key_prefix", previousKeyPrefix),
+	)
+
+	return patchedPod, nil
+}
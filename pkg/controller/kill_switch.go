@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/box/kube-exec-controller/pkg/metrics"
+)
+
+// KillSwitchConfigMapName is the name of the ConfigMap to disable all evictions in an emergency,
+// by setting its "disabled" key to "true".
+const KillSwitchConfigMapName = "kube-exec-controller-config"
+
+const killSwitchDisabledKey = "disabled"
+
+// killSwitch defers all evictions while it is set, keeping the Pods whose eviction is deferred to re-arm their
+// termination timers once it gets cleared.
+type killSwitch struct {
+	mu       sync.Mutex
+	disabled bool
+	deferred map[types.UID]corev1.Pod
+}
+
+// newKillSwitch returns a killSwitch with evictions enabled.
+func newKillSwitch() *killSwitch {
+	return &killSwitch{
+		deferred: make(map[types.UID]corev1.Pod),
+	}
+}
+
+// set disables or enables all evictions. It returns the Pods whose eviction has been deferred if enabled again,
+// which are kept deferred until their timers are re-armed.
+func (ks *killSwitch) set(disabled bool) []corev1.Pod {
+	ks.mu.Lock()
+	if ks.disabled == disabled {
+		ks.mu.Unlock()
+		return nil
+	}
+
+	ks.disabled = disabled
+	var deferredPods []corev1.Pod
+	if !disabled {
+		for _, pod := range ks.deferred {
+			deferredPods = append(deferredPods, pod)
+		}
+	}
+	ks.mu.Unlock()
+
+	if disabled {
+		metrics.EvictionDisabled.Set(1)
+		zap.L().Warn("!!! KILL SWITCH IS ON: all Pod evictions are paused until it is cleared !!!",
+			zap.String("configmap", KillSwitchConfigMapName),
+		)
+		return nil
+	}
+
+	metrics.EvictionDisabled.Set(0)
+	zap.L().Warn("Kill switch is cleared, resuming Pod evictions",
+		zap.String("configmap", KillSwitchConfigMapName),
+		zap.Int("deferred_evictions", len(deferredPods)),
+	)
+	return deferredPods
+}
+
+// deferIfDisabled keeps the given Pod to re-arm its termination timer later and returns true if evictions are
+// disabled.
+func (ks *killSwitch) deferIfDisabled(pod corev1.Pod) bool {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.disabled {
+		ks.deferred[pod.UID] = pod
+	}
+
+	return ks.disabled
+}
+
+// drop forgets the deferred eviction of the Pod with the given UID, e.g. as its timer is reset or removed. It returns
+// true if its eviction has been deferred, i.e. its timer has expired.
+func (ks *killSwitch) drop(uid types.UID) bool {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	_, present := ks.deferred[uid]
+	delete(ks.deferred, uid)
+
+	return present
+}
+
+// resumeDeferredEvictions re-arms the termination timers of the given Pods whose eviction has been deferred by the
+// kill switch, re-reading each Pod as it may have been extended or deleted in the meantime.
+func (c *Controller) resumeDeferredEvictions(pods []corev1.Pod) {
+	for _, pod := range pods {
+		latestPod, err := getPod(c.kubeClient, pod.Namespace, pod.Name, c.kubeAPITimeout)
+		if apierrors.IsNotFound(err) {
+			c.deleteTerminationTimer(pod.UID)
+			continue
+		}
+		if err != nil {
+			zap.L().Warn("Failed to get a Pod whose eviction has been deferred, re-arming its timer as last observed",
+				zap.String("pod_name", pod.Name),
+				zap.String("pod_namespace", pod.Namespace),
+				zap.Error(err),
+			)
+			latestPod = &pod
+		}
+
+		if err := c.setTermination(*latestPod); err != nil {
+			zap.L().Error("Error in re-arming the termination timer of a Pod whose eviction has been deferred",
+				zap.String("pod_name", pod.Name),
+				zap.String("pod_namespace", pod.Namespace),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// WatchKillSwitch keeps the kill switch in sync with the KillSwitchConfigMapName ConfigMap under the given
+// namespace until stopCh is closed. It blocks until the initial state of the ConfigMap is synced.
+func (c *Controller) WatchKillSwitch(namespace string, stopCh <-chan struct{}) {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.kubeClient, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", KillSwitchConfigMapName).String()
+		}),
+	)
+	informer := factory.Core().V1().ConfigMaps().Informer()
+
+	// the deferred evictions are resumed outside the informer's handler, which must not block on the K8s API
+	setKillSwitch := func(disabled bool) {
+		if deferredPods := c.killSwitch.set(disabled); len(deferredPods) > 0 {
+			go c.resumeDeferredEvictions(deferredPods)
+		}
+	}
+	handleConfigMap := func(obj interface{}) {
+		if configMap, ok := obj.(*corev1.ConfigMap); ok {
+			setKillSwitch(configMap.Data[killSwitchDisabledKey] == "true")
+		}
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    handleConfigMap,
+		UpdateFunc: func(_, obj interface{}) { handleConfigMap(obj) },
+		DeleteFunc: func(_ interface{}) { setKillSwitch(false) },
+	})
+
+	c.addInformerSync(informer.HasSynced)
+
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// WithKubeAPITimeout bounds each call to the K8s API getting, listing, patching, evicting or deleting the interacted
+// Pods (and their owners), their eviction Leases and the history ConfigMap by the given timeout, so that a hung API
+// server cannot block the controller indefinitely.
+// A timed out call fails like any other error, e.g. an interaction is retried with backoff. Zero means no timeout.
+func WithKubeAPITimeout(timeout time.Duration) Option {
+	return func(c *Controller) {
+		c.kubeAPITimeout = timeout
+		c.evictionAPI.timeout = timeout
+	}
+}
+
+// kubeAPIContext returns a context of a call to the K8s API, timing out as set by WithKubeAPITimeout.
+func (c *Controller) kubeAPIContext() (context.Context, context.CancelFunc) {
+	return newKubeAPIContext(c.kubeAPITimeout)
+}
+
+// newKubeAPIContext returns a context of a call to the K8s API, timing out after the given timeout if positive.
+func newKubeAPIContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.TODO())
+	}
+
+	return context.WithTimeout(context.TODO(), timeout)
+}
+
+// getPod returns the Pod of the given name and namespace, timing out after the given timeout if positive.
+func getPod(kubeClient kubernetes.Interface, namespace, name string, timeout time.Duration) (*corev1.Pod, error) {
+	ctx, cancel := newKubeAPIContext(timeout)
+	defer cancel()
+
+	return kubeClient.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+}
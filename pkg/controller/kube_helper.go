@@ -1,15 +1,16 @@
 package controller
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
-	policy "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
@@ -17,6 +18,9 @@ import (
 	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/tools/reference"
+
+	"github.com/box/kube-exec-controller/pkg/duration"
+	"github.com/box/kube-exec-controller/pkg/metrics"
 )
 
 // metadataType contains the metadata type of a K8s object.
@@ -28,21 +32,75 @@ const (
 )
 
 // These labels are set when a Pod interaction occurs and not supposed to change after.
-const (
+// They are set as annotations instead if configured by WithInteractionMetadata.
+var (
 	PodInteractionTimestampLabel = "box.com/podInitialInteractionTimestamp"
 	PodInteractorLabel           = "box.com/podInteractorUsername"
 	PodTTLDurationLabel          = "box.com/podTTLDuration"
 )
 
 // These annotations are set when requesting extended termination time to an interacted Pod.
-const (
+var (
 	PodExtendDurationAnnotate  = "box.com/podExtendedDuration"
 	PodExtendRequesterAnnotate = "box.com/podExtensionRequester"
 	PodTerminationTimeAnnotate = "box.com/podTerminationTime"
 )
 
-// initEventRecorder returns a record.EventRecorder to submit K8s events.
-func initEventRecorder(kubeClient kubernetes.Interface) record.EventRecorder {
+// These annotations are set by users (by "kubectl pi justify") to justify interacting a Pod.
+var (
+	PodExecJustificationAnnotate     = "box.com/execJustification"
+	PodExecJustificationTimeAnnotate = "box.com/execJustificationTimestamp"
+)
+
+// PodInUseAnnotate is set to "true" by the interactor (by "kubectl pi hold") to pause evicting an interacted Pod
+// until it gets removed (by "kubectl pi release") or its max hold expires.
+var PodInUseAnnotate = "box.com/podInUse"
+
+// FieldManager is the field manager of the controller's Pod updates, recording them in the managed fields of Pods.
+const FieldManager = "kube-exec-controller"
+
+// These are the reasons of K8s events submitted to interacted Pods.
+const (
+	podInteractionEventReason            = "PodInteraction"
+	unjustifiedPodInteractionEventReason = "UnjustifiedPodInteraction"
+	privilegedPodInteractionEventReason  = "PrivilegedPodInteraction"
+	foreignPodExtensionEventReason       = "ForeignPodExtension"
+	evictionWarningEventReason           = "PodEvictionWarning"
+	trackedPodsLimitEventReason          = "TrackedPodsLimitExceeded"
+	retryGivenUpEventReason              = "RetryGivenUp"
+)
+
+// These annotations are set to the K8s event of a Pod interaction, so that its container and (redacted) command can be
+// audited without parsing the event message, in which the command may be truncated.
+var (
+	EventInteractionContainerAnnotate = "box.com/interactionContainer"
+	EventInteractionCommandAnnotate   = "box.com/interactionCommand"
+)
+
+// maxEventCommandLength is the max number of characters of the command shown in the event message of a Pod
+// interaction, and maxEventCommandAnnotationLength of the one set to EventInteractionCommandAnnotate, beyond which
+// it is truncated with CommandTruncatedMarker.
+const (
+	maxEventCommandLength           = 256
+	maxEventCommandAnnotationLength = 4096
+)
+
+// CommandTruncatedMarker is appended to a command list if it gets truncated by TruncateCommands.
+const CommandTruncatedMarker = "...(truncated)"
+
+// PodInteractorClientAnnotate is set to the client metadata of a Pod interaction in JSON, if any is available.
+var PodInteractorClientAnnotate = "box.com/podInteractorClientInfo"
+
+// PodLastInteractorAnnotate is set to the username of the latest interaction of a Pod, while PodInteractorLabel keeps
+// the one of its initial interaction.
+var PodLastInteractorAnnotate = "box.com/podLastInteractorUsername"
+
+// PodTrackingLabel is injected to the Pods at creation by the mutating webhook, if configured, so that their labels
+// exist before any interaction. It is never changed by the controller.
+var PodTrackingLabel = "box.com/execTracking"
+
+// NewEventRecorder returns a record.EventRecorder to submit K8s events as the controller, e.g. by the webhook server.
+func NewEventRecorder(kubeClient kubernetes.Interface) record.EventRecorder {
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartRecordingToSink(&typedv1.EventSinkImpl{
 		Interface: kubeClient.CoreV1().Events(""),
@@ -51,8 +109,32 @@ func initEventRecorder(kubeClient kubernetes.Interface) record.EventRecorder {
 	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component})
 }
 
-// submitEvent posts a K8s event to the target Pod with the given message.
-func submitEvent(pod *corev1.Pod, message string, recorder record.EventRecorder) error {
+// submitEvent posts a K8s event of the given category to the target Pod with the given message, of the type and
+// reason set by WithEventConfig.
+func (c *Controller) submitEvent(pod *corev1.Pod, category EventCategory, message string) error {
+	return c.submitEventWithReason(pod, category, c.getEventConfig(category).reason, message)
+}
+
+// submitEventWithReason posts a K8s event of the given category to the target Pod with the given reason and message.
+// It is suppressed for a terminating Pod if set by WithTerminatingPodEventSuppression.
+func (c *Controller) submitEventWithReason(pod *corev1.Pod, category EventCategory, reason, message string) error {
+	return c.submitAnnotatedEvent(pod, category, reason, message, nil)
+}
+
+// submitAnnotatedEvent posts a K8s event of the given category with the given annotations to the target Pod with
+// the given reason and message. It is suppressed for a terminating Pod if set by WithTerminatingPodEventSuppression.
+func (c *Controller) submitAnnotatedEvent(pod *corev1.Pod, category EventCategory, reason, message string,
+	annotations map[string]string) error {
+	if c.suppressTerminatingPodEvents && isPodTerminating(*pod) {
+		metrics.SuppressedEventsTotal.WithLabelValues(pod.Namespace, reason).Inc()
+		zap.L().Debug("Suppressed a K8s event to a terminating Pod",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+			zap.String("event_message", message),
+		)
+		return nil
+	}
+
 	ref, err := reference.GetReference(scheme.Scheme, pod)
 	if err != nil {
 		zap.L().Error("Failed to submit K8s event to the target Pod",
@@ -64,41 +146,163 @@ func submitEvent(pod *corev1.Pod, message string, recorder record.EventRecorder)
 		return err
 	}
 
-	reason := "PodInteraction"
-	recorder.Event(ref, corev1.EventTypeWarning, reason, message)
+	eventType := c.getEventConfig(category).eventType
+	if len(annotations) > 0 {
+		c.recorder.AnnotatedEventf(ref, annotations, eventType, reason, "%s", message)
+	} else {
+		c.recorder.Event(ref, eventType, reason, message)
+	}
 
 	return nil
 }
 
-// evictPodFunc returns a function to evict a Pod specified by its name and namespace
-func evictPodFunc(name, namespace string, kubeClient kubernetes.Interface) func() {
-	return func() {
-		err := kubeClient.PolicyV1beta1().Evictions(namespace).Evict(context.TODO(), &policy.Eviction{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      name,
-				Namespace: namespace,
-			},
-		})
+// getEventCommand returns the given command list joined to be shown in an event, truncated to the given max number
+// of characters with CommandTruncatedMarker if longer.
+func getEventCommand(commands []string, maxLength int) string {
+	commands, _ = TruncateCommands(commands, 0, maxLength)
+	return strings.Join(commands, " ")
+}
+
+// TruncateCommands returns the given commands truncated to at most maxArgs args and maxLength characters
+// in total, followed by CommandTruncatedMarker if anything is truncated. Zero value means no limit. The characters
+// are counted as runes, so a multi-byte character is never split.
+func TruncateCommands(commands []string, maxArgs, maxLength int) ([]string, bool) {
+	var res []string
+	length := 0
+	for i, arg := range commands {
+		if maxArgs > 0 && i >= maxArgs {
+			return append(res, CommandTruncatedMarker), true
+		}
+
+		argLength := utf8.RuneCountInString(arg)
+		if maxLength > 0 && length+argLength > maxLength {
+			// keep the beginning of the arg that still fits in the limit
+			if remaining := maxLength - length; remaining > 0 {
+				res = append(res, string([]rune(arg)[:remaining]))
+			}
+			return append(res, CommandTruncatedMarker), true
+		}
+
+		length += argLength
+		res = append(res, arg)
+	}
+
+	return commands, false
+}
+
+// evictPodFunc returns a function to evict the given Pod through the given evictionAPI with the given grace period
+// (zero means the Pod's own one). If an evictionLease is given, the Pod is evicted only after acquiring its Lease,
+// so exactly one of multiple controller replicas evicts it. The function returns true if the Pod is evicted.
+// An eviction forbidden by missing RBAC is logged once with the rule needed, until a Pod is evicted again. An eviction
+// refused by a PodDisruptionBudget is retried and handled as set by WithPDBBlockedAction.
+func evictPodFunc(pod corev1.Pod, kubeClient kubernetes.Interface, api *evictionAPI, lease *evictionLease,
+	gracePeriod time.Duration) func() bool {
+	name, namespace := pod.Name, pod.Namespace
+
+	return func() bool {
+		if !lease.acquireForEviction(pod, api.timeout) {
+			return false
+		}
+
+		// the Pod may have been extended since the timer was set, so its current metadata is observed if available
+		current, err := getPod(kubeClient, namespace, name, api.timeout)
+		if err != nil {
+			current = &pod
+		}
+
+		err = api.evictOrHandleBlocked(kubeClient, name, namespace, gracePeriod)
+		if apierrors.IsForbidden(err) {
+			if api.permission.setForbidden(true) {
+				zap.L().Error("Forbidden to evict interacted Pods, grant the controller's ServiceAccount the RBAC rule needed!",
+					zap.String("rbac_rule", api.requiredRBACRule(namespace)),
+					zap.String("pod_name", name),
+					zap.String("namespace", namespace),
+					zap.Error(err),
+				)
+			}
+			return false
+		}
+		if isPDBRefusal(err) {
+			zap.L().Error("Eviction of a Pod is refused by a PodDisruptionBudget, giving up!",
+				zap.String("pod_name", name),
+				zap.String("namespace", namespace),
+				zap.Error(err),
+			)
+			return false
+		}
 		if err != nil {
 			zap.L().Error("Error in evicting a Pod!",
 				zap.String("pod_name", name),
 				zap.String("namespace", namespace),
 				zap.Error(err),
 			)
-			return
+			return false
 		}
 
+		if api.permission.setForbidden(false) {
+			zap.L().Info("Permitted to evict interacted Pods again.")
+		}
+		metrics.EvictionsTotal.WithLabelValues(namespace).Inc()
+		observePodAgeAtEviction(*current)
 		zap.L().Info("Successfully evicted an interacted Pod.",
 			zap.String("name", name),
 			zap.String("namespace", namespace),
 		)
+
+		return true
 	}
 }
 
-// patch updates a K8s Pod with given metadata type and values passed from a map.
-// It returns the patched Pod.
-func patch(pod corev1.Pod, dataType metadataType, dataMap map[string]string, kubeClient kubernetes.Interface) (
-	*corev1.Pod, error) {
+// observePodAgeAtEviction observes the age of the given evicted Pod since its first interaction, if labeled.
+func observePodAgeAtEviction(pod corev1.Pod) {
+	timestamp, _ := GetInteractionMetadata(pod, PodInteractionTimestampLabel)
+	interactedTime, err := parseUnixTime(timestamp)
+	if err != nil {
+		return
+	}
+
+	_, extended := pod.Annotations[PodExtendDurationAnnotate]
+	metrics.PodAgeAtEvictionSeconds.WithLabelValues(pod.Namespace, strconv.FormatBool(extended)).
+		Observe(time.Since(interactedTime).Seconds())
+}
+
+// isEphemeralContainer returns true if the given container name is an ephemeral container of the given Pod, rather
+// than a regular or init one. An empty name (the Pod's default container) is never an ephemeral one.
+func isEphemeralContainer(pod corev1.Pod, containerName string) bool {
+	if containerName == "" {
+		return false
+	}
+
+	for _, container := range pod.Spec.EphemeralContainers {
+		if container.Name == containerName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// patch updates a K8s Pod with given metadata type and values passed from a map, timing out after the given timeout
+// if positive. It returns the patched Pod.
+func patch(pod corev1.Pod, dataType metadataType, dataMap map[string]string, kubeClient kubernetes.Interface,
+	timeout time.Duration) (*corev1.Pod, error) {
+	return applyJSONPatch(pod, getJSONPatchAddStrs(pod, dataType, dataMap), kubeClient, timeout)
+}
+
+// patchIfUnchanged updates a K8s Pod like patch, unless it has changed since the given Pod was read (i.e. its
+// resourceVersion differs, as the Pod is read from a stale informer cache), in which case the patch fails.
+func patchIfUnchanged(pod corev1.Pod, dataType metadataType, dataMap map[string]string,
+	kubeClient kubernetes.Interface, timeout time.Duration) (*corev1.Pod, error) {
+	patchStrs := getJSONPatchAddStrs(pod, dataType, dataMap)
+	if pod.ResourceVersion != "" {
+		patchStrs = append([]string{getJSONPatchTestStr("resourceVersion", pod.ResourceVersion)}, patchStrs...)
+	}
+
+	return applyJSONPatch(pod, patchStrs, kubeClient, timeout)
+}
+
+// getJSONPatchAddStrs returns the JSON patch strings adding the given metadata type and values to the given Pod.
+func getJSONPatchAddStrs(pod corev1.Pod, dataType metadataType, dataMap map[string]string) []string {
 	var patchStrs []string
 	var isEmpty bool
 	if dataType == typeLabels {
@@ -117,9 +321,52 @@ func patch(pod corev1.Pod, dataType metadataType, dataMap map[string]string, kub
 		patchStrs = append(patchStrs, patchStr)
 	}
 
+	return patchStrs
+}
+
+// applyJSONPatch applies the given JSON patch strings to a K8s Pod, timing out after the given timeout if positive.
+// It returns the patched Pod.
+func applyJSONPatch(pod corev1.Pod, patchStrs []string, kubeClient kubernetes.Interface, timeout time.Duration) (
+	*corev1.Pod, error) {
 	patchData := []byte(fmt.Sprintf("[%s]", strings.Join(patchStrs, ",")))
-	patchOpts := metav1.PatchOptions{FieldManager: "kube-exec-controller"}
-	return kubeClient.CoreV1().Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.JSONPatchType, patchData, patchOpts)
+	patchOpts := metav1.PatchOptions{FieldManager: FieldManager}
+	ctx, cancel := newKubeAPIContext(timeout)
+	defer cancel()
+	return kubeClient.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.JSONPatchType, patchData, patchOpts)
+}
+
+// removeMetadata removes the given keys of a metadata type from a K8s Pod, ignoring any key not present, timing out
+// after the given timeout if positive. It returns the patched Pod.
+func removeMetadata(pod corev1.Pod, dataType metadataType, keys []string, kubeClient kubernetes.Interface,
+	timeout time.Duration) (*corev1.Pod, error) {
+	existingData := pod.Labels
+	if dataType == typeAnnotations {
+		existingData = pod.Annotations
+	}
+
+	var patchStrs []string
+	for _, key := range keys {
+		if _, present := existingData[key]; present {
+			patchStrs = append(patchStrs, getJSONPatchRemoveStr(dataType, key))
+		}
+	}
+	if len(patchStrs) == 0 {
+		return &pod, nil
+	}
+
+	return applyJSONPatch(pod, patchStrs, kubeClient, timeout)
+}
+
+// getJSONPatchRemoveStr returns a JSON patch string removing the given key of a metadata type.
+func getJSONPatchRemoveStr(dataType metadataType, key string) string {
+	return fmt.Sprintf("{\"op\":\"remove\",\"path\":\"/metadata/%s/%s\"}", dataType, EscapeJSONPointer(key))
+}
+
+// getJSONPatchTestStr returns a JSON patch string testing the given field of the Pod's metadata equals the given
+// value, failing the whole patch otherwise.
+func getJSONPatchTestStr(field, val string) string {
+	quotedVal, _ := json.Marshal(val)
+	return fmt.Sprintf("{\"op\":\"test\",\"path\":\"/metadata/%s\",\"value\":%s}", field, quotedVal)
 }
 
 // getJSONPatchStr returns a JSON patch string from the given metadata type, key and value.
@@ -130,26 +377,41 @@ func getJSONPatchStr(dataType metadataType, key, val string) string {
 	}
 
 	// replace invalid characters in key to satisfy JSON patch format
-	key = strings.ReplaceAll(key, "~", "~0")
-	key = strings.ReplaceAll(key, "/", "~1")
+	key = EscapeJSONPointer(key)
 
-	// replace invalid characters in label's val to satisfy K8s requirement
 	if dataType == typeLabels {
-		val = strings.ReplaceAll(val, ":", "_")
+		val = sanitizeLabelValue(val)
 	}
 
-	return fmt.Sprintf("{\"op\":\"add\",\"path\":\"/metadata/%s/%s\",\"value\":\"%s\"}",
-		dataType, key, val)
+	// quote the val as a JSON string, as annotations may contain characters like '"'
+	quotedVal, _ := json.Marshal(val)
+
+	return fmt.Sprintf("{\"op\":\"add\",\"path\":\"/metadata/%s/%s\",\"value\":%s}",
+		dataType, key, quotedVal)
+}
+
+// sanitizeLabelValue replaces invalid characters in the given label value to satisfy K8s requirement.
+func sanitizeLabelValue(val string) string {
+	return strings.ReplaceAll(val, ":", "_")
+}
+
+// EscapeJSONPointer replaces invalid characters in the given key (e.g. of a label) to satisfy JSON patch format.
+func EscapeJSONPointer(key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	return strings.ReplaceAll(key, "/", "~1")
 }
 
 // getTerminationTime returns the termination time by parsing current related metadata from the target Pod.
-func getTerminationTime(pod corev1.Pod) (time.Time, error) {
-	interactedTime, err := parseUnixTime(pod.Labels[PodInteractionTimestampLabel])
+// The TTL counts from its latest interaction if recorded and idleTTL is set (i.e. the TTLModeIdle mode), or its
+// initial one otherwise. The extension is capped to the given maxExtension unless it is zero.
+func getTerminationTime(pod corev1.Pod, maxExtension time.Duration, idleTTL bool) (time.Time, error) {
+	interactedTime, err := getTTLStartTime(pod, idleTTL)
 	if err != nil {
 		return time.Time{}, err
 	}
 
-	ttlDuration, err := time.ParseDuration(pod.Labels[PodTTLDurationLabel])
+	ttl, _ := GetInteractionMetadata(pod, PodTTLDurationLabel)
+	ttlDuration, err := duration.Parse(ttl)
 	if err != nil {
 		return time.Time{}, err
 	}
@@ -157,15 +419,45 @@ func getTerminationTime(pod corev1.Pod) (time.Time, error) {
 	extendDuration := time.Duration(0)
 	extendDurationStr, present := pod.Annotations[PodExtendDurationAnnotate]
 	if present {
-		extendDuration, err = time.ParseDuration(extendDurationStr)
+		extendDuration, err = duration.Parse(extendDurationStr)
 		if err != nil {
 			return time.Time{}, err
 		}
 	}
 
+	if maxExtension > 0 && extendDuration > maxExtension {
+		zap.L().Info("Capped the requested extension of a Pod to the max extension",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+			zap.String("requested_extension", extendDuration.String()),
+			zap.String("max_extension", maxExtension.String()),
+		)
+		extendDuration = maxExtension
+	}
+
 	return interactedTime.Add(ttlDuration).Add(extendDuration), nil
 }
 
+// getTTLStartTime returns the time the TTL of the target Pod counts from, i.e. its latest interaction if recorded
+// and idleTTL is set (i.e. the TTLModeIdle mode), or its initial one otherwise. The latest interaction left over
+// from the TTLModeIdle mode is ignored otherwise.
+func getTTLStartTime(pod corev1.Pod, idleTTL bool) (time.Time, error) {
+	timestamp, _ := GetInteractionMetadata(pod, PodInteractionTimestampLabel)
+	interactedTime, err := parseUnixTime(timestamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !idleTTL {
+		return interactedTime, nil
+	}
+	if lastTime, err := parseUnixTime(pod.Annotations[PodLastInteractionTimestampAnnotate]); err == nil &&
+		lastTime.After(interactedTime) {
+		interactedTime = lastTime
+	}
+
+	return interactedTime, nil
+}
+
 // parseUnixTime parses the given Unix time string and returns a time.Time object.
 func parseUnixTime(str string) (time.Time, error) {
 	timeInt, err := strconv.ParseInt(str, 10, 64)
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/box/kube-exec-controller/pkg/metrics"
+)
+
+// leaderElectionLeaseName is the name of the Lease elected by controller replicas.
+const leaderElectionLeaseName = "kube-exec-controller-leader"
+
+// These are the timings of the leader election, the same as the defaults of kube-controller-manager.
+const (
+	leaderElectionLeaseDuration = 15 * time.Second
+	leaderElectionRenewDeadline = 10 * time.Second
+	leaderElectionRetryPeriod   = 2 * time.Second
+)
+
+// leaderState tracks whether the Controller replica is the elected leader, which is the only one setting
+// termination timers when multiple replicas are running.
+type leaderState struct {
+	mu      sync.Mutex
+	leading bool
+}
+
+// WithLeaderElection makes the Controller set termination timers (and so evict Pods) only while it is elected as
+// the leader by RunLeaderElection. Other replicas still handle the interactions and extensions admitted by their
+// webhook, i.e. set the metadata of the Pods, which the leader reconciles by WatchInteractedPods.
+func WithLeaderElection() Option {
+	return func(c *Controller) {
+		c.leader = &leaderState{}
+	}
+}
+
+// RunLeaderElection runs for the leader of controller replicas as the given identity, by a coordination/v1 Lease
+// in the given namespace, until stopCh is closed. It sets termination timers to all interacted Pods once elected,
+// and removes them once the leadership is lost, running for it again. It does nothing unless WithLeaderElection
+// is set.
+func (c *Controller) RunLeaderElection(namespace, identity string, stopCh <-chan struct{}) error {
+	if c.leader == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      leaderElectionLeaseName,
+				Namespace: namespace,
+			},
+			Client:     c.kubeClient.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration:   leaderElectionLeaseDuration,
+		RenewDeadline:   leaderElectionRenewDeadline,
+		RetryPeriod:     leaderElectionRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            leaderElectionLeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) { c.startLeading() },
+			OnStoppedLeading: c.stopLeading,
+			OnNewLeader: func(leader string) {
+				zap.L().Info("Observed a new leader of controller replicas.", zap.String("leader", leader))
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// run for the leader again once the leadership is lost, e.g. failing to renew it in time
+	for ctx.Err() == nil {
+		elector.Run(ctx)
+	}
+
+	return nil
+}
+
+// isLeading returns true if the Controller replica is the elected leader or no leader election is set.
+func (c *Controller) isLeading() bool {
+	if c.leader == nil {
+		return true
+	}
+
+	c.leader.mu.Lock()
+	defer c.leader.mu.Unlock()
+
+	return c.leader.leading
+}
+
+// startLeading marks the Controller replica as the leader and sets termination timers to all interacted Pods.
+func (c *Controller) startLeading() {
+	c.leader.mu.Lock()
+	c.leader.leading = true
+	c.leader.mu.Unlock()
+	zap.L().Info("Started leading controller replicas, setting termination timers to interacted Pods.")
+
+	retryNotifier := func(err error, t time.Duration) {
+		zap.L().Warn(
+			fmt.Sprintf("Failed to check previous Pod interactions as the leader, will retry in %s", t.String()),
+			zap.Error(err),
+		)
+	}
+	if err := backoff.RetryNotify(c.handlePreviousInteraction, c.newRetryBackOff(), retryNotifier); err != nil {
+		zap.L().Error("Error in retrying to check previous Pod interactions as the leader, giving up!", zap.Error(err))
+		metrics.GiveUpsTotal.WithLabelValues(retryKindPreviousInteractions).Inc()
+	}
+}
+
+// stopLeading marks the Controller replica as no longer the leader and removes all its termination timers,
+// as the new leader takes them over.
+func (c *Controller) stopLeading() {
+	c.leader.mu.Lock()
+	leading := c.leader.leading
+	c.leader.leading = false
+	c.leader.mu.Unlock()
+	if !leading {
+		return
+	}
+
+	c.terminationTimersMu.Lock()
+	uids := make([]types.UID, 0, len(c.terminationTimersMap))
+	for uid := range c.terminationTimersMap {
+		uids = append(uids, uid)
+	}
+	c.terminationTimersMu.Unlock()
+
+	for _, uid := range uids {
+		c.deleteTerminationTimer(uid)
+	}
+	zap.L().Warn("Stopped leading controller replicas, removed all termination timers.", zap.Int("timers", len(uids)))
+}
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/box/kube-exec-controller/pkg/duration"
+)
+
+// WithMaxExtension caps the total extension an interacted Pod can be given on top of its TTL, unless overridden
+// by the ExecTrackingPolicy. Zero means unlimited.
+func WithMaxExtension(maxExtension time.Duration) Option {
+	return func(c *Controller) {
+		c.maxExtension = maxExtension
+	}
+}
+
+// getMaxExtension returns the max extension of the Pods in the given namespace, set in the ExecTrackingPolicy (or the
+// NamespacedExecTrackingPolicy of the namespace) or by WithMaxExtension, zero means unlimited.
+func (c *Controller) getMaxExtension(namespace string) time.Duration {
+	return c.policy.MaxExtension(namespace, c.maxExtension)
+}
+
+// adviseCappedExtension submits an event explaining the cap to the given Pod if its requested extension exceeds
+// the max extension, which is capped by getTerminationTime.
+func (c *Controller) adviseCappedExtension(pod corev1.Pod, requester string) error {
+	maxExtension := c.getMaxExtension(pod.Namespace)
+	extension, err := duration.Parse(pod.Annotations[PodExtendDurationAnnotate])
+	if err != nil || maxExtension == 0 || extension <= maxExtension {
+		return nil
+	}
+
+	message := fmt.Sprintf(
+		"Pod extension '%s' requested from user '%s' exceeds the max extension of interacted Pods, capped to '%s'",
+		extension, requester, maxExtension)
+	return c.submitEvent(&pod, EventCategoryExtension, message)
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/box/kube-exec-controller/pkg/duration"
+)
+
+// NamespaceTTLDurationAnnotate is set to a Namespace object (e.g. "2h") to override the TTL of Pods interacted in it.
+var NamespaceTTLDurationAnnotate = "box.com/podTTLDuration"
+
+// getNamespaceTTL returns the TTL set by NamespaceTTLDurationAnnotate of the given namespace, or the given fallback
+// if the annotation is absent, invalid or the namespace cannot be read.
+func (c *Controller) getNamespaceTTL(namespace string, fallback time.Duration) time.Duration {
+	return c.getNamespaceDuration(namespace, NamespaceTTLDurationAnnotate, fallback)
+}
+
+// getNamespaceDuration returns the positive duration set by the given annotation of the given namespace, or the given
+// fallback if the annotation is absent, invalid or the namespace cannot be read.
+func (c *Controller) getNamespaceDuration(namespace, annotation string, fallback time.Duration) time.Duration {
+	val, present := c.getNamespaceAnnotation(namespace, annotation, fallback.String())
+	if !present {
+		return fallback
+	}
+
+	d, err := duration.Parse(val)
+	if err != nil || d <= 0 {
+		zap.L().Warn("Invalid duration annotated to a namespace, using the default value",
+			zap.String("namespace", namespace),
+			zap.String("annotation", annotation),
+			zap.String("annotation_value", val),
+			zap.String("default_value", fallback.String()),
+		)
+		return fallback
+	}
+
+	return d
+}
+
+// getNamespaceAnnotation returns the value of the given annotation of the given namespace, or false if the annotation
+// is absent or the namespace cannot be read, logging the given default value used instead in the latter case.
+func (c *Controller) getNamespaceAnnotation(namespace, annotation, defaultValue string) (string, bool) {
+	ctx, cancel := c.kubeAPIContext()
+	defer cancel()
+	ns, err := c.kubeClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			zap.L().Warn("Failed to get the namespace of an interacted Pod, using the default value",
+				zap.String("namespace", namespace),
+				zap.String("annotation", annotation),
+				zap.String("default_value", defaultValue),
+				zap.Error(err),
+			)
+		}
+		return "", false
+	}
+
+	val, present := ns.Annotations[annotation]
+	if !present && previousKeyPrefix != "" {
+		val, present = ns.Annotations[getPreviousKey(annotation, previousKeyPrefix)]
+	}
+	return val, present
+}
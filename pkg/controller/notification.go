@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// notificationBufferSize is the number of notifications buffered to be posted, further ones are dropped once full.
+const notificationBufferSize = 100
+
+// InteractionNotification is the JSON payload posted to the notification webhook for every new Pod interaction.
+type InteractionNotification struct {
+	// Text summarizes the interaction, as required by Slack incoming webhooks
+	Text           string    `json:"text"`
+	Username       string    `json:"username"`
+	PodNamespace   string    `json:"pod_namespace"`
+	PodName        string    `json:"pod_name"`
+	ContainerName  string    `json:"container_name,omitempty"`
+	Commands       []string  `json:"commands,omitempty"`
+	InteractedTime time.Time `json:"interacted_time"`
+	// EvictionTime is unset if the Pod is exempt from eviction
+	EvictionTime *time.Time `json:"eviction_time,omitempty"`
+}
+
+// interactionNotifier posts InteractionNotifications to a webhook (e.g. of Slack) in the background, so that a slow
+// or unavailable webhook never blocks the controller.
+type interactionNotifier struct {
+	url           string
+	client        *http.Client
+	notifications chan InteractionNotification
+}
+
+// WithInteractionNotification posts an InteractionNotification of every new Pod interaction to the given URL,
+// e.g. a Slack incoming webhook, in the background by RunInteractionNotifier. Each request times out after the given
+// timeout.
+func WithInteractionNotification(url string, timeout time.Duration) Option {
+	return func(c *Controller) {
+		c.notifier = &interactionNotifier{
+			url:           url,
+			client:        &http.Client{Timeout: timeout},
+			notifications: make(chan InteractionNotification, notificationBufferSize),
+		}
+	}
+}
+
+// RunInteractionNotifier posts the buffered notifications one by one until stopCh is closed, then posts the ones still
+// buffered before returning. It must be stopped once nothing more is buffered, e.g. on shutdown after Drain returns.
+// It returns right away if no notification webhook is set by WithInteractionNotification.
+func (c *Controller) RunInteractionNotifier(stopCh <-chan struct{}) {
+	n := c.notifier
+	if n == nil {
+		return
+	}
+
+	for {
+		select {
+		case notification := <-n.notifications:
+			n.notify(notification)
+		case <-stopCh:
+			for {
+				select {
+				case notification := <-n.notifications:
+					n.notify(notification)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// notify posts the given notification, logging it if it fails.
+func (n *interactionNotifier) notify(notification InteractionNotification) {
+	if err := n.post(notification); err != nil {
+		zap.L().Error("Error in posting a notification of a Pod interaction",
+			zap.String("pod_name", notification.PodName),
+			zap.String("pod_namespace", notification.PodNamespace),
+			zap.String("username", notification.Username),
+			zap.Error(err),
+		)
+	}
+}
+
+// post posts the given notification as JSON, failing on any non-2xx response.
+func (n *interactionNotifier) post(notification InteractionNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %q posting a notification", resp.Status)
+	}
+
+	return nil
+}
+
+// notifyInteraction buffers a notification of the given Pod interaction to be posted if a notification webhook is
+// set, without blocking. The given Pod is the one updated with its termination time, or nil if it is exempt.
+func (c *Controller) notifyInteraction(pi PodInteraction, updatedPod *corev1.Pod) {
+	if c.notifier == nil {
+		return
+	}
+
+	notification := InteractionNotification{
+		Text:           fmt.Sprintf("User '%s' interacted with Pod '%s/%s'", pi.Username, pi.PodNamespace, pi.PodName),
+		Username:       pi.Username,
+		PodNamespace:   pi.PodNamespace,
+		PodName:        pi.PodName,
+		ContainerName:  pi.ContainerName,
+		Commands:       pi.Commands,
+		InteractedTime: pi.InitTime,
+	}
+	if len(pi.Commands) > 0 {
+		notification.Text += fmt.Sprintf(", running command '%s'", getEventCommand(pi.Commands, maxEventCommandLength))
+	}
+	if updatedPod == nil {
+		notification.Text += ", it will not be evicted"
+	} else if terminationTime, err := getTerminationTime(*updatedPod, c.getMaxExtension(updatedPod.Namespace), c.idleTTL); err == nil {
+		terminationTime = terminationTime.UTC()
+		notification.EvictionTime = &terminationTime
+		notification.Text += fmt.Sprintf(", it will be evicted at %s", terminationTime.Format(time.RFC3339))
+	}
+
+	select {
+	case c.notifier.notifications <- notification:
+	default:
+		zap.L().Warn("Dropped a notification of a Pod interaction as the buffer is full",
+			zap.String("pod_name", pi.PodName),
+			zap.String("pod_namespace", pi.PodNamespace),
+			zap.String("username", pi.Username),
+		)
+	}
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// These annotations are set to the owning workload of an evicted Pod, if enabled by WithEvictedPodOwnerAnnotation,
+// so the recreated Pods can be correlated to the eviction.
+var (
+	OwnerLastEvictionTimeAnnotate   = "box.com/lastInteractedPodEvictionTime"
+	OwnerLastEvictionReasonAnnotate = "box.com/lastInteractedPodEvictionReason"
+)
+
+// WithEvictedPodOwnerAnnotation makes the Controller annotate the owning workload (e.g. the Deployment of a
+// ReplicaSet) of every evicted Pod with the time and reason of the eviction.
+func WithEvictedPodOwnerAnnotation() Option {
+	return func(c *Controller) {
+		c.annotateEvictedPodOwner = true
+	}
+}
+
+// getWorkloadOwner returns the kind and name of the workload controlling the given Pod, resolving the Deployment
+// of its ReplicaSet if any. It returns empty strings if the Pod has no controller.
+func (c *Controller) getWorkloadOwner(pod corev1.Pod) (string, string) {
+	owner := metav1.GetControllerOf(&pod)
+	if owner == nil {
+		return "", ""
+	}
+	if owner.Kind != "ReplicaSet" {
+		return owner.Kind, owner.Name
+	}
+
+	ctx, cancel := c.kubeAPIContext()
+	defer cancel()
+	replicaSet, err := c.kubeClient.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+	if err != nil {
+		return owner.Kind, owner.Name
+	}
+	if deployment := metav1.GetControllerOf(replicaSet); deployment != nil && deployment.Kind == "Deployment" {
+		return deployment.Kind, deployment.Name
+	}
+
+	return owner.Kind, owner.Name
+}
+
+// annotateWorkloadOwner annotates the owning workload of the given evicted Pod with the given eviction time
+// and the reason of the eviction. It does nothing if the Pod has no owner of a supported kind.
+func (c *Controller) annotateWorkloadOwner(pod corev1.Pod, evictedTime time.Time) error {
+	kind, name := c.getWorkloadOwner(pod)
+	if name == "" {
+		return nil
+	}
+
+	reason := fmt.Sprintf("Pod '%s' was evicted after being interacted", pod.Name)
+	if interactor, present := GetInteractionMetadata(pod, PodInteractorLabel); present {
+		reason += fmt.Sprintf(" by a user '%s'", interactor)
+	}
+	patchData, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				OwnerLastEvictionTimeAnnotate:   evictedTime.Format(time.RFC3339),
+				OwnerLastEvictionReasonAnnotate: reason,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.kubeAPIContext()
+	defer cancel()
+	patchOpts := metav1.PatchOptions{FieldManager: FieldManager}
+	switch kind {
+	case "Deployment":
+		_, err = c.kubeClient.AppsV1().Deployments(pod.Namespace).Patch(ctx, name, types.MergePatchType, patchData, patchOpts)
+	case "ReplicaSet":
+		_, err = c.kubeClient.AppsV1().ReplicaSets(pod.Namespace).Patch(ctx, name, types.MergePatchType, patchData, patchOpts)
+	case "StatefulSet":
+		_, err = c.kubeClient.AppsV1().StatefulSets(pod.Namespace).Patch(ctx, name, types.MergePatchType, patchData, patchOpts)
+	case "DaemonSet":
+		_, err = c.kubeClient.AppsV1().DaemonSets(pod.Namespace).Patch(ctx, name, types.MergePatchType, patchData, patchOpts)
+	case "Job":
+		_, err = c.kubeClient.BatchV1().Jobs(pod.Namespace).Patch(ctx, name, types.MergePatchType, patchData, patchOpts)
+	default:
+		zap.L().Debug("Skipped annotating the owner of an evicted Pod of an unsupported kind.",
+			zap.String("owner_kind", kind),
+			zap.String("owner_name", name),
+		)
+		return nil
+	}
+
+	return err
+}
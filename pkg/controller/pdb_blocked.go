@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"go.uber.org/zap"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PDBBlockedAction is what the controller does with an interacted Pod whose eviction is still refused by a
+// PodDisruptionBudget after retrying it.
+type PDBBlockedAction string
+
+// These are the supported actions of evictions blocked by a PodDisruptionBudget.
+const (
+	// PDBBlockedActionLog logs the blocked eviction and leaves the Pod alive
+	PDBBlockedActionLog PDBBlockedAction = "log"
+	// PDBBlockedActionDelete deletes the Pod directly, bypassing its PodDisruptionBudgets
+	PDBBlockedActionDelete PDBBlockedAction = "delete"
+)
+
+// pdbBlockedConfig is how the evictions refused by a PodDisruptionBudget are retried and handled once exhausted.
+type pdbBlockedConfig struct {
+	action   PDBBlockedAction
+	retries  int
+	interval time.Duration
+}
+
+// ParsePDBBlockedAction returns the PDBBlockedAction of the given name, or an error if it is not supported.
+func ParsePDBBlockedAction(name string) (PDBBlockedAction, error) {
+	switch action := PDBBlockedAction(strings.ToLower(name)); action {
+	case PDBBlockedActionLog, PDBBlockedActionDelete:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unsupported PDB blocked action %q, expected one of: log, delete", name)
+	}
+}
+
+// WithPDBBlockedAction retries an eviction refused by a PodDisruptionBudget (429 Too Many Requests) up to the given
+// number of times, with exponential backoff starting from the given interval, e.g. until another replica of the
+// workload gets ready. Once the retries are exhausted, the given action is taken, PDBBlockedActionLog by default.
+// Pods deleted in the TerminationModeDelete mode are never blocked.
+func WithPDBBlockedAction(action PDBBlockedAction, retries int, interval time.Duration) Option {
+	return func(c *Controller) {
+		if retries < 0 {
+			retries = 0
+		}
+		c.evictionAPI.pdbBlocked = pdbBlockedConfig{
+			action:   action,
+			retries:  retries,
+			interval: interval,
+		}
+	}
+}
+
+// evictOrHandleBlocked evicts the Pod of the given name and namespace as evict does, retrying while the eviction is
+// refused by a PodDisruptionBudget. If it is still refused, the Pod is deleted in the PDBBlockedActionDelete action
+// with the given grace period if positive or else the one set by WithTerminationMode, and the refusal is returned
+// otherwise.
+func (ea *evictionAPI) evictOrHandleBlocked(kubeClient kubernetes.Interface, name, namespace string,
+	gracePeriod time.Duration) error {
+	evictOperation := func() error {
+		err := ea.evict(kubeClient, name, namespace, gracePeriod)
+		if isPDBRefusal(err) {
+			return err
+		}
+		return backoff.Permanent(err)
+	}
+
+	ebo := backoff.NewExponentialBackOff()
+	ebo.InitialInterval = ea.pdbBlocked.interval
+	ebo.MaxElapsedTime = 0
+	retryNotifier := func(err error, d time.Duration) {
+		zap.L().Warn("Eviction of a Pod is refused by a PodDisruptionBudget, retrying.",
+			zap.String("pod_name", name),
+			zap.String("namespace", namespace),
+			zap.String("retry_after", d.String()),
+			zap.Error(err),
+		)
+	}
+	err := backoff.RetryNotify(evictOperation, backoff.WithMaxRetries(ebo, uint64(ea.pdbBlocked.retries)), retryNotifier)
+	if !isPDBRefusal(err) || ea.pdbBlocked.action != PDBBlockedActionDelete {
+		return err
+	}
+
+	zap.L().Warn("Eviction of a Pod is still refused by a PodDisruptionBudget, deleting it instead.",
+		zap.String("pod_name", name),
+		zap.String("namespace", namespace),
+		zap.Error(err),
+	)
+	if gracePeriod <= 0 {
+		gracePeriod = ea.deleteGracePeriod
+	}
+	return deletePod(kubeClient, name, namespace, gracePeriod, ea.timeout)
+}
+
+// isPDBRefusal returns true if the given error refuses an eviction due to a PodDisruptionBudget, rather than any other
+// 429 Too Many Requests (e.g. throttled by API Priority and Fairness). It is told by its DisruptionBudget cause, or by
+// its message if returned by an API server setting no cause.
+func isPDBRefusal(err error) bool {
+	if !apierrors.IsTooManyRequests(err) {
+		return false
+	}
+
+	return apierrors.HasStatusCause(err, policyv1.DisruptionBudgetCause) ||
+		strings.Contains(strings.ToLower(err.Error()), "disruption budget")
+}
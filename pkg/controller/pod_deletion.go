@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/box/kube-exec-controller/pkg/metrics"
+)
+
+// handlePodDeletion removes the termination timer of the given deleted Pod, e.g. deleted by something other than
+// the controller.
+func (c *Controller) handlePodDeletion(obj interface{}) {
+	// the deleted Pod's final state may be unknown if the watch missed its deletion
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+
+	if c.deleteTerminationTimer(pod.UID) {
+		zap.L().Info("Removed the termination timer of a deleted Pod",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+		)
+	}
+}
+
+// deleteTerminationTimer stops and removes the termination timer of the Pod with the given UID, and its warning.
+// It returns false if no such timer exists.
+func (c *Controller) deleteTerminationTimer(uid types.UID) bool {
+	c.terminationTimersMu.Lock()
+	defer c.terminationTimersMu.Unlock()
+
+	return c.removeTerminationTimer(uid)
+}
+
+// deleteFiredTerminationTimer removes the termination timer of the Pod with the given UID as deleteTerminationTimer
+// does, unless the given func returning the fired timer tells it has been replaced or removed meanwhile.
+func (c *Controller) deleteFiredTerminationTimer(uid types.UID, firedTimer func() *time.Timer) {
+	c.terminationTimersMu.Lock()
+	defer c.terminationTimersMu.Unlock()
+
+	if timer, present := c.terminationTimersMap[uid]; present && timer == firedTimer() {
+		c.removeTerminationTimer(uid)
+	}
+}
+
+// removeTerminationTimer stops and removes the termination timer of the Pod with the given UID, and its warning.
+// It returns false if no such timer exists. It must be called with terminationTimersMu held.
+func (c *Controller) removeTerminationTimer(uid types.UID) bool {
+	c.killSwitch.drop(uid)
+	timer, present := c.terminationTimersMap[uid]
+	if !present {
+		return false
+	}
+	timer.Stop()
+	c.stopEvictionWarning(uid)
+	delete(c.terminationTimersMap, uid)
+	delete(c.heldTimers, uid)
+	delete(c.terminationTimes, uid)
+	c.trackedPodsLimit.untrack(uid)
+	metrics.TerminationTimers.Set(float64(len(c.terminationTimersMap)))
+
+	return true
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodDisableEvictionAnnotate is set to "true" by the owner of a Pod to pin it, i.e. exempting it from eviction after
+// being interacted regardless of WithPodExemptSelector. It is set at creation, as the webhook denies setting it on an
+// existing Pod to anyone but the allowlisted users and the controller.
+var PodDisableEvictionAnnotate = "box.com/disableExecEviction"
+
+// ParsePodExemptSelector parses a label selector (e.g. "app in (ci-runner, sandbox)") of Pods exempt from eviction.
+// An empty selector exempts no Pod, rather than all of them.
+func ParsePodExemptSelector(raw string) (labels.Selector, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	selector, err := labels.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod exempt selector %q: %v", raw, err)
+	}
+
+	return selector, nil
+}
+
+// WithPodExemptSelector exempts interacted Pods whose labels match the given selector from eviction, e.g. CI runners
+// or dev sandboxes which are meant to be interacted. Their interactions are still submitted as events and audited.
+func WithPodExemptSelector(selector labels.Selector) Option {
+	return func(c *Controller) {
+		c.podExemptSelector = selector
+	}
+}
+
+// getPodExemption returns the reason why the given Pod is exempt from eviction and true, if it is annotated with
+// PodDisableEvictionAnnotate or its labels match the selector set by WithPodExemptSelector.
+func (c *Controller) getPodExemption(pod corev1.Pod) (string, bool) {
+	if pod.Annotations[PodDisableEvictionAnnotate] == "true" {
+		return fmt.Sprintf("Pod is annotated with '%s: \"true\"'", PodDisableEvictionAnnotate), true
+	}
+
+	if c.podExemptSelector == nil || c.podExemptSelector.Empty() {
+		return "", false
+	}
+	if !c.podExemptSelector.Matches(labels.Set(pod.Labels)) {
+		return "", false
+	}
+
+	return fmt.Sprintf("Pod matches the exempt selector '%s'", c.podExemptSelector.String()), true
+}
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// WatchInteractedPods keeps the termination timers of interacted Pods consistent with their metadata until stopCh is
+// closed, e.g. for a Pod interacted or extended while the controller was unavailable, and removes the timer of a Pod
+// once it gets deleted. It blocks until the informer cache of interacted Pods is synced.
+func (c *Controller) WatchInteractedPods(stopCh <-chan struct{}) {
+	// all Pods are watched if the interaction timestamp is not stored as a label
+	factory := informers.NewSharedInformerFactoryWithOptions(c.kubeClient, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			if c.interactionMetadata == typeLabels {
+				options.LabelSelector = PodInteractionTimestampLabel
+			}
+		}),
+	)
+	informer := factory.Core().V1().Pods().Informer()
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				c.reconcilePod(*pod, true)
+			}
+		},
+		UpdateFunc: func(oldObj, obj interface{}) {
+			oldPod, oldOk := oldObj.(*corev1.Pod)
+			if pod, ok := obj.(*corev1.Pod); ok && oldOk {
+				// a Pod getting interacted is newly observed, e.g. interacted through another replica
+				_, interacted := GetInteractionMetadata(*oldPod, PodInteractionTimestampLabel)
+				c.reconcilePod(*pod, !interacted)
+			}
+		},
+		DeleteFunc: c.handlePodDeletion,
+	})
+
+	c.addInformerSync(informer.HasSynced)
+
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	// the Pods interacted under the previous key prefix are watched as well if selected by label, whereas all Pods
+	// are watched above otherwise
+	if previousKeyPrefix != "" && c.interactionMetadata == typeLabels {
+		c.watchPreviousInteractedPods(stopCh)
+	}
+}
+
+// watchPreviousInteractedPods migrates the Pods interacted under the previous key prefix set by SetPreviousKeyPrefix
+// to the current prefix until stopCh is closed, so that they are then reconciled like any other interacted Pod. It
+// blocks until the informer cache of those Pods is synced.
+func (c *Controller) watchPreviousInteractedPods(stopCh <-chan struct{}) {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.kubeClient, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = getPreviousKey(PodInteractionTimestampLabel, previousKeyPrefix)
+		}),
+	)
+	informer := factory.Core().V1().Pods().Informer()
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				c.reconcilePod(*pod, true)
+			}
+		},
+		UpdateFunc: func(oldObj, obj interface{}) {
+			if pod, ok := obj.(*corev1.Pod); ok {
+				c.reconcilePod(*pod, true)
+			}
+		},
+	})
+
+	c.addInformerSync(informer.HasSynced)
+
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+}
+
+// reconcilePod sets termination to the given interacted Pod if its termination timer is inconsistent with its
+// current metadata, e.g. its extension has changed without the controller being notified. A missing timer is only
+// set for a newly observed Pod, as the timer of a known Pod is removed on purpose once it fires.
+func (c *Controller) reconcilePod(pod corev1.Pod, observed bool) {
+	// the Pod being handled from the channels gets its termination set anyway
+	if !c.isLeading() || c.isPodInProgress(pod) {
+		return
+	}
+
+	// a Pod tracked under the previous key prefix is migrated first, so that its interaction metadata is recognized
+	migratedPod, err := migratePreviousKeys(pod, c.kubeClient, c.kubeAPITimeout)
+	if err != nil {
+		zap.L().Error("Error in migrating an interacted Pod to the current key prefix, skipping.",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+			zap.Error(err),
+		)
+		return
+	}
+	pod = *migratedPod
+
+	if _, interacted := GetInteractionMetadata(pod, PodInteractionTimestampLabel); !interacted ||
+		pod.DeletionTimestamp != nil {
+		return
+	}
+
+	terminationTime, err := getTerminationTime(pod, c.getMaxExtension(pod.Namespace), c.idleTTL)
+	if err != nil {
+		zap.L().Error("Error in getting the termination time of an interacted Pod to reconcile, skipping.",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+			zap.Error(err),
+		)
+		return
+	}
+
+	present, consistent := c.checkTerminationTimer(pod, terminationTime)
+	if consistent || (!present && !observed) {
+		return
+	}
+
+	zap.L().Info("Reconciling the termination of an interacted Pod inconsistent with its metadata.",
+		zap.String("pod_name", pod.Name),
+		zap.String("pod_namespace", pod.Namespace),
+		zap.Bool("timer_present", present),
+		zap.String("termination_time", terminationTime.String()),
+	)
+	if err := c.setTermination(pod); err != nil {
+		zap.L().Error("Error in reconciling the termination of an interacted Pod, skipping.",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+			zap.Error(err),
+		)
+	}
+}
+
+// checkTerminationTimer returns if a termination timer of the given Pod is present, and if it is set for the given
+// termination time and paused only while the Pod is held in use.
+func (c *Controller) checkTerminationTimer(pod corev1.Pod, terminationTime time.Time) (bool, bool) {
+	_, held := c.isPodHeld(pod)
+
+	c.terminationTimersMu.Lock()
+	defer c.terminationTimersMu.Unlock()
+
+	if _, present := c.terminationTimersMap[pod.UID]; !present {
+		return false, false
+	}
+
+	recordedTime, recorded := c.terminationTimes[pod.UID]
+	consistent := recorded && recordedTime.Equal(terminationTime) && c.heldTimers[pod.UID] == held
+	return true, consistent
+}
+
+// isPodInProgress returns true if an interaction or extension update of the given Pod is being handled by
+// CheckPodInteraction or CheckPodExtensionUpdate.
+func (c *Controller) isPodInProgress(pod corev1.Pod) bool {
+	c.drainState.mu.Lock()
+	defer c.drainState.mu.Unlock()
+
+	for _, pi := range c.drainState.interactions {
+		if pi.PodNamespace == pod.Namespace && pi.PodName == pod.Name {
+			return true
+		}
+	}
+	pd := c.drainState.update
+	return pd != nil && pd.Pod.UID == pod.UID
+}
+
+// setTerminationTimeRecord records the termination time the timer of the Pod with the given UID is set for.
+func (c *Controller) setTerminationTimeRecord(uid types.UID, terminationTime time.Time) {
+	c.terminationTimersMu.Lock()
+	defer c.terminationTimersMu.Unlock()
+
+	c.terminationTimes[uid] = terminationTime
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+)
+
+// WatchTrackedPods keeps an informer cache of the Pods labeled with PodTrackingLabel by the mutating webhook until
+// stopCh is closed, from which a newly interacted Pod is read rather than got from the K8s API on the hot path. Pods
+// not labeled (e.g. created before the mutating webhook is configured) are still got from the K8s API. It blocks
+// until the cache is synced.
+func (c *Controller) WatchTrackedPods(stopCh <-chan struct{}) {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.kubeClient, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = PodTrackingLabel
+		}),
+	)
+	podInformer := factory.Core().V1().Pods()
+	c.trackedPods = podInformer.Lister()
+
+	c.addInformerSync(podInformer.Informer().HasSynced)
+
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+}
+
+// getTrackedPod returns a copy of the Pod of the given namespace and name from the cache of WatchTrackedPods, or
+// false if it is not cached or not watched.
+func (c *Controller) getTrackedPod(namespace, name string) (*corev1.Pod, bool) {
+	if c.trackedPods == nil {
+		return nil, false
+	}
+
+	pod, err := c.trackedPods.Pods(namespace).Get(name)
+	if err != nil {
+		return nil, false
+	}
+
+	return pod.DeepCopy(), true
+}
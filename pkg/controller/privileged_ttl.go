@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// WithPrivilegedTTL reduces the TTL of interacted Pods running a privileged container or sharing the host's
+// network or PID namespace to the given one, as they are at higher risk. A flagged event is submitted to them too.
+// Zero means no reduction.
+func WithPrivilegedTTL(ttl time.Duration) Option {
+	return func(c *Controller) {
+		c.privilegedTTL = ttl
+	}
+}
+
+// getPrivilegedAccess returns the host access of the given Pod putting it at higher risk, e.g. "hostNetwork" or
+// "privileged container 'app'", or nothing if it has none.
+func getPrivilegedAccess(pod corev1.Pod) []string {
+	var access []string
+	if pod.Spec.HostNetwork {
+		access = append(access, "hostNetwork")
+	}
+	if pod.Spec.HostPID {
+		access = append(access, "hostPID")
+	}
+
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, container := range containers {
+		sc := container.SecurityContext
+		if sc != nil && sc.Privileged != nil && *sc.Privileged {
+			access = append(access, fmt.Sprintf("privileged container '%s'", container.Name))
+		}
+	}
+
+	return access
+}
+
+// reducePrivilegedTTL returns the given TTL reduced to the privileged TTL if the given Pod is privileged.
+// It also submits a flagged event to the Pod in that case.
+func (c *Controller) reducePrivilegedTTL(pod corev1.Pod, pi PodInteraction, ttl time.Duration) (time.Duration, error) {
+	if c.privilegedTTL <= 0 {
+		return ttl, nil
+	}
+	access := getPrivilegedAccess(pod)
+	if len(access) == 0 {
+		return ttl, nil
+	}
+
+	if c.privilegedTTL < ttl {
+		ttl = c.privilegedTTL
+	}
+	message := fmt.Sprintf("Pod with %s was interacted by a user '%s', its TTL is reduced to %s",
+		strings.Join(access, ", "),
+		pi.Username,
+		ttl.String(),
+	)
+	if err := c.submitEventWithReason(&pod, EventCategoryInteraction, privilegedPodInteractionEventReason, message); err != nil {
+		return 0, err
+	}
+
+	zap.L().Warn("An interaction of a privileged Pod is detected",
+		zap.Object("pod_interaction", &pi),
+		zap.Strings("privileged_access", access),
+		zap.String("ttl", ttl.String()),
+	)
+
+	return ttl, nil
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"sync"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// syncState tracks whether the Controller is warmed up, i.e. all its informer caches are synced
+// and previously interacted Pods are checked, so that it does not miss any Pod to evict.
+type syncState struct {
+	mu                          sync.Mutex
+	informerSyncs               []cache.InformerSynced
+	previousInteractionsChecked bool
+}
+
+// HasSynced returns true once all informer caches of the Controller are synced and previously interacted Pods
+// are checked by CheckPodInteraction.
+func (c *Controller) HasSynced() bool {
+	c.syncState.mu.Lock()
+	defer c.syncState.mu.Unlock()
+
+	if !c.syncState.previousInteractionsChecked {
+		return false
+	}
+	for _, hasSynced := range c.syncState.informerSyncs {
+		if !hasSynced() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Ready returns true once the Controller HasSynced. It is meant to gate the readiness probe. Evictions forbidden by
+// missing RBAC do not fail it, as an unready webhook would no longer receive the interactions to evict Pods for, but
+// are reported by the metrics.EvictionForbidden gauge instead.
+func (c *Controller) Ready() bool {
+	return c.HasSynced()
+}
+
+// addInformerSync adds the HasSynced func of an informer used by the Controller to be checked by HasSynced.
+func (c *Controller) addInformerSync(hasSynced cache.InformerSynced) {
+	c.syncState.mu.Lock()
+	defer c.syncState.mu.Unlock()
+
+	c.syncState.informerSyncs = append(c.syncState.informerSyncs, hasSynced)
+}
+
+// setPreviousInteractionsChecked marks previously interacted Pods as checked.
+func (c *Controller) setPreviousInteractionsChecked() {
+	c.syncState.mu.Lock()
+	defer c.syncState.mu.Unlock()
+
+	c.syncState.previousInteractionsChecked = true
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/box/kube-exec-controller/pkg/duration"
+)
+
+// minReducedRemainingDuration is the least remaining duration of a Pod whose lifetime is reduced by a negative
+// extension, so that it is evicted soon rather than at a termination time in the past.
+var minReducedRemainingDuration = 30 * time.Second
+
+// floorReducedExtension returns the given Pod with its extension annotation patched if it is a reduction setting the
+// termination time earlier than minReducedRemainingDuration from now, to the extension terminating the Pod at that
+// time instead. The floored extension is stored, so the termination time calculated from it no longer moves.
+func (c *Controller) floorReducedExtension(pod corev1.Pod) (corev1.Pod, error) {
+	extension, err := duration.Parse(pod.Annotations[PodExtendDurationAnnotate])
+	if err != nil || extension >= 0 {
+		return pod, nil
+	}
+
+	terminationTime, err := getTerminationTime(pod, c.getMaxExtension(pod.Namespace), c.idleTTL)
+	if err != nil {
+		return pod, err
+	}
+	earliest := time.Now().Add(minReducedRemainingDuration).Truncate(time.Second)
+	if !terminationTime.Before(earliest) {
+		return pod, nil
+	}
+
+	flooredExtension := extension + earliest.Sub(terminationTime)
+	annotationPatchMap := map[string]string{PodExtendDurationAnnotate: flooredExtension.String()}
+	patchedPod, err := patch(pod, typeAnnotations, annotationPatchMap, c.kubeClient, c.kubeAPITimeout)
+	if err != nil {
+		return pod, err
+	}
+
+	zap.L().Info("Floored the reduction of a Pod terminating it before the least remaining duration",
+		zap.String("pod_name", pod.Name),
+		zap.String("pod_namespace", pod.Namespace),
+		zap.String("requested_extension", extension.String()),
+		zap.String("floored_extension", flooredExtension.String()),
+	)
+
+	return *patchedPod, nil
+}
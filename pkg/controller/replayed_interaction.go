@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// getReplaySkipReason returns the reason to skip the given interaction replayed from an audit log, if any, so that
+// replaying the same log again (e.g. on every restart) neither tracks nor counts its interactions twice.
+// It is skipped if the Pod was created after it (i.e. a new Pod of the same name), or if the Pod is labeled already,
+// unless it is later than the latest interaction recorded in the TTLModeIdle mode. An interaction older than the TTL
+// of an unlabeled Pod is skipped too, as the Pod would be evicted right away although no longer in use.
+func (c *Controller) getReplaySkipReason(pod corev1.Pod, pi PodInteraction) (string, bool) {
+	if pod.CreationTimestamp.Time.After(pi.InitTime) {
+		return "the Pod was created after the interaction", true
+	}
+
+	if _, present := GetInteractionMetadata(pod, PodInteractionTimestampLabel); present {
+		startTime, err := getTTLStartTime(pod, c.idleTTL)
+		if !c.idleTTL || err != nil || !pi.InitTime.After(startTime) {
+			return "the Pod has recorded an interaction already", true
+		}
+		return "", false
+	}
+
+	ttl := c.getNamespaceTTL(pod.Namespace, c.policy.TTL(pod.Namespace, c.podTTLDuration))
+	if time.Since(pi.InitTime) > ttl {
+		return "the interaction is older than the TTL of the Pod", true
+	}
+
+	return "", false
+}
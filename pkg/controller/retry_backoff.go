@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"go.uber.org/zap"
+
+	"github.com/box/kube-exec-controller/pkg/metrics"
+)
+
+// These are the kinds of items whose retries can be given up, as counted by metrics.GiveUpsTotal.
+const (
+	retryKindInteraction          = "pod_interaction"
+	retryKindExtensionUpdate      = "pod_extension_update"
+	retryKindPreviousInteractions = "previous_pod_interactions"
+)
+
+// retryBackoff bounds the exponential backoff retrying to handle Pod interactions and extension updates.
+type retryBackoff struct {
+	maxElapsedTime time.Duration
+	maxInterval    time.Duration
+}
+
+// WithRetryBackoff bounds retrying to handle a Pod interaction or extension update with exponential backoff: the
+// retries are given up once the given max elapsed time has passed, and are at most the given max interval apart.
+// Zero keeps the default of either, 15 minutes and 1 minute respectively. A Pod whose retries are given up gets
+// a RetryGivenUp event explaining it, if still reachable.
+func WithRetryBackoff(maxElapsedTime, maxInterval time.Duration) Option {
+	return func(c *Controller) {
+		c.retryBackoff = retryBackoff{
+			maxElapsedTime: maxElapsedTime,
+			maxInterval:    maxInterval,
+		}
+	}
+}
+
+// newRetryBackOff returns an exponential backoff bounded as set by WithRetryBackoff.
+func (c *Controller) newRetryBackOff() *backoff.ExponentialBackOff {
+	ebo := backoff.NewExponentialBackOff()
+	if c.retryBackoff.maxElapsedTime > 0 {
+		ebo.MaxElapsedTime = c.retryBackoff.maxElapsedTime
+	}
+	if c.retryBackoff.maxInterval > 0 {
+		ebo.MaxInterval = c.retryBackoff.maxInterval
+		if ebo.InitialInterval > ebo.MaxInterval {
+			ebo.InitialInterval = ebo.MaxInterval
+		}
+	}
+	ebo.Reset()
+
+	return ebo
+}
+
+// giveUpRetrying counts giving up retrying to handle an item of the given kind (e.g. retryKindInteraction) of the Pod
+// of the given namespace and name, and submits a K8s event of the given category explaining it to the Pod if it is
+// still reachable, as the item is lost otherwise.
+func (c *Controller) giveUpRetrying(kind string, category EventCategory, namespace, name string, err error) {
+	metrics.GiveUpsTotal.WithLabelValues(kind).Inc()
+
+	pod, getErr := getPod(c.kubeClient, namespace, name, c.kubeAPITimeout)
+	if getErr != nil {
+		zap.L().Warn("Failed to get a Pod whose retries are given up, skipped submitting an event to it",
+			zap.String("pod_name", name),
+			zap.String("pod_namespace", namespace),
+			zap.Error(getErr),
+		)
+		return
+	}
+
+	item := strings.ReplaceAll(strings.TrimPrefix(kind, "pod_"), "_", " ")
+	message := fmt.Sprintf("Gave up retrying to handle the %s of the Pod, which is lost: %v", item, err)
+	if err := c.submitEventWithReason(pod, category, retryGivenUpEventReason, message); err != nil {
+		zap.L().Warn("Failed to submit an event to a Pod whose retries are given up",
+			zap.String("pod_name", name),
+			zap.String("pod_namespace", namespace),
+			zap.Error(err),
+		)
+	}
+}
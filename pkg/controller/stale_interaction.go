@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// getInteractionLabels returns the labels set by the controller to an interacted Pod. They are listed on every call
+// rather than once, as their keys are renamed by SetKeyPrefix.
+func getInteractionLabels() []string {
+	return []string{
+		PodInteractionTimestampLabel,
+		PodInteractorLabel,
+		PodTTLDurationLabel,
+		// an annotation, but left over as a label by previous versions
+		PodInteractionCountAnnotate,
+	}
+}
+
+// getInteractionAnnotations returns the annotations set by the controller to an interacted Pod, besides the
+// interaction labels stored as annotations if set by WithInteractionMetadata.
+func getInteractionAnnotations() []string {
+	return []string{
+		PodExtendDurationAnnotate,
+		PodExtendRequesterAnnotate,
+		PodTerminationTimeAnnotate,
+		PodInteractorClientAnnotate,
+		PodLastInteractorAnnotate,
+		PodExtensionHistoryAnnotate,
+		PodInUseAnnotate,
+		PodLastInteractionTimestampAnnotate,
+	}
+}
+
+// WithStaleInteractionCleanup sets the duration after a Pod's termination time at which its interaction
+// metadata is considered stale (e.g. the Pod was never evicted) and cleared by CleanupStaleInteractions.
+// The Pod updates clearing it must be identified by the webhook as the controller's own, i.e. by
+// Server.ControllerUsername, as the interaction labels are immutable to anyone else.
+func WithStaleInteractionCleanup(staleAfter time.Duration) Option {
+	return func(c *Controller) {
+		c.staleInteractionAfter = staleAfter
+	}
+}
+
+// RunStaleInteractionCleanup calls CleanupStaleInteractions in every given interval until stopCh is closed.
+// It does nothing if no stale duration is set by WithStaleInteractionCleanup, or if interacted Pods are evicted once
+// due, i.e. unless in the audit-only mode or exempting the Pods owned by a StatefulSet.
+func (c *Controller) RunStaleInteractionCleanup(interval time.Duration, stopCh <-chan struct{}) {
+	if c.staleInteractionAfter <= 0 || !c.leavesPodsRunning() {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			// only the leader cleans them up if multiple replicas are running
+			if !c.isLeading() {
+				continue
+			}
+
+			if err := c.CleanupStaleInteractions(); err != nil {
+				zap.L().Error("Error in cleaning up stale Pod interactions", zap.Error(err))
+			}
+		}
+	}
+}
+
+// CleanupStaleInteractions lists all interacted Pods and clears the interaction metadata of the ones
+// still running after their termination time plus the stale duration, so they are not perpetually
+// shown as interacted.
+func (c *Controller) CleanupStaleInteractions() error {
+	pods, err := c.listInteractedPods()
+	if err != nil {
+		return err
+	}
+
+	for _, pod := range pods {
+		// a Pod held in use is expected to keep running after its termination time
+		if _, held := c.isPodHeld(pod); held {
+			continue
+		}
+
+		terminationTime, err := getTerminationTime(pod, c.getMaxExtension(pod.Namespace), c.idleTTL)
+		// the eviction of a Pod terminating outside the eviction window is deferred to its next opening
+		terminationTime = c.evictionWindow.evictionTime(terminationTime)
+		if err != nil || time.Since(terminationTime) < c.staleInteractionAfter {
+			continue
+		}
+
+		if err := c.clearInteraction(pod, terminationTime); err != nil {
+			zap.L().Error("Error in clearing metadata of a stale Pod interaction, skipping.",
+				zap.String("pod_name", pod.Name),
+				zap.String("pod_namespace", pod.Namespace),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return nil
+}
+
+// clearInteraction removes all interaction metadata and the termination timer of the given Pod.
+func (c *Controller) clearInteraction(pod corev1.Pod, terminationTime time.Time) error {
+	updatedPod, err := removeMetadata(pod, typeLabels, getInteractionLabels(), c.kubeClient, c.kubeAPITimeout)
+	if err != nil {
+		return err
+	}
+
+	// the interaction labels may be stored as annotations
+	annotations := append(getInteractionLabels(), getInteractionAnnotations()...)
+	updatedPod, err = removeMetadata(*updatedPod, typeAnnotations, annotations, c.kubeClient, c.kubeAPITimeout)
+	if err != nil {
+		return err
+	}
+
+	c.deleteTerminationTimer(pod.UID)
+
+	message := fmt.Sprintf("Pod interaction metadata is cleared as the Pod is still running %s after its eviction time %s",
+		c.staleInteractionAfter.String(),
+		terminationTime.String(),
+	)
+	if err := c.submitEvent(updatedPod, EventCategoryInteraction, message); err != nil {
+		return err
+	}
+
+	zap.L().Info("Cleared metadata of a stale Pod interaction",
+		zap.String("pod_name", pod.Name),
+		zap.String("pod_namespace", pod.Namespace),
+		zap.String("termination_time", terminationTime.String()),
+	)
+
+	return nil
+}
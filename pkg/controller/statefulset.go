@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// statefulSetHandling handles the eviction of Pods owned by a StatefulSet, which often need an ordered
+// and graceful shutdown.
+type statefulSetHandling struct {
+	exempt      bool
+	gracePeriod time.Duration
+}
+
+// WithStatefulSetHandling exempts interacted Pods owned by a StatefulSet from eviction if exempt is true, or evicts
+// them with the given grace period otherwise (zero means the Pod's own one). In both cases, an event advising
+// to handle the Pod manually is submitted to it when its termination time is reached.
+func WithStatefulSetHandling(exempt bool, gracePeriod time.Duration) Option {
+	return func(c *Controller) {
+		c.statefulSet = &statefulSetHandling{
+			exempt:      exempt,
+			gracePeriod: gracePeriod,
+		}
+	}
+}
+
+// getStatefulSetOwner returns the name of the StatefulSet controlling the given Pod, or an empty string if none.
+func getStatefulSetOwner(pod corev1.Pod) string {
+	if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "StatefulSet" {
+		return owner.Name
+	}
+
+	return ""
+}
+
+// getGracePeriod returns the grace period to evict the given Pod, zero means the Pod's own one.
+func (sh *statefulSetHandling) getGracePeriod(pod corev1.Pod) time.Duration {
+	if sh == nil || getStatefulSetOwner(pod) == "" {
+		return 0
+	}
+
+	return sh.gracePeriod
+}
+
+// adviseStatefulSetPod submits an event advising to handle the given Pod manually if it is owned by a StatefulSet.
+// It returns true if the Pod is exempt from eviction.
+func (c *Controller) adviseStatefulSetPod(pod corev1.Pod) bool {
+	statefulSet := getStatefulSetOwner(pod)
+	if c.statefulSet == nil || statefulSet == "" {
+		return false
+	}
+
+	var message string
+	if c.statefulSet.exempt {
+		message = fmt.Sprintf("Pod is owned by the StatefulSet '%s' and exempt from eviction, "+
+			"please restart it manually in order once done with the interaction", statefulSet)
+	} else {
+		gracePeriod := "its own termination grace period"
+		if c.statefulSet.gracePeriod > 0 {
+			gracePeriod = c.statefulSet.gracePeriod.String()
+		}
+		message = fmt.Sprintf("Pod is owned by the StatefulSet '%s' and evicted with a grace period of %s, "+
+			"consider restarting it manually in order instead", statefulSet, gracePeriod)
+	}
+	// the Pod is handled regardless of failing to submit the event, which is logged in submitEvent
+	_ = c.submitEvent(&pod, EventCategoryEviction, message)
+
+	return c.statefulSet.exempt
+}
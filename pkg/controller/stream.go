@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/box/kube-exec-controller/pkg/metrics"
+)
+
+// StreamRecordType is the type of the lifecycle event of an interacted Pod published as a StreamRecord.
+type StreamRecordType string
+
+// These are the published lifecycle events of interacted Pods.
+const (
+	StreamRecordInteraction StreamRecordType = "interaction"
+	StreamRecordExtension   StreamRecordType = "extension"
+	StreamRecordEviction    StreamRecordType = "eviction"
+)
+
+// defaultStreamBufferSize is the number of records buffered for a StreamProducer, unless set by WithStreamProducer.
+const defaultStreamBufferSize = 1000
+
+// StreamRecord is a lifecycle event of an interacted Pod published to a message topic for streaming audit.
+type StreamRecord struct {
+	Type          StreamRecordType `json:"type"`
+	Timestamp     time.Time        `json:"timestamp"`
+	Username      string           `json:"username,omitempty"`
+	PodNamespace  string           `json:"pod_namespace"`
+	PodName       string           `json:"pod_name"`
+	ContainerName string           `json:"container_name,omitempty"`
+	Commands      []string         `json:"commands,omitempty"`
+	Extension     string           `json:"extension,omitempty"`
+}
+
+// streamRecord returns the StreamRecord of the Pod interaction.
+func (pi *PodInteraction) streamRecord() StreamRecord {
+	return StreamRecord{
+		Type:          StreamRecordInteraction,
+		Timestamp:     pi.InitTime,
+		Username:      pi.Username,
+		PodNamespace:  pi.PodNamespace,
+		PodName:       pi.PodName,
+		ContainerName: pi.ContainerName,
+		Commands:      pi.Commands,
+	}
+}
+
+// StreamProducer publishes StreamRecords to a message topic, e.g. of Kafka or NATS. It is called sequentially
+// from a single goroutine, so it does not need to be safe for concurrent use.
+type StreamProducer interface {
+	Publish(record StreamRecord) error
+}
+
+// HTTPProducer is a StreamProducer posting each record as JSON to an HTTP endpoint publishing it to a topic,
+// e.g. a Kafka REST proxy or a NATS HTTP gateway.
+type HTTPProducer struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPProducer returns an HTTPProducer posting records to the given URL.
+func NewHTTPProducer(url string) *HTTPProducer {
+	return &HTTPProducer{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Publish posts the given record as JSON, failing on any non-2xx response.
+func (p *HTTPProducer) Publish(record StreamRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Post(p.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %q publishing a %s record", resp.Status, record.Type)
+	}
+
+	return nil
+}
+
+// streamPublisher buffers StreamRecords for a StreamProducer, so that a slow or unavailable topic never blocks
+// the controller. Records are dropped once the buffer is full.
+type streamPublisher struct {
+	producer StreamProducer
+	records  chan StreamRecord
+}
+
+// WithStreamProducer publishes a StreamRecord of every Pod interaction, extension and eviction to the given
+// producer in the background by RunStreamPublisher, buffering up to the given number of records (zero means
+// defaultStreamBufferSize).
+func WithStreamProducer(producer StreamProducer, bufferSize int) Option {
+	if bufferSize <= 0 {
+		bufferSize = defaultStreamBufferSize
+	}
+
+	return func(c *Controller) {
+		c.streamPublisher = &streamPublisher{
+			producer: producer,
+			records:  make(chan StreamRecord, bufferSize),
+		}
+	}
+}
+
+// RunStreamPublisher publishes the buffered records one by one until stopCh is closed, then publishes the ones still
+// buffered before returning. It must be stopped once nothing more is buffered, e.g. on shutdown after Drain returns.
+// It returns right away if no StreamProducer is set by WithStreamProducer.
+func (c *Controller) RunStreamPublisher(stopCh <-chan struct{}) {
+	sp := c.streamPublisher
+	if sp == nil {
+		return
+	}
+
+	for {
+		select {
+		case record := <-sp.records:
+			sp.publish(record)
+		case <-stopCh:
+			for {
+				select {
+				case record := <-sp.records:
+					sp.publish(record)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// publish publishes the given record, logging and counting it by metrics.StreamRecordsFailedTotal if it fails.
+func (sp *streamPublisher) publish(record StreamRecord) {
+	if err := sp.producer.Publish(record); err != nil {
+		metrics.StreamRecordsFailedTotal.WithLabelValues(string(record.Type)).Inc()
+		zap.L().Error("Error in publishing a stream record",
+			zap.String("type", string(record.Type)),
+			zap.String("pod_name", record.PodName),
+			zap.String("pod_namespace", record.PodNamespace),
+			zap.Error(err),
+		)
+	}
+}
+
+// publishStreamRecord buffers the given record to be published if a StreamProducer is set, without blocking.
+func (c *Controller) publishStreamRecord(record StreamRecord) {
+	if c.streamPublisher == nil {
+		return
+	}
+
+	select {
+	case c.streamPublisher.records <- record:
+	default:
+		metrics.StreamRecordsFailedTotal.WithLabelValues(string(record.Type)).Inc()
+		zap.L().Warn("Dropped a stream record as the buffer is full",
+			zap.String("type", string(record.Type)),
+			zap.String("pod_name", record.PodName),
+			zap.String("pod_namespace", record.PodNamespace),
+		)
+	}
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TerminationMode is how the controller terminates interacted Pods once they are due.
+type TerminationMode string
+
+// These are the supported termination modes.
+const (
+	// TerminationModeEvict evicts Pods through the Eviction API, which respects their PodDisruptionBudgets
+	TerminationModeEvict TerminationMode = "evict"
+	// TerminationModeDelete deletes Pods directly, e.g. on clusters where evicting single-replica Pods is refused
+	TerminationModeDelete TerminationMode = "delete"
+)
+
+// NamespaceTerminationModeAnnotate is set to a Namespace object (e.g. "delete") to override the termination mode of
+// Pods interacted in it, which is resolved when their termination timer fires.
+var NamespaceTerminationModeAnnotate = "box.com/terminationMode"
+
+// ParseTerminationMode returns the TerminationMode of the given name, or an error if it is not supported.
+func ParseTerminationMode(name string) (TerminationMode, error) {
+	switch mode := TerminationMode(strings.ToLower(name)); mode {
+	case TerminationModeEvict, TerminationModeDelete:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported termination mode %q, expected one of: evict, delete", name)
+	}
+}
+
+// WithTerminationMode sets how interacted Pods are terminated, TerminationModeEvict by default, which is overridden
+// by NamespaceTerminationModeAnnotate of their namespace or else the termination action of the ExecTrackingPolicy if
+// set. In the TerminationModeDelete mode, Pods are deleted with the given grace period (zero means the Pod's own one),
+// unless a grace period is set for their owner, i.e. by WithStatefulSetHandling.
+func WithTerminationMode(mode TerminationMode, deleteGracePeriod time.Duration) Option {
+	return func(c *Controller) {
+		c.evictionAPI.deletePods = mode == TerminationModeDelete
+		c.evictionAPI.deleteGracePeriod = deleteGracePeriod
+	}
+}
+
+// getNamespaceTerminationMode returns the TerminationMode set by NamespaceTerminationModeAnnotate of the given
+// namespace, or false if the annotation is absent, invalid or the namespace cannot be read.
+func (c *Controller) getNamespaceTerminationMode(namespace string) (TerminationMode, bool) {
+	val, present := c.getNamespaceAnnotation(namespace, NamespaceTerminationModeAnnotate, "the default termination mode")
+	if !present {
+		return "", false
+	}
+
+	mode, err := ParseTerminationMode(val)
+	if err != nil {
+		zap.L().Warn("Invalid termination mode annotated to a namespace, using the default termination mode",
+			zap.String("namespace", namespace),
+			zap.String("annotation", NamespaceTerminationModeAnnotate),
+			zap.String("annotation_value", val),
+			zap.Error(err),
+		)
+		return "", false
+	}
+
+	return mode, true
+}
+
+// getPolicyTerminationMode returns the TerminationMode of the Pods in the given namespace set by the termination
+// action of the ExecTrackingPolicy (or the NamespacedExecTrackingPolicy of the namespace), or false if no policy or
+// action is set.
+func (c *Controller) getPolicyTerminationMode(namespace string) (TerminationMode, bool) {
+	mode, err := ParseTerminationMode(c.policy.TerminationAction(namespace, ""))
+	return mode, err == nil
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// These bound the exponential backoff re-arming the termination timer of a Pod failed to be evicted, e.g. refused by
+// a PodDisruptionBudget or forbidden by missing RBAC, so the Pod is still tracked until it is evicted.
+var (
+	terminationRetryInitialInterval = 30 * time.Second
+	terminationRetryMaxInterval     = 10 * time.Minute
+)
+
+// retryTermination re-arms the given func's fired termination timer of the given Pod failed to be evicted the given
+// number of times in a row, after an exponential backoff. The timer is removed instead if the Pod is gone, and left
+// as is if it has been replaced or removed meanwhile.
+func (c *Controller) retryTermination(pod corev1.Pod, firedTimer func() *time.Timer, failures int) {
+	// a Pod of the same name may have been recreated (e.g. by a StatefulSet), which is tracked on its own
+	current, err := getPod(c.kubeClient, pod.Namespace, pod.Name, c.kubeAPITimeout)
+	if apierrors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+		c.deleteFiredTerminationTimer(pod.UID, firedTimer)
+		return
+	}
+
+	interval := terminationRetryInitialInterval
+	for i := 1; i < failures && interval < terminationRetryMaxInterval; i++ {
+		interval *= 2
+	}
+	if interval > terminationRetryMaxInterval {
+		interval = terminationRetryMaxInterval
+	}
+
+	c.terminationTimersMu.Lock()
+	defer c.terminationTimersMu.Unlock()
+
+	if timer, present := c.terminationTimersMap[pod.UID]; present && timer == firedTimer() {
+		timer.Reset(interval)
+		zap.L().Warn("Failed to evict a Pod, retrying later.",
+			zap.String("pod_name", pod.Name),
+			zap.String("pod_namespace", pod.Namespace),
+			zap.Int("failures", failures),
+			zap.String("retry_after", interval.String()),
+		)
+	}
+}
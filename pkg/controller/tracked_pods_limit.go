@@ -0,0 +1,247 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// TrackedPodsPolicy is how the controller enforces the max number of Pods tracked per user.
+type TrackedPodsPolicy string
+
+// These are the supported policies of enforcing the max number of tracked Pods per user.
+const (
+	// TrackedPodsPolicyEvict evicts the oldest tracked Pod of the user right away
+	TrackedPodsPolicyEvict TrackedPodsPolicy = "evict"
+	// TrackedPodsPolicyShortenTTL shortens the TTL of the oldest tracked Pod of the user, to be evicted shortly
+	TrackedPodsPolicyShortenTTL TrackedPodsPolicy = "shorten-ttl"
+)
+
+// shortenedRemainingTime is the time left to a Pod whose TTL is shortened by TrackedPodsPolicyShortenTTL.
+const shortenedRemainingTime = 5 * time.Minute
+
+// ParseTrackedPodsPolicy returns the TrackedPodsPolicy of the given name, or an error if it is not supported.
+func ParseTrackedPodsPolicy(name string) (TrackedPodsPolicy, error) {
+	switch policy := TrackedPodsPolicy(strings.ToLower(name)); policy {
+	case TrackedPodsPolicyEvict, TrackedPodsPolicyShortenTTL:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unsupported tracked pods policy %q, expected one of: evict, shorten-ttl", name)
+	}
+}
+
+// trackedPod is a Pod with a termination timer set, tracked under the user who interacted with it.
+type trackedPod struct {
+	namespace      string
+	name           string
+	interactedTime time.Time
+}
+
+// trackedPodsLimit counts the Pods tracked per user, to enforce the max number of them by its policy. Users are keyed
+// by their names sanitized as a label value, as the interactor of a tracked Pod is read from its label.
+type trackedPodsLimit struct {
+	mu        sync.Mutex
+	maxPods   int
+	policy    TrackedPodsPolicy
+	podsOf    map[string]map[types.UID]trackedPod
+	userOfPod map[types.UID]string
+}
+
+// WithMaxTrackedPodsPerUser limits the number of Pods tracked per user to the given max. Once a new interaction
+// exceeds it, the oldest tracked Pod of the user is evicted or has its TTL shortened, by the given policy.
+// Zero means unlimited.
+func WithMaxTrackedPodsPerUser(maxPods int, policy TrackedPodsPolicy) Option {
+	return func(c *Controller) {
+		if maxPods <= 0 {
+			return
+		}
+
+		c.trackedPodsLimit = &trackedPodsLimit{
+			maxPods:   maxPods,
+			policy:    policy,
+			podsOf:    make(map[string]map[types.UID]trackedPod),
+			userOfPod: make(map[types.UID]string),
+		}
+	}
+}
+
+// track counts the given Pod as tracked under its interactor, if the max number of tracked Pods per user is set.
+func (l *trackedPodsLimit) track(pod corev1.Pod) {
+	if l == nil {
+		return
+	}
+	interactor, _ := GetInteractionMetadata(pod, PodInteractorLabel)
+	timestamp, _ := GetInteractionMetadata(pod, PodInteractionTimestampLabel)
+	interactedTime, err := parseUnixTime(timestamp)
+	if interactor == "" || err != nil {
+		return
+	}
+
+	interactor = sanitizeLabelValue(interactor)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.podsOf[interactor] == nil {
+		l.podsOf[interactor] = make(map[types.UID]trackedPod)
+	}
+	l.podsOf[interactor][pod.UID] = trackedPod{
+		namespace:      pod.Namespace,
+		name:           pod.Name,
+		interactedTime: interactedTime,
+	}
+	l.userOfPod[pod.UID] = interactor
+}
+
+// untrack stops counting the Pod of the given UID, if tracked.
+func (l *trackedPodsLimit) untrack(uid types.UID) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	interactor, present := l.userOfPod[uid]
+	if !present {
+		return
+	}
+	delete(l.userOfPod, uid)
+	delete(l.podsOf[interactor], uid)
+	if len(l.podsOf[interactor]) == 0 {
+		delete(l.podsOf, interactor)
+	}
+}
+
+// getExceedingPods returns the number of Pods tracked under the given user beyond the max number, and the Pods to
+// enforce it on from the oldest interacted one, excluding the Pod of the given UID just interacted.
+func (l *trackedPodsLimit) getExceedingPods(interactor string, newUID types.UID) (int, []trackedPod) {
+	interactor = sanitizeLabelValue(interactor)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	exceeding := len(l.podsOf[interactor]) - l.maxPods
+	if exceeding <= 0 {
+		return 0, nil
+	}
+
+	var pods []trackedPod
+	for uid, pod := range l.podsOf[interactor] {
+		if uid != newUID {
+			pods = append(pods, pod)
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].interactedTime.Before(pods[j].interactedTime)
+	})
+
+	return exceeding, pods
+}
+
+// enforceMaxTrackedPods evicts (or shortens the TTL of) the oldest tracked Pods of the given user beyond the max
+// number of tracked Pods per user, once the Pod of the given UID is newly interacted by the user. Pods held in use
+// are passed over for the next oldest ones.
+func (c *Controller) enforceMaxTrackedPods(interactor string, newUID types.UID) error {
+	if c.trackedPodsLimit == nil {
+		return nil
+	}
+
+	exceeding, candidates := c.trackedPodsLimit.getExceedingPods(interactor, newUID)
+	for _, tracked := range candidates {
+		if exceeding <= 0 {
+			break
+		}
+
+		pod, err := getPod(c.kubeClient, tracked.namespace, tracked.name, c.kubeAPITimeout)
+		// a Pod gone since no longer counts
+		if apierrors.IsNotFound(err) {
+			exceeding--
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// the eviction of a Pod held in use is paused on purpose
+		if _, held := c.isPodHeld(*pod); held {
+			continue
+		}
+		exceeding--
+
+		if c.trackedPodsLimit.policy == TrackedPodsPolicyShortenTTL {
+			err = c.shortenTrackedPodTTL(*pod, interactor)
+		} else {
+			err = c.evictTrackedPod(*pod, interactor)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// evictTrackedPod evicts the given Pod right away by firing its termination timer, as its interactor exceeds
+// the max number of tracked Pods.
+func (c *Controller) evictTrackedPod(pod corev1.Pod, interactor string) error {
+	message := fmt.Sprintf("User '%s' is tracked in more than %d interacted Pods, this oldest one is evicted now",
+		interactor, c.trackedPodsLimit.maxPods)
+	if err := c.submitEventWithReason(&pod, EventCategoryEviction, trackedPodsLimitEventReason, message); err != nil {
+		return err
+	}
+
+	c.setTerminationTimer(pod, 0)
+	zap.L().Info("Evicting the oldest tracked Pod of a user exceeding the max number of tracked Pods.",
+		zap.String("pod_name", pod.Name),
+		zap.String("pod_namespace", pod.Namespace),
+		zap.String("interactor", interactor),
+	)
+
+	return nil
+}
+
+// shortenTrackedPodTTL shortens the TTL of the given Pod, removing its extension if any, so that it gets evicted
+// in shortenedRemainingTime, as its interactor exceeds the max number of tracked Pods. The TTL is kept if the Pod
+// is already to be evicted by then.
+func (c *Controller) shortenTrackedPodTTL(pod corev1.Pod, interactor string) error {
+	terminationTime, err := getTerminationTime(pod, c.getMaxExtension(pod.Namespace), c.idleTTL)
+	if err != nil {
+		return err
+	}
+	targetTime := time.Now().Add(shortenedRemainingTime)
+	if !terminationTime.After(targetTime) {
+		return nil
+	}
+
+	startTime, err := getTTLStartTime(pod, c.idleTTL)
+	if err != nil {
+		return err
+	}
+	ttl := targetTime.Sub(startTime).Round(time.Second)
+	updatedPod, err := patch(pod, c.interactionMetadata, map[string]string{PodTTLDurationLabel: ttl.String()}, c.kubeClient, c.kubeAPITimeout)
+	if err != nil {
+		return err
+	}
+	if updatedPod, err = removeMetadata(*updatedPod, typeAnnotations, []string{PodExtendDurationAnnotate}, c.kubeClient, c.kubeAPITimeout); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("User '%s' is tracked in more than %d interacted Pods, the TTL of this oldest one is shortened to %s",
+		interactor, c.trackedPodsLimit.maxPods, ttl.String())
+	if err := c.submitEventWithReason(updatedPod, EventCategoryEviction, trackedPodsLimitEventReason, message); err != nil {
+		return err
+	}
+	zap.L().Info("Shortened the TTL of the oldest tracked Pod of a user exceeding the max number of tracked Pods.",
+		zap.String("pod_name", pod.Name),
+		zap.String("pod_namespace", pod.Namespace),
+		zap.String("interactor", interactor),
+		zap.String("ttl", ttl.String()),
+	)
+
+	return c.setTermination(*updatedPod)
+}
@@ -0,0 +1,36 @@
+package duration
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// hoursPerUnit contains the number of hours of the units supported on top of time.ParseDuration.
+var hoursPerUnit = map[string]float64{
+	"d": 24,
+	"w": 7 * 24,
+}
+
+// dayUnitRegexp matches a number followed by a day ("d") or week ("w") unit, e.g. "1d" or "1.5w".
+var dayUnitRegexp = regexp.MustCompile(`([0-9]*\.?[0-9]+)([dw])`)
+
+// Parse parses a duration string as time.ParseDuration does, additionally supporting days ("d") and weeks ("w")
+// which are expanded to 24 and 168 hours respectively, e.g. "1d12h" is parsed as 36 hours.
+// It is shared by the controller, the webhook and the kubectl plugin so that they accept the same values.
+func Parse(s string) (time.Duration, error) {
+	expanded := dayUnitRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		groups := dayUnitRegexp.FindStringSubmatch(match)
+		// the number always parses as it is matched by dayUnitRegexp
+		val, _ := strconv.ParseFloat(groups[1], 64)
+		return strconv.FormatFloat(val*hoursPerUnit[groups[2]], 'f', -1, 64) + "h"
+	})
+
+	d, err := time.ParseDuration(expanded)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	return d, nil
+}
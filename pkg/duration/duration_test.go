@@ -0,0 +1,36 @@
+package duration_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/box/kube-exec-controller/pkg/duration"
+)
+
+// TestParse tests parsing durations with days and weeks on top of the Go duration format
+func TestParse(t *testing.T) {
+	validDurations := map[string]time.Duration{
+		"30m":    30 * time.Minute,
+		"1h30m":  90 * time.Minute,
+		"1d":     24 * time.Hour,
+		"1.5d":   36 * time.Hour,
+		"1d12h":  36 * time.Hour,
+		"2w":     14 * 24 * time.Hour,
+		"1w1d1s": 8*24*time.Hour + time.Second,
+		"-1d":    -24 * time.Hour,
+	}
+	for s, expected := range validDurations {
+		d, err := duration.Parse(s)
+		if err != nil {
+			t.Errorf("expected %q to be parsed, got error: %v", s, err)
+		} else if d != expected {
+			t.Errorf("expected %q parsed as %s, got: %s", s, expected, d)
+		}
+	}
+
+	for _, s := range []string{"", "1", "d", "abc", "1y", "1dd"} {
+		if d, err := duration.Parse(s); err == nil {
+			t.Errorf("expected an error parsing %q, got: %s", s, d)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace is the common prefix of all metrics exposed by kube-exec-controller.
+const namespace = "kube_exec"
+
+// Registry contains all metrics exposed by kube-exec-controller.
+var Registry = prometheus.NewRegistry()
+
+// CommandsTruncatedTotal counts Pod interactions whose command list was truncated for exceeding the limits.
+var CommandsTruncatedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_truncated_total",
+		Help:      "Number of Pod interactions whose command list was truncated for exceeding the limits.",
+	},
+	[]string{"namespace"},
+)
+
+// DeadLetteredItemsTotal counts items left unhandled in the controller's channels at the drain deadline on shutdown,
+// by their kind (pod_interaction or pod_extension_update).
+var DeadLetteredItemsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dead_lettered_items_total",
+		Help:      "Number of items left unhandled in the controller's channels at the drain deadline on shutdown.",
+	},
+	[]string{"kind"},
+)
+
+// EvictionDisabled is set to 1 while all evictions are paused by the kill switch, otherwise 0.
+var EvictionDisabled = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "eviction_disabled",
+		Help:      "Whether all Pod evictions are paused by the kill switch (1) or not (0).",
+	},
+)
+
+// EvictionForbidden is set to 1 while the latest attempt of terminating a Pod is forbidden by missing RBAC, otherwise 0.
+var EvictionForbidden = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "eviction_forbidden",
+		Help:      "Whether the latest attempt of evicting (or deleting) a Pod is forbidden by missing RBAC (1) or not (0).",
+	},
+)
+
+// ForeignExtensionsTotal counts Pod extensions requested by a user other than the Pod's original interactor.
+var ForeignExtensionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "foreign_extensions_total",
+		Help:      "Number of Pod extensions requested by a user other than the Pod's original interactor.",
+	},
+	[]string{"namespace"},
+)
+
+// GiveUpsTotal counts items the controller gave up retrying to handle with backoff, by their kind (pod_interaction,
+// pod_extension_update or previous_pod_interactions).
+var GiveUpsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "give_ups_total",
+		Help:      "Number of items the controller gave up retrying to handle with backoff.",
+	},
+	[]string{"kind"},
+)
+
+// InteractionsTotal counts Pod interactions admitted to be tracked, by their verb (e.g. exec or attach).
+var InteractionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "interactions_total",
+		Help:      "Number of Pod interactions admitted to be tracked.",
+	},
+	[]string{"namespace", "verb"},
+)
+
+// DeniedUpdatesTotal counts updates to interacted Pods denied by the webhook, e.g. changing an immutable label.
+var DeniedUpdatesTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "denied_updates_total",
+		Help:      "Number of updates to interacted Pods denied by the webhook.",
+	},
+	[]string{"namespace"},
+)
+
+// ExtensionsTotal counts Pod extensions handled by the controller.
+var ExtensionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "extensions_total",
+		Help:      "Number of Pod extensions handled by the controller.",
+	},
+	[]string{"namespace"},
+)
+
+// EvictionsTotal counts interacted Pods evicted by the controller.
+var EvictionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "evictions_total",
+		Help:      "Number of interacted Pods evicted by the controller.",
+	},
+	[]string{"namespace"},
+)
+
+// PodAgeAtEvictionSeconds observes how long interacted Pods lived from their first interaction until getting evicted,
+// by whether they were extended, e.g. to tell if the TTL is too short (mostly extended) or too long.
+var PodAgeAtEvictionSeconds = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "pod_age_at_eviction_seconds",
+		Help:      "Age of interacted Pods from their first interaction until getting evicted by the controller.",
+		Buckets:   prometheus.ExponentialBuckets(60, 2, 10),
+	},
+	[]string{"namespace", "extended"},
+)
+
+// StreamRecordsFailedTotal counts records of Pod lifecycle events failed to be published to the stream producer,
+// or dropped as its buffer is full, by their type (interaction, extension or eviction).
+var StreamRecordsFailedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_records_failed_total",
+		Help:      "Number of records of Pod lifecycle events failed to be published or dropped as the buffer is full.",
+	},
+	[]string{"type"},
+)
+
+// SuppressedEventsTotal counts K8s events not submitted to interacted Pods as they are terminating, by their reason.
+var SuppressedEventsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "suppressed_events_total",
+		Help:      "Number of K8s events not submitted to interacted Pods as they are terminating.",
+	},
+	[]string{"namespace", "reason"},
+)
+
+// TerminationTimers is set to the number of active timers to evict interacted Pods.
+var TerminationTimers = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "termination_timers",
+		Help:      "Number of active timers to evict interacted Pods.",
+	},
+)
+
+func init() {
+	Registry.MustRegister(
+		CommandsTruncatedTotal,
+		DeadLetteredItemsTotal,
+		EvictionDisabled,
+		EvictionForbidden,
+		ForeignExtensionsTotal,
+		GiveUpsTotal,
+		InteractionsTotal,
+		DeniedUpdatesTotal,
+		ExtensionsTotal,
+		EvictionsTotal,
+		PodAgeAtEvictionSeconds,
+		StreamRecordsFailedTotal,
+		SuppressedEventsTotal,
+		TerminationTimers,
+	)
+}
+
+// Handler returns an http.Handler exposing all metrics in Registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
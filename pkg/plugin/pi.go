@@ -3,32 +3,94 @@ package plugin
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
+	"github.com/box/kube-exec-controller/pkg/duration"
 	// load the GCP authentication plug-in
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 )
 
 // PodInteractionInfo contains all information of a pod interaction
 type PodInteractionInfo struct {
-	podName         string
-	interactor      string
-	ttlDuration     string
-	extension       string
-	requester       string
-	terminationTime string
+	PodName         string `json:"podName"`
+	Namespace       string `json:"namespace"`
+	Interactor      string `json:"interactor"`
+	TTLDuration     string `json:"ttlDuration"`
+	Extension       string `json:"extension"`
+	Requester       string `json:"extensionRequester"`
+	TerminationTime string `json:"evictionTime"`
+	Remaining       string `json:"remaining,omitempty"`
+	// The following fields are shown in the table output of the 'describe' action only
+	InteractionTime     string `json:"interactionTime,omitempty"`
+	LastInteractionTime string `json:"lastInteractionTime,omitempty"`
+	InteractionCount    string `json:"interactionCount,omitempty"`
+	LastInteractor      string `json:"lastInteractor,omitempty"`
+	InUse               bool   `json:"inUse,omitempty"`
+	// Exempt is true if the pod is annotated to be exempt from eviction, regardless of the controller's selector
+	Exempt bool `json:"exempt,omitempty"`
+}
+
+// OverduePodInfo contains the information of an interacted pod whose termination time has passed
+type OverduePodInfo struct {
+	PodInteractionInfo
+	DueTime string `json:"dueTime"`
+	Overdue string `json:"overdue"`
 }
 
+// extensionRecord is an entry of the extension history of a pod, which must match to the ExtensionRecord
+// defined in controller/extension_history.go file
+type extensionRecord struct {
+	Requester string    `json:"requester"`
+	Duration  string    `json:"duration"`
+	Time      time.Time `json:"time"`
+}
+
+// historyRecord is a record of a pod interaction, extension or eviction kept in the history ConfigMap, which must
+// match to the StreamRecord defined in controller/stream.go file
+type historyRecord struct {
+	Type          string    `json:"type"`
+	Timestamp     time.Time `json:"timestamp"`
+	Username      string    `json:"username,omitempty"`
+	PodNamespace  string    `json:"pod_namespace"`
+	PodName       string    `json:"pod_name"`
+	ContainerName string    `json:"container_name,omitempty"`
+	Commands      []string  `json:"commands,omitempty"`
+	Extension     string    `json:"extension,omitempty"`
+}
+
+// extensionOutcome is the outcome of requesting an extension to a pod
+type extensionOutcome string
+
+const (
+	outcomeExtended    extensionOutcome = "extended"
+	outcomeReduced     extensionOutcome = "reduced"
+	outcomeOverwritten extensionOutcome = "overwritten"
+	outcomeReset       extensionOutcome = "reset"
+	outcomeSkipped     extensionOutcome = "skipped"
+	outcomeFailed      extensionOutcome = "failed"
+)
+
 // CmdOptions provides context required to run the program
 type CmdOptions struct {
 	genericclioptions.IOStreams
+	// reused across confirmation prompts so that buffered input is not lost
+	inReader    *bufio.Reader
 	configFlags *genericclioptions.ConfigFlags
 	// using kubernetes.Interface to allow testing by fake client
 	kubeClient kubernetes.Interface
@@ -36,7 +98,25 @@ type CmdOptions struct {
 	args              []string
 	action            string
 	extendDurationStr string
+	refusePast        bool
+	assumeYes         bool
 	specifiedAll      bool
+	allNamespaces     bool
+	interactedOnly    bool
+	metadata          string
+	selector          string
+	excludeSelector   string
+	migrateFrom       string
+	migrateTo         string
+	keyPrefix         string
+	justification     string
+	outputFormat      string
+	withinStr         string
+	sortBy            string
+	showRaw           bool
+
+	// clock returns the current time to compute the remaining time of pods, time.Now if not set
+	clock func() time.Time
 
 	podNames  []string
 	namespace string
@@ -47,6 +127,7 @@ func NewCmdOptions(streams genericclioptions.IOStreams) *CmdOptions {
 	return &CmdOptions{
 		configFlags: genericclioptions.NewConfigFlags(false),
 		IOStreams:   streams,
+		clock:       time.Now,
 	}
 }
 
@@ -58,7 +139,12 @@ func NewCmdPi(streams genericclioptions.IOStreams) *cobra.Command {
 		Short:        cmdShortMsg,
 		Example:      cmdExampleMsg,
 		SilenceUsage: true,
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// migrate to the key prefix the controller recognizes unless set otherwise
+			if !cmd.Flags().Changed("to") {
+				opts.migrateTo = opts.keyPrefix
+			}
+
 			if err := opts.Complete(args); err != nil {
 				return err
 			}
@@ -77,12 +163,71 @@ func NewCmdPi(streams genericclioptions.IOStreams) *cobra.Command {
 
 	// add "--duration/-d" flag to allow setting duration for pod extension request
 	cmd.Flags().StringVarP(&opts.extendDurationStr, "duration", "d", defaultExtendDuration,
-		fmt.Sprintf("a relative duration such as 5s, 2m, or 3h, default to %s", defaultExtendDuration))
+		fmt.Sprintf("a relative duration such as 5s, 2m, 3h, or 1d, default to %s", defaultExtendDuration))
+
+	// add "--yes/-y" flag to allow confirming prompts automatically for non-interactive usage
+	cmd.Flags().BoolVarP(&opts.assumeYes, "yes", "y", false,
+		"if present, confirm prompts automatically, e.g. overwriting an existing extension, without reading from stdin")
+
+	// add "--refuse-past" flag to allow refusing an extension that would leave pods evicted right away
+	cmd.Flags().BoolVar(&opts.refusePast, "refuse-past", false,
+		"if present, refuse extending pods whose resulting eviction time would be in the past, used by the 'extend' action")
 
 	// add "--all/-a" flag to allow selecting all pods under the given namespace
 	cmd.Flags().BoolVarP(&opts.specifiedAll, "all", "a", false,
 		fmt.Sprintf("if present, select all pods under specified namespace (and ignore any given pod podName)"))
 
+	// add "--all-namespaces/-A" flag to allow selecting all pods across all namespaces
+	cmd.Flags().BoolVarP(&opts.allNamespaces, "all-namespaces", "A", false,
+		"if present, select all pods across all namespaces (implies --all), only supported by the 'get' action")
+
+	// add "--interacted-only" flag to allow hiding pods with no interaction from the 'get' action
+	cmd.Flags().BoolVar(&opts.interactedOnly, "interacted-only", false,
+		"if present, only get the pods interacted with, only supported by the 'get' action")
+
+	// add "--interaction-metadata" flag to allow selecting the interacted pods by their label on the API server
+	cmd.Flags().StringVar(&opts.metadata, "interaction-metadata", metadataLabels,
+		fmt.Sprintf("type of metadata the controller stores interactions in, one of: %s or %s, the interacted pods of '--interacted-only' are selected by their label unless %s",
+			metadataLabels, metadataAnnotations, metadataAnnotations))
+
+	// add "--selector/-l" flag to allow selecting the pods matching a label selector under the given namespace
+	cmd.Flags().StringVarP(&opts.selector, "selector", "l", "",
+		"a label selector (e.g. app=web) of pods to select under specified namespace, instead of any given pod name")
+
+	// add "--exclude-selector" flag to allow hiding pods from the "--all" selection
+	cmd.Flags().StringVar(&opts.excludeSelector, "exclude-selector", "",
+		"a label selector (e.g. tier=system) of pods to exclude when selecting all pods under specified namespace")
+
+	// add "--reason/-r" flag to allow setting justification of interacting pods
+	cmd.Flags().StringVarP(&opts.justification, "reason", "r", "",
+		"a justification of interacting the pods, required by the 'justify' action")
+
+	// add "--output/-o" flag to allow printing the result of 'get' and 'would-evict' actions in a scriptable format
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", outputTable,
+		fmt.Sprintf("output format of the 'get' and 'would-evict' actions, one of: %s, %s or %s", outputTable, outputJSON, outputYAML))
+
+	// add "--within" flag to allow getting only the pods to be evicted soon
+	cmd.Flags().StringVar(&opts.withinStr, "within", "",
+		"a relative duration such as 10m or 1h, if present, only get pods to be evicted within it")
+
+	// add "--sort-by" flag to allow sorting the pods of the 'get' action, e.g. to audit the ones evicted soon
+	cmd.Flags().StringVar(&opts.sortBy, "sort-by", sortByName,
+		fmt.Sprintf("sort the pods of the 'get' action by either %s or %s (pods of no eviction time last)", sortByName, sortByEvictionTime))
+
+	// add "--show-raw" flag to allow dumping the interaction labels/annotations verbatim for troubleshooting
+	cmd.Flags().BoolVar(&opts.showRaw, "show-raw", false,
+		"if present, also print the labels/annotations of the pods under the key prefix verbatim, only supported by the 'get' action in table output")
+
+	// add "--key-prefix" flag to allow matching the key prefix of interaction labels/annotations set in the controller
+	cmd.Flags().StringVar(&opts.keyPrefix, "key-prefix", getDefaultKeyPrefix(),
+		fmt.Sprintf("the key prefix of interaction labels/annotations set in the controller, default to $%s if set", keyPrefixEnv))
+
+	// add "--from" and "--to" flags to allow setting key prefixes for migrating pod metadata
+	cmd.Flags().StringVar(&opts.migrateFrom, "from", "",
+		"the old key prefix (e.g. example.com) of interaction labels/annotations to migrate from")
+	cmd.Flags().StringVar(&opts.migrateTo, "to", "",
+		"the new key prefix of interaction labels/annotations to migrate to, default to '--key-prefix' which the controller recognizes")
+
 	// bind kubectl default options to the cmd flag set
 	opts.configFlags.AddFlags(cmd.Flags())
 
@@ -99,8 +244,13 @@ func (o *CmdOptions) Complete(args []string) error {
 	o.action = args[0]
 	o.podNames = args[1:]
 
-	// select all pods if no specific pod name set
-	if len(o.podNames) == 0 {
+	// name the interaction labels/annotations under the key prefix set in the controller
+	if err := setKeyPrefix(o.keyPrefix); err != nil {
+		return err
+	}
+
+	// select all pods if no specific pod name set, or across all namespaces
+	if len(o.podNames) == 0 || o.allNamespaces {
 		o.specifiedAll = true
 	}
 
@@ -109,13 +259,13 @@ func (o *CmdOptions) Complete(args []string) error {
 	configLoader := o.configFlags.ToRawKubeConfigLoader()
 	o.namespace, _, err = configLoader.Namespace()
 	if err != nil {
-		return err
+		return o.checkContextError(err)
 	}
 
-	// set up K8s client config
+	// set up K8s client config, of the context set in '--context' if any
 	clientConfig, err := configLoader.ClientConfig()
 	if err != nil {
-		return err
+		return o.checkContextError(err)
 	}
 
 	o.kubeClient, err = kubernetes.NewForConfig(clientConfig)
@@ -126,6 +276,16 @@ func (o *CmdOptions) Complete(args []string) error {
 	return nil
 }
 
+// checkContextError returns a clear error if the given error of loading the kubeconfig is caused by the context set
+// in '--context' not found in it, or the given error as is otherwise
+func (o *CmdOptions) checkContextError(err error) error {
+	if clientcmd.IsContextNotFound(err) && o.configFlags.Context != nil && *o.configFlags.Context != "" {
+		return fmt.Errorf(cmdInvalidContextError, *o.configFlags.Context)
+	}
+
+	return err
+}
+
 // Validate ensures that all required arguments and flag values are provided and validated
 func (o *CmdOptions) Validate() error {
 	// validate given action
@@ -133,16 +293,77 @@ func (o *CmdOptions) Validate() error {
 		return fmt.Errorf(cmdInvalidActionError)
 	}
 
+	// validate all namespaces are only selected to get all pods in them
+	if o.allNamespaces && (o.action != cmdGetAction || len(o.podNames) > 0) {
+		return fmt.Errorf(cmdInvalidAllNamespacesError)
+	}
+
+	// validate only interacted pods are selected to get them
+	if o.interactedOnly && o.action != cmdGetAction {
+		return fmt.Errorf(cmdInvalidInteractedOnlyError)
+	}
+	if o.metadata != "" && o.metadata != metadataLabels && o.metadata != metadataAnnotations {
+		return fmt.Errorf(cmdInvalidMetadataError)
+	}
+
+	// validate pods are selected either by a label selector or by their names
+	if o.selector != "" && len(o.podNames) > 0 {
+		return fmt.Errorf(cmdSelectorWithPodNamesError)
+	}
+	if _, err := labels.Parse(o.selector); err != nil {
+		return fmt.Errorf(cmdInvalidSelectorError, err)
+	}
+
+	// validate the format of exclude selector if set
+	if _, err := labels.Parse(o.excludeSelector); err != nil {
+		return fmt.Errorf(cmdInvalidExcludeSelectorError, err)
+	}
+
 	// validate the format of extended duration if set
-	if o.action == cmdExtendAction && !isValidDuration(o.extendDurationStr) {
+	if (o.action == cmdExtendAction || o.action == cmdReduceAction) && !isValidDuration(o.extendDurationStr) {
 		return fmt.Errorf(cmdInValidDurationError)
 	}
 
+	// validate the format of the '--within' duration if set
+	if o.withinStr != "" && !isValidDuration(o.withinStr) {
+		return fmt.Errorf(cmdInvalidWithinError)
+	}
+
+	// validate the output format of the 'get' and 'would-evict' actions
+	if (o.action == cmdGetAction || o.action == cmdWouldEvictAction) && !isValidOutputFormat(o.outputFormat) {
+		return fmt.Errorf(cmdInvalidOutputError)
+	}
+
+	// validate the sort key of the 'get' action if set, which no other action supports
+	if o.sortBy != "" && (!isValidSortBy(o.sortBy) || (o.sortBy != sortByName && o.action != cmdGetAction)) {
+		return fmt.Errorf(cmdInvalidSortByError)
+	}
+
+	// validate raw labels/annotations are only printed along with the table of the 'get' action
+	if o.showRaw && (o.action != cmdGetAction || o.outputFormat != outputTable) {
+		return fmt.Errorf(cmdInvalidShowRawError)
+	}
+
+	// validate justification is set to justify pods
+	if o.action == cmdJustifyAction && strings.TrimSpace(o.justification) == "" {
+		return fmt.Errorf(cmdMissingReasonError)
+	}
+
+	// validate key prefixes to migrate pod metadata between
+	if o.action == cmdMigrateAction && (o.migrateFrom == "" || o.migrateTo == "" || o.migrateFrom == o.migrateTo) {
+		return fmt.Errorf(cmdInvalidMigratePrefixError)
+	}
+
 	return nil
 }
 
 // Run executes the command
 func (o *CmdOptions) Run() error {
+	// the history is read from the controller's ConfigMap rather than the pods, which may be evicted already
+	if o.action == cmdHistoryAction {
+		return o.handleActionHistory()
+	}
+
 	pods, err := o.getSpecifiedPods()
 	if err != nil {
 		return err
@@ -150,6 +371,9 @@ func (o *CmdOptions) Run() error {
 
 	if len(pods) == 0 {
 		fmt.Println()
+		if o.allNamespaces {
+			return fmt.Errorf(noPodReturnedOfAllNamespacesMsg)
+		}
 		return fmt.Errorf(noPodReturnedOfNamespaceMsg, o.namespace)
 	}
 
@@ -157,9 +381,34 @@ func (o *CmdOptions) Run() error {
 	case cmdGetAction:
 		return o.handleActionGet(pods)
 
+	case cmdDescribeAction:
+		return o.handleActionDescribe(pods)
+
 	case cmdExtendAction:
 		return o.handleActionExtend(pods)
 
+	case cmdReduceAction:
+		return o.handleActionReduce(pods)
+
+	// cancel is an alias of reset for undoing a mistaken extension
+	case cmdResetAction, cmdCancelAction:
+		return o.handleActionReset(pods)
+
+	case cmdHoldAction:
+		return o.handleActionHold(pods)
+
+	case cmdReleaseAction:
+		return o.handleActionRelease(pods)
+
+	case cmdJustifyAction:
+		return o.handleActionJustify(pods)
+
+	case cmdMigrateAction:
+		return o.handleActionMigrate(pods)
+
+	case cmdWouldEvictAction:
+		return o.handleActionWouldEvict(pods)
+
 	default:
 		return fmt.Errorf("unknown action %s", o.action)
 	}
@@ -169,13 +418,37 @@ func (o *CmdOptions) Run() error {
 func (o *CmdOptions) getSpecifiedPods() ([]corev1.Pod, error) {
 	var specifiedPods []corev1.Pod
 	if o.specifiedAll {
-		// get all pods under the given namespace
-		pods, err := o.kubeClient.CoreV1().Pods(o.namespace).List(context.TODO(), metav1.ListOptions{})
+		// get all pods (matching the label selector if set) under the given namespace, or across all namespaces if set
+		namespace := o.namespace
+		if o.allNamespaces {
+			namespace = corev1.NamespaceAll
+		}
+		// select only the interacted pods by their label if set, unless stored as annotations which cannot be selected
+		selector := o.selector
+		if o.interactedOnly && o.metadata != metadataAnnotations {
+			if selector != "" {
+				selector += ","
+			}
+			selector += podInteractionTimestampLabel
+		}
+		pods, err := o.kubeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
 		if err != nil {
 			return []corev1.Pod{}, err
 		}
 
-		specifiedPods = pods.Items
+		// exclude pods matching the given exclude selector
+		excludeSelector, err := labels.Parse(o.excludeSelector)
+		if err != nil {
+			return []corev1.Pod{}, err
+		}
+
+		for _, pod := range pods.Items {
+			if !excludeSelector.Empty() && excludeSelector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+
+			specifiedPods = append(specifiedPods, pod)
+		}
 	} else {
 		// get pod matching the specified pod name
 		for _, podName := range o.podNames {
@@ -193,41 +466,444 @@ func (o *CmdOptions) getSpecifiedPods() ([]corev1.Pod, error) {
 	return specifiedPods, nil
 }
 
-// handleActionGet gets the pod interaction info and prints out the result in a formatted table
+// handleActionGet gets the pod interaction info and prints out the result in the specified output format,
+// a formatted table by default. Only the pods to be evicted within '--within' are included if set, and only the
+// interacted ones if '--interacted-only' is set. The pods are sorted by '--sort-by', their name by default.
+// The interaction labels/annotations of the pods are printed verbatim after the table if '--show-raw' is set.
 func (o *CmdOptions) handleActionGet(pods []corev1.Pod) error {
-	var infoList []PodInteractionInfo
+	now := o.now()
+	within, _ := duration.Parse(o.withinStr)
+	infoList := make([]PodInteractionInfo, 0, len(pods))
+	selectedPods := make(map[string]corev1.Pod, len(pods))
+	for _, pod := range pods {
+		// the pods given by name are not selected by the interaction label, nor the ones storing it as an annotation
+		if _, interacted := getInteractionMetadata(pod, podInteractionTimestampLabel); o.interactedOnly && !interacted {
+			continue
+		}
+
+		if o.withinStr != "" {
+			if remaining, present := getRemainingTime(pod, now); !present || remaining > within {
+				continue
+			}
+		}
+
+		infoList = append(infoList, getPodInteractionInfo(pod, now))
+		selectedPods[pod.Namespace+"/"+pod.Name] = pod
+	}
+	sortPodInteractionInfo(infoList, o.sortBy)
+
+	if o.outputFormat == outputJSON || o.outputFormat == outputYAML {
+		return o.printStructured(infoList)
+	}
+
+	if err := o.printTable(infoList); err != nil {
+		return err
+	}
+
+	if o.showRaw {
+		for _, info := range infoList {
+			if err := o.printRawMetadata(selectedPods[info.Namespace+"/"+info.PodName]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// handleActionWouldEvict lists the specified interacted pods whose termination time has passed, i.e. the ones the
+// controller would evict right away, in the specified output format. Pods held in use are not listed as their
+// eviction is paused.
+func (o *CmdOptions) handleActionWouldEvict(pods []corev1.Pod) error {
+	now := o.now()
+	infoList := make([]OverduePodInfo, 0, len(pods))
 	for _, pod := range pods {
-		infoList = append(infoList, getPodInteractionInfo(pod))
+		if pod.Annotations[podInUseAnnotate] == "true" {
+			continue
+		}
+
+		dueTime, present := getDueTime(pod)
+		if !present || dueTime.After(now) {
+			continue
+		}
+
+		infoList = append(infoList, OverduePodInfo{
+			PodInteractionInfo: getPodInteractionInfo(pod, now),
+			DueTime:            dueTime.String(),
+			Overdue:            now.Sub(dueTime).Round(time.Second).String(),
+		})
+	}
+
+	if o.outputFormat == outputJSON || o.outputFormat == outputYAML {
+		return o.printStructured(infoList)
+	}
+
+	if len(infoList) == 0 {
+		_, err := fmt.Fprintf(o.Out, noOverduePodOfNamespaceMsg, o.namespace)
+		return err
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(o.Out, 0, 8, 2, '\t', 0)
+	fmt.Fprintln(w, "POD-NAME\tINTERACTOR\tPOD-TTL\tEXTENSION\tDUE-TIME\tOVERDUE")
+	for _, info := range infoList {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			info.PodName,
+			info.Interactor,
+			info.TTLDuration,
+			info.Extension,
+			info.DueTime,
+			info.Overdue,
+		)
+	}
+
+	return w.Flush()
+}
+
+// handleActionDescribe prints out the pod interaction info of the specified pods in detail, including their extension history
+// and raw interaction labels/annotations
+func (o *CmdOptions) handleActionDescribe(pods []corev1.Pod) error {
+	for i, pod := range pods {
+		if _, interacted := getInteractionMetadata(pod, podInteractionTimestampLabel); !interacted {
+			fmt.Fprintf(o.Out, noInteractionOfPodMsg, pod.Name)
+			continue
+		}
+
+		if i > 0 {
+			fmt.Fprintln(o.Out)
+		}
+		if err := o.printDescription(pod); err != nil {
+			return err
+		}
 	}
 
-	return o.printTable(infoList)
+	return nil
 }
 
-// handleActionExtend sets the requested extension to the specified pods
+// handleActionExtend sets the requested extension to the specified pods.
+// It prints a summary of per-pod outcomes when processing multiple pods, and returns an error if any failed.
 func (o *CmdOptions) handleActionExtend(pods []corev1.Pod) error {
+	summary := map[extensionOutcome]int{}
+	for _, pod := range pods {
+		outcome, err := o.setExtensionMetadata(pod)
+		if err != nil {
+			fmt.Fprintf(o.Out, failedExtensionOfPodMsg, pod.Name, err)
+		}
+
+		summary[outcome]++
+	}
+
+	if len(pods) > 1 {
+		fmt.Fprintf(o.Out, extensionSummaryMsg,
+			summary[outcomeExtended],
+			summary[outcomeOverwritten],
+			summary[outcomeSkipped],
+			summary[outcomeFailed],
+		)
+	}
+
+	if summary[outcomeFailed] > 0 {
+		return fmt.Errorf(cmdExtensionFailedError, summary[outcomeFailed])
+	}
+
+	return nil
+}
+
+// handleActionReduce shortens the remaining lifetime of the specified pods by the duration set in '--duration',
+// subtracting it from their current extension (which may become negative). The controller evicts a pod soon if its
+// reduced eviction time is in the past, never right away. It prints a summary of per-pod outcomes when processing
+// multiple pods, and returns an error if any failed.
+func (o *CmdOptions) handleActionReduce(pods []corev1.Pod) error {
+	summary := map[extensionOutcome]int{}
+	for _, pod := range pods {
+		outcome, err := o.setReducedExtensionMetadata(pod)
+		if err != nil {
+			fmt.Fprintf(o.Out, failedReductionOfPodMsg, pod.Name, err)
+		}
+
+		summary[outcome]++
+	}
+
+	if len(pods) > 1 {
+		fmt.Fprintf(o.Out, reductionSummaryMsg,
+			summary[outcomeReduced],
+			summary[outcomeSkipped],
+			summary[outcomeFailed],
+		)
+	}
+
+	if summary[outcomeFailed] > 0 {
+		return fmt.Errorf(cmdReductionFailedError, summary[outcomeFailed])
+	}
+
+	return nil
+}
+
+// handleActionReset removes the extension from the specified pods after a confirmation, so the controller reverts
+// their termination time back to the base TTL. Pods with no extension are skipped, so it is safe to run repeatedly.
+// It prints a summary of per-pod outcomes when processing multiple pods, and returns an error if any failed.
+func (o *CmdOptions) handleActionReset(pods []corev1.Pod) error {
+	summary := map[extensionOutcome]int{}
+	var extendedPods []corev1.Pod
 	for _, pod := range pods {
-		if err := o.setExtensionMetadata(pod); err != nil {
+		if _, interacted := getInteractionMetadata(pod, podInteractionTimestampLabel); !interacted {
+			fmt.Fprintf(o.Out, noInteractionOfPodMsg, pod.Name)
+			summary[outcomeSkipped]++
+			continue
+		}
+
+		if _, extended := pod.Annotations[podExtendDurationAnnotate]; !extended {
+			fmt.Fprintf(o.Out, noExtensionOfPodMsg, pod.Name)
+			summary[outcomeSkipped]++
+			continue
+		}
+
+		extendedPods = append(extendedPods, pod)
+	}
+
+	if len(extendedPods) > 0 {
+		confirmed, err := o.askConfirmation(fmt.Sprintf(resetExtensionPromptMsg, len(extendedPods)))
+		if err != nil {
 			return err
 		}
+
+		for _, pod := range extendedPods {
+			if !confirmed {
+				summary[outcomeSkipped]++
+				continue
+			}
+
+			patchData := []byte(fmt.Sprintf("[%s]", getRemoveJsonPatchStr("annotations", podExtendDurationAnnotate)))
+			_, err := o.kubeClient.CoreV1().Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.JSONPatchType, patchData, metav1.PatchOptions{})
+			if err != nil {
+				fmt.Fprintf(o.Out, failedResetOfPodMsg, pod.Name, err)
+				summary[outcomeFailed]++
+				continue
+			}
+
+			fmt.Fprintf(o.Out, successResetOfPodMsg, pod.Name)
+			summary[outcomeReset]++
+		}
+	}
+
+	if len(pods) > 1 {
+		fmt.Fprintf(o.Out, resetSummaryMsg,
+			summary[outcomeReset],
+			summary[outcomeSkipped],
+			summary[outcomeFailed],
+		)
+	}
+
+	if summary[outcomeFailed] > 0 {
+		return fmt.Errorf(cmdResetFailedError, summary[outcomeFailed])
+	}
+
+	return nil
+}
+
+// handleActionHold annotates the specified interacted pods as in use, so the controller pauses evicting them until
+// released. Pods already held are skipped.
+func (o *CmdOptions) handleActionHold(pods []corev1.Pod) error {
+	failed := 0
+	for _, pod := range pods {
+		if _, interacted := getInteractionMetadata(pod, podInteractionTimestampLabel); !interacted {
+			fmt.Fprintf(o.Out, noInteractionOfPodMsg, pod.Name)
+			continue
+		}
+
+		if pod.Annotations[podInUseAnnotate] == "true" {
+			fmt.Fprintf(o.Out, alreadyHeldOfPodMsg, pod.Name)
+			continue
+		}
+
+		if _, err := patchAnnotations(pod, map[string]string{podInUseAnnotate: "true"}, o.kubeClient); err != nil {
+			fmt.Fprintf(o.Out, failedHoldOfPodMsg, pod.Name, err)
+			failed++
+			continue
+		}
+
+		fmt.Fprintf(o.Out, successHoldOfPodMsg, pod.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf(cmdHoldFailedError, failed)
+	}
+
+	return nil
+}
+
+// handleActionRelease removes the in-use annotation from the specified pods, so the controller resumes evicting them
+// at their termination time. Pods not held are skipped.
+func (o *CmdOptions) handleActionRelease(pods []corev1.Pod) error {
+	failed := 0
+	for _, pod := range pods {
+		if _, held := pod.Annotations[podInUseAnnotate]; !held {
+			fmt.Fprintf(o.Out, notHeldOfPodMsg, pod.Name)
+			continue
+		}
+
+		patchData := []byte(fmt.Sprintf("[%s]", getRemoveJsonPatchStr("annotations", podInUseAnnotate)))
+		_, err := o.kubeClient.CoreV1().Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.JSONPatchType, patchData, metav1.PatchOptions{})
+		if err != nil {
+			fmt.Fprintf(o.Out, failedReleaseOfPodMsg, pod.Name, err)
+			failed++
+			continue
+		}
+
+		fmt.Fprintf(o.Out, successReleaseOfPodMsg, pod.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf(cmdReleaseFailedError, failed)
+	}
+
+	return nil
+}
+
+// handleActionJustify annotates the specified pods with the given justification and the current time,
+// which is required by the controller before interacting pods in some namespaces
+func (o *CmdOptions) handleActionJustify(pods []corev1.Pod) error {
+	patchDataMap := map[string]string{
+		podExecJustificationAnnotate:     o.justification,
+		podExecJustificationTimeAnnotate: strconv.FormatInt(o.now().Unix(), 10),
+	}
+
+	failed := 0
+	for _, pod := range pods {
+		if _, err := patchAnnotations(pod, patchDataMap, o.kubeClient); err != nil {
+			fmt.Fprintf(o.Out, failedJustificationOfPodMsg, pod.Name, err)
+			failed++
+			continue
+		}
+
+		fmt.Fprintf(o.Out, successJustificationOfPodMsg, pod.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf(cmdJustificationFailedError, failed)
 	}
 
 	return nil
 }
 
-// printTable prints pod interaction related info from the given PodInteractionInfo list
+// handleActionMigrate moves interaction labels/annotations of the specified pods from the old key prefix to the new one.
+// Each pod is updated in a single patch, so it never ends up with partially migrated keys. A controller run with the
+// old key prefix as its --previous-key-prefix recognizes the pods not migrated yet, and migrates them itself.
+func (o *CmdOptions) handleActionMigrate(pods []corev1.Pod) error {
+	failed := 0
+	for _, pod := range pods {
+		labelPatchStrs, migratedLabels := getMigrateJsonPatchStrs("labels", pod.Labels, interactionLabelNames, o.migrateFrom, o.migrateTo)
+		annotationPatchStrs, migratedAnnotations := getMigrateJsonPatchStrs("annotations", pod.Annotations, interactionAnnotationNames, o.migrateFrom, o.migrateTo)
+		patchStrs := append(labelPatchStrs, annotationPatchStrs...)
+		if len(patchStrs) == 0 {
+			continue
+		}
+
+		patchData := []byte(fmt.Sprintf("[%s]", strings.Join(patchStrs, ",")))
+		_, err := o.kubeClient.CoreV1().Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.JSONPatchType, patchData, metav1.PatchOptions{})
+		if err != nil {
+			fmt.Fprintf(o.Out, failedMigrationOfPodMsg, pod.Name, err)
+			failed++
+			continue
+		}
+
+		fmt.Fprintf(o.Out, successMigrationOfPodMsg, migratedLabels+migratedAnnotations, pod.Name, o.migrateFrom, o.migrateTo)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf(cmdMigrationFailedError, failed)
+	}
+
+	return nil
+}
+
+// handleActionHistory prints out the most recent pod interactions, extensions and evictions kept by the controller
+// in the history ConfigMap of the given namespace, optionally of the specified pods only
+func (o *CmdOptions) handleActionHistory() error {
+	configMap, err := o.kubeClient.CoreV1().ConfigMaps(o.namespace).Get(context.TODO(), historyConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf(noHistoryOfNamespaceMsg, o.namespace)
+	}
+	if err != nil {
+		return err
+	}
+
+	records, warnings := getHistoryRecords(*configMap)
+	for _, warning := range warnings {
+		fmt.Fprint(o.Out, warning)
+	}
+
+	specifiedPods := make(map[string]bool, len(o.podNames))
+	for _, podName := range o.podNames {
+		specifiedPods[podName] = true
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(o.Out, 0, 8, 2, '\t', 0)
+	fmt.Fprintln(w, "TIME\tTYPE\tNAMESPACE\tPOD-NAME\tUSER\tDETAILS")
+	for _, record := range records {
+		if !o.specifiedAll && !specifiedPods[record.PodName] {
+			continue
+		}
+
+		details := record.Extension
+		if len(record.Commands) > 0 {
+			details = strings.Join(record.Commands, " ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.Timestamp.Format(time.RFC3339),
+			record.Type,
+			record.PodNamespace,
+			record.PodName,
+			record.Username,
+			details,
+		)
+	}
+
+	return w.Flush()
+}
+
+// printStructured prints the given object in the specified output format, either JSON or YAML
+func (o *CmdOptions) printStructured(obj interface{}) error {
+	if o.outputFormat == outputYAML {
+		output, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(o.Out, string(output))
+		return err
+	}
+
+	output, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(o.Out, string(output))
+	return err
+}
+
+// printTable prints pod interaction related info from the given PodInteractionInfo list, with the namespace of
+// each pod if selected across all namespaces
 func (o *CmdOptions) printTable(infoList []PodInteractionInfo) error {
 	w := new(tabwriter.Writer)
 	// format in tab-separated columns with a tab stop of 8
 	w.Init(o.Out, 0, 8, 2, '\t', 0)
-	fmt.Fprintln(w, "POD-NAME\tINTERACTOR\tPOD-TTL\tEXTENSION\tEXTENSION-REQUESTER\tEVICTION-TIME")
+	if o.allNamespaces {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, "POD-NAME\tINTERACTOR\tPOD-TTL\tEXTENSION\tEXTENSION-REQUESTER\tEVICTION-TIME\tREMAINING")
 	for _, info := range infoList {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s",
-			info.podName,
-			info.interactor,
-			info.ttlDuration,
-			info.extension,
-			info.requester,
-			info.terminationTime,
+		if o.allNamespaces {
+			fmt.Fprintf(w, "%s\t", info.Namespace)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s",
+			info.PodName,
+			info.Interactor,
+			info.TTLDuration,
+			info.Extension,
+			info.Requester,
+			info.TerminationTime,
+			info.Remaining,
 		)
 		fmt.Fprintln(w)
 	}
@@ -235,26 +911,119 @@ func (o *CmdOptions) printTable(infoList []PodInteractionInfo) error {
 	return w.Flush()
 }
 
+// printRawMetadata prints the interaction labels/annotations of the given pod verbatim, including the ones set under
+// another key prefix, to help diagnose a pod partially labeled or labeled under a mismatched prefix
+func (o *CmdOptions) printRawMetadata(pod corev1.Pod) error {
+	fmt.Fprintf(o.Out, "\nRaw labels/annotations of pod/%s:\n", pod.Name)
+	w := new(tabwriter.Writer)
+	w.Init(o.Out, 0, 8, 2, ' ', 0)
+	printed := 0
+	for _, kind := range []struct {
+		name string
+		data map[string]string
+	}{{"label", pod.Labels}, {"annotation", pod.Annotations}} {
+		for _, key := range getRawInteractionKeys(kind.data) {
+			fmt.Fprintf(w, "  %s\t%s=%s\n", kind.name, key, kind.data[key])
+			printed++
+		}
+	}
+	if printed == 0 {
+		fmt.Fprintln(w, "  <none>")
+	}
+
+	return w.Flush()
+}
+
+// printDescription prints the pod interaction info, the extension history and the raw interaction labels/annotations
+// of the given pod
+func (o *CmdOptions) printDescription(pod corev1.Pod) error {
+	info := getPodInteractionInfo(pod, o.now())
+	w := new(tabwriter.Writer)
+	w.Init(o.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", info.PodName)
+	fmt.Fprintf(w, "Namespace:\t%s\n", info.Namespace)
+	fmt.Fprintf(w, "Interactor:\t%s\n", info.Interactor)
+	fmt.Fprintf(w, "Interaction Time:\t%s\n", info.InteractionTime)
+	fmt.Fprintf(w, "Last Interaction Time:\t%s\n", info.LastInteractionTime)
+	fmt.Fprintf(w, "Interaction Count:\t%s\n", info.InteractionCount)
+	fmt.Fprintf(w, "Last Interactor:\t%s\n", info.LastInteractor)
+	fmt.Fprintf(w, "In Use:\t%t\n", info.InUse)
+	fmt.Fprintf(w, "Pod TTL:\t%s\n", info.TTLDuration)
+	fmt.Fprintf(w, "Extension:\t%s\n", info.Extension)
+	fmt.Fprintf(w, "Extension Requester:\t%s\n", info.Requester)
+	fmt.Fprintf(w, "Eviction Time:\t%s\n", info.TerminationTime)
+	fmt.Fprintf(w, "Remaining:\t%s\n", info.Remaining)
+	fmt.Fprintf(w, "Exempt:\t%t\n", info.Exempt)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if err := o.printExtensionHistory(pod); err != nil {
+		return err
+	}
+
+	return o.printRawMetadata(pod)
+}
+
+// printExtensionHistory prints the extension history of the given pod
+func (o *CmdOptions) printExtensionHistory(pod corev1.Pod) error {
+	history, err := getExtensionHistory(pod)
+	if err != nil {
+		fmt.Fprintf(o.Out, invalidExtensionHistoryOfPodMsg, pod.Name, err)
+		return nil
+	}
+	if len(history) == 0 {
+		fmt.Fprintln(o.Out, "Extension History:\t<none>")
+		return nil
+	}
+
+	fmt.Fprintln(o.Out, "Extension History:")
+	w := new(tabwriter.Writer)
+	w.Init(o.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "  REQUESTER\tDURATION\tTIME")
+	for _, record := range history {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", record.Requester, record.Duration, record.Time.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
 // setExtensionMetadata adds metadata to the given pod with the extension related info
-func (o *CmdOptions) setExtensionMetadata(pod corev1.Pod) error {
+// It returns the outcome of the extension request to the pod
+func (o *CmdOptions) setExtensionMetadata(pod corev1.Pod) (extensionOutcome, error) {
 	// pod with no termination label (non-interacted pod)
-	if _, hasTerminationLabel := pod.Labels[podInteractionTimestampLabel]; !hasTerminationLabel {
+	if _, hasTerminationLabel := getInteractionMetadata(pod, podInteractionTimestampLabel); !hasTerminationLabel {
 		fmt.Fprintf(o.Out, noInteractionOfPodMsg, pod.Name)
 
-		return nil
+		return outcomeSkipped, nil
+	}
+
+	// preview the eviction time the controller would compute, as the extension is not counted from now
+	if dueTime, present := getExtendedDueTime(pod, o.extendDurationStr); present {
+		fmt.Fprintf(o.Out, extensionPreviewOfPodMsg, pod.Name, dueTime.String(), o.extendDurationStr)
+		if !dueTime.After(o.now()) {
+			if o.refusePast {
+				fmt.Fprintf(o.Out, refusedPastExtensionOfPodMsg, pod.Name)
+				return outcomeSkipped, nil
+			}
+			fmt.Fprintf(o.Out, pastExtensionOfPodWarningMsg, pod.Name)
+		}
 	}
 
 	// ask confirmation before overwriting an existing extension of a pod
+	outcome := outcomeExtended
 	if extendedDuration, present := pod.Annotations[podExtendDurationAnnotate]; present {
 		fmt.Fprintf(o.Out, extensionExistsOfPodWarningMsg, pod.Name, extendedDuration)
 		confirmed, err := o.askConfirmation(overwriteExtensionPromptMsg)
 		if err != nil {
-			return err
+			return outcomeFailed, err
 		}
 
 		if !confirmed {
-			return nil
+			return outcomeSkipped, nil
 		}
+
+		outcome = outcomeOverwritten
 	}
 
 	// set metadata to the pod with requested extension
@@ -263,21 +1032,89 @@ func (o *CmdOptions) setExtensionMetadata(pod corev1.Pod) error {
 		podExtendDurationAnnotate: o.extendDurationStr,
 	}
 	if _, err := patchAnnotations(pod, patchDataMap, o.kubeClient); err != nil {
-		return err
+		return outcomeFailed, err
 	}
 
 	fmt.Fprintf(o.Out, successExtensionOfPodWithDurationMsg, pod.Name, o.extendDurationStr)
 
-	return nil
+	return outcome, nil
 }
 
-// askConfirmation prompts users to confirm their action by typing "y" or "yes"
+// setReducedExtensionMetadata sets the extension of the given pod to its current one minus the requested duration,
+// after a confirmation if the pod would be evicted soon. It returns the outcome of the reduction request to the pod
+func (o *CmdOptions) setReducedExtensionMetadata(pod corev1.Pod) (extensionOutcome, error) {
+	// pod with no termination label (non-interacted pod)
+	if _, hasTerminationLabel := getInteractionMetadata(pod, podInteractionTimestampLabel); !hasTerminationLabel {
+		fmt.Fprintf(o.Out, noInteractionOfPodMsg, pod.Name)
+
+		return outcomeSkipped, nil
+	}
+
+	reduction, err := duration.Parse(o.extendDurationStr)
+	if err != nil {
+		return outcomeFailed, err
+	}
+	extension := time.Duration(0)
+	if extensionStr, present := pod.Annotations[podExtendDurationAnnotate]; present {
+		if extension, err = duration.Parse(extensionStr); err != nil {
+			return outcomeFailed, err
+		}
+	}
+	reducedExtension := (extension - reduction).String()
+
+	// preview the eviction time the controller would compute, asking confirmation if it has passed already
+	if dueTime, present := getExtendedDueTime(pod, reducedExtension); present {
+		fmt.Fprintf(o.Out, reductionPreviewOfPodMsg, pod.Name, dueTime.String(), reducedExtension)
+		if !dueTime.After(o.now()) {
+			fmt.Fprintf(o.Out, pastReductionOfPodWarningMsg, pod.Name)
+			confirmed, err := o.askConfirmation(pastReductionPromptMsg)
+			if err != nil {
+				return outcomeFailed, err
+			}
+
+			if !confirmed {
+				return outcomeSkipped, nil
+			}
+		}
+	}
+
+	// set metadata to the pod with the reduced extension
+	// we do not add username here as it will be done by the admission controller in the cluster
+	patchDataMap := map[string]string{
+		podExtendDurationAnnotate: reducedExtension,
+	}
+	if _, err := patchAnnotations(pod, patchDataMap, o.kubeClient); err != nil {
+		return outcomeFailed, err
+	}
+
+	fmt.Fprintf(o.Out, successReductionOfPodWithDurationMsg, pod.Name, o.extendDurationStr, reducedExtension)
+
+	return outcomeReduced, nil
+}
+
+// now returns the current time from the clock of the command options
+func (o *CmdOptions) now() time.Time {
+	if o.clock == nil {
+		return time.Now()
+	}
+
+	return o.clock()
+}
+
+// askConfirmation prompts users to confirm their action by typing "y" or "yes", or confirms it automatically
+// if '--yes' is set
 func (o *CmdOptions) askConfirmation(prompt string) (bool, error) {
-	reader := bufio.NewReader(o.In)
+	if o.assumeYes {
+		return true, nil
+	}
+
+	if o.inReader == nil {
+		o.inReader = bufio.NewReader(o.In)
+	}
 
 	for {
 		fmt.Fprintf(o.Out, "%s [y/n]: ", prompt)
-		response, err := reader.ReadString('\n')
+		response, err := o.inReader.ReadString('\n')
 		if err != nil {
 			return false, err
 		}
@@ -2,14 +2,21 @@ package plugin
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"regexp"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
+
+	"github.com/box/kube-exec-controller/pkg/duration"
 )
 
 const (
@@ -22,65 +29,574 @@ const (
     # get interaction info of all pods under the given namespace
     kubectl pi get -n <pod-namespace> --all
 
+    # get interaction info of all pods across all namespaces
+    kubectl pi get -A
+
+    # get interaction info of the pods matching a label selector under the given namespace
+    kubectl pi get -n <pod-namespace> -l <key>=<value>
+
+    # get interaction info of all pods under the given namespace except the ones matching a label selector
+    kubectl pi get -n <pod-namespace> --all --exclude-selector <key>=<value>
+
+    # get interaction info of all pods under the given namespace in JSON (or YAML) for scripting
+    kubectl pi get -n <pod-namespace> --all -o json
+
+    # get interaction info of all pods under the given namespace to be evicted within the given duration
+    kubectl pi get -n <pod-namespace> --all --within <duration>
+
+    # get interaction info of all pods under the given namespace, sorted by their eviction time
+    kubectl pi get -n <pod-namespace> --all --sort-by eviction-time
+
+    # get interaction info of only the interacted pods under the given namespace
+    kubectl pi get -n <pod-namespace> --all --interacted-only
+
+    # get interaction info of specified pod(s) along with their interaction labels/annotations verbatim for troubleshooting
+    kubectl pi get <pod-name-1> <pod-name-2> <...> -n POD_NAMESPACE --show-raw
+
+    # describe interaction info of specified pod(s) in detail, including their extension history and raw labels/annotations
+    kubectl pi describe <pod-name-1> <pod-name-2> <...> -n POD_NAMESPACE
+
     # extend termination time of interacted pod(s)
     kubectl pi extend -d <duration> <pod-name-1> <pod-name-2> <...> -n POD_NAMESPACE
 
     # extend termination time of all interacted pods under the given namespace
     kubectl pi extend -d <duration> -n <pod-namespace> --all
+
+    # extend termination time of all interacted pods under the given namespace, overwriting existing extensions without a prompt
+    kubectl pi extend -d <duration> -n <pod-namespace> --all --yes
+
+    # extend termination time of the interacted pods matching a label selector under the given namespace
+    kubectl pi extend -d <duration> -n <pod-namespace> -l <key>=<value>
+
+    # reduce termination time of interacted pod(s), evicting them soon if it would have passed already
+    kubectl pi reduce -d <duration> <pod-name-1> <pod-name-2> <...> -n POD_NAMESPACE
+
+    # reset extensions of all interacted pods under the given namespace back to their base TTL
+    kubectl pi reset -n <pod-namespace> --all
+
+    # cancel a mistaken extension of interacted pod(s), same as "reset"
+    kubectl pi cancel <pod-name-1> <pod-name-2> <...> -n POD_NAMESPACE
+
+    # hold interacted pod(s) in use to pause their eviction until released
+    kubectl pi hold <pod-name-1> <pod-name-2> <...> -n POD_NAMESPACE
+
+    # release held pod(s) to resume their eviction
+    kubectl pi release <pod-name-1> <pod-name-2> <...> -n POD_NAMESPACE
+
+    # justify interacting pod(s) in namespaces requiring a justification, before running "kubectl exec"
+    kubectl pi justify -r "<reason>" <pod-name-1> <pod-name-2> <...> -n POD_NAMESPACE
+
+    # migrate interaction labels/annotations of all pods under the given namespace from another key prefix
+    kubectl pi migrate --from <old-prefix> -n <pod-namespace> --all
+
+    # list all interacted pods under the given namespace whose termination time has passed, i.e. evicted right away
+    kubectl pi would-evict -n <pod-namespace> --all
+
+    # get the most recent interactions, extensions and evictions kept by the controller in its history namespace
+    kubectl pi history -n <history-namespace>
+
+    # get the most recent interactions, extensions and evictions of specified pod(s) only
+    kubectl pi history <pod-name-1> <pod-name-2> <...> -n <history-namespace>
 `
 
-	cmdGetAction    = "get"
-	cmdExtendAction = "extend"
+	cmdGetAction        = "get"
+	cmdDescribeAction   = "describe"
+	cmdExtendAction     = "extend"
+	cmdReduceAction     = "reduce"
+	cmdResetAction      = "reset"
+	cmdCancelAction     = "cancel"
+	cmdHoldAction       = "hold"
+	cmdReleaseAction    = "release"
+	cmdMigrateAction    = "migrate"
+	cmdJustifyAction    = "justify"
+	cmdHistoryAction    = "history"
+	cmdWouldEvictAction = "would-evict"
+
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+
+	sortByName         = "name"
+	sortByEvictionTime = "eviction-time"
+
+	metadataLabels      = "labels"
+	metadataAnnotations = "annotations"
 
 	cmdArgsLengthError      = "expecting at least one argument"
-	cmdInvalidActionError   = "expecting an action of either 'get' or 'extend' in the command"
-	cmdInValidDurationError = "expecting an duration in the following format: 30s, 10m, 6h, 1d, etc"
+	cmdInvalidActionError   = "expecting an action of either 'get', 'describe', 'extend', 'reduce', 'reset', 'cancel', 'hold', 'release', 'justify', 'migrate', 'history' or 'would-evict' in the command"
+	cmdInValidDurationError = "expecting an duration in the following format: 30s, 10m, 6h, 1d, 1w, etc"
+
+	cmdInvalidSelectorError        = "expecting a valid label selector in '--selector': %v"
+	cmdSelectorWithPodNamesError   = "expecting either '--selector' or pod names set, not both"
+	cmdInvalidExcludeSelectorError = "expecting a valid label selector in '--exclude-selector': %v"
+	cmdExtensionFailedError        = "failed to extend the termination time of %d pod(s)"
+	cmdReductionFailedError        = "failed to reduce the termination time of %d pod(s)"
+	cmdResetFailedError            = "failed to reset the extension of %d pod(s)"
+	cmdInvalidMigratePrefixError   = "expecting two different key prefixes set in '--from' and '--to'"
+	cmdMigrationFailedError        = "failed to migrate the labels/annotations of %d pod(s)"
+	cmdMissingReasonError          = "expecting a justification set in '--reason'"
+	cmdJustificationFailedError    = "failed to justify %d pod(s)"
+	cmdHoldFailedError             = "failed to hold %d pod(s) in use"
+	cmdReleaseFailedError          = "failed to release %d pod(s)"
+	cmdInvalidWithinError          = "expecting a duration in '--within' in the following format: 30s, 10m, 6h, 1d, 1w, etc"
+	cmdInvalidOutputError          = "expecting an output format of either 'table', 'json' or 'yaml' in '--output'"
+	cmdInvalidAllNamespacesError   = "expecting '--all-namespaces' set only to get all pods, without any pod name"
+	cmdInvalidShowRawError         = "expecting '--show-raw' set only to get pods in the table output"
+	cmdInvalidInteractedOnlyError  = "expecting '--interacted-only' set only to get pods"
+	cmdInvalidMetadataError        = "expecting '--interaction-metadata' of either 'labels' or 'annotations'"
+	cmdInvalidKeyPrefixError       = "expecting a valid DNS subdomain in '--key-prefix', got %q: %s"
+	cmdInvalidContextError         = "expecting a context in '--context' existing in the kubeconfig, got %q"
+	cmdInvalidSortByError          = "expecting '--sort-by' of either 'name' or 'eviction-time', set only to get pods"
 
 	noPodReturnedOfNamespaceMsg          = "no pods returned under the namespace '%s'\n"
+	noPodReturnedOfAllNamespacesMsg      = "no pods returned across all namespaces\n"
 	noInteractionOfPodMsg                = "no interaction detected from the pod/%s\n"
+	noOverduePodOfNamespaceMsg           = "no interacted pods would be evicted now under the namespace '%s'\n"
 	extensionExistsOfPodWarningMsg       = "Warning: pod/%s is already annotated with an extension=%s\n"
 	overwriteExtensionPromptMsg          = "Please confirm to overwrite the existing extension"
+	extensionPreviewOfPodMsg             = "pod/%s would be evicted at %s (uncapped, the cluster's max extension if any is not applied), as the extension=%s is added to its TTL counted from its interaction (replacing any existing extension), not from now\n"
+	pastExtensionOfPodWarningMsg         = "Warning: the eviction time of pod/%s would be in the past, it would be evicted right away\n"
+	refusedPastExtensionOfPodMsg         = "Refused to extend pod/%s as its eviction time would be in the past ('--refuse-past' set)\n"
 	successExtensionOfPodWithDurationMsg = "Successfully extended the termination time of pod/%s with a duration=%s\n"
+	failedExtensionOfPodMsg              = "Failed to extend the termination time of pod/%s: %v\n"
+	extensionSummaryMsg                  = "Summary: %d extended, %d overwritten, %d skipped, %d failed\n"
+	reductionPreviewOfPodMsg             = "pod/%s would be evicted at %s (uncapped, the cluster's max extension if any is not applied), as the reduced extension=%s is added to its TTL counted from its interaction\n"
+	pastReductionOfPodWarningMsg         = "Warning: the eviction time of pod/%s would be in the past, it would be evicted soon\n"
+	pastReductionPromptMsg               = "Please confirm to reduce the termination time"
+	successReductionOfPodWithDurationMsg = "Successfully reduced the termination time of pod/%s by a duration=%s, to an extension=%s\n"
+	failedReductionOfPodMsg              = "Failed to reduce the termination time of pod/%s: %v\n"
+	reductionSummaryMsg                  = "Summary: %d reduced, %d skipped, %d failed\n"
+	noExtensionOfPodMsg                  = "no extension to reset from the pod/%s\n"
+	resetExtensionPromptMsg              = "Please confirm to reset the extension of %d pod(s) back to their base TTL"
+	successResetOfPodMsg                 = "Successfully reset the extension of pod/%s\n"
+	failedResetOfPodMsg                  = "Failed to reset the extension of pod/%s: %v\n"
+	resetSummaryMsg                      = "Summary: %d reset, %d skipped, %d failed\n"
+	successMigrationOfPodMsg             = "Successfully migrated %d label(s)/annotation(s) of pod/%s from prefix '%s' to '%s'\n"
+	failedMigrationOfPodMsg              = "Failed to migrate the labels/annotations of pod/%s: %v\n"
+	successJustificationOfPodMsg         = "Successfully justified interacting pod/%s\n"
+	failedJustificationOfPodMsg          = "Failed to justify interacting pod/%s: %v\n"
+	invalidExtensionHistoryOfPodMsg      = "Warning: failed to parse the extension history of pod/%s: %v\n"
+	noHistoryOfNamespaceMsg              = "no history kept by the controller under the namespace '%s'\n"
+	invalidHistoryRecordMsg              = "Warning: failed to parse the history record in slot %s: %v\n"
+	alreadyHeldOfPodMsg                  = "pod/%s is already held in use\n"
+	successHoldOfPodMsg                  = "Successfully held pod/%s in use, its eviction is paused until released or its max hold expires\n"
+	failedHoldOfPodMsg                   = "Failed to hold pod/%s in use: %v\n"
+	notHeldOfPodMsg                      = "pod/%s is not held in use\n"
+	successReleaseOfPodMsg               = "Successfully released pod/%s, its eviction is resumed\n"
+	failedReleaseOfPodMsg                = "Failed to release pod/%s: %v\n"
+
+	// remainingExpired is shown as the remaining time of pods whose termination time has passed
+	remainingExpired = "EXPIRED"
+
+	// terminationTimeLayout is the layout of time.Time.String, which the controller annotates termination time in
+	terminationTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
 
 	defaultExtendDuration = "30m"
+	defaultKeyPrefix      = "box.com"
+
+	// keyPrefixEnv is the environment variable setting the default of '--key-prefix', which also sets the
+	// controller's one (as its KEC_ prefixed flags), so both can share it
+	keyPrefixEnv = "KEC_KEY_PREFIX"
+
+	// The following names must match to the constants defined in controller/history_configmap.go file
+	historyConfigMapName = "kube-exec-controller-history"
+	historyNextSlotKey   = "next"
+)
+
+var (
+	// The following label/annotation names must match to the ones defined in controller/kube_helper.go file, which are
+	// renamed under the key prefix set by setKeyPrefix the same way as the controller
+	podInteractionTimestampLabel        = "box.com/podInitialInteractionTimestamp"
+	podInteractorLabel                  = "box.com/podInteractorUsername"
+	podTTLDurationLabel                 = "box.com/podTTLDuration"
+	podExtendDurationAnnotate           = "box.com/podExtendedDuration"
+	podExtendRequesterAnnotate          = "box.com/podExtensionRequester"
+	podTerminationTimeAnnotate          = "box.com/podTerminationTime"
+	podInteractorClientAnnotate         = "box.com/podInteractorClientInfo"
+	podExtensionHistoryAnnotate         = "box.com/podExtensionHistory"
+	podInUseAnnotate                    = "box.com/podInUse"
+	podLastInteractionTimestampAnnotate = "box.com/podLastInteractionTimestamp"
+	podInteractionCountAnnotate         = "box.com/podInteractionCount"
+	podLastInteractorAnnotate           = "box.com/podLastInteractorUsername"
+	podDisableEvictionAnnotate          = "box.com/disableExecEviction"
 
-	// The following label/annotation names must match to the constants defined in controller/kube_helper.go file
-	podInteractionTimestampLabel = "box.com/podInitialInteractionTimestamp"
-	podInteractorLabel           = "box.com/podInteractorUsername"
-	podTTLDurationLabel          = "box.com/podTTLDuration"
-	podExtendDurationAnnotate    = "box.com/podExtendedDuration"
-	podExtendRequesterAnnotate   = "box.com/podExtensionRequester"
-	podTerminationTimeAnnotate   = "box.com/podTerminationTime"
+	podExecJustificationAnnotate     = "box.com/execJustification"
+	podExecJustificationTimeAnnotate = "box.com/execJustificationTimestamp"
+)
+
+// The names of interaction labels/annotations without their key prefix
+var (
+	interactionLabelNames = []string{
+		getKeyName(podInteractionTimestampLabel),
+		getKeyName(podInteractorLabel),
+		getKeyName(podTTLDurationLabel),
+		// the interaction count is left over as a label by previous versions
+		getKeyName(podInteractionCountAnnotate),
+	}
+	// the interaction labels are stored as annotations if configured in the controller
+	interactionAnnotationNames = []string{
+		getKeyName(podInteractionTimestampLabel),
+		getKeyName(podInteractorLabel),
+		getKeyName(podTTLDurationLabel),
+		getKeyName(podInteractionCountAnnotate),
+		getKeyName(podExtendDurationAnnotate),
+		getKeyName(podExtendRequesterAnnotate),
+		getKeyName(podTerminationTimeAnnotate),
+		getKeyName(podInteractorClientAnnotate),
+		getKeyName(podLastInteractorAnnotate),
+		getKeyName(podExtensionHistoryAnnotate),
+		getKeyName(podInUseAnnotate),
+		getKeyName(podLastInteractionTimestampAnnotate),
+	}
 )
 
 // isValidAction returns if the given action is valid in the command
 func isValidAction(action string) bool {
 	action = strings.ToLower(action)
 
-	return action == cmdGetAction || action == cmdExtendAction
+	return action == cmdGetAction || action == cmdDescribeAction || action == cmdExtendAction || action == cmdReduceAction || action == cmdResetAction || action == cmdCancelAction || action == cmdHoldAction || action == cmdReleaseAction || action == cmdMigrateAction || action == cmdJustifyAction || action == cmdHistoryAction || action == cmdWouldEvictAction
+}
+
+// isValidOutputFormat returns if the given output format is supported by the 'get' and 'would-evict' actions
+func isValidOutputFormat(format string) bool {
+	return format == outputTable || format == outputJSON || format == outputYAML
+}
+
+// isValidSortBy returns if the given sort key is supported by the 'get' action
+func isValidSortBy(sortBy string) bool {
+	return sortBy == sortByName || sortBy == sortByEvictionTime
+}
+
+// isValidDuration returns if the given duration is in valid format and positive, as parsed by the controller
+func isValidDuration(durationStr string) bool {
+	// example valid duration format: 30s, 20m, 90m, 6h, 1h30m, 1d, 1w, 1d12h
+	d, err := duration.Parse(durationStr)
+
+	return err == nil && d > 0
+}
+
+// setKeyPrefix sets the prefix of all interaction labels/annotations to the given one, which must match to the key
+// prefix set in the controller, or returns an error if it is not a valid DNS subdomain
+func setKeyPrefix(prefix string) error {
+	if errs := validation.IsDNS1123Subdomain(prefix); len(errs) > 0 {
+		return fmt.Errorf(cmdInvalidKeyPrefixError, prefix, strings.Join(errs, ", "))
+	}
+
+	for _, key := range []*string{
+		&podInteractionTimestampLabel,
+		&podInteractorLabel,
+		&podTTLDurationLabel,
+		&podExtendDurationAnnotate,
+		&podExtendRequesterAnnotate,
+		&podTerminationTimeAnnotate,
+		&podInteractorClientAnnotate,
+		&podExtensionHistoryAnnotate,
+		&podInUseAnnotate,
+		&podLastInteractionTimestampAnnotate,
+		&podInteractionCountAnnotate,
+		&podLastInteractorAnnotate,
+		&podDisableEvictionAnnotate,
+		&podExecJustificationAnnotate,
+		&podExecJustificationTimeAnnotate,
+	} {
+		*key = prefix + "/" + getKeyName(*key)
+	}
+
+	return nil
+}
+
+// getDefaultKeyPrefix returns the key prefix set in the KEC_KEY_PREFIX environment variable, or box.com if not set
+func getDefaultKeyPrefix() string {
+	if prefix := os.Getenv(keyPrefixEnv); prefix != "" {
+		return prefix
+	}
+
+	return defaultKeyPrefix
+}
+
+// getKeyPrefix returns the prefix part of the given label/annotation key without its name
+func getKeyPrefix(key string) string {
+	return key[:strings.Index(key, "/")+1]
+}
+
+// getKeyName returns the name part of the given label/annotation key without its prefix
+func getKeyName(key string) string {
+	return key[strings.Index(key, "/")+1:]
+}
+
+// getRawInteractionKeys returns the sorted keys of the given labels/annotations set under the key prefix,
+// or named as an interaction label/annotation under any other prefix
+func getRawInteractionKeys(data map[string]string) []string {
+	var keys []string
+	for key := range data {
+		if strings.HasPrefix(key, getKeyPrefix(podInteractorLabel)) || (strings.Contains(key, "/") && isInteractionKeyName(getKeyName(key))) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// isInteractionKeyName returns if the given name is of an interaction label/annotation
+func isInteractionKeyName(name string) bool {
+	for _, n := range interactionAnnotationNames {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getMigrateJsonPatchStrs returns Json patch strings moving the interaction related keys of the given metadata
+// from the old prefix to the new one, and the number of keys moved. A key already set with the new prefix is kept as is.
+func getMigrateJsonPatchStrs(dataType string, data map[string]string, keyNames []string, from, to string) ([]string, int) {
+	var patchStrs []string
+	migrated := 0
+	for _, name := range keyNames {
+		oldKey, newKey := from+"/"+name, to+"/"+name
+		val, present := data[oldKey]
+		if !present {
+			continue
+		}
+
+		if _, exists := data[newKey]; !exists {
+			valJSON, _ := json.Marshal(val)
+			patchStrs = append(patchStrs, fmt.Sprintf("{\"op\":\"add\",\"path\":\"/metadata/%s/%s\",\"value\":%s}",
+				dataType, escapeJsonPointer(newKey), valJSON))
+		}
+		patchStrs = append(patchStrs, getRemoveJsonPatchStr(dataType, oldKey))
+		migrated++
+	}
+
+	return patchStrs, migrated
+}
+
+// getRemoveJsonPatchStr returns a Json patch string removing the given key from the given metadata type
+func getRemoveJsonPatchStr(dataType, key string) string {
+	return fmt.Sprintf("{\"op\":\"remove\",\"path\":\"/metadata/%s/%s\"}", dataType, escapeJsonPointer(key))
+}
+
+// escapeJsonPointer replaces invalid characters from key to satisfy Json patch format
+func escapeJsonPointer(key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	key = strings.ReplaceAll(key, "/", "~1")
+
+	return key
 }
 
-// isValidDuration returns if the given duration is in valid format
-func isValidDuration(duration string) bool {
-	// example valid duration format: 30s, 20m, 6h, 1d
-	validFormat := regexp.MustCompile(`^[0-9]+[smhd]$`)
+// getInteractionMetadata returns the value of the given interaction label of the given pod, which is looked up in
+// its annotations too as the controller can be configured to store it there
+func getInteractionMetadata(pod corev1.Pod, key string) (string, bool) {
+	if val, present := pod.Labels[key]; present {
+		return val, true
+	}
 
-	return validFormat.MatchString(duration)
+	val, present := pod.Annotations[key]
+	return val, present
 }
 
-// getPodInteractionInfo constructs a PodInteractionInfo by parsing the metadata of the given pod
-func getPodInteractionInfo(pod corev1.Pod) PodInteractionInfo {
-	labels := pod.GetLabels()
+// getPodInteractionInfo constructs a PodInteractionInfo by parsing the metadata of the given pod, with its
+// remaining time until eviction at the given current time, or EXPIRED once its termination time has passed, and
+// whether it is annotated to be exempt from eviction
+func getPodInteractionInfo(pod corev1.Pod, now time.Time) PodInteractionInfo {
 	annotations := pod.GetAnnotations()
+	interactor, _ := getInteractionMetadata(pod, podInteractorLabel)
+	ttlDuration, _ := getInteractionMetadata(pod, podTTLDurationLabel)
+	// the interaction count is an annotation, unless left over as a label by previous versions
+	interactionCount, present := annotations[podInteractionCountAnnotate]
+	if !present {
+		interactionCount = pod.Labels[podInteractionCountAnnotate]
+	}
+	var interactionTimeStr, lastInteractionTimeStr string
+	timestamp, _ := getInteractionMetadata(pod, podInteractionTimestampLabel)
+	if interactionTime, err := parseUnixTime(timestamp); err == nil {
+		interactionTimeStr = interactionTime.String()
+	}
+	if lastInteractionTime, err := parseUnixTime(annotations[podLastInteractionTimestampAnnotate]); err == nil {
+		lastInteractionTimeStr = lastInteractionTime.String()
+	}
+	var remainingStr string
+	if remaining, present := getRemainingTime(pod, now); present {
+		remainingStr = remaining.String()
+		if remaining == 0 {
+			remainingStr = remainingExpired
+		}
+	}
 
 	return PodInteractionInfo{
-		podName:         pod.Name,
-		interactor:      labels[podInteractorLabel],
-		ttlDuration:     labels[podTTLDurationLabel],
-		extension:       annotations[podExtendDurationAnnotate],
-		requester:       annotations[podExtendRequesterAnnotate],
-		terminationTime: annotations[podTerminationTimeAnnotate],
+		PodName:             pod.Name,
+		Namespace:           pod.Namespace,
+		Interactor:          interactor,
+		TTLDuration:         ttlDuration,
+		Extension:           annotations[podExtendDurationAnnotate],
+		Requester:           annotations[podExtendRequesterAnnotate],
+		TerminationTime:     annotations[podTerminationTimeAnnotate],
+		Remaining:           remainingStr,
+		InteractionTime:     interactionTimeStr,
+		LastInteractionTime: lastInteractionTimeStr,
+		InteractionCount:    interactionCount,
+		LastInteractor:      annotations[podLastInteractorAnnotate],
+		InUse:               annotations[podInUseAnnotate] == "true",
+		Exempt:              annotations[podDisableEvictionAnnotate] == "true",
+	}
+}
+
+// sortPodInteractionInfo sorts the given PodInteractionInfo list by the given sort key, either by namespace and
+// pod name, or by eviction time with the pods of no valid termination time last. Ties keep their order by name.
+func sortPodInteractionInfo(infoList []PodInteractionInfo, sortBy string) {
+	sort.SliceStable(infoList, func(i, j int) bool {
+		if infoList[i].Namespace != infoList[j].Namespace {
+			return infoList[i].Namespace < infoList[j].Namespace
+		}
+		return infoList[i].PodName < infoList[j].PodName
+	})
+	if sortBy != sortByEvictionTime {
+		return
+	}
+
+	sort.SliceStable(infoList, func(i, j int) bool {
+		iTime, iErr := parseTerminationTime(infoList[i].TerminationTime)
+		jTime, jErr := parseTerminationTime(infoList[j].TerminationTime)
+		if iErr != nil || jErr != nil {
+			return iErr == nil && jErr != nil
+		}
+		return iTime.Before(jTime)
+	})
+}
+
+// getRemainingTime returns the time remaining at the given current time until the given pod gets evicted,
+// rounded to seconds and zero once due. It returns false if the pod has no valid termination time.
+func getRemainingTime(pod corev1.Pod, now time.Time) (time.Duration, bool) {
+	terminationTime, err := parseTerminationTime(pod.Annotations[podTerminationTimeAnnotate])
+	if err != nil {
+		return 0, false
+	}
+
+	remaining := terminationTime.Sub(now).Round(time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// getDueTime returns the termination time of the given pod computed from its interaction metadata the same way as
+// the controller, from its latest interaction if recorded, though without any max extension the controller caps.
+// It falls back to the annotated termination time if the metadata is invalid, and returns false if neither is valid.
+func getDueTime(pod corev1.Pod) (time.Time, bool) {
+	annotatedTime, annotatedErr := parseTerminationTime(pod.Annotations[podTerminationTimeAnnotate])
+
+	timestamp, _ := getInteractionMetadata(pod, podInteractionTimestampLabel)
+	interactedTime, err := parseUnixTime(timestamp)
+	if err != nil {
+		return annotatedTime, annotatedErr == nil
 	}
+	if lastTime, err := parseUnixTime(pod.Annotations[podLastInteractionTimestampAnnotate]); err == nil &&
+		lastTime.After(interactedTime) {
+		interactedTime = lastTime
+	}
+
+	ttl, _ := getInteractionMetadata(pod, podTTLDurationLabel)
+	ttlDuration, err := duration.Parse(ttl)
+	if err != nil {
+		return annotatedTime, annotatedErr == nil
+	}
+
+	extension := time.Duration(0)
+	if extensionStr, present := pod.Annotations[podExtendDurationAnnotate]; present {
+		if extension, err = duration.Parse(extensionStr); err != nil {
+			return annotatedTime, annotatedErr == nil
+		}
+	}
+
+	return interactedTime.Add(ttlDuration).Add(extension), true
+}
+
+// getExtendedDueTime returns the termination time the controller would compute for the given pod once extended by the
+// given duration, which replaces any existing extension. It returns false if the interaction metadata is invalid.
+// Like getDueTime, it is uncapped, as the max extension is only known by the webhook and the controller.
+func getExtendedDueTime(pod corev1.Pod, extension string) (time.Time, bool) {
+	annotations := make(map[string]string, len(pod.Annotations)+1)
+	for key, val := range pod.Annotations {
+		// not to fall back to the annotated termination time, which is prior to the extension
+		if key != podTerminationTimeAnnotate {
+			annotations[key] = val
+		}
+	}
+	annotations[podExtendDurationAnnotate] = extension
+	pod.Annotations = annotations
+
+	return getDueTime(pod)
+}
+
+// parseUnixTime parses the given Unix time string, as the interaction timestamp is labeled by the controller
+func parseUnixTime(str string) (time.Time, error) {
+	timeInt, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(timeInt, 0), nil
+}
+
+// parseTerminationTime parses the termination time annotated by the controller in the format of time.Time.String,
+// ignoring the monotonic clock reading it may carry
+func parseTerminationTime(str string) (time.Time, error) {
+	if i := strings.Index(str, " m="); i >= 0 {
+		str = str[:i]
+	}
+
+	return time.Parse(terminationTimeLayout, str)
+}
+
+// getExtensionHistory returns the extension history recorded by the controller in the annotation of the given pod
+func getExtensionHistory(pod corev1.Pod) ([]extensionRecord, error) {
+	var history []extensionRecord
+	historyJSON, present := pod.Annotations[podExtensionHistoryAnnotate]
+	if !present || historyJSON == "" {
+		return history, nil
+	}
+
+	if err := json.Unmarshal([]byte(historyJSON), &history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// getHistoryRecords returns the records kept in the slots of the given history ConfigMap, from the oldest to the
+// newest one. The ring buffer wraps at the slot to write the next record to, so the slots from it onwards are the
+// oldest ones. It also returns a warning message for each slot failing to be parsed.
+func getHistoryRecords(configMap corev1.ConfigMap) ([]historyRecord, []string) {
+	next, _ := strconv.Atoi(configMap.Data[historyNextSlotKey])
+	var slots []int
+	for key := range configMap.Data {
+		if slot, err := strconv.Atoi(key); err == nil {
+			slots = append(slots, slot)
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		// slots from the next one onwards come first
+		if (slots[i] >= next) != (slots[j] >= next) {
+			return slots[i] >= next
+		}
+		return slots[i] < slots[j]
+	})
+
+	var records []historyRecord
+	var warnings []string
+	for _, slot := range slots {
+		key := strconv.Itoa(slot)
+		var record historyRecord
+		if err := json.Unmarshal([]byte(configMap.Data[key]), &record); err != nil {
+			warnings = append(warnings, fmt.Sprintf(invalidHistoryRecordMsg, key, err))
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records, warnings
 }
 
 // patchAnnotations will update a K8s pod with given metadata type and values stored from a map.
@@ -110,9 +626,8 @@ func getAnnotatedJsonPatchStr(key, val string) string {
 		return "{\"op\":\"add\",\"path\":\"/metadata/annotations\",\"value\":{}}"
 	}
 
-	// replace invalid characters from key to satisfy Json patchAnnotations format
-	key = strings.ReplaceAll(key, "~", "~0")
-	key = strings.ReplaceAll(key, "/", "~1")
+	// quote the value as a Json string, as it may contain characters like '"' (e.g. a justification)
+	valJSON, _ := json.Marshal(val)
 
-	return fmt.Sprintf("{\"op\":\"add\",\"path\":\"/metadata/annotations/%s\",\"value\":\"%s\"}", key, val)
+	return fmt.Sprintf("{\"op\":\"add\",\"path\":\"/metadata/annotations/%s\",\"value\":%s}", escapeJsonPointer(key), valJSON)
 }
@@ -2,7 +2,13 @@ package plugin
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,6 +20,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
 )
 
 func TestEmptyCommand(t *testing.T) {
@@ -31,6 +38,70 @@ func TestInvalidAction(t *testing.T) {
 	checkErrMsg(t, err, cmdInvalidActionError)
 }
 
+// testKubeconfig is a kubeconfig of two contexts targeting different servers
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: default-cluster
+  cluster:
+    server: https://default.example.com:6443
+- name: other-cluster
+  cluster:
+    server: https://other.example.com:6443
+contexts:
+- name: default-context
+  context:
+    cluster: default-cluster
+    namespace: default-namespace
+    user: test-user
+- name: other-context
+  context:
+    cluster: other-cluster
+    namespace: other-namespace
+    user: test-user
+current-context: default-context
+users:
+- name: test-user
+  user:
+    token: test-token
+`
+
+func TestCompleteWithContext(t *testing.T) {
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(kubeconfigPath, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		context           string
+		expectedServer    string
+		expectedNamespace string
+	}{
+		{"", "default.example.com:6443", "default-namespace"},
+		{"other-context", "other.example.com:6443", "other-namespace"},
+	}
+	newOptions := func(context string) *CmdOptions {
+		opts := NewCmdOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+		opts.keyPrefix = getDefaultKeyPrefix()
+		opts.configFlags.KubeConfig = &kubeconfigPath
+		opts.configFlags.Context = &context
+		return opts
+	}
+	for _, tc := range testCases {
+		opts := newOptions(tc.context)
+		if err := opts.Complete([]string{cmdGetAction}); err != nil {
+			t.Fatal(err)
+		}
+
+		checkMatches(t, tc.expectedServer, opts.kubeClient.CoreV1().RESTClient().Get().URL().Host)
+		checkMatches(t, tc.expectedNamespace, opts.namespace)
+	}
+
+	// testing a context not found in the kubeconfig
+	err := newOptions("invalid-context").Complete([]string{cmdGetAction})
+	checkErrMsg(t, err, fmt.Sprintf(cmdInvalidContextError, "invalid-context"))
+}
+
 func TestInvalidArguments(t *testing.T) {
 	testCmd := getTestInstance().cmd
 
@@ -48,6 +119,11 @@ func TestInvalidArguments(t *testing.T) {
 	testCmd.Flags().Set("duration", "30 minutes")
 	err = testCmd.RunE(testCmd, []string{cmdExtendAction, "test-pod"})
 	checkErrMsg(t, err, cmdInValidDurationError)
+
+	// testing unsupported value set for "--output"
+	testCmd.Flags().Set("output", "xml")
+	err = testCmd.RunE(testCmd, []string{cmdGetAction, "test-pod"})
+	checkErrMsg(t, err, cmdInvalidOutputError)
 }
 
 func TestGetSpecifiedPods(t *testing.T) {
@@ -88,6 +164,172 @@ func TestGetSpecifiedPods(t *testing.T) {
 	}
 }
 
+func TestGetSpecifiedPodsInteractedOnly(t *testing.T) {
+	testNamespace := "test-ns"
+	labeledPod := getFakePod("test-pod-labeled", testNamespace,
+		map[string]string{podInteractionTimestampLabel: "1634407604", "app": "web"}, nil)
+	otherLabeledPod := getFakePod("test-pod-labeled-other", testNamespace,
+		map[string]string{podInteractionTimestampLabel: "1634407604", "app": "db"}, nil)
+	annotatedPod := getFakePod("test-pod-annotated", testNamespace, nil,
+		map[string]string{podInteractionTimestampLabel: "1634407604"})
+	noInteractionPod := getFakePod("test-pod-none", testNamespace, map[string]string{"app": "web"}, nil)
+	fakeClient := fake.NewSimpleClientset(labeledPod, otherLabeledPod, annotatedPod, noInteractionPod)
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	fakeOptions.namespace = testNamespace
+	fakeOptions.specifiedAll = true
+	fakeOptions.interactedOnly = true
+	getPodNames := func() string {
+		resPods, err := fakeOptions.getSpecifiedPods()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, pod := range resPods {
+			names = append(names, pod.Name)
+		}
+		sort.Strings(names)
+		return strings.Join(names, ",")
+	}
+
+	// testing the interacted pods are selected by their label on the API server, along with the given selector
+	checkMatches(t, strings.Join([]string{labeledPod.Name, otherLabeledPod.Name}, ","), getPodNames())
+	fakeOptions.selector = "app=web"
+	checkMatches(t, strings.Join([]string{labeledPod.Name}, ","), getPodNames())
+
+	// testing all pods are listed if the interactions are stored as annotations, to be filtered by the 'get' action
+	fakeOptions.selector = ""
+	fakeOptions.metadata = metadataAnnotations
+	checkMatches(t, strings.Join([]string{annotatedPod.Name, labeledPod.Name, otherLabeledPod.Name, noInteractionPod.Name}, ","), getPodNames())
+
+	// testing an invalid metadata type
+	fakeOptions.action = cmdGetAction
+	fakeOptions.metadata = "configmap"
+	checkErrMsg(t, fakeOptions.Validate(), cmdInvalidMetadataError)
+}
+
+func TestGetSpecifiedPodsWithExcludeSelector(t *testing.T) {
+	testNamespace := "test-ns"
+	testPod1 := getFakePod("test-pod-1", testNamespace, nil, nil)
+	testPod2 := getFakePod("test-pod-2", testNamespace, map[string]string{"tier": "system"}, nil)
+	testPod3 := getFakePod("test-pod-3", testNamespace, map[string]string{"tier": "app"}, nil)
+	fakeClient := fake.NewSimpleClientset(testPod1, testPod2, testPod3)
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	fakeOptions.namespace = testNamespace
+	fakeOptions.specifiedAll = true
+
+	// testing pods matching the exclude selector are not returned
+	fakeOptions.excludeSelector = "tier=system"
+	resPods, err := fakeOptions.getSpecifiedPods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	podExistMap := make(map[string]bool)
+	for _, pod := range resPods {
+		podExistMap[pod.Name] = true
+	}
+	if len(resPods) != 2 || !podExistMap[testPod1.Name] || !podExistMap[testPod3.Name] {
+		t.Fatalf("expecting only %s and %s but got %v", testPod1.Name, testPod3.Name, podExistMap)
+	}
+
+	// testing an invalid exclude selector
+	fakeOptions.excludeSelector = "tier in (system"
+	if _, err := fakeOptions.getSpecifiedPods(); err == nil {
+		t.Fatal("expecting an error from an invalid exclude selector but error = nil")
+	}
+}
+
+func TestGetSpecifiedPodsWithSelector(t *testing.T) {
+	testNamespace := "test-ns"
+	testPod1 := getFakePod("test-pod-1", testNamespace, map[string]string{"app": "web"}, nil)
+	testPod2 := getFakePod("test-pod-2", testNamespace, map[string]string{"app": "web", "tier": "canary"}, nil)
+	testPod3 := getFakePod("test-pod-3", testNamespace, map[string]string{"app": "db"}, nil)
+	fakeClient := fake.NewSimpleClientset(testPod1, testPod2, testPod3)
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	fakeOptions.namespace = testNamespace
+	fakeOptions.action = cmdExtendAction
+	fakeOptions.extendDurationStr = defaultExtendDuration
+	fakeOptions.specifiedAll = true
+
+	// testing only the pods matching the selector are returned
+	fakeOptions.selector = "app=web"
+	resPods, err := fakeOptions.getSpecifiedPods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	podExistMap := make(map[string]bool)
+	for _, pod := range resPods {
+		podExistMap[pod.Name] = true
+	}
+	if len(resPods) != 2 || !podExistMap[testPod1.Name] || !podExistMap[testPod2.Name] {
+		t.Fatalf("expecting only %s and %s but got %v", testPod1.Name, testPod2.Name, podExistMap)
+	}
+
+	// testing the selector combined with the exclude selector
+	fakeOptions.excludeSelector = "tier=canary"
+	resPods, err = fakeOptions.getSpecifiedPods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resPods) != 1 || resPods[0].Name != testPod1.Name {
+		t.Fatalf("expecting only %s but got %v", testPod1.Name, resPods)
+	}
+
+	// testing a valid selector passes the validation
+	if err := fakeOptions.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// testing an invalid selector
+	fakeOptions.selector = "app in (web"
+	if err := fakeOptions.Validate(); err == nil || !strings.Contains(err.Error(), "expecting a valid label selector in '--selector'") {
+		t.Fatalf("expecting an error from an invalid selector but got %v", err)
+	}
+
+	// testing the selector cannot be set with pod names
+	fakeOptions.selector = "app=web"
+	fakeOptions.podNames = []string{testPod1.Name}
+	checkErrMsg(t, fakeOptions.Validate(), cmdSelectorWithPodNamesError)
+}
+
+func TestGetSpecifiedPodsAcrossAllNamespaces(t *testing.T) {
+	testPod1 := getFakePod("test-pod-1", "test-ns-1", map[string]string{podInteractorLabel: "test-interactor-1"}, nil)
+	testPod2 := getFakePod("test-pod-2", "test-ns-2", map[string]string{podInteractorLabel: "test-interactor-2"}, nil)
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fake.NewSimpleClientset(testPod1, testPod2)
+	fakeOptions.namespace = "test-ns-1"
+	fakeOptions.action = cmdGetAction
+	fakeOptions.specifiedAll = true
+	fakeOptions.allNamespaces = true
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+
+	// testing the pods in both namespaces are selected
+	resPods, err := fakeOptions.getSpecifiedPods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resPods) != 2 {
+		t.Fatalf("expecting two pods but got %v", len(resPods))
+	}
+
+	// testing the table output includes the namespace of each pod
+	testOut.Reset()
+	if err := fakeOptions.handleActionGet(resPods); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{"NAMESPACE", "test-ns-1", "test-pod-1", "test-ns-2", "test-pod-2"}, testOut.String())
+
+	// testing all namespaces cannot be selected with a pod name or for another action
+	fakeOptions.podNames = []string{"test-pod-1"}
+	checkErrMsg(t, fakeOptions.Validate(), cmdInvalidAllNamespacesError)
+	fakeOptions.podNames = nil
+	fakeOptions.action = cmdExtendAction
+	checkErrMsg(t, fakeOptions.Validate(), cmdInvalidAllNamespacesError)
+}
+
 func TestHandleActionGet(t *testing.T) {
 	podNamespace := "test-namespace"
 
@@ -152,6 +394,453 @@ func TestHandleActionGet(t *testing.T) {
 	checkStrContainsAll(t, getAllValues(extendedPodAnnotations), testOut.String())
 }
 
+func TestHandleActionGetOutputFormats(t *testing.T) {
+	podNamespace := "test-namespace"
+	noInteractionPod := getFakePod("test-pod-1", podNamespace, nil, nil)
+	interactedPod := getFakePod("test-pod-2", podNamespace,
+		map[string]string{
+			podInteractorLabel:  "test-interactor-2",
+			podTTLDurationLabel: "30s",
+		},
+		map[string]string{
+			podTerminationTimeAnnotate: time.Now().String(),
+			podExtendDurationAnnotate:  "2h",
+			podExtendRequesterAnnotate: "test-requester-2",
+		},
+	)
+	pods := []corev1.Pod{*noInteractionPod, *interactedPod}
+	now := time.Now()
+	expect := []PodInteractionInfo{getPodInteractionInfo(*noInteractionPod, now), getPodInteractionInfo(*interactedPod, now)}
+
+	fakeOptions := CmdOptions{clock: func() time.Time { return now }}
+	fakeOptions.kubeClient = fake.NewSimpleClientset(noInteractionPod, interactedPod)
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+
+	// testing JSON output
+	testOut.Reset()
+	fakeOptions.outputFormat = outputJSON
+	if err := fakeOptions.handleActionGet(pods); err != nil {
+		t.Fatal(err)
+	}
+	var jsonResult []PodInteractionInfo
+	if err := json.Unmarshal(testOut.Bytes(), &jsonResult); err != nil {
+		t.Fatalf("expected valid JSON output, got error %v from: %s", err, testOut.String())
+	}
+	if !reflect.DeepEqual(expect, jsonResult) {
+		t.Fatalf("expected pod interaction info: %v, got: %v", expect, jsonResult)
+	}
+	checkStrContainsAll(t, []string{"\"namespace\": \"" + podNamespace + "\""}, testOut.String())
+
+	// testing YAML output
+	testOut.Reset()
+	fakeOptions.outputFormat = outputYAML
+	if err := fakeOptions.handleActionGet(pods); err != nil {
+		t.Fatal(err)
+	}
+	var yamlResult []PodInteractionInfo
+	if err := yaml.UnmarshalStrict(testOut.Bytes(), &yamlResult); err != nil {
+		t.Fatalf("expected valid YAML output, got error %v from: %s", err, testOut.String())
+	}
+	if !reflect.DeepEqual(expect, yamlResult) {
+		t.Fatalf("expected pod interaction info: %v, got: %v", expect, yamlResult)
+	}
+	checkStrContainsAll(t, []string{"namespace: " + podNamespace}, testOut.String())
+}
+
+func TestHandleActionGetRemaining(t *testing.T) {
+	podNamespace := "test-namespace"
+	now := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	getInteractedPod := func(name string, terminationTime string) *corev1.Pod {
+		return getFakePod(name, podNamespace,
+			map[string]string{
+				podInteractorLabel:  "test-interactor",
+				podTTLDurationLabel: "1h",
+			},
+			map[string]string{podTerminationTimeAnnotate: terminationTime},
+		)
+	}
+	soonPod := getInteractedPod("test-pod-soon", now.Add(5*time.Minute+20*time.Second).String())
+	laterPod := getInteractedPod("test-pod-later", now.Add(2*time.Hour).String())
+	// a termination time annotated with a monotonic clock reading
+	duePod := getInteractedPod("test-pod-due", now.Add(-time.Minute).String()+" m=+3600.000000001")
+	noInteractionPod := getFakePod("test-pod-no-interaction", podNamespace, nil, nil)
+	pods := []corev1.Pod{*soonPod, *laterPod, *duePod, *noInteractionPod}
+
+	fakeOptions := CmdOptions{clock: func() time.Time { return now }}
+	fakeOptions.kubeClient = fake.NewSimpleClientset(soonPod, laterPod, duePod, noInteractionPod)
+	fakeOptions.outputFormat = outputJSON
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+
+	// testing the remaining time of each pod at the fixed current time
+	testOut.Reset()
+	if err := fakeOptions.handleActionGet(pods); err != nil {
+		t.Fatal(err)
+	}
+	var infoList []PodInteractionInfo
+	if err := json.Unmarshal(testOut.Bytes(), &infoList); err != nil {
+		t.Fatal(err)
+	}
+	remaining := map[string]string{}
+	for _, info := range infoList {
+		remaining[info.PodName] = info.Remaining
+	}
+	expectRemaining := map[string]string{
+		soonPod.Name:          "5m20s",
+		laterPod.Name:         "2h0m0s",
+		duePod.Name:           remainingExpired,
+		noInteractionPod.Name: "",
+	}
+	if !reflect.DeepEqual(expectRemaining, remaining) {
+		t.Fatalf("expected remaining time: %v, got: %v", expectRemaining, remaining)
+	}
+
+	// testing the REMAINING column of the table output
+	testOut.Reset()
+	fakeOptions.outputFormat = outputTable
+	if err := fakeOptions.handleActionGet([]corev1.Pod{*soonPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{"REMAINING", "5m20s"}, testOut.String())
+
+	// testing the REMAINING column of a pod whose termination time has passed
+	testOut.Reset()
+	if err := fakeOptions.handleActionGet([]corev1.Pod{*duePod}); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{duePod.Name, remainingExpired}, testOut.String())
+
+	// testing only the pods to be evicted within the given duration are listed
+	testOut.Reset()
+	fakeOptions.withinStr = "10m"
+	if err := fakeOptions.handleActionGet(pods); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{soonPod.Name, duePod.Name}, testOut.String())
+	for _, podName := range []string{laterPod.Name, noInteractionPod.Name} {
+		if strings.Contains(testOut.String(), podName) {
+			t.Fatalf("expecting pod/%s not listed, got \"%s\"", podName, testOut.String())
+		}
+	}
+
+	// testing an invalid duration set in '--within'
+	fakeOptions.action = cmdGetAction
+	fakeOptions.withinStr = "soon"
+	checkErrMsg(t, fakeOptions.Validate(), cmdInvalidWithinError)
+}
+
+func TestHandleActionGetSortBy(t *testing.T) {
+	podNamespace := "test-namespace"
+	now := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	getInteractedPod := func(name string, terminationTime string) corev1.Pod {
+		return *getFakePod(name, podNamespace,
+			map[string]string{podInteractorLabel: "test-interactor"},
+			map[string]string{podTerminationTimeAnnotate: terminationTime},
+		)
+	}
+	pods := []corev1.Pod{
+		getInteractedPod("test-pod-c", now.Add(time.Hour).String()),
+		getInteractedPod("test-pod-invalid", "not-a-time"),
+		getInteractedPod("test-pod-a", now.Add(2*time.Hour).String()),
+		*getFakePod("test-pod-no-interaction", podNamespace, nil, nil),
+		// a termination time annotated with a monotonic clock reading
+		getInteractedPod("test-pod-b", now.Add(-time.Minute).String()+" m=+3600.000000001"),
+	}
+
+	fakeOptions := CmdOptions{clock: func() time.Time { return now }}
+	fakeOptions.outputFormat = outputJSON
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+	checkPodOrder := func(expectPodNames []string) {
+		testOut.Reset()
+		if err := fakeOptions.handleActionGet(pods); err != nil {
+			t.Fatal(err)
+		}
+		var infoList []PodInteractionInfo
+		if err := json.Unmarshal(testOut.Bytes(), &infoList); err != nil {
+			t.Fatal(err)
+		}
+		podNames := make([]string, 0, len(infoList))
+		for _, info := range infoList {
+			podNames = append(podNames, info.PodName)
+		}
+		if !reflect.DeepEqual(expectPodNames, podNames) {
+			t.Fatalf("expected pods sorted by %s: %v, got: %v", fakeOptions.sortBy, expectPodNames, podNames)
+		}
+	}
+
+	// testing the pods are sorted by name by default
+	checkPodOrder([]string{"test-pod-a", "test-pod-b", "test-pod-c", "test-pod-invalid", "test-pod-no-interaction"})
+
+	// testing the pods are sorted by eviction time, with the ones of no valid termination time last by name
+	fakeOptions.sortBy = sortByEvictionTime
+	checkPodOrder([]string{"test-pod-b", "test-pod-c", "test-pod-a", "test-pod-invalid", "test-pod-no-interaction"})
+
+	// testing an unsupported sort key, or one set to any other action, is rejected
+	fakeOptions.action = cmdGetAction
+	fakeOptions.sortBy = "remaining"
+	checkErrMsg(t, fakeOptions.Validate(), cmdInvalidSortByError)
+	fakeOptions.action = cmdExtendAction
+	fakeOptions.extendDurationStr = defaultExtendDuration
+	fakeOptions.sortBy = sortByEvictionTime
+	checkErrMsg(t, fakeOptions.Validate(), cmdInvalidSortByError)
+}
+
+func TestHandleActionGetInteractedOnly(t *testing.T) {
+	podNamespace := "test-namespace"
+	interactedPod := getFakePod("test-pod-interacted", podNamespace,
+		map[string]string{
+			podInteractionTimestampLabel: "1634407604",
+			podInteractorLabel:           "test-interactor",
+		}, nil)
+	// the interaction labels stored as annotations
+	annotatedPod := getFakePod("test-pod-annotated", podNamespace, nil,
+		map[string]string{
+			podInteractionTimestampLabel: "1634407604",
+			podInteractorLabel:           "test-interactor",
+		})
+	noInteractionPod := getFakePod("test-pod-none", podNamespace, nil, nil)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fake.NewSimpleClientset(interactedPod, annotatedPod, noInteractionPod)
+	fakeOptions.action = cmdGetAction
+	fakeOptions.outputFormat = outputTable
+	fakeOptions.interactedOnly = true
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+
+	// testing only the interacted pods are printed
+	testOut.Reset()
+	if err := fakeOptions.handleActionGet([]corev1.Pod{*interactedPod, *annotatedPod, *noInteractionPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{interactedPod.Name, annotatedPod.Name}, testOut.String())
+	if strings.Contains(testOut.String(), noInteractionPod.Name) {
+		t.Fatalf("expecting no %s printed but got %s", noInteractionPod.Name, testOut.String())
+	}
+
+	// testing only interacted pods are selected for the 'get' action only
+	fakeOptions.action = cmdExtendAction
+	checkErrMsg(t, fakeOptions.Validate(), cmdInvalidInteractedOnlyError)
+}
+
+func TestHandleActionGetShowRaw(t *testing.T) {
+	podNamespace := "test-namespace"
+	// a pod partially labeled, with one of its interaction annotations under a mismatched prefix
+	rawPod := getFakePod("test-pod-raw", podNamespace,
+		map[string]string{
+			podInteractorLabel: "test-interactor",
+			"app":              "test-app",
+		},
+		map[string]string{
+			podTerminationTimeAnnotate:        "2021-10-16 18:06:44 +0000 UTC",
+			"example.com/podExtendedDuration": "30m",
+			"example.com/unrelated":           "true",
+		},
+	)
+	noInteractionPod := getFakePod("test-pod-none", podNamespace, nil, nil)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fake.NewSimpleClientset(rawPod, noInteractionPod)
+	fakeOptions.action = cmdGetAction
+	fakeOptions.outputFormat = outputTable
+	fakeOptions.showRaw = true
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+
+	// testing the interaction keys are printed verbatim after the parsed table
+	testOut.Reset()
+	if err := fakeOptions.handleActionGet([]corev1.Pod{*rawPod, *noInteractionPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{
+		"POD-NAME",
+		"Raw labels/annotations of pod/test-pod-raw:",
+		"label       " + podInteractorLabel + "=test-interactor",
+		"annotation  " + podTerminationTimeAnnotate + "=2021-10-16 18:06:44 +0000 UTC",
+		"annotation  example.com/podExtendedDuration=30m",
+		"Raw labels/annotations of pod/test-pod-none:\n  <none>",
+	}, testOut.String())
+	for _, key := range []string{"app=test-app", "example.com/unrelated"} {
+		if strings.Contains(testOut.String(), key) {
+			t.Fatalf("expecting no %q printed as raw but got %s", key, testOut.String())
+		}
+	}
+
+	// testing raw labels/annotations are only printed along with the table of the 'get' action
+	fakeOptions.outputFormat = outputJSON
+	checkErrMsg(t, fakeOptions.Validate(), cmdInvalidShowRawError)
+	fakeOptions.outputFormat = outputTable
+	fakeOptions.action = cmdDescribeAction
+	checkErrMsg(t, fakeOptions.Validate(), cmdInvalidShowRawError)
+}
+
+func TestHandleActionWouldEvict(t *testing.T) {
+	podNamespace := "test-namespace"
+	now := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	getInteractedPod := func(name string, interactedTime time.Time, annotations map[string]string) *corev1.Pod {
+		return getFakePod(name, podNamespace,
+			map[string]string{
+				podInteractionTimestampLabel: strconv.FormatInt(interactedTime.Unix(), 10),
+				podInteractorLabel:           "test-interactor",
+				podTTLDurationLabel:          "1h",
+			},
+			annotations,
+		)
+	}
+	overduePod := getInteractedPod("test-pod-overdue", now.Add(-90*time.Minute), nil)
+	notOverduePod := getInteractedPod("test-pod-not-overdue", now.Add(-30*time.Minute), nil)
+	extendedPod := getInteractedPod("test-pod-extended", now.Add(-90*time.Minute),
+		map[string]string{podExtendDurationAnnotate: "1h"})
+	heldPod := getInteractedPod("test-pod-held", now.Add(-90*time.Minute),
+		map[string]string{podInUseAnnotate: "true"})
+	reinteractedPod := getInteractedPod("test-pod-reinteracted", now.Add(-90*time.Minute),
+		map[string]string{podLastInteractionTimestampAnnotate: strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10)})
+	// a pod with invalid interaction metadata falls back to its annotated termination time
+	annotatedPod := getFakePod("test-pod-annotated", podNamespace, nil,
+		map[string]string{podTerminationTimeAnnotate: now.Add(-time.Minute).String()})
+	noInteractionPod := getFakePod("test-pod-no-interaction", podNamespace, nil, nil)
+	pods := []corev1.Pod{*overduePod, *notOverduePod, *extendedPod, *heldPod, *reinteractedPod, *annotatedPod, *noInteractionPod}
+
+	fakeOptions := CmdOptions{clock: func() time.Time { return now }}
+	fakeOptions.namespace = podNamespace
+	fakeOptions.outputFormat = outputJSON
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+
+	// testing only the overdue pods are listed with how long they are overdue
+	testOut.Reset()
+	if err := fakeOptions.handleActionWouldEvict(pods); err != nil {
+		t.Fatal(err)
+	}
+	var infoList []OverduePodInfo
+	if err := json.Unmarshal(testOut.Bytes(), &infoList); err != nil {
+		t.Fatal(err)
+	}
+	overdue := map[string]string{}
+	for _, info := range infoList {
+		overdue[info.PodName] = info.Overdue
+	}
+	expectOverdue := map[string]string{
+		overduePod.Name:   "30m0s",
+		annotatedPod.Name: "1m0s",
+	}
+	if !reflect.DeepEqual(expectOverdue, overdue) {
+		t.Fatalf("expected overdue pods: %v, got: %v", expectOverdue, overdue)
+	}
+
+	// testing the table output
+	testOut.Reset()
+	fakeOptions.outputFormat = outputTable
+	if err := fakeOptions.handleActionWouldEvict(pods); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{"OVERDUE", overduePod.Name, "test-interactor", "30m0s"}, testOut.String())
+
+	// testing no overdue pods
+	testOut.Reset()
+	if err := fakeOptions.handleActionWouldEvict([]corev1.Pod{*notOverduePod, *heldPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, fmt.Sprintf(noOverduePodOfNamespaceMsg, podNamespace), testOut.String())
+}
+
+func TestHandleActionDescribe(t *testing.T) {
+	podNamespace := "test-namespace"
+
+	// a pod with no interaction
+	noInteractionPodName := "test-pod-1"
+	noInteractionPod := getFakePod(noInteractionPodName, podNamespace, nil, nil)
+
+	// an interacted pod extended twice
+	extendedPodName := "test-pod-2"
+	extendedPodLabels := map[string]string{
+		podInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10),
+		podInteractorLabel:           "test-interactor",
+		podTTLDurationLabel:          "45m",
+	}
+	firstExtensionTime := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	secondExtensionTime := firstExtensionTime.Add(time.Hour)
+	extendedPodAnnotations := map[string]string{
+		podTerminationTimeAnnotate: time.Now().String(),
+		podExtendDurationAnnotate:  "2h",
+		podExtendRequesterAnnotate: "test-requester-2",
+		podExtensionHistoryAnnotate: fmt.Sprintf(
+			`[{"requester":"test-requester-1","duration":"30m","time":"%s"},{"requester":"test-requester-2","duration":"2h","time":"%s"}]`,
+			firstExtensionTime.Format(time.RFC3339), secondExtensionTime.Format(time.RFC3339)),
+	}
+	extendedPod := getFakePod(extendedPodName, podNamespace, extendedPodLabels, extendedPodAnnotations)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fake.NewSimpleClientset(noInteractionPod, extendedPod)
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+
+	// testing a no interaction pod
+	testOut.Reset()
+	if err := fakeOptions.handleActionDescribe([]corev1.Pod{*noInteractionPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, fmt.Sprintf(noInteractionOfPodMsg, noInteractionPodName), testOut.String())
+
+	// testing an extended pod with its extension history listed in order
+	testOut.Reset()
+	if err := fakeOptions.handleActionDescribe([]corev1.Pod{*extendedPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{extendedPodName, "test-interactor", "45m", "Extension History:"}, testOut.String())
+	firstRecordIdx := strings.Index(testOut.String(), firstExtensionTime.Format(time.RFC3339))
+	secondRecordIdx := strings.Index(testOut.String(), secondExtensionTime.Format(time.RFC3339))
+	if firstRecordIdx < 0 || secondRecordIdx < firstRecordIdx {
+		t.Fatalf("expecting both extension records listed in order, got \"%s\"", testOut.String())
+	}
+	checkStrContainsAll(t, []string{"test-requester-1", "30m", "test-requester-2", "2h"}, testOut.String())
+
+	// testing an extended pod with a malformed extension history
+	extendedPod.Annotations[podExtensionHistoryAnnotate] = "not-a-json"
+	testOut.Reset()
+	if err := fakeOptions.handleActionDescribe([]corev1.Pod{*extendedPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{extendedPodName, "Warning: failed to parse the extension history"}, testOut.String())
+
+	// testing an interacted pod described with its interaction time, count and remaining time
+	now := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	interactionTime := now.Add(-15 * time.Minute)
+	describedPodName := "test-pod-3"
+	describedPodLabels := map[string]string{
+		podInteractionTimestampLabel: strconv.FormatInt(interactionTime.Unix(), 10),
+		podInteractorLabel:           "test-interactor",
+		podTTLDurationLabel:          "45m",
+	}
+	describedPodAnnotations := map[string]string{
+		podInteractionCountAnnotate: "3",
+		podLastInteractorAnnotate:   "test-last-interactor",
+		podTerminationTimeAnnotate:  now.Add(30 * time.Minute).String(),
+		podInUseAnnotate:            "true",
+		podDisableEvictionAnnotate:  "true",
+	}
+	describedPod := getFakePod(describedPodName, podNamespace, describedPodLabels, describedPodAnnotations)
+	fakeOptions.clock = func() time.Time { return now }
+	testOut.Reset()
+	if err := fakeOptions.handleActionDescribe([]corev1.Pod{*describedPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{
+		time.Unix(interactionTime.Unix(), 0).String(),
+		"30m0s",
+		"Interaction Count:",
+		"3",
+		"test-last-interactor",
+		"In Use:",
+		"Exempt:",
+		"true",
+		"Raw labels/annotations of pod/" + describedPodName,
+		podInteractionCountAnnotate + "=3",
+	}, testOut.String())
+}
+
 func TestHandleActionExtend(t *testing.T) {
 	podName := "test-pod"
 	fakePod := getFakePod(podName, "test-ns", nil, nil)
@@ -201,28 +890,641 @@ func TestHandleActionExtend(t *testing.T) {
 	checkStrContainsAll(t, expectedOutAll, testOut.String())
 }
 
-func TestGetPodInteraction(t *testing.T) {
-	podName := "test-pop"
-	labelsMap := map[string]string{
-		podInteractorLabel:  "test-user-1",
+func TestHandleActionReduce(t *testing.T) {
+	podName := "test-pod"
+	now := time.Now()
+	fakePod := getFakePod(podName, "test-ns",
+		map[string]string{
+			podInteractionTimestampLabel: strconv.FormatInt(now.Unix(), 10),
+			podTTLDurationLabel:          "1h",
+		},
+		map[string]string{podExtendDurationAnnotate: "1h"},
+	)
+	fakeClient := fake.NewSimpleClientset(fakePod)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	fakeOptions.clock = func() time.Time { return now }
+	testIn := getTestInstance().in
+	testOut := getTestInstance().out
+	fakeOptions.In = testIn
+	fakeOptions.Out = testOut
+
+	// testing a reduction subtracted from the existing extension, keeping the eviction time in the future
+	testOut.Reset()
+	fakeOptions.extendDurationStr = "30m"
+	if err := fakeOptions.handleActionReduce([]corev1.Pod{*fakePod}); err != nil {
+		t.Fatal(err)
+	}
+	expectedOut := fmt.Sprintf(successReductionOfPodWithDurationMsg, podName, "30m", "30m0s")
+	checkStrContainsAll(t, []string{expectedOut}, testOut.String())
+	if strings.Contains(testOut.String(), pastReductionPromptMsg) {
+		t.Errorf("expected no confirmation prompt, got %q", testOut.String())
+	}
+	reducedPod, err := fakeClient.CoreV1().Pods("test-ns").Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, "30m0s", reducedPod.Annotations[podExtendDurationAnnotate])
+
+	// testing a reduction beyond the TTL, which results in a negative extension after a confirmation
+	testOut.Reset()
+	testIn.WriteString("y\n")
+	fakeOptions.extendDurationStr = "3h"
+	if err := fakeOptions.handleActionReduce([]corev1.Pod{*fakePod}); err != nil {
+		t.Fatal(err)
+	}
+	expectedOutAll := []string{
+		fmt.Sprintf(pastReductionOfPodWarningMsg, podName),
+		pastReductionPromptMsg,
+		fmt.Sprintf(successReductionOfPodWithDurationMsg, podName, "3h", "-2h0m0s"),
+	}
+	checkStrContainsAll(t, expectedOutAll, testOut.String())
+
+	// testing a declined confirmation skips the pod
+	testOut.Reset()
+	testIn.WriteString("n\n")
+	if err := fakeOptions.handleActionReduce([]corev1.Pod{*fakePod}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(testOut.String(), "Successfully reduced") {
+		t.Errorf("expected the reduction skipped, got %q", testOut.String())
+	}
+}
+
+func TestCustomKeyPrefix(t *testing.T) {
+	if err := setKeyPrefix("example.com"); err != nil {
+		t.Fatal(err)
+	}
+	defer setKeyPrefix(defaultKeyPrefix)
+
+	podName := "test-pod"
+	fakePod := getFakePod(podName, "test-ns",
+		map[string]string{
+			"example.com/podInitialInteractionTimestamp": strconv.FormatInt(time.Now().Unix(), 10),
+			"example.com/podInteractorUsername":          "test-interactor",
+			"example.com/podTTLDuration":                 "1h",
+		},
+		map[string]string{"box.com/podExtendedDuration": "2h"},
+	)
+	fakeClient := fake.NewSimpleClientset(fakePod)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	fakeOptions.outputFormat = outputJSON
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+
+	// testing the interaction labels/annotations are parsed under the custom prefix only
+	testOut.Reset()
+	if err := fakeOptions.handleActionGet([]corev1.Pod{*fakePod}); err != nil {
+		t.Fatal(err)
+	}
+	var infoList []PodInteractionInfo
+	if err := json.Unmarshal(testOut.Bytes(), &infoList); err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, 1, len(infoList))
+	checkMatches(t, "test-interactor", infoList[0].Interactor)
+	checkMatches(t, "1h", infoList[0].TTLDuration)
+	checkMatches(t, "", infoList[0].Extension)
+
+	// testing an extension is patched under the custom prefix
+	testOut.Reset()
+	fakeOptions.extendDurationStr = "30m"
+	if err := fakeOptions.handleActionExtend([]corev1.Pod{*fakePod}); err != nil {
+		t.Fatal(err)
+	}
+	extendedPod, err := fakeClient.CoreV1().Pods(fakePod.Namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, "30m", extendedPod.Annotations["example.com/podExtendedDuration"])
+	checkMatches(t, "2h", extendedPod.Annotations["box.com/podExtendedDuration"])
+
+	// testing the default key prefix is read from the environment, and an invalid prefix is rejected
+	os.Setenv(keyPrefixEnv, "example.org")
+	defer os.Unsetenv(keyPrefixEnv)
+	checkMatches(t, "example.org", getDefaultKeyPrefix())
+	if err := setKeyPrefix("Example_com"); err == nil {
+		t.Fatal("expected an error setting an invalid key prefix, got nil")
+	}
+}
+
+func TestHandleActionExtendWithYes(t *testing.T) {
+	podName := "test-pod"
+	fakePod := getFakePod(podName, "test-ns",
+		map[string]string{podInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10)},
+		map[string]string{podExtendDurationAnnotate: "30m"},
+	)
+	fakeClient := fake.NewSimpleClientset(fakePod)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	fakeOptions.extendDurationStr = "2h"
+	fakeOptions.assumeYes = true
+	testIn := getTestInstance().in
+	testOut := getTestInstance().out
+	fakeOptions.In = testIn
+	fakeOptions.Out = testOut
+
+	// testing the existing extension is overwritten without a prompt, leaving stdin unread
+	testOut.Reset()
+	testIn.Reset()
+	testIn.WriteString("n\n")
+	if err := fakeOptions.handleActionExtend([]corev1.Pod{*fakePod}); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{fmt.Sprintf(successExtensionOfPodWithDurationMsg, podName, "2h")}, testOut.String())
+	if strings.Contains(testOut.String(), overwriteExtensionPromptMsg) {
+		t.Fatalf("expecting no confirmation prompt with '--yes' but got %s", testOut.String())
+	}
+	checkMatches(t, "n\n", testIn.String())
+
+	extendedPod, err := fakeClient.CoreV1().Pods(fakePod.Namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, "2h", extendedPod.Annotations[podExtendDurationAnnotate])
+}
+
+func TestHandleActionExtendPreview(t *testing.T) {
+	namespace := "test-ns"
+	now := time.Date(2021, time.October, 16, 18, 0, 0, 0, time.UTC)
+	interactedTime := now.Add(-2 * time.Hour)
+	interactedLabels := map[string]string{
+		podInteractionTimestampLabel: strconv.FormatInt(interactedTime.Unix(), 10),
+		podTTLDurationLabel:          "1h",
+	}
+	// an extension annotated already, which is replaced rather than added to
+	extendedAnnotations := map[string]string{
+		podExtendDurationAnnotate:  "30m",
+		podTerminationTimeAnnotate: interactedTime.Add(90 * time.Minute).String(),
+	}
+	extendedPod := getFakePod("test-pod-extended", namespace, interactedLabels, extendedAnnotations)
+	fakeClient := fake.NewSimpleClientset(extendedPod)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	fakeOptions.clock = func() time.Time { return now }
+	testIn := getTestInstance().in
+	testOut := getTestInstance().out
+	fakeOptions.In = testIn
+	fakeOptions.Out = testOut
+
+	// testing the preview counts the extension from the interaction plus the TTL, as the controller does
+	testOut.Reset()
+	testIn.Reset()
+	testIn.WriteString("y\n")
+	fakeOptions.extendDurationStr = "2h"
+	if _, err := fakeOptions.setExtensionMetadata(*extendedPod); err != nil {
+		t.Fatal(err)
+	}
+	expectedDueTime := interactedTime.Add(time.Hour).Add(2 * time.Hour)
+	expectedPreview := fmt.Sprintf(extensionPreviewOfPodMsg, extendedPod.Name, expectedDueTime.Local().String(), "2h")
+	checkStrContainsAll(t, []string{expectedPreview, overwriteExtensionPromptMsg}, testOut.String())
+
+	// testing an extension resulting in the past is warned of but still set
+	testOut.Reset()
+	testIn.Reset()
+	testIn.WriteString("y\n")
+	fakeOptions.extendDurationStr = "30m"
+	outcome, err := fakeOptions.setExtensionMetadata(*extendedPod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, string(outcomeOverwritten), string(outcome))
+	checkStrContainsAll(t, []string{fmt.Sprintf(pastExtensionOfPodWarningMsg, extendedPod.Name)}, testOut.String())
+
+	// testing an extension resulting in the past is refused with '--refuse-past'
+	testOut.Reset()
+	testIn.Reset()
+	fakeOptions.refusePast = true
+	outcome, err = fakeOptions.setExtensionMetadata(*extendedPod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, string(outcomeSkipped), string(outcome))
+	checkStrContainsAll(t, []string{fmt.Sprintf(refusedPastExtensionOfPodMsg, extendedPod.Name)}, testOut.String())
+	if strings.Contains(testOut.String(), overwriteExtensionPromptMsg) {
+		t.Fatalf("expecting no confirmation prompt for a refused extension but got %s", testOut.String())
+	}
+}
+
+func TestHandleActionExtendSummary(t *testing.T) {
+	namespace := "test-ns"
+	interactedLabels := map[string]string{podInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10)}
+	extendedAnnotations := map[string]string{podExtendDurationAnnotate: "1h"}
+
+	nonInteractedPod := getFakePod("test-pod-non-interacted", namespace, nil, nil)
+	interactedPod := getFakePod("test-pod-interacted", namespace, interactedLabels, nil)
+	overwrittenPod := getFakePod("test-pod-overwritten", namespace, interactedLabels, extendedAnnotations)
+	declinedPod := getFakePod("test-pod-declined", namespace, interactedLabels, extendedAnnotations)
+	// a pod that does not exist in the cluster (patching it would fail)
+	missingPod := getFakePod("test-pod-missing", namespace, interactedLabels, nil)
+	fakeClient := fake.NewSimpleClientset(nonInteractedPod, interactedPod, overwrittenPod, declinedPod)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	fakeOptions.extendDurationStr = "2h"
+	testIn := getTestInstance().in
+	testOut := getTestInstance().out
+	fakeOptions.In = testIn
+	fakeOptions.Out = testOut
+
+	// testing a mixed batch of pods (confirm to overwrite the first extended pod and decline the second)
+	testOut.Reset()
+	testIn.Reset()
+	testIn.WriteString("y\nn\n")
+	pods := []corev1.Pod{*nonInteractedPod, *interactedPod, *overwrittenPod, *declinedPod, *missingPod}
+	err := fakeOptions.handleActionExtend(pods)
+	checkErrMsg(t, err, fmt.Sprintf(cmdExtensionFailedError, 1))
+
+	expectedSummary := fmt.Sprintf(extensionSummaryMsg, 1, 1, 2, 1)
+	checkStrContainsAll(t, []string{expectedSummary}, testOut.String())
+}
+
+func TestHandleActionReset(t *testing.T) {
+	namespace := "test-ns"
+	interactedLabels := map[string]string{podInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10)}
+
+	nonInteractedPod := getFakePod("test-pod-non-interacted", namespace, nil, nil)
+	interactedPod := getFakePod("test-pod-interacted", namespace, interactedLabels, nil)
+	extendedPod1 := getFakePod("test-pod-extended-1", namespace, interactedLabels, map[string]string{
+		podExtendDurationAnnotate:  "1h",
+		podExtendRequesterAnnotate: "test-requester",
+	})
+	extendedPod2 := getFakePod("test-pod-extended-2", namespace, interactedLabels, map[string]string{
+		podExtendDurationAnnotate: "2h",
+	})
+	// an extended pod that does not exist in the cluster (patching it would fail)
+	missingPod := getFakePod("test-pod-missing", namespace, interactedLabels, map[string]string{
+		podExtendDurationAnnotate: "3h",
+	})
+	fakeClient := fake.NewSimpleClientset(nonInteractedPod, interactedPod, extendedPod1, extendedPod2)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	testIn := getTestInstance().in
+	testOut := getTestInstance().out
+	fakeOptions.In = testIn
+	fakeOptions.Out = testOut
+
+	// testing declining to reset the extended pods
+	testOut.Reset()
+	testIn.Reset()
+	testIn.WriteString("n\n")
+	pods := []corev1.Pod{*nonInteractedPod, *interactedPod, *extendedPod1, *extendedPod2}
+	if err := fakeOptions.handleActionReset(pods); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{fmt.Sprintf(resetExtensionPromptMsg, 2), fmt.Sprintf(resetSummaryMsg, 0, 4, 0)}, testOut.String())
+
+	// testing resetting all pods, only the extended ones are reset and the missing one fails
+	testOut.Reset()
+	testIn.WriteString("y\n")
+	pods = append(pods, *missingPod)
+	err := fakeOptions.handleActionReset(pods)
+	checkErrMsg(t, err, fmt.Sprintf(cmdResetFailedError, 1))
+	checkStrContainsAll(t, []string{fmt.Sprintf(resetExtensionPromptMsg, 3), fmt.Sprintf(resetSummaryMsg, 2, 2, 1)}, testOut.String())
+
+	for _, podName := range []string{extendedPod1.Name, extendedPod2.Name} {
+		resetPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if extension, present := resetPod.Annotations[podExtendDurationAnnotate]; present {
+			t.Fatalf("expecting the extension of pod/%s to be reset, got %s", podName, extension)
+		}
+	}
+
+	// testing resetting the reset pods again, which are skipped with no confirmation
+	testOut.Reset()
+	resetPods, err := fakeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fakeOptions.handleActionReset(resetPods.Items); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{fmt.Sprintf(resetSummaryMsg, 0, 4, 0)}, testOut.String())
+	if strings.Contains(testOut.String(), "Please confirm") {
+		t.Fatalf("expecting no confirmation prompt, got \"%s\"", testOut.String())
+	}
+}
+
+func TestHandleActionCancel(t *testing.T) {
+	namespace := "test-ns"
+	interactedLabels := map[string]string{podInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10)}
+
+	nonInteractedPod := getFakePod("test-pod-non-interacted", namespace, nil, nil)
+	interactedPod := getFakePod("test-pod-interacted", namespace, interactedLabels, nil)
+	extendedPod := getFakePod("test-pod-extended", namespace, interactedLabels, map[string]string{
+		podExtendDurationAnnotate: "1d",
+	})
+	fakeClient := fake.NewSimpleClientset(nonInteractedPod, interactedPod, extendedPod)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	fakeOptions.namespace = namespace
+	fakeOptions.action = cmdCancelAction
+	testIn := getTestInstance().in
+	testOut := getTestInstance().out
+	fakeOptions.In = testIn
+	fakeOptions.Out = testOut
+
+	// testing canceling the extension of an extended pod
+	testOut.Reset()
+	testIn.Reset()
+	testIn.WriteString("y\n")
+	fakeOptions.podNames = []string{extendedPod.Name}
+	if err := fakeOptions.Run(); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{fmt.Sprintf(successResetOfPodMsg, extendedPod.Name)}, testOut.String())
+	canceledPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), extendedPod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extension, present := canceledPod.Annotations[podExtendDurationAnnotate]; present {
+		t.Fatalf("expecting the extension of pod/%s to be canceled, got %s", extendedPod.Name, extension)
+	}
+
+	// testing canceling a pod with no extension, which is skipped
+	testOut.Reset()
+	fakeOptions.podNames = []string{interactedPod.Name}
+	if err := fakeOptions.Run(); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{fmt.Sprintf(noExtensionOfPodMsg, interactedPod.Name)}, testOut.String())
+
+	// testing canceling a non-interacted pod, which is skipped
+	testOut.Reset()
+	fakeOptions.podNames = []string{nonInteractedPod.Name}
+	if err := fakeOptions.Run(); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{fmt.Sprintf(noInteractionOfPodMsg, nonInteractedPod.Name)}, testOut.String())
+}
+
+func TestHandleActionHoldAndRelease(t *testing.T) {
+	namespace := "test-ns"
+	interactedPod := getFakePod("test-pod-interacted", namespace, map[string]string{
+		podInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10),
+	}, nil)
+	noInteractionPod := getFakePod("test-pod-no-interaction", namespace, nil, nil)
+	fakeClient := fake.NewSimpleClientset(interactedPod, noInteractionPod)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+
+	// testing only the interacted pod gets held in use
+	testOut.Reset()
+	if err := fakeOptions.handleActionHold([]corev1.Pod{*interactedPod, *noInteractionPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{
+		fmt.Sprintf(successHoldOfPodMsg, interactedPod.Name),
+		fmt.Sprintf(noInteractionOfPodMsg, noInteractionPod.Name),
+	}, testOut.String())
+
+	heldPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), interactedPod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, "true", heldPod.Annotations[podInUseAnnotate])
+
+	// testing a held pod is skipped from holding again
+	testOut.Reset()
+	if err := fakeOptions.handleActionHold([]corev1.Pod{*heldPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, fmt.Sprintf(alreadyHeldOfPodMsg, heldPod.Name), testOut.String())
+
+	// testing the held pod gets released
+	testOut.Reset()
+	if err := fakeOptions.handleActionRelease([]corev1.Pod{*heldPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, fmt.Sprintf(successReleaseOfPodMsg, heldPod.Name), testOut.String())
+
+	releasedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), interactedPod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, held := releasedPod.Annotations[podInUseAnnotate]; held {
+		t.Fatalf("expecting the in-use annotation removed but got %v", releasedPod.Annotations)
+	}
+
+	// testing a pod not held is skipped from releasing
+	testOut.Reset()
+	if err := fakeOptions.handleActionRelease([]corev1.Pod{*releasedPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, fmt.Sprintf(notHeldOfPodMsg, releasedPod.Name), testOut.String())
+}
+
+func TestHandleActionJustify(t *testing.T) {
+	namespace := "test-ns"
+	testPod := getFakePod("test-pod", namespace, nil, nil)
+	fakeClient := fake.NewSimpleClientset(testPod)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	fakeOptions.justification = `debugging "incident-123"`
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+
+	// testing a pod gets annotated with the justification and its time
+	testOut.Reset()
+	if err := fakeOptions.handleActionJustify([]corev1.Pod{*testPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, fmt.Sprintf(successJustificationOfPodMsg, testPod.Name), testOut.String())
+
+	justifiedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), testPod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, fakeOptions.justification, justifiedPod.Annotations[podExecJustificationAnnotate])
+	justifiedTimestamp, err := strconv.ParseInt(justifiedPod.Annotations[podExecJustificationTimeAnnotate], 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(time.Unix(justifiedTimestamp, 0)) > time.Minute {
+		t.Fatalf("expecting a recent justification time but got %v", time.Unix(justifiedTimestamp, 0))
+	}
+
+	// testing a missing justification
+	fakeOptions.action = cmdJustifyAction
+	fakeOptions.justification = " "
+	checkErrMsg(t, fakeOptions.Validate(), cmdMissingReasonError)
+}
+
+func TestHandleActionMigrate(t *testing.T) {
+	namespace := "test-ns"
+	oldPrefix := "example.com"
+	interactedTimestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	migratingPod := getFakePod("test-pod-migrating", namespace,
+		map[string]string{
+			oldPrefix + "/podInitialInteractionTimestamp": interactedTimestamp,
+			oldPrefix + "/podInteractorUsername":          "test-user",
+			oldPrefix + "/podTTLDuration":                 "2h0m0s",
+			"app":                                         "test-app",
+		},
+		map[string]string{
+			oldPrefix + "/podExtendedDuration": "30m",
+			oldPrefix + "/podTerminationTime":  "2021-10-16 18:06:44 +0000 UTC",
+		},
+	)
+	nonInteractedPod := getFakePod("test-pod-non-interacted", namespace, map[string]string{"app": "test-app"}, nil)
+	fakeClient := fake.NewSimpleClientset(migratingPod, nonInteractedPod)
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fakeClient
+	fakeOptions.migrateFrom = oldPrefix
+	fakeOptions.migrateTo = defaultKeyPrefix
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+
+	// testing a tracked pod gets all its keys migrated and a non-interacted pod is untouched
+	testOut.Reset()
+	if err := fakeOptions.handleActionMigrate([]corev1.Pod{*migratingPod, *nonInteractedPod}); err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, fmt.Sprintf(successMigrationOfPodMsg, 5, migratingPod.Name, oldPrefix, defaultKeyPrefix), testOut.String())
+
+	migratedPod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), migratingPod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLabels := map[string]string{
+		podInteractionTimestampLabel: interactedTimestamp,
+		podInteractorLabel:           "test-user",
+		podTTLDurationLabel:          "2h0m0s",
+		"app":                        "test-app",
+	}
+	expectedAnnotations := map[string]string{
+		podExtendDurationAnnotate:  "30m",
+		podTerminationTimeAnnotate: "2021-10-16 18:06:44 +0000 UTC",
+	}
+	if !reflect.DeepEqual(expectedLabels, migratedPod.Labels) {
+		t.Fatalf("expecting labels %v but got %v", expectedLabels, migratedPod.Labels)
+	}
+	if !reflect.DeepEqual(expectedAnnotations, migratedPod.Annotations) {
+		t.Fatalf("expecting annotations %v but got %v", expectedAnnotations, migratedPod.Annotations)
+	}
+
+	// testing the same prefix set in both '--from' and '--to'
+	fakeOptions.action = cmdMigrateAction
+	fakeOptions.migrateFrom = defaultKeyPrefix
+	checkErrMsg(t, fakeOptions.Validate(), cmdInvalidMigratePrefixError)
+}
+
+func TestHandleActionHistory(t *testing.T) {
+	historyNamespace := "test-history-ns"
+	firstRecordTime := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	// a full ring buffer of 3 records with the next (oldest) slot at 1
+	historyConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      historyConfigMapName,
+			Namespace: historyNamespace,
+		},
+		Data: map[string]string{
+			"1": fmt.Sprintf(`{"type":"interaction","timestamp":"%s","username":"test-user","pod_namespace":"test-ns","pod_name":"test-pod-1","commands":["sh","-c","ls"]}`,
+				firstRecordTime.Format(time.RFC3339)),
+			"2": fmt.Sprintf(`{"type":"extension","timestamp":"%s","username":"test-requester","pod_namespace":"test-ns","pod_name":"test-pod-1","extension":"1h"}`,
+				firstRecordTime.Add(time.Minute).Format(time.RFC3339)),
+			"0": fmt.Sprintf(`{"type":"eviction","timestamp":"%s","pod_namespace":"test-ns","pod_name":"test-pod-2"}`,
+				firstRecordTime.Add(2*time.Minute).Format(time.RFC3339)),
+			historyNextSlotKey: "1",
+		},
+	}
+
+	fakeOptions := CmdOptions{}
+	fakeOptions.kubeClient = fake.NewSimpleClientset(historyConfigMap)
+	fakeOptions.namespace = historyNamespace
+	fakeOptions.specifiedAll = true
+	testOut := getTestInstance().out
+	fakeOptions.Out = testOut
+
+	// testing all records are listed from the oldest to the newest one
+	testOut.Reset()
+	if err := fakeOptions.handleActionHistory(); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{"TYPE", "test-user", "sh -c ls", "test-requester", "1h", "test-pod-2"}, testOut.String())
+	interactionIdx := strings.Index(testOut.String(), "interaction")
+	extensionIdx := strings.Index(testOut.String(), "extension")
+	evictionIdx := strings.Index(testOut.String(), "eviction")
+	if interactionIdx < 0 || extensionIdx < interactionIdx || evictionIdx < extensionIdx {
+		t.Fatalf("expecting all history records listed in order, got \"%s\"", testOut.String())
+	}
+
+	// testing only the records of the specified pod are listed
+	fakeOptions.specifiedAll = false
+	fakeOptions.podNames = []string{"test-pod-2"}
+	testOut.Reset()
+	if err := fakeOptions.handleActionHistory(); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{"eviction", "test-pod-2"}, testOut.String())
+	if strings.Contains(testOut.String(), "test-pod-1") {
+		t.Fatalf("expecting no records of other pods listed, got \"%s\"", testOut.String())
+	}
+
+	// testing a malformed record is warned and skipped
+	fakeOptions.podNames = []string{"test-pod-1"}
+	historyConfigMap.Data["2"] = "not-a-json"
+	if _, err := fakeOptions.kubeClient.CoreV1().ConfigMaps(historyNamespace).Update(context.TODO(), historyConfigMap, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	testOut.Reset()
+	if err := fakeOptions.handleActionHistory(); err != nil {
+		t.Fatal(err)
+	}
+	checkStrContainsAll(t, []string{"Warning: failed to parse the history record in slot 2", "interaction"}, testOut.String())
+
+	// testing a namespace with no history kept
+	fakeOptions.namespace = "test-ns"
+	checkErrMsg(t, fakeOptions.handleActionHistory(), fmt.Sprintf(noHistoryOfNamespaceMsg, "test-ns"))
+}
+
+func TestGetPodInteraction(t *testing.T) {
+	podName := "test-pop"
+	now := time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)
+	labelsMap := map[string]string{
+		podInteractorLabel:  "test-user-1",
 		podTTLDurationLabel: "2h",
 	}
 	annotationsMap := map[string]string{
 		podExtendDurationAnnotate:  "30m",
 		podExtendRequesterAnnotate: "test-user-2",
-		podTerminationTimeAnnotate: time.Now().String(),
+		podTerminationTimeAnnotate: now.Add(2 * time.Hour).String(),
 	}
 	fakePod := getFakePod(podName, "test-ns", labelsMap, annotationsMap)
 
 	expect := PodInteractionInfo{
-		podName:         podName,
-		interactor:      labelsMap[podInteractorLabel],
-		ttlDuration:     labelsMap[podTTLDurationLabel],
-		extension:       annotationsMap[podExtendDurationAnnotate],
-		requester:       annotationsMap[podExtendRequesterAnnotate],
-		terminationTime: annotationsMap[podTerminationTimeAnnotate],
-	}
-	result := getPodInteractionInfo(*fakePod)
+		PodName:         podName,
+		Namespace:       "test-ns",
+		Interactor:      labelsMap[podInteractorLabel],
+		TTLDuration:     labelsMap[podTTLDurationLabel],
+		Extension:       annotationsMap[podExtendDurationAnnotate],
+		Requester:       annotationsMap[podExtendRequesterAnnotate],
+		TerminationTime: annotationsMap[podTerminationTimeAnnotate],
+		Remaining:       "2h0m0s",
+	}
+	result := getPodInteractionInfo(*fakePod, now)
+	checkMatches(t, expect, result)
+
+	// testing a pod with its interaction labels stored as annotations by the controller
+	for key, val := range labelsMap {
+		annotationsMap[key] = val
+	}
+	fakePod = getFakePod(podName, "test-ns", nil, annotationsMap)
+	result = getPodInteractionInfo(*fakePod, now)
 	checkMatches(t, expect, result)
 }
 
@@ -240,6 +1542,14 @@ func TestIsValidDuration(t *testing.T) {
 	result = isValidDuration(invalidDuration)
 	checkMatches(t, false, result)
 
+	invalidDuration = "0s"
+	result = isValidDuration(invalidDuration)
+	checkMatches(t, false, result)
+
+	invalidDuration = "1x"
+	result = isValidDuration(invalidDuration)
+	checkMatches(t, false, result)
+
 	// testing valid duration input
 	validDuration := "60s"
 	result = isValidDuration(validDuration)
@@ -256,6 +1566,23 @@ func TestIsValidDuration(t *testing.T) {
 	validDuration = "1d"
 	result = isValidDuration(validDuration)
 	checkMatches(t, true, result)
+
+	validDuration = "1w"
+	result = isValidDuration(validDuration)
+	checkMatches(t, true, result)
+
+	validDuration = "1d12h"
+	result = isValidDuration(validDuration)
+	checkMatches(t, true, result)
+
+	// testing compound and non-normalized durations accepted by time.ParseDuration
+	validDuration = "1h30m"
+	result = isValidDuration(validDuration)
+	checkMatches(t, true, result)
+
+	validDuration = "90m"
+	result = isValidDuration(validDuration)
+	checkMatches(t, true, result)
 }
 
 // Helpful vars and utility functions for testing
@@ -0,0 +1,358 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/box/kube-exec-controller/pkg/duration"
+)
+
+// GroupVersionResource of the ExecTrackingPolicy custom resource (see demo/exec-tracking-policy-crd.yaml).
+var GroupVersionResource = schema.GroupVersionResource{
+	Group:    "box.com",
+	Version:  "v1alpha1",
+	Resource: "exectrackingpolicies",
+}
+
+// NamespacedGroupVersionResource of the NamespacedExecTrackingPolicy custom resource, which has the same spec as the
+// ExecTrackingPolicy but only applies to the Pods in its namespace (see demo/exec-tracking-policy-crd.yaml).
+var NamespacedGroupVersionResource = schema.GroupVersionResource{
+	Group:    "box.com",
+	Version:  "v1alpha1",
+	Resource: "namespacedexectrackingpolicies",
+}
+
+// These are the termination actions to take on an interacted Pod once its TTL expires.
+const (
+	// TerminationActionEvict evicts an interacted Pod through the Eviction API.
+	TerminationActionEvict = "evict"
+	// TerminationActionDelete deletes an interacted Pod directly, bypassing its PodDisruptionBudgets.
+	TerminationActionDelete = "delete"
+)
+
+// ExecTrackingPolicy is a cluster-scoped object that configures how interacted Pods are handled.
+// Any field set in its spec takes precedence over the value set from the controller's flags.
+// A NamespacedExecTrackingPolicy is decoded into it as well, with its namespace set, whose fields take precedence
+// over the cluster-scoped one for the Pods in its namespace (except ExemptNamespaces, which is ignored).
+type ExecTrackingPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ExecTrackingPolicySpec `json:"spec"`
+}
+
+// ExecTrackingPolicySpec contains the desired settings of an ExecTrackingPolicy.
+type ExecTrackingPolicySpec struct {
+	// TTL of interacted Pods before getting evicted, e.g. "10m" or "2h".
+	TTL string `json:"ttl,omitempty"`
+	// MaxExtension is the maximum extension a Pod can be given on top of its TTL.
+	MaxExtension string `json:"maxExtension,omitempty"`
+	// ExemptUsers are usernames that can interact with Pods without getting them evicted.
+	ExemptUsers []string `json:"exemptUsers,omitempty"`
+	// ExemptNamespaces are namespaces that allow interaction without evicting their Pods.
+	ExemptNamespaces []string `json:"exemptNamespaces,omitempty"`
+	// TerminationAction to take on an interacted Pod once its TTL expires.
+	TerminationAction string `json:"terminationAction,omitempty"`
+}
+
+// effectivePolicy contains the parsed values of an ExecTrackingPolicySpec.
+type effectivePolicy struct {
+	ttl               time.Duration
+	maxExtension      time.Duration
+	exemptUsers       map[string]bool
+	exemptNamespaces  map[string]bool
+	terminationAction string
+}
+
+// Store holds the currently effective ExecTrackingPolicy, along with the NamespacedExecTrackingPolicy of each
+// namespace, and is safe for concurrent use.
+// All its lookup methods can be called on a nil Store, which returns the given fallback values.
+type Store struct {
+	mu         sync.RWMutex
+	policy     *effectivePolicy
+	namespaced map[string]*effectivePolicy
+}
+
+// NewStore returns an empty Store with no policy set.
+func NewStore() *Store {
+	return &Store{namespaced: map[string]*effectivePolicy{}}
+}
+
+// Set parses the given ExecTrackingPolicy and makes it the effective policy of the Store, or of its namespace if set
+// (i.e. a NamespacedExecTrackingPolicy). Fields with an invalid value are logged and ignored (the value of the
+// cluster-scoped policy or the flag is used instead).
+func (s *Store) Set(etp *ExecTrackingPolicy) {
+	spec := etp.Spec
+	ep := &effectivePolicy{
+		exemptUsers:      toSet(spec.ExemptUsers),
+		exemptNamespaces: toSet(spec.ExemptNamespaces),
+	}
+	if etp.Namespace != "" && len(spec.ExemptNamespaces) > 0 {
+		zap.L().Warn("Ignored the exempt namespaces set in a NamespacedExecTrackingPolicy",
+			zap.String("policy_name", etp.Name),
+			zap.String("policy_namespace", etp.Namespace),
+		)
+		ep.exemptNamespaces = map[string]bool{}
+	}
+
+	if spec.TTL != "" {
+		ttl, err := duration.Parse(spec.TTL)
+		if err != nil || ttl < 0 {
+			zap.L().Warn("Ignored an invalid TTL set in ExecTrackingPolicy",
+				zap.String("policy_name", etp.Name),
+				zap.String("ttl", spec.TTL),
+			)
+		} else {
+			ep.ttl = ttl
+		}
+	}
+
+	if spec.MaxExtension != "" {
+		maxExtension, err := duration.Parse(spec.MaxExtension)
+		if err != nil || maxExtension < 0 {
+			zap.L().Warn("Ignored an invalid max extension set in ExecTrackingPolicy",
+				zap.String("policy_name", etp.Name),
+				zap.String("max_extension", spec.MaxExtension),
+			)
+		} else {
+			ep.maxExtension = maxExtension
+		}
+	}
+
+	switch spec.TerminationAction {
+	case "", TerminationActionEvict, TerminationActionDelete:
+		ep.terminationAction = spec.TerminationAction
+	default:
+		zap.L().Warn("Ignored an unsupported termination action set in ExecTrackingPolicy",
+			zap.String("policy_name", etp.Name),
+			zap.String("termination_action", spec.TerminationAction),
+		)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if etp.Namespace != "" {
+		if s.namespaced == nil {
+			s.namespaced = map[string]*effectivePolicy{}
+		}
+		s.namespaced[etp.Namespace] = ep
+		zap.L().Info("Applied NamespacedExecTrackingPolicy",
+			zap.String("policy_name", etp.Name),
+			zap.String("policy_namespace", etp.Namespace),
+		)
+		return
+	}
+	s.policy = ep
+
+	zap.L().Info("Applied ExecTrackingPolicy", zap.String("policy_name", etp.Name))
+}
+
+// Clear removes the effective policy so that all lookups return their fallback values, unless set by the
+// NamespacedExecTrackingPolicy of the namespace looked up.
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = nil
+
+	zap.L().Info("Cleared ExecTrackingPolicy, falling back to flag values")
+}
+
+// ClearNamespace removes the effective policy of the given namespace, so that its lookups return the values of the
+// cluster-scoped policy or their fallback values.
+func (s *Store) ClearNamespace(namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.namespaced, namespace)
+
+	zap.L().Info("Cleared NamespacedExecTrackingPolicy, falling back to ExecTrackingPolicy or flag values",
+		zap.String("policy_namespace", namespace))
+}
+
+// TTL returns the TTL set in the policy of the given namespace or else the cluster-scoped one, or the given fallback
+// if unset.
+func (s *Store) TTL(namespace string, fallback time.Duration) time.Duration {
+	for _, ep := range s.get(namespace) {
+		if ep.ttl > 0 {
+			return ep.ttl
+		}
+	}
+
+	return fallback
+}
+
+// MaxExtension returns the max extension set in the policy of the given namespace or else the cluster-scoped one, or
+// the given fallback if unset.
+func (s *Store) MaxExtension(namespace string, fallback time.Duration) time.Duration {
+	for _, ep := range s.get(namespace) {
+		if ep.maxExtension > 0 {
+			return ep.maxExtension
+		}
+	}
+
+	return fallback
+}
+
+// TerminationAction returns the termination action set in the policy of the given namespace or else the
+// cluster-scoped one, or the given fallback if unset.
+func (s *Store) TerminationAction(namespace, fallback string) string {
+	for _, ep := range s.get(namespace) {
+		if ep.terminationAction != "" {
+			return ep.terminationAction
+		}
+	}
+
+	return fallback
+}
+
+// IsExemptUser returns if the given username is exempted in the given namespace, by its policy or the
+// cluster-scoped one.
+func (s *Store) IsExemptUser(namespace, username string) bool {
+	for _, ep := range s.get(namespace) {
+		if ep.exemptUsers[username] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsExemptNamespace returns if the given namespace is exempted by the cluster-scoped policy.
+func (s *Store) IsExemptNamespace(namespace string) bool {
+	ep := s.getClusterPolicy()
+	return ep != nil && ep.exemptNamespaces[namespace]
+}
+
+// Watch keeps the Store in sync with the ExecTrackingPolicy of the given name until stopCh is closed.
+// It blocks until the initial list of the policy object is synced, returning an error if not synced within the given
+// sync timeout, e.g. as the CRD is not installed or listing it is forbidden by missing RBAC.
+func (s *Store) Watch(dynamicClient dynamic.Interface, name string, syncTimeout time.Duration,
+	stopCh <-chan struct{}) error {
+	return s.watch(dynamicClient, GroupVersionResource, name, syncTimeout, stopCh, cache.ResourceEventHandlerFuncs{
+		AddFunc:    s.handlePolicyObject,
+		UpdateFunc: func(_, obj interface{}) { s.handlePolicyObject(obj) },
+		DeleteFunc: func(_ interface{}) { s.Clear() },
+	})
+}
+
+// WatchNamespaced keeps the Store in sync with the NamespacedExecTrackingPolicy objects of the given name in all
+// namespaces until stopCh is closed. Like Watch, it blocks until their initial list is synced, returning an error if
+// not synced within the given sync timeout.
+func (s *Store) WatchNamespaced(dynamicClient dynamic.Interface, name string, syncTimeout time.Duration,
+	stopCh <-chan struct{}) error {
+	return s.watch(dynamicClient, NamespacedGroupVersionResource, name, syncTimeout, stopCh,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    s.handlePolicyObject,
+			UpdateFunc: func(_, obj interface{}) { s.handlePolicyObject(obj) },
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				if u, ok := obj.(*unstructured.Unstructured); ok {
+					s.ClearNamespace(u.GetNamespace())
+				}
+			},
+		})
+}
+
+// watch syncs the objects of the given resource and name with the given handler until stopCh is closed, blocking
+// until their initial list is synced within the given sync timeout.
+func (s *Store) watch(dynamicClient dynamic.Interface, resource schema.GroupVersionResource, name string,
+	syncTimeout time.Duration, stopCh <-chan struct{}, handler cache.ResourceEventHandler) error {
+	tweakListOptions := func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	}
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(
+		dynamicClient, 0, metav1.NamespaceAll, tweakListOptions)
+	informer := factory.ForResource(resource).Informer()
+	informer.AddEventHandler(handler)
+
+	factory.Start(stopCh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return fmt.Errorf("%s %q is not synced within %s", resource.Resource, name, syncTimeout)
+	}
+
+	return nil
+}
+
+// handlePolicyObject converts an object received from the informer and sets it to the Store.
+func (s *Store) handlePolicyObject(obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		zap.L().Warn("Received an unexpected object type from the ExecTrackingPolicy informer, ignoring")
+		return
+	}
+
+	etp := &ExecTrackingPolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), etp); err != nil {
+		zap.L().Error("Failed to convert an ExecTrackingPolicy object, ignoring",
+			zap.String("policy_name", u.GetName()),
+			zap.Error(err),
+		)
+		return
+	}
+
+	s.Set(etp)
+}
+
+// get returns the current effective policies applying to the given namespace in order of precedence, i.e. its
+// namespaced policy and then the cluster-scoped one, which is empty if the Store is nil or has no policy set.
+func (s *Store) get(namespace string) []*effectivePolicy {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var policies []*effectivePolicy
+	if ep, present := s.namespaced[namespace]; present {
+		policies = append(policies, ep)
+	}
+	if s.policy != nil {
+		policies = append(policies, s.policy)
+	}
+
+	return policies
+}
+
+// getClusterPolicy returns the current cluster-scoped policy, or nil if the Store is nil or has no policy set.
+func (s *Store) getClusterPolicy() *effectivePolicy {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy
+}
+
+// toSet converts the given list into a Map to have O(1) lookup time.
+func toSet(list []string) map[string]bool {
+	resMap := map[string]bool{}
+	for _, val := range list {
+		resMap[val] = true
+	}
+
+	return resMap
+}
@@ -0,0 +1,286 @@
+package policy_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/box/kube-exec-controller/pkg/policy"
+)
+
+// TestStoreFallback tests a Store without any policy set returning the given fallback values
+func TestStoreFallback(t *testing.T) {
+	setupZapLogging(t)
+
+	var nilStore *policy.Store
+	for _, store := range []*policy.Store{nilStore, policy.NewStore()} {
+		if ttl := store.TTL("test-namespace", time.Minute); ttl != time.Minute {
+			t.Errorf("expected fallback TTL: %s, got: %s", time.Minute, ttl)
+		}
+		if maxExtension := store.MaxExtension("test-namespace", time.Hour); maxExtension != time.Hour {
+			t.Errorf("expected fallback max extension: %s, got: %s", time.Hour, maxExtension)
+		}
+		if action := store.TerminationAction("test-namespace", policy.TerminationActionEvict); action != policy.TerminationActionEvict {
+			t.Errorf("expected fallback termination action: %s, got: %s", policy.TerminationActionEvict, action)
+		}
+		if store.IsExemptUser("test-namespace", "test-user") || store.IsExemptNamespace("test-namespace") {
+			t.Error("expected no exemption from a store without policy")
+		}
+	}
+}
+
+// TestStoreSet tests a Store applying valid values and ignoring invalid values of a policy
+func TestStoreSet(t *testing.T) {
+	setupZapLogging(t)
+
+	store := policy.NewStore()
+	store.Set(&policy.ExecTrackingPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-policy"},
+		Spec: policy.ExecTrackingPolicySpec{
+			TTL:               "2h",
+			MaxExtension:      "invalid",
+			ExemptUsers:       []string{"test-user"},
+			ExemptNamespaces:  []string{"test-namespace"},
+			TerminationAction: "unsupported",
+		},
+	})
+
+	if ttl := store.TTL("test-namespace", time.Minute); ttl != 2*time.Hour {
+		t.Errorf("expected policy TTL: %s, got: %s", 2*time.Hour, ttl)
+	}
+	if maxExtension := store.MaxExtension("test-namespace", time.Hour); maxExtension != time.Hour {
+		t.Errorf("expected fallback max extension for an invalid value: %s, got: %s", time.Hour, maxExtension)
+	}
+	if action := store.TerminationAction("test-namespace", policy.TerminationActionEvict); action != policy.TerminationActionEvict {
+		t.Errorf("expected fallback termination action for an unsupported value: %s, got: %s", policy.TerminationActionEvict, action)
+	}
+	if !store.IsExemptUser("test-namespace", "test-user") || store.IsExemptUser("test-namespace", "other-user") {
+		t.Error("expected only 'test-user' to be exempted")
+	}
+	if !store.IsExemptNamespace("test-namespace") || store.IsExemptNamespace("other-namespace") {
+		t.Error("expected only 'test-namespace' to be exempted")
+	}
+
+	store.Set(&policy.ExecTrackingPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-policy"},
+		Spec:       policy.ExecTrackingPolicySpec{TerminationAction: policy.TerminationActionDelete},
+	})
+	if action := store.TerminationAction("test-namespace", policy.TerminationActionEvict); action != policy.TerminationActionDelete {
+		t.Errorf("expected policy termination action: %s, got: %s", policy.TerminationActionDelete, action)
+	}
+
+	store.Clear()
+	if ttl := store.TTL("test-namespace", time.Minute); ttl != time.Minute {
+		t.Errorf("expected fallback TTL after clearing the policy: %s, got: %s", time.Minute, ttl)
+	}
+}
+
+// TestStoreSetNamespaced tests a Store applying the policy of a namespace over the cluster-scoped one, only to the
+// lookups in that namespace
+func TestStoreSetNamespaced(t *testing.T) {
+	setupZapLogging(t)
+
+	store := policy.NewStore()
+	store.Set(&policy.ExecTrackingPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-policy"},
+		Spec: policy.ExecTrackingPolicySpec{
+			TTL:              "2h",
+			MaxExtension:     "1h",
+			ExemptUsers:      []string{"test-user"},
+			ExemptNamespaces: []string{"test-namespace-exempt"},
+		},
+	})
+	store.Set(&policy.ExecTrackingPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-policy", Namespace: "test-namespace"},
+		Spec: policy.ExecTrackingPolicySpec{
+			TTL:               "10m",
+			ExemptUsers:       []string{"test-user-namespaced"},
+			ExemptNamespaces:  []string{"test-namespace-ignored"},
+			TerminationAction: policy.TerminationActionDelete,
+		},
+	})
+
+	// verify the fields set in the namespaced policy take precedence in its namespace only
+	if ttl := store.TTL("test-namespace", time.Minute); ttl != 10*time.Minute {
+		t.Errorf("expected namespaced policy TTL: %s, got: %s", 10*time.Minute, ttl)
+	}
+	if ttl := store.TTL("other-namespace", time.Minute); ttl != 2*time.Hour {
+		t.Errorf("expected cluster policy TTL in another namespace: %s, got: %s", 2*time.Hour, ttl)
+	}
+	if maxExtension := store.MaxExtension("test-namespace", 0); maxExtension != time.Hour {
+		t.Errorf("expected cluster policy max extension unset in the namespaced policy: %s, got: %s", time.Hour, maxExtension)
+	}
+	if action := store.TerminationAction("other-namespace", policy.TerminationActionEvict); action != policy.TerminationActionEvict {
+		t.Errorf("expected fallback termination action in another namespace: %s, got: %s", policy.TerminationActionEvict, action)
+	}
+	if !store.IsExemptUser("test-namespace", "test-user") || !store.IsExemptUser("test-namespace", "test-user-namespaced") ||
+		store.IsExemptUser("other-namespace", "test-user-namespaced") {
+		t.Error("expected 'test-user-namespaced' to be exempted in 'test-namespace' only, along with 'test-user'")
+	}
+	if !store.IsExemptNamespace("test-namespace-exempt") || store.IsExemptNamespace("test-namespace-ignored") {
+		t.Error("expected only the namespaces exempted by the cluster policy to be exempted")
+	}
+
+	// verify the namespace falls back to the cluster policy once its policy is cleared
+	store.ClearNamespace("test-namespace")
+	if ttl := store.TTL("test-namespace", time.Minute); ttl != 2*time.Hour {
+		t.Errorf("expected cluster policy TTL after clearing the namespaced policy: %s, got: %s", 2*time.Hour, ttl)
+	}
+}
+
+// TestStoreWatch tests a Store watching the creation, update and deletion of a policy object
+func TestStoreWatch(t *testing.T) {
+	setupZapLogging(t)
+
+	policyName := "test-policy"
+	fakeClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{policy.GroupVersionResource: "ExecTrackingPolicyList"},
+		getPolicyObject(policyName, "1h"),
+	)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	store := policy.NewStore()
+	if err := store.Watch(fakeClient, policyName, 5*time.Second, stopCh); err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the existing policy is applied once the watch is synced
+	if ttl := store.TTL("test-namespace", time.Minute); ttl != time.Hour {
+		t.Fatalf("expected policy TTL: %s, got: %s", time.Hour, ttl)
+	}
+
+	// verify a policy update is applied
+	policyClient := fakeClient.Resource(policy.GroupVersionResource)
+	if _, err := policyClient.Update(context.TODO(), getPolicyObject(policyName, "3h"), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForTTL(t, store, 3*time.Hour)
+
+	// verify the store falls back to the flag values after the policy is deleted
+	if err := policyClient.Delete(context.TODO(), policyName, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForTTL(t, store, time.Minute)
+}
+
+// TestStoreWatchNotSynced tests a Store failing to watch a policy object whose list is forbidden within the timeout
+func TestStoreWatchNotSynced(t *testing.T) {
+	setupZapLogging(t)
+
+	fakeClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{policy.GroupVersionResource: "ExecTrackingPolicyList"},
+	)
+	fakeClient.PrependReactor("list", policy.GroupVersionResource.Resource,
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(policy.GroupVersionResource.GroupResource(), "", nil)
+		},
+	)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	store := policy.NewStore()
+	if err := store.Watch(fakeClient, "test-policy", 100*time.Millisecond, stopCh); err == nil {
+		t.Fatal("expected an error watching a policy object not synced within the timeout, but got none")
+	}
+}
+
+// TestStoreWatchNamespaced tests a Store watching the namespaced policy objects of the given name across namespaces
+func TestStoreWatchNamespaced(t *testing.T) {
+	setupZapLogging(t)
+
+	policyName := "test-policy"
+	namespacedPolicy := getPolicyObject(policyName, "1h")
+	namespacedPolicy.SetKind("NamespacedExecTrackingPolicy")
+	namespacedPolicy.SetNamespace("test-namespace")
+	otherPolicy := getPolicyObject(policyName, "2h")
+	otherPolicy.SetKind("NamespacedExecTrackingPolicy")
+	otherPolicy.SetNamespace("other-namespace")
+	fakeClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{policy.NamespacedGroupVersionResource: "NamespacedExecTrackingPolicyList"},
+		namespacedPolicy, otherPolicy,
+	)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	store := policy.NewStore()
+	if err := store.WatchNamespaced(fakeClient, policyName, 5*time.Second, stopCh); err != nil {
+		t.Fatal(err)
+	}
+
+	// verify each policy is applied to its own namespace only
+	if ttl := store.TTL("test-namespace", time.Minute); ttl != time.Hour {
+		t.Fatalf("expected namespaced policy TTL: %s, got: %s", time.Hour, ttl)
+	}
+	if ttl := store.TTL("other-namespace", time.Minute); ttl != 2*time.Hour {
+		t.Fatalf("expected namespaced policy TTL in another namespace: %s, got: %s", 2*time.Hour, ttl)
+	}
+	if ttl := store.TTL("test-namespace-none", time.Minute); ttl != time.Minute {
+		t.Fatalf("expected fallback TTL in a namespace without policy: %s, got: %s", time.Minute, ttl)
+	}
+
+	// verify the namespace falls back to the flag values after its policy is deleted
+	policyClient := fakeClient.Resource(policy.NamespacedGroupVersionResource).Namespace("test-namespace")
+	if err := policyClient.Delete(context.TODO(), policyName, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for store.TTL("test-namespace", time.Minute) != time.Minute {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected fallback TTL after deleting the namespaced policy, got: %s", store.TTL("test-namespace", time.Minute))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if ttl := store.TTL("other-namespace", time.Minute); ttl != 2*time.Hour {
+		t.Errorf("expected the policy of another namespace kept: %s, got: %s", 2*time.Hour, ttl)
+	}
+}
+
+/*
+  Helper functions used by the testings above.
+*/
+
+// setupZapLogging gives better visibility when running a test
+func setupZapLogging(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	zap.ReplaceGlobals(logger)
+}
+
+// getPolicyObject returns an unstructured ExecTrackingPolicy object with the given name and TTL
+func getPolicyObject(name, ttl string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": policy.GroupVersionResource.GroupVersion().String(),
+			"kind":       "ExecTrackingPolicy",
+			"metadata": map[string]interface{}{
+				"name": name,
+			},
+			"spec": map[string]interface{}{
+				"ttl": ttl,
+			},
+		},
+	}
+}
+
+// waitForTTL waits until the store returns the expected TTL (with a fallback of one minute)
+func waitForTTL(t *testing.T, store *policy.Store, expected time.Duration) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if store.TTL("test-namespace", time.Minute) == expected {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("expected TTL: %s, got: %s", expected, store.TTL("test-namespace", time.Minute))
+}
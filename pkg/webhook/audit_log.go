@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+
+	"github.com/box/kube-exec-controller/pkg/controller"
+)
+
+// AuditLogRecord is a Pod interaction admitted by the webhook, whether tracked by the controller or exempted.
+type AuditLogRecord struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Username      string    `json:"username"`
+	PodNamespace  string    `json:"pod_namespace"`
+	PodName       string    `json:"pod_name"`
+	SubResource   string    `json:"subresource"`
+	ContainerName string    `json:"container_name,omitempty"`
+	Commands      []string  `json:"commands,omitempty"`
+	// Tracked is false if the interaction is exempted, e.g. by an allowlist, so its Pod is not evicted
+	Tracked bool `json:"tracked"`
+}
+
+// AuditLogger records every admitted Pod interaction, as an immutable record independent of K8s events.
+type AuditLogger interface {
+	Log(record AuditLogRecord) error
+}
+
+// FileAuditLogger is an AuditLogger appending each record as a line of JSON to a file.
+type FileAuditLogger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileAuditLogger opens (or creates) the file of the given path to append the records to.
+func NewFileAuditLogger(path string) (*FileAuditLogger, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileAuditLogger{file: file}, nil
+}
+
+// Log appends the given record to the file.
+func (l *FileAuditLogger) Log(record AuditLogRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file.
+func (l *FileAuditLogger) Close() error {
+	return l.file.Close()
+}
+
+// writeAuditLog records the admitted Pod interaction of the given request and Decision, if an AuditLogger is set.
+func (s *Server) writeAuditLog(admissionRequest *admissionv1.AdmissionRequest, decision Decision) {
+	if s.AuditLogger == nil || !decision.Allowed {
+		return
+	}
+
+	record := AuditLogRecord{
+		Timestamp:    time.Now(),
+		Username:     admissionRequest.UserInfo.Username,
+		PodNamespace: admissionRequest.Namespace,
+		PodName:      admissionRequest.Name,
+		SubResource:  admissionRequest.SubResource,
+	}
+	if pi := decision.PodInteraction; pi != nil {
+		record.Timestamp = pi.InitTime
+		record.ContainerName = pi.ContainerName
+		record.Commands = pi.Commands
+		record.Tracked = true
+	} else if options, err := parseInteractionOptions(admissionRequest); err == nil {
+		// an exempted interaction is not parsed into a PodInteraction, so redact and truncate its command likewise
+		record.ContainerName = options.Container
+		record.Commands = controller.RedactCommands(options.Command, s.CommandRedactions)
+		record.Commands, _ = controller.TruncateCommands(record.Commands, s.MaxCommandArgs, s.MaxCommandLength)
+	}
+
+	if err := s.AuditLogger.Log(record); err != nil {
+		zap.L().Error("Error in writing an audit log record of a Pod interaction",
+			zap.String("pod_name", record.PodName),
+			zap.String("pod_namespace", record.PodNamespace),
+			zap.String("username", record.Username),
+			zap.Error(err),
+		)
+	}
+}
+
+// interactionOptions contains the fields used from the options object of an interaction request.
+type interactionOptions struct {
+	Container string   `json:"container"`
+	Command   []string `json:"command"`
+}
+
+// parseInteractionOptions returns the options object of the given interaction request.
+func parseInteractionOptions(admissionRequest *admissionv1.AdmissionRequest) (interactionOptions, error) {
+	var options interactionOptions
+	err := json.Unmarshal(admissionRequest.Object.Raw, &options)
+	return options, err
+}
@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/box/kube-exec-controller/pkg/controller"
+)
+
+// auditEvent contains the fields used from a K8s API audit event (audit.k8s.io/v1 Event).
+type auditEvent struct {
+	AuditID                  string                     `json:"auditID"`
+	Stage                    string                     `json:"stage"`
+	RequestURI               string                     `json:"requestURI"`
+	User                     authenticationv1.UserInfo  `json:"user"`
+	ImpersonatedUser         *authenticationv1.UserInfo `json:"impersonatedUser,omitempty"`
+	ObjectRef                *auditObjectRef            `json:"objectRef,omitempty"`
+	ResponseStatus           *metav1.Status             `json:"responseStatus,omitempty"`
+	RequestReceivedTimestamp metav1.MicroTime           `json:"requestReceivedTimestamp"`
+}
+
+// auditObjectRef contains the fields used from the object reference of a K8s API audit event.
+type auditObjectRef struct {
+	Resource    string `json:"resource"`
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Subresource string `json:"subresource"`
+}
+
+// These are the stages of an audit event whose response status tells if a Pod interaction succeeded.
+const (
+	auditStageResponseStarted  = "ResponseStarted"
+	auditStageResponseComplete = "ResponseComplete"
+)
+
+// ReplayAuditLog reads K8s API audit events (as written by the audit log backend in JSON) from the given path and
+// sends their successful "exec" and "attach" requests to the controller through the same decision logic used
+// by the webhook handlers, so that Pods interacted while the webhook was unavailable get tracked retroactively.
+// The controller skips the interactions of Pods no longer existing or recorded already (e.g. by replaying the same
+// log on a previous start), and the ones older than the TTL of untracked Pods. The replay stops once the given stop
+// channel is closed, or the channel to the controller is closed by CloseControllerChannels. It returns the number of
+// replayed ones.
+func (s *Server) ReplayAuditLog(path string, stopCh <-chan struct{}) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	interactions, err := s.decideAuditEvents(file)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, podInteraction := range interactions {
+		select {
+		case <-stopCh:
+			return i, nil
+		default:
+		}
+		if !s.sendPodInteraction(podInteraction) {
+			return i, nil
+		}
+	}
+
+	return len(interactions), nil
+}
+
+// decideAuditEvents returns the PodInteractions to be tracked from the audit events read from the given reader.
+// Each request is decided once, even if it is logged at multiple stages.
+func (s *Server) decideAuditEvents(r io.Reader) ([]controller.PodInteraction, error) {
+	var interactions []controller.PodInteraction
+	decided := map[string]bool{}
+
+	decoder := json.NewDecoder(r)
+	for {
+		var event auditEvent
+		if err := decoder.Decode(&event); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode an audit event: %v", err)
+		}
+
+		if decided[event.AuditID] {
+			continue
+		}
+		admissionRequest, ok := getAuditAdmissionRequest(event)
+		if !ok {
+			continue
+		}
+		decided[event.AuditID] = true
+
+		decision := s.DecidePodInteraction(admissionRequest)
+		if decision.PodInteraction == nil {
+			continue
+		}
+		// track the Pod from when it was actually interacted, unless recorded by a previous replay
+		decision.PodInteraction.InitTime = event.RequestReceivedTimestamp.Time
+		decision.PodInteraction.Replayed = true
+		interactions = append(interactions, *decision.PodInteraction)
+	}
+
+	return interactions, nil
+}
+
+// getAuditAdmissionRequest converts the given audit event into the admission request the webhook would have received.
+// It returns false if the event is not a successfully responded "exec" or "attach" request of a Pod.
+func getAuditAdmissionRequest(event auditEvent) (*admissionv1.AdmissionRequest, bool) {
+	ref := event.ObjectRef
+	if ref == nil || ref.Resource != "pods" {
+		return nil, false
+	}
+
+	var kind string
+	switch ref.Subresource {
+	case "exec":
+		kind = PodExecAdmissionRequestKind
+	case "attach":
+		kind = PodAttachAdmissionRequestKind
+	default:
+		return nil, false
+	}
+
+	// the response status is unknown until the response starts, and the upgraded (101) connection means a success
+	if event.Stage != auditStageResponseStarted && event.Stage != auditStageResponseComplete {
+		return nil, false
+	}
+	if event.ResponseStatus == nil || event.ResponseStatus.Code >= 400 {
+		return nil, false
+	}
+
+	// the interaction options are only available as query parameters of the request URI
+	requestURI, err := url.Parse(event.RequestURI)
+	if err != nil {
+		zap.L().Warn("Skipped an audit event with an invalid request URI",
+			zap.String("audit_id", event.AuditID),
+			zap.Error(err),
+		)
+		return nil, false
+	}
+	query := requestURI.Query()
+	raw, err := json.Marshal(map[string]interface{}{
+		"kind":      kind,
+		"container": query.Get("container"),
+		"command":   query["command"],
+	})
+	if err != nil {
+		return nil, false
+	}
+
+	userInfo := event.User
+	if event.ImpersonatedUser != nil {
+		userInfo = *event.ImpersonatedUser
+	}
+
+	return &admissionv1.AdmissionRequest{
+		Kind:        metav1.GroupVersionKind{Version: "v1", Kind: kind},
+		Name:        ref.Name,
+		Namespace:   ref.Namespace,
+		SubResource: ref.Subresource,
+		UserInfo:    userInfo,
+		Object:      runtime.RawExtension{Raw: raw},
+	}, true
+}
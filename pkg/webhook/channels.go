@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/box/kube-exec-controller/pkg/controller"
+)
+
+// sendPodInteraction sends the given PodInteraction to the controller, returning false if it is given up as the
+// channel is closed or being closed by CloseControllerChannels.
+func (s *Server) sendPodInteraction(podInteraction controller.PodInteraction) bool {
+	s.sendMu.RLock()
+	defer s.sendMu.RUnlock()
+
+	if !s.channelsClosed {
+		select {
+		case controller.PodInteractionCh <- podInteraction:
+			return true
+		case <-s.getStopSending():
+		}
+	}
+
+	zap.L().Warn("Gave up sending a Pod interaction to the controller shutting down.",
+		zap.Object("pod_interaction", &podInteraction),
+	)
+	return false
+}
+
+// sendPodExtensionUpdate sends the given PodExtensionUpdate to the controller, returning false if it is given up as
+// the channel is closed or being closed by CloseControllerChannels.
+func (s *Server) sendPodExtensionUpdate(update controller.PodExtensionUpdate) bool {
+	s.sendMu.RLock()
+	defer s.sendMu.RUnlock()
+
+	if !s.channelsClosed {
+		select {
+		case controller.PodExtensionUpdateCh <- update:
+			return true
+		case <-s.getStopSending():
+		}
+	}
+
+	zap.L().Warn("Gave up sending a Pod extension update to the controller shutting down.",
+		zap.String("pod_name", update.Pod.Name),
+		zap.String("pod_namespace", update.Pod.Namespace),
+	)
+	return false
+}
+
+// CloseControllerChannels closes the channels sending Pod interactions and extension updates to the controller, once
+// every request handler and audit log replay sending to them has returned. The sends still blocked after the given
+// timeout (e.g. the controller is stuck) are given up, and nothing is sent to the channels afterwards.
+func (s *Server) CloseControllerChannels(timeout time.Duration) {
+	stopSending := s.getStopSending()
+	timer := time.AfterFunc(timeout, func() { close(stopSending) })
+	defer timer.Stop()
+
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	s.channelsClosed = true
+	close(controller.PodInteractionCh)
+	close(controller.PodExtensionUpdateCh)
+}
+
+// getStopSending returns the channel closed once the sends to the controller in progress are given up.
+func (s *Server) getStopSending() chan struct{} {
+	s.stopSendingOnce.Do(func() {
+		s.stopSending = make(chan struct{})
+	})
+
+	return s.stopSending
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+
+	"github.com/box/kube-exec-controller/pkg/controller"
+)
+
+// podTrackingLabelValue is the value of controller.PodTrackingLabel injected to the Pods at creation.
+const podTrackingLabelValue = "true"
+
+// jsonPatchOperation is an operation of a JSON patch (RFC 6902) returned by a mutating admission response.
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MutatePodCreation handles an incoming request of creating a Pod, injecting controller.PodTrackingLabel to it so that
+// its labels exist before any interaction. It is served on the "/mutate-pod" path for an optional
+// MutatingWebhookConfiguration, and never denies a Pod creation.
+func (s *Server) MutatePodCreation(w http.ResponseWriter, r *http.Request) {
+	admissionReview, err := parseIncomingRequest(r)
+	if err != nil || admissionReview.Request == nil {
+		zap.L().Error("Received a bad request when mutating Pod creation", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	decision := s.DecidePodCreation(admissionReview.Request)
+	writeAdmitResponse(w, admissionReview, decision)
+}
+
+// DecidePodCreation decides the given admission request of creating a Pod, allowing it with a JSON patch adding
+// controller.PodTrackingLabel, unless the Pod is labeled already or never tracked (e.g. in an allowed namespace).
+func (s *Server) DecidePodCreation(admissionRequest *admissionv1.AdmissionRequest) Decision {
+	decision := allowedDecision()
+	if admissionRequest.Operation != admissionv1.Create || s.ExemptAll || s.isAllowedNamespace(admissionRequest.Namespace) {
+		return decision
+	}
+
+	pod, err := getPodStruct(admissionRequest.Object.Raw)
+	if err != nil {
+		zap.L().Error("Error in getting Pod struct from admissionRequest.Object.Raw, allowed it without mutation",
+			zap.Error(err),
+		)
+		return decision
+	}
+	if _, present := pod.Labels[controller.PodTrackingLabel]; present {
+		return decision
+	}
+
+	operation := jsonPatchOperation{
+		Op:    "add",
+		Path:  "/metadata/labels/" + controller.EscapeJSONPointer(controller.PodTrackingLabel),
+		Value: podTrackingLabelValue,
+	}
+	// the labels have to exist before adding a single one
+	if len(pod.Labels) == 0 {
+		operation.Path = "/metadata/labels"
+		operation.Value = map[string]string{controller.PodTrackingLabel: podTrackingLabelValue}
+	}
+	patch, err := json.Marshal([]jsonPatchOperation{operation})
+	if err != nil {
+		zap.L().Error("Error in marshaling the JSON patch of a Pod creation, allowed it without mutation",
+			zap.Error(err),
+		)
+		return decision
+	}
+	decision.Patch = patch
+
+	return decision
+}
@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// podKind is the kind of an admission request changing a Pod object.
+const podKind = "Pod"
+
+// ReviewResult contains the outgoing AdmissionReview and the Decision of admitting a recorded AdmissionReview.
+type ReviewResult struct {
+	Response admissionv1.AdmissionReview `json:"response"`
+	Decision Decision                    `json:"decision"`
+}
+
+// NewOfflineServer returns a Server to admit recorded AdmissionReviews offline (no TLS or listener set up).
+func NewOfflineServer(namespaceAllowlistRaw string) *Server {
+	allowedNamespaces, allowedNamespacePatterns := parseNamespaceAllowlist(namespaceAllowlistRaw)
+	return &Server{
+		AllowedNamespaces:        allowedNamespaces,
+		AllowedNamespacePatterns: allowedNamespacePatterns,
+	}
+}
+
+// Review admits the given AdmissionReview through the same decision logic used by the webhook handlers.
+// It never sends anything to the controller, the result tells what would be tracked instead.
+func (s *Server) Review(incomingReview admissionv1.AdmissionReview) (ReviewResult, error) {
+	admissionRequest := incomingReview.Request
+	if admissionRequest == nil {
+		return ReviewResult{}, fmt.Errorf("the given AdmissionReview contains no request")
+	}
+
+	var decision Decision
+	if admissionRequest.Kind.Kind == podKind && admissionRequest.Operation == admissionv1.Create {
+		decision = s.DecidePodCreation(admissionRequest)
+	} else if admissionRequest.Kind.Kind == podKind {
+		decision = s.DecidePodUpdate(admissionRequest)
+	} else {
+		decision = s.DecidePodInteraction(admissionRequest)
+	}
+
+	return ReviewResult{
+		Response: getOutgoingReview(incomingReview, decision),
+		Decision: decision,
+	}, nil
+}
+
+// ReviewFile reads a recorded AdmissionReview JSON from the given path, admits it offline by Review
+// and prints the result to the given writer.
+func (s *Server) ReviewFile(path string, out io.Writer) error {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var incomingReview admissionv1.AdmissionReview
+	deserializer := codec.UniversalDeserializer()
+	if _, _, err := deserializer.Decode(body, nil, &incomingReview); err != nil {
+		return err
+	}
+
+	result, err := s.Review(incomingReview)
+	if err != nil {
+		return err
+	}
+
+	output, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(output))
+	return err
+}
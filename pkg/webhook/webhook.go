@@ -1,32 +1,77 @@
 package webhook
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/box/kube-exec-controller/pkg/controller"
+	"github.com/box/kube-exec-controller/pkg/duration"
+	"github.com/box/kube-exec-controller/pkg/metrics"
+	"github.com/box/kube-exec-controller/pkg/policy"
 )
 
 var codec = serializer.NewCodecFactory(runtime.NewScheme())
 
+// interactionVerbs maps the kinds of interaction requests to the verbs of their controller.PodInteraction.
+var interactionVerbs = map[string]controller.InteractionVerb{
+	PodExecAdmissionRequestKind:        controller.InteractionVerbExec,
+	PodAttachAdmissionRequestKind:      controller.InteractionVerbAttach,
+	PodPortForwardAdmissionRequestKind: controller.InteractionVerbPortForward,
+}
+
+// defaultShutdownTimeout is the max time to wait for the requests in progress when stopping the server, unless set
+// by Server.ShutdownTimeout. No request takes longer than the server's WriteTimeout anyway.
+const defaultShutdownTimeout = 5 * time.Second
+
+// deniedPodUpdateEventReason is the reason of the K8s event submitted to a Pod of a denied update.
+const deniedPodUpdateEventReason = "PodUpdateDenied"
+
 const (
-	PodExecAdmissionRequestKind   = "PodExecOptions"
-	PodAttachAdmissionRequestKind = "PodAttachOptions"
+	PodExecAdmissionRequestKind        = "PodExecOptions"
+	PodAttachAdmissionRequestKind      = "PodAttachOptions"
+	PodPortForwardAdmissionRequestKind = "PodPortForwardOptions"
 
 	ImmutableLabelsDisallowMsg = "The following Pod labels cannot be updated or removed once set:"
 	InvalidAnnotationsValueMsg = "The given annotation has an invalid value set in the Pod object:"
+	ExceededMaxExtensionMsg    = "The given extension exceeds the max extension of interacted Pods:"
+	ControllerOnlyDisallowMsg  = "The following Pod metadata can only be set or removed by the controller:"
+	PrivilegedOnlyDisallowMsg  = "The following Pod metadata can only be set or removed by allowlisted users:"
+	InteractorOnlyDisallowMsg  = "The following Pod metadata can only be set by the Pod's interactor or allowlisted users:"
+
+	// DefaultPluginWarning is the admission warning advising users of "kubectl pi" on tracked interactions,
+	// unless set otherwise by Server.PluginWarning
+	DefaultPluginWarning = "This Pod will be evicted after your session, see 'kubectl pi get' or extend it by 'kubectl pi extend' (install by 'kubectl krew install pi')"
+
+	// EvictionWarning is the admission warning telling users that the Pod of a tracked interaction gets evicted, if
+	// set by Server.EvictionWarning. Its eviction time is not stated, as it is only known by the controller (e.g. a TTL
+	// overridden by the namespace, or an earlier interaction of the Pod).
+	EvictionWarning = "This Pod will be evicted once its TTL expires, see 'kubectl pi get' for when or run 'kubectl pi extend' to keep it"
+
+	// CommandTruncatedMarker is appended to the command list of a PodInteraction if it gets truncated
+	CommandTruncatedMarker = controller.CommandTruncatedMarker
 )
 
 // Server handles admission requests received from K8s API-Server.
@@ -34,6 +79,63 @@ type Server struct {
 	port              int
 	tlsConfig         *tls.Config
 	AllowedNamespaces map[string]bool
+	// AllowedNamespacePatterns allow the namespaces matching any of the given patterns, set in the namespace
+	// allowlist as globs (e.g. "team-*-sandbox") or regular expressions prefixed with "regex:"
+	AllowedNamespacePatterns []*regexp.Regexp
+	// AllowedUsers and AllowedGroups exempt the requests of the given authenticated users, or of the users
+	// in any of the given groups, regardless of their namespace (e.g. for on-call engineers)
+	AllowedUsers  map[string]bool
+	AllowedGroups map[string]bool
+	// AllowedCommands exempt the interactions running any of the given read-only commands (e.g. "cat" or "ls"),
+	// matched against the first element of the command list. An entry without a slash also matches the command by
+	// its base name under a system directory (e.g. "/bin/cat"), while an entry with a slash only matches the full path
+	AllowedCommands map[string]bool
+	// ExemptAll makes all requests allowed without tracking anything, taking precedence over any allowlist
+	ExemptAll bool
+	// Policy provides additional exemptions from an ExecTrackingPolicy (nil if not configured)
+	Policy *policy.Store
+	// MaxCommandArgs and MaxCommandLength limit the number of args and total characters kept
+	// from the command list of an interaction (zero means unlimited)
+	MaxCommandArgs   int
+	MaxCommandLength int
+	// CommandRedactions redact the sensitive segments of the command list of an interaction, e.g. passwords,
+	// before it is truncated and sent to the controller (nil means no redaction)
+	CommandRedactions []*regexp.Regexp
+	// MaxExtension is the max extension an interacted Pod can be given on top of its TTL, requests exceeding it
+	// are denied unless overridden by the Policy (zero means unlimited)
+	MaxExtension time.Duration
+	// ControllerUsername is the user of the controller, whose Pod updates (e.g. clearing stale
+	// interaction metadata) are always allowed and never sent back to it (empty if not configured)
+	ControllerUsername string
+	// Ready reports whether the controller is ready, e.g. its caches are synced, before the readiness
+	// probe succeeds (nil means always ready)
+	Ready func() bool
+	// ShutdownTimeout is the max time to wait for the requests in progress when stopping the server
+	// (zero means defaultShutdownTimeout)
+	ShutdownTimeout time.Duration
+	// PluginWarning is returned as an admission warning of every tracked interaction, so that users learn about
+	// "kubectl pi" (empty means no warning)
+	PluginWarning string
+	// EvictionWarning makes every tracked interaction return EvictionWarning as an admission warning
+	EvictionWarning bool
+	// AuditLogger records every admitted interaction, including the exempted ones (nil means no audit log)
+	AuditLogger AuditLogger
+	// Recorder submits a K8s event to the Pod of every denied update, e.g. changing its immutable labels by
+	// "kubectl edit", for an audit trail of who attempted it (nil means no event)
+	Recorder record.EventRecorder
+	// PodExemptSelector is the selector of Pods exempt from eviction set on the controller, whose label keys cannot be
+	// changed on an existing Pod by anyone but the allowlisted users and the controller (nil means no selector)
+	PodExemptSelector labels.Selector
+	// HealthPort is a separate port serving the health checks over plain HTTP, for the probes that cannot verify the
+	// webhook certificate. They are still served on the TLS port as well (zero means no separate port)
+	HealthPort int
+
+	// sendMu guards sending to the controller's channels against CloseControllerChannels closing them, which gives up
+	// the sends in progress once stopSending is closed
+	sendMu          sync.RWMutex
+	channelsClosed  bool
+	stopSending     chan struct{}
+	stopSendingOnce sync.Once
 }
 
 // NewServer sets up required configuration and returns a new Server object.
@@ -48,20 +150,27 @@ func NewServer(port int, certPath, keyPath, namespaceAllowlistRaw string) (*Serv
 		Certificates: []tls.Certificate{keyPair},
 	}
 
+	allowedNamespaces, allowedNamespacePatterns := parseNamespaceAllowlist(namespaceAllowlistRaw)
 	return &Server{
-		port:              port,
-		tlsConfig:         tlsConf,
-		AllowedNamespaces: parseNamespaceAllowlist(namespaceAllowlistRaw),
+		port:                     port,
+		tlsConfig:                tlsConf,
+		AllowedNamespaces:        allowedNamespaces,
+		AllowedNamespacePatterns: allowedNamespacePatterns,
 	}, nil
 }
 
-// Run will starts the webhook server listening to the specified paths.
-func (s *Server) Run() error {
+// Run will starts the webhook server listening to the specified paths until the given stop channel is closed.
+// On stop, it stops accepting new requests and waits for the ones in progress (up to the ShutdownTimeout),
+// so that no more Pod interactions or extension updates are sent to the controller once it returns.
+// It returns nil if all requests in progress are completed, or the error of the server otherwise.
+func (s *Server) Run(stopCh <-chan struct{}) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health/liveness", handleLiveness)
-	mux.HandleFunc("/health/readiness", handleReadiness)
+	mux.HandleFunc("/health/readiness", s.HandleReadiness)
 	mux.HandleFunc("/admit-pod-interaction", s.AdmitPodInteraction)
 	mux.HandleFunc("/admit-pod-update", s.AdmitPodUpdate)
+	mux.HandleFunc("/mutate-pod", s.MutatePodCreation)
+	mux.Handle("/metrics", metrics.Handler())
 
 	loggedHandler := loggingMiddleware()(mux)
 	httpServer := &http.Server{
@@ -72,10 +181,73 @@ func (s *Server) Run() error {
 		WriteTimeout:      5 * time.Second,
 	}
 
-	return httpServer.ListenAndServeTLS("", "")
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- httpServer.ListenAndServeTLS("", "")
+	}()
+
+	var healthServer *http.Server
+	if s.HealthPort > 0 {
+		healthServer = &http.Server{
+			Addr:              fmt.Sprintf(":%d", s.HealthPort),
+			Handler:           loggingMiddleware()(s.HealthHandler()),
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      5 * time.Second,
+		}
+		go func() {
+			errCh <- healthServer.ListenAndServe()
+		}()
+	}
+
+	select {
+	case err := <-errCh:
+		if healthServer != nil {
+			healthServer.Close()
+		}
+		httpServer.Close()
+		return err
+	case <-stopCh:
+		timeout := s.ShutdownTimeout
+		if timeout == 0 {
+			timeout = defaultShutdownTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		// the health checks have no request in progress worth waiting for
+		if healthServer != nil {
+			healthServer.Close()
+		}
+		return httpServer.Shutdown(ctx)
+	}
+}
+
+// HealthHandler returns an http.Handler serving the liveness and readiness checks only, which is served over plain
+// HTTP on the HealthPort if set.
+func (s *Server) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health/liveness", handleLiveness)
+	mux.HandleFunc("/health/readiness", s.HandleReadiness)
+
+	return mux
+}
+
+// Decision contains the outcome of admitting a request, without any side effect applied to the controller.
+type Decision struct {
+	StatusCode int    `json:"statusCode"`
+	Allowed    bool   `json:"allowed"`
+	Message    string `json:"message,omitempty"`
+	// Warnings are shown to the user by kubectl even if the request is allowed
+	Warnings []string `json:"warnings,omitempty"`
+	// PodInteraction is set if the request is an interaction to be tracked by the controller
+	PodInteraction *controller.PodInteraction `json:"podInteraction,omitempty"`
+	// PodExtensionUpdate is set if the request is an extension to be handled by the controller
+	PodExtensionUpdate *controller.PodExtensionUpdate `json:"podExtensionUpdate,omitempty"`
+	// Patch is the JSON patch mutating the object of the request, if any
+	Patch []byte `json:"patch,omitempty"`
 }
 
-// AdmitPodInteraction handles an incoming request of interacting a Pod (by kubectl "exec" or "attach" command).
+// AdmitPodInteraction handles an incoming request of interacting a Pod (by kubectl "exec", "attach" or
+// "port-forward" command).
 func (s *Server) AdmitPodInteraction(w http.ResponseWriter, r *http.Request) {
 	admissionReview, err := parseIncomingRequest(r)
 	if err != nil || admissionReview.Request == nil {
@@ -84,140 +256,468 @@ func (s *Server) AdmitPodInteraction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	admissionRequest := admissionReview.Request
+	decision := s.DecidePodInteraction(admissionReview.Request)
+	if decision.PodInteraction != nil {
+		metrics.InteractionsTotal.WithLabelValues(admissionReview.Request.Namespace, string(decision.PodInteraction.Verb)).Inc()
+		s.sendPodInteraction(*decision.PodInteraction)
+	}
+	s.writeAuditLog(admissionReview.Request, decision)
+	writeAdmitResponse(w, admissionReview, decision)
+}
+
+// AdmitPodUpdate handles an incoming request of changing a Pod object.
+func (s *Server) AdmitPodUpdate(w http.ResponseWriter, r *http.Request) {
+	admissionReview, err := parseIncomingRequest(r)
+	if err != nil || admissionReview.Request == nil {
+		zap.L().Error("Received a bad request when admitting Pod update", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	decision := s.DecidePodUpdate(admissionReview.Request)
+	if !decision.Allowed {
+		metrics.DeniedUpdatesTotal.WithLabelValues(admissionReview.Request.Namespace).Inc()
+		s.recordDeniedUpdate(admissionReview.Request, decision)
+	}
+	if decision.PodExtensionUpdate != nil {
+		s.sendPodExtensionUpdate(*decision.PodExtensionUpdate)
+	}
+	writeAdmitResponse(w, admissionReview, decision)
+}
+
+// recordDeniedUpdate submits a K8s event to the Pod of the given denied update request, describing the denial and
+// the requesting user, if a Recorder is set.
+func (s *Server) recordDeniedUpdate(admissionRequest *admissionv1.AdmissionRequest, decision Decision) {
+	if s.Recorder == nil {
+		return
+	}
+
+	pod, err := getPodStruct(admissionRequest.OldObject.Raw)
+	if err != nil {
+		zap.L().Error("Error in getting Pod struct from admissionRequest.OldObject.Raw", zap.Error(err))
+		return
+	}
+	// the name and namespace may be unset in the object of an update request
+	pod.Name = admissionRequest.Name
+	pod.Namespace = admissionRequest.Namespace
+
+	message := fmt.Sprintf("Pod update by user '%s' is denied: %s",
+		admissionRequest.UserInfo.Username, strings.TrimSpace(decision.Message))
+	s.Recorder.Event(&pod, corev1.EventTypeWarning, deniedPodUpdateEventReason, message)
+}
+
+// DecidePodInteraction returns the Decision of a request interacting a Pod (by kubectl "exec", "attach" or
+// "port-forward" command).
+func (s *Server) DecidePodInteraction(admissionRequest *admissionv1.AdmissionRequest) Decision {
+	// skip if all requests are exempted (e.g. verifying the webhook registration before enforcing anything)
+	if s.ExemptAll {
+		zap.L().Debug("Skipped as all requests are exempted", zap.String("namespace", admissionRequest.Namespace))
+		return allowedDecision()
+	}
 
 	// skip if a request contains any namespace in the predefined allow-list
-	if s.AllowedNamespaces[admissionReview.Request.Namespace] {
+	if s.isAllowedNamespace(admissionRequest.Namespace) {
 		zap.L().Debug("Skipped as the request's namespace is in the predefined allow-list",
 			zap.String("namespace", admissionRequest.Namespace),
 		)
-		writeAdmitResponse(w, http.StatusOK, admissionReview, true, "")
-		return
+		return allowedDecision()
+	}
+
+	// skip if a request is sent from any user or group in the predefined allow-list
+	if s.isAllowedUser(admissionRequest.UserInfo) {
+		zap.L().Debug("Skipped as the request's user or group is in the predefined allow-list",
+			zap.String("username", admissionRequest.UserInfo.Username),
+		)
+		return allowedDecision()
+	}
+
+	// skip if a request is sent from any user exempted by the ExecTrackingPolicy
+	if s.Policy.IsExemptUser(admissionRequest.Namespace, admissionRequest.UserInfo.Username) {
+		zap.L().Debug("Skipped as the request's user is exempted by the ExecTrackingPolicy",
+			zap.String("username", admissionRequest.UserInfo.Username),
+		)
+		return allowedDecision()
 	}
 
-	// parse the request into an PodInteraction object and add it to channel for controller to process
-	podInteraction, err := getPodInteractionStruct(admissionRequest)
+	// parse the request into an PodInteraction object for controller to process
+	podInteraction, err := s.getPodInteractionStruct(admissionRequest)
 	if err != nil {
 		zap.L().Error("Unable to construct a PodInteraction struct from the admission request", zap.Error(err))
-		writeAdmitResponse(w, http.StatusBadRequest, admissionReview, true, "")
-		return
+		return Decision{StatusCode: http.StatusBadRequest, Allowed: true}
 	}
 
-	controller.PodInteractionCh <- podInteraction
-	writeAdmitResponse(w, http.StatusOK, admissionReview, true, "")
+	// skip if a request runs any read-only command in the predefined allow-list
+	if s.isAllowedCommand(podInteraction.Commands) {
+		zap.L().Debug("Skipped as the request's command is in the predefined allow-list",
+			zap.String("namespace", admissionRequest.Namespace),
+			zap.Strings("commands", podInteraction.Commands),
+		)
+		return allowedDecision()
+	}
+
+	decision := allowedDecision()
+	decision.PodInteraction = &podInteraction
+	decision.Warnings = s.getInteractionWarnings()
+	return decision
 }
 
-// AdmitPodUpdate handles an incoming request of changing a Pod object.
-func (s *Server) AdmitPodUpdate(w http.ResponseWriter, r *http.Request) {
-	admissionReview, err := parseIncomingRequest(r)
-	if err != nil || admissionReview.Request == nil {
-		zap.L().Error("Received a bad request when admitting Pod update", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
-		return
+// getInteractionWarnings returns the admission warnings of a tracked interaction: when its Pod gets evicted if the
+// TTL is set, followed by the plugin warning if set.
+func (s *Server) getInteractionWarnings() []string {
+	var warnings []string
+	if s.EvictionWarning {
+		warnings = append(warnings, EvictionWarning)
+	}
+	if s.PluginWarning != "" {
+		warnings = append(warnings, s.PluginWarning)
 	}
 
-	admissionRequest := admissionReview.Request
+	return warnings
+}
+
+// DecidePodUpdate returns the Decision of a request changing a Pod object.
+func (s *Server) DecidePodUpdate(admissionRequest *admissionv1.AdmissionRequest) Decision {
+	// skip if all requests are exempted (e.g. verifying the webhook registration before enforcing anything)
+	if s.ExemptAll {
+		zap.L().Debug("Skipped as all requests are exempted", zap.String("namespace", admissionRequest.Namespace))
+		return allowedDecision()
+	}
 
 	// skip if a request contains any namespace in the predefined allow-list.
-	if s.AllowedNamespaces[admissionRequest.Namespace] {
+	if s.isAllowedNamespace(admissionRequest.Namespace) {
 		zap.L().Debug("Skipped as the request's namespace is in the predefined allow-list",
 			zap.String("namespace", admissionRequest.Namespace),
 		)
-		writeAdmitResponse(w, http.StatusOK, admissionReview, true, "")
-		return
+		return allowedDecision()
+	}
+
+	// skip if a request is sent from the controller itself, never sending its own updates back to it (e.g. clearing
+	// a stale interaction) and preventing a feedback loop; it is identified by its authenticated user, as the field
+	// manager of a request can be set by anyone
+	if s.ControllerUsername != "" && admissionRequest.UserInfo.Username == s.ControllerUsername {
+		zap.L().Debug("Skipped as the request is sent from the controller itself",
+			zap.String("username", admissionRequest.UserInfo.Username),
+		)
+		return allowedDecision()
 	}
 
-	// skip if the given Pod did not have label "PodInteractionTimestampLabel" set previously (not an interacted Pod)
 	oldPod, err := getPodStruct(admissionRequest.OldObject.Raw)
 	if err != nil {
 		zap.L().Error("Error in getting Pod struct from admissionRequest.OldObject.Raw", zap.Error(err))
-		writeAdmitResponse(w, http.StatusBadRequest, admissionReview, true, "")
-		return
+		return Decision{StatusCode: http.StatusBadRequest, Allowed: true}
 	}
-	oldTimestamp, present := oldPod.Labels[controller.PodInteractionTimestampLabel]
+	pod, err := getPodStruct(admissionRequest.Object.Raw)
+	if err != nil {
+		zap.L().Error("Error in getting Pod struct from admitRequest.Object.Raw", zap.Error(err))
+		return Decision{StatusCode: http.StatusBadRequest, Allowed: true}
+	}
+
+	// the users in the allow-list are only exempted from interaction tracking, their updates are still validated and
+	// handled like anyone else's, except for being privileged to pin Pods and hold them in use
+	privileged := s.isAllowedUser(admissionRequest.UserInfo)
+
+	// disallow pinning or unpinning any Pod, interacted or not, by anyone but the users in the allow-list or the
+	// controller (allowed above), as it exempts the Pod from eviction; its owner can still pin it at creation
+	if key, changed := getChangedKey(oldPod.Annotations, pod.Annotations,
+		controller.PodDisableEvictionAnnotate); changed && !privileged {
+		message := fmt.Sprintln(PrivilegedOnlyDisallowMsg, key)
+		return Decision{StatusCode: http.StatusOK, Allowed: false, Message: message}
+	}
+
+	// disallow changing any label selected by the exempt selector likewise, which would exempt the Pod from eviction
+	// right before interacting it (or unexempt someone else's)
+	if key, changed := getChangedKey(oldPod.Labels, pod.Labels, getSelectorKeys(s.PodExemptSelector)...); changed && !privileged {
+		message := fmt.Sprintln(PrivilegedOnlyDisallowMsg, key)
+		return Decision{StatusCode: http.StatusOK, Allowed: false, Message: message}
+	}
+
+	// disallow labeling any Pod as a debug Job or unlabeling it likewise, as the controller deletes the owning Job of
+	// such a Pod instead of evicting it; its owner can still label it at creation, e.g. through the Job's pod template
+	if key, changed := getChangedKey(oldPod.Labels, pod.Labels, controller.DebugJobLabel); changed && !privileged {
+		message := fmt.Sprintln(PrivilegedOnlyDisallowMsg, key)
+		return Decision{StatusCode: http.StatusOK, Allowed: false, Message: message}
+	}
+
+	// disallow justifying any Pod, interacted or not, at an invalid or future time, which would justify it forever
+	oldJustifiedTimestamp := oldPod.Annotations[controller.PodExecJustificationTimeAnnotate]
+	newJustifiedTimestamp, justifiedPresent := pod.Annotations[controller.PodExecJustificationTimeAnnotate]
+	if justifiedPresent && newJustifiedTimestamp != oldJustifiedTimestamp {
+		justifiedTime, err := strconv.ParseInt(newJustifiedTimestamp, 10, 64)
+		if err != nil || time.Unix(justifiedTime, 0).After(time.Now().Add(controller.MaxClockSkew)) {
+			message := fmt.Sprintln(InvalidAnnotationsValueMsg, controller.PodExecJustificationTimeAnnotate)
+			return Decision{StatusCode: http.StatusOK, Allowed: false, Message: message}
+		}
+	}
+
+	// skip if the given Pod did not have "PodInteractionTimestampLabel" set previously (not an interacted Pod)
+	// it can be stored as either a label or an annotation, depending on the controller's interaction metadata
+	oldTimestamp, present := controller.GetInteractionMetadata(oldPod, controller.PodInteractionTimestampLabel)
 	if !present {
 		zap.L().Debug("Skipped as the request's Pod did not have label \"PodInteractedTimestampLabelKey\" set")
-		writeAdmitResponse(w, http.StatusOK, admissionReview, true, "")
-		return
+		return allowedDecision()
 	}
 
 	// disallow if changing the Pod's label "PodInteractionTimestampLabel" or "PodTTLDurationLabel"
 	// they are required to get a Pod's termination time and should not be changed once set
-	pod, err := getPodStruct(admissionRequest.Object.Raw)
-	if err != nil {
-		zap.L().Error("Error in getting Pod struct from admitRequest.Object.Raw", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
-		writeAdmitResponse(w, http.StatusBadRequest, admissionReview, true, "")
-		return
-	}
-
-	oldTTLDuration := oldPod.Labels[controller.PodTTLDurationLabel]
-	if pod.Labels[controller.PodInteractionTimestampLabel] != oldTimestamp ||
-		pod.Labels[controller.PodTTLDurationLabel] != oldTTLDuration {
+	oldTTLDuration, _ := controller.GetInteractionMetadata(oldPod, controller.PodTTLDurationLabel)
+	newTimestamp, _ := controller.GetInteractionMetadata(pod, controller.PodInteractionTimestampLabel)
+	newTTLDuration, _ := controller.GetInteractionMetadata(pod, controller.PodTTLDurationLabel)
+	if newTimestamp != oldTimestamp || newTTLDuration != oldTTLDuration {
 		zap.L().Debug("Disallowed an request changing the PodInteractionTimestampLabel or PodTTLDurationLabel")
 		message := fmt.Sprintln(ImmutableLabelsDisallowMsg, controller.PodInteractionTimestampLabel, controller.PodTTLDurationLabel)
-		writeAdmitResponse(w, http.StatusOK, admissionReview, false, message)
-		return
+		return Decision{StatusCode: http.StatusOK, Allowed: false, Message: message}
 	}
 
 	// check annotation change (for extending termination time)
+	decision := allowedDecision()
 	oldExtendDuration := oldPod.Annotations[controller.PodExtendDurationAnnotate]
 	newExtendDuration := pod.Annotations[controller.PodExtendDurationAnnotate]
 	if oldExtendDuration != newExtendDuration {
 		// disallow if setting an invalid duration
-		if _, err := time.ParseDuration(newExtendDuration); newExtendDuration != "" && err != nil {
+		extension, err := duration.Parse(newExtendDuration)
+		if newExtendDuration != "" && err != nil {
 			message := fmt.Sprintln(InvalidAnnotationsValueMsg, controller.PodExtendDurationAnnotate)
-			writeAdmitResponse(w, http.StatusOK, admissionReview, false, message)
-			return
+			return Decision{StatusCode: http.StatusOK, Allowed: false, Message: message}
+		}
+
+		// disallow if exceeding the max extension, which would be capped by the controller anyway
+		if maxExtension := s.Policy.MaxExtension(admissionRequest.Namespace, s.MaxExtension); maxExtension > 0 && extension > maxExtension {
+			message := fmt.Sprintln(ExceededMaxExtensionMsg, maxExtension.String())
+			return Decision{StatusCode: http.StatusOK, Allowed: false, Message: message}
 		}
 
-		podExtensionUpdate := controller.PodExtensionUpdate{
+		decision.PodExtensionUpdate = &controller.PodExtensionUpdate{
 			Pod:      pod,
 			Username: admissionRequest.UserInfo.Username,
 		}
-		controller.PodExtensionUpdateCh <- podExtensionUpdate
 	}
 
-	writeAdmitResponse(w, http.StatusOK, admissionReview, true, "")
+	// check annotation change (for holding the Pod in use or releasing it)
+	oldInUse, oldPresent := oldPod.Annotations[controller.PodInUseAnnotate]
+	newInUse, newPresent := pod.Annotations[controller.PodInUseAnnotate]
+	if oldInUse != newInUse || oldPresent != newPresent {
+		// disallow if setting any value other than "true", the annotation is removed to release the Pod
+		if newPresent && newInUse != "true" {
+			message := fmt.Sprintln(InvalidAnnotationsValueMsg, controller.PodInUseAnnotate)
+			return Decision{StatusCode: http.StatusOK, Allowed: false, Message: message}
+		}
+		// disallow holding the Pod by anyone but its interactor or the users in the allow-list, as it pauses its
+		// eviction; anyone can release it
+		if newPresent && !privileged && !controller.IsInteractor(oldPod, admissionRequest.UserInfo.Username) {
+			message := fmt.Sprintln(InteractorOnlyDisallowMsg, controller.PodInUseAnnotate)
+			return Decision{StatusCode: http.StatusOK, Allowed: false, Message: message}
+		}
+
+		if decision.PodExtensionUpdate == nil {
+			decision.PodExtensionUpdate = &controller.PodExtensionUpdate{
+				Pod:          pod,
+				Username:     admissionRequest.UserInfo.Username,
+				InUseToggled: true,
+			}
+		}
+	}
+
+	// disallow changing the latest interaction (recorded by the controller in the idle TTL mode), which would
+	// postpone evicting the Pod beyond its TTL, or forging the extension history, as the controller's own updates
+	// are allowed above
+	if key, changed := getChangedKey(oldPod.Annotations, pod.Annotations,
+		controller.PodLastInteractionTimestampAnnotate, controller.PodExtensionHistoryAnnotate); changed {
+		message := fmt.Sprintln(ControllerOnlyDisallowMsg, key)
+		return Decision{StatusCode: http.StatusOK, Allowed: false, Message: message}
+	}
+
+	return decision
+}
+
+// getChangedKey returns the first of the given keys set, changed or removed between the given old and new labels or
+// annotations of a Pod, or false if none of them is.
+func getChangedKey(oldMetadata, metadata map[string]string, keys ...string) (string, bool) {
+	for _, key := range keys {
+		oldVal, oldPresent := oldMetadata[key]
+		val, present := metadata[key]
+		if oldVal != val || oldPresent != present {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// getSelectorKeys returns the label keys of the requirements of the given selector, if any.
+func getSelectorKeys(selector labels.Selector) []string {
+	if selector == nil {
+		return nil
+	}
+
+	requirements, _ := selector.Requirements()
+	keys := make([]string, 0, len(requirements))
+	for _, requirement := range requirements {
+		keys = append(keys, requirement.Key())
+	}
+	return keys
 }
 
-// parseNamespaceAllowlist parses a comma-separated list of namespaces into a Map to have O(1) lookup time.
-func parseNamespaceAllowlist(raw string) map[string]bool {
+// allowedDecision returns a Decision allowing the request with nothing to be handled by the controller.
+func allowedDecision() Decision {
+	return Decision{StatusCode: http.StatusOK, Allowed: true}
+}
+
+// isAllowedNamespace returns if the given namespace is in the predefined allow-list, matches any of its patterns or
+// is exempted by the ExecTrackingPolicy.
+func (s *Server) isAllowedNamespace(namespace string) bool {
+	if s.AllowedNamespaces[namespace] {
+		return true
+	}
+	for _, pattern := range s.AllowedNamespacePatterns {
+		if pattern.MatchString(namespace) {
+			return true
+		}
+	}
+
+	return s.Policy.IsExemptNamespace(namespace)
+}
+
+// isAllowedUser returns if the given user or any of its groups is in the predefined allow-list.
+func (s *Server) isAllowedUser(userInfo authenticationv1.UserInfo) bool {
+	if s.AllowedUsers[userInfo.Username] {
+		return true
+	}
+	for _, group := range userInfo.Groups {
+		if s.AllowedGroups[group] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trustedCommandDirs are the directories whose commands are matched by their base name against the entries of
+// Server.AllowedCommands without a slash, as a binary of an allowlisted name can be dropped anywhere else (e.g. "/tmp/ls").
+var trustedCommandDirs = []string{"/bin", "/sbin", "/usr/bin", "/usr/sbin", "/usr/local/bin", "/usr/local/sbin"}
+
+// isAllowedCommand returns if the first element of the given command list is in the predefined allow-list, or its
+// base name if it is under any of trustedCommandDirs. An interaction without any command (e.g. attaching to a
+// container) is never allowed.
+func (s *Server) isAllowedCommand(commands []string) bool {
+	if len(commands) == 0 {
+		return false
+	}
+
+	command := commands[0]
+	if s.AllowedCommands[command] {
+		return true
+	}
+	if !strings.Contains(command, "/") {
+		return false
+	}
+
+	dir, name := path.Split(path.Clean(command))
+	for _, trustedDir := range trustedCommandDirs {
+		if path.Clean(dir) == trustedDir {
+			return s.AllowedCommands[name]
+		}
+	}
+
+	return false
+}
+
+// ParseAllowlist parses a comma-separated list (e.g. of users or groups) into a Map to have O(1) lookup time.
+func ParseAllowlist(raw string) map[string]bool {
+	resMap := map[string]bool{}
+	for _, val := range strings.Split(raw, ",") {
+		if entry := strings.TrimSpace(val); entry != "" {
+			resMap[entry] = true
+		}
+	}
+
+	return resMap
+}
+
+// parseNamespaceAllowlist parses a comma-separated list of namespaces into a Map to have O(1) lookup time, and the
+// patterns in it (globs or regular expressions prefixed with namespacePatternRegexPrefix) into compiled regular
+// expressions. Entries that are neither valid namespace names nor patterns can never match a request, so they are
+// skipped with a warning.
+func parseNamespaceAllowlist(raw string) (map[string]bool, []*regexp.Regexp) {
 	namespaces := strings.TrimSpace(raw)
 	resMap := map[string]bool{}
+	var patterns []*regexp.Regexp
 
 	for _, val := range strings.Split(namespaces, ",") {
-		if ns := strings.TrimSpace(val); ns != "" {
-			resMap[ns] = true
+		ns := strings.TrimSpace(val)
+		if ns == "" {
+			continue
+		}
+
+		if isNamespacePattern(ns) {
+			pattern, err := compileNamespacePattern(ns)
+			if err != nil {
+				zap.L().Warn("Ignored an invalid namespace pattern in the namespace allowlist",
+					zap.String("pattern", ns),
+					zap.Error(err),
+				)
+				continue
+			}
+			patterns = append(patterns, pattern)
+			continue
+		}
+
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			zap.L().Warn("Ignored an invalid namespace name in the namespace allowlist",
+				zap.String("namespace", ns),
+				zap.Strings("errors", errs),
+			)
+			continue
 		}
+		resMap[ns] = true
 	}
 
-	return resMap
+	return resMap, patterns
 }
 
-// writeAdmitResponse sends an allowed or disallowed response with additional message to the given admission request.
-func writeAdmitResponse(w http.ResponseWriter, statusCode int, incomingReview admissionv1.AdmissionReview, isAllowed bool, message string) {
-	w.Header().Set("Content-Type", "application/json")
+// namespacePatternRegexPrefix prefixes a regular expression in the namespace allowlist, e.g. "regex:^team-[0-9]+$".
+const namespacePatternRegexPrefix = "regex:"
 
-	outgoingReview := admissionv1.AdmissionReview{
-		TypeMeta: incomingReview.TypeMeta,
-		Response: &admissionv1.AdmissionResponse{
-			Allowed: isAllowed,
-		},
-	}
+// isNamespacePattern returns if the given entry of the namespace allowlist is a regular expression or a glob, which
+// has a wildcard ("*" or "?") that a namespace name cannot have.
+func isNamespacePattern(entry string) bool {
+	return strings.HasPrefix(entry, namespacePatternRegexPrefix) || strings.ContainsAny(entry, "*?")
+}
 
-	if incomingReview.Request != nil {
-		outgoingReview.Response.UID = incomingReview.Request.UID
+// compileNamespacePattern compiles the given pattern of the namespace allowlist to match whole namespace names. In a
+// glob, "*" matches any sequence of characters and "?" any single one.
+func compileNamespacePattern(pattern string) (*regexp.Regexp, error) {
+	if expr := strings.TrimPrefix(pattern, namespacePatternRegexPrefix); expr != pattern {
+		return regexp.Compile("^(?:" + expr + ")$")
 	}
 
-	// add a message with 403 HTTP status code when rejecting a request
-	if !isAllowed {
-		outgoingReview.Response.Result = &metav1.Status{
-			Code:    http.StatusForbidden,
-			Message: message,
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	return regexp.Compile("^" + expr + "$")
+}
+
+// WarnMissingNamespaces logs a warning for each namespace in the allowlist that does not exist in the cluster,
+// as it is likely a typo and the intended namespace is not exempted.
+func (s *Server) WarnMissingNamespaces(kubeClient kubernetes.Interface) error {
+	for ns := range s.AllowedNamespaces {
+		_, err := kubeClient.CoreV1().Namespaces().Get(context.TODO(), ns, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			zap.L().Warn("Namespace in the namespace allowlist does not exist", zap.String("namespace", ns))
+			continue
+		}
+		if err != nil {
+			return err
 		}
 	}
 
+	return nil
+}
+
+// writeAdmitResponse sends an allowed or disallowed response of the given Decision to the given admission request.
+func writeAdmitResponse(w http.ResponseWriter, incomingReview admissionv1.AdmissionReview, decision Decision) {
+	w.Header().Set("Content-Type", "application/json")
+
+	outgoingReview := getOutgoingReview(incomingReview, decision)
 	response, err := json.Marshal(outgoingReview)
 	if err != nil {
 		zap.L().Error("Error in marshaling outgoing admission review, returning 500", zap.Error(err))
@@ -231,7 +731,38 @@ func writeAdmitResponse(w http.ResponseWriter, statusCode int, incomingReview ad
 		return
 	}
 
-	w.WriteHeader(statusCode)
+	w.WriteHeader(decision.StatusCode)
+}
+
+// getOutgoingReview returns an AdmissionReview responding to the given incoming review with the given Decision.
+func getOutgoingReview(incomingReview admissionv1.AdmissionReview, decision Decision) admissionv1.AdmissionReview {
+	outgoingReview := admissionv1.AdmissionReview{
+		TypeMeta: incomingReview.TypeMeta,
+		Response: &admissionv1.AdmissionResponse{
+			Allowed:  decision.Allowed,
+			Warnings: decision.Warnings,
+		},
+	}
+
+	if incomingReview.Request != nil {
+		outgoingReview.Response.UID = incomingReview.Request.UID
+	}
+
+	if len(decision.Patch) > 0 {
+		patchType := admissionv1.PatchTypeJSONPatch
+		outgoingReview.Response.Patch = decision.Patch
+		outgoingReview.Response.PatchType = &patchType
+	}
+
+	// add a message with 403 HTTP status code when rejecting a request
+	if !decision.Allowed {
+		outgoingReview.Response.Result = &metav1.Status{
+			Code:    http.StatusForbidden,
+			Message: decision.Message,
+		}
+	}
+
+	return outgoingReview
 }
 
 // parseIncomingRequest parses the incoming request body and returns an admission.AdmissionReview object.
@@ -262,26 +793,71 @@ func getPodStruct(fromAdmitRequestObjectRaw []byte) (corev1.Pod, error) {
 }
 
 // getPodInteractionStruct parses the given admission request and returns a controller.PodInteraction object.
-// The request must be either corev1.PodExecOptions or corev1.PodAttachOptions kind.
-func getPodInteractionStruct(fromRequest *admissionv1.AdmissionRequest) (controller.PodInteraction, error) {
+// The request must be either corev1.PodExecOptions, corev1.PodAttachOptions or corev1.PodPortForwardOptions kind,
+// whose command is optional and ports are recorded for the latter.
+// Its command list is redacted by the Server's CommandRedactions, and truncated (with CommandTruncatedMarker
+// appended) if exceeding the Server's limits.
+func (s *Server) getPodInteractionStruct(fromRequest *admissionv1.AdmissionRequest) (controller.PodInteraction, error) {
 	var data map[string]interface{}
 	err := json.Unmarshal(fromRequest.Object.Raw, &data)
 	if err != nil {
 		return controller.PodInteraction{}, err
 	}
 
-	kind := data["kind"].(string)
-	if kind != PodExecAdmissionRequestKind && kind != PodAttachAdmissionRequestKind {
+	kind, err := getStringField(data, "kind")
+	if err != nil {
+		return controller.PodInteraction{}, err
+	}
+	if kind != PodExecAdmissionRequestKind && kind != PodAttachAdmissionRequestKind &&
+		kind != PodPortForwardAdmissionRequestKind {
 		return controller.PodInteraction{}, fmt.Errorf("invalid kind '%s' in the given admission request", kind)
 	}
 
-	container := data["container"].(string)
+	container, err := getStringField(data, "container")
+	if err != nil {
+		return controller.PodInteraction{}, err
+	}
+
+	// convert the raw command list from []interface to []string, which is absent when attaching to
+	// the running process of a container (an interactive session), e.g. by "kubectl attach"
+	commandRaw, err := getListField(data, "command")
+	if err != nil {
+		return controller.PodInteraction{}, err
+	}
+	commands := make([]string, 0, len(commandRaw))
+	for _, cr := range commandRaw {
+		command, ok := cr.(string)
+		if !ok {
+			return controller.PodInteraction{}, fmt.Errorf(
+				"invalid element of type %T in field 'command' of the given admission request, expected a string", cr)
+		}
+		commands = append(commands, command)
+	}
+
+	// convert the raw port list of a port-forward request, which has no command
+	portsRaw, err := getListField(data, "ports")
+	if err != nil {
+		return controller.PodInteraction{}, err
+	}
+	var ports []int32
+	for _, pr := range portsRaw {
+		port, ok := pr.(float64)
+		if !ok {
+			return controller.PodInteraction{}, fmt.Errorf(
+				"invalid element of type %T in field 'ports' of the given admission request, expected a number", pr)
+		}
+		ports = append(ports, int32(port))
+	}
 
-	// convert the raw command list from []interface to []string
-	commandRaw := data["command"].([]interface{})
-	commands := make([]string, len(commandRaw))
-	for i, cr := range commandRaw {
-		commands[i] = cr.(string)
+	commands = controller.RedactCommands(commands, s.CommandRedactions)
+	commands, truncated := controller.TruncateCommands(commands, s.MaxCommandArgs, s.MaxCommandLength)
+	if truncated {
+		zap.L().Info("Truncated the command list of a Pod interaction for exceeding the limits",
+			zap.String("pod_name", fromRequest.Name),
+			zap.String("pod_namespace", fromRequest.Namespace),
+			zap.Int("command_args", len(commandRaw)),
+		)
+		metrics.CommandsTruncatedTotal.WithLabelValues(fromRequest.Namespace).Inc()
 	}
 
 	return controller.PodInteraction{
@@ -291,9 +867,70 @@ func getPodInteractionStruct(fromRequest *admissionv1.AdmissionRequest) (control
 		Username:      fromRequest.UserInfo.Username,
 		Commands:      commands,
 		InitTime:      time.Now(),
+		Verb:          interactionVerbs[kind],
+		Interactive:   len(commandRaw) == 0 && kind != PodPortForwardAdmissionRequestKind,
+		Ports:         ports,
+		ClientInfo:    getClientInfo(fromRequest.UserInfo),
 	}, nil
 }
 
+// getStringField returns the string value of the given field of an admission request object, empty if it is absent,
+// or an error if it is of another type.
+func getStringField(data map[string]interface{}, field string) (string, error) {
+	raw, present := data[field]
+	if !present || raw == nil {
+		return "", nil
+	}
+
+	val, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid field '%s' of type %T in the given admission request, expected a string", field, raw)
+	}
+	return val, nil
+}
+
+// getListField returns the list value of the given field of an admission request object, nil if it is absent,
+// or an error if it is of another type.
+func getListField(data map[string]interface{}, field string) ([]interface{}, error) {
+	raw, present := data[field]
+	if !present || raw == nil {
+		return nil, nil
+	}
+
+	val, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid field '%s' of type %T in the given admission request, expected a list", field, raw)
+	}
+	return val, nil
+}
+
+// clientInfoKeys are the keys of the extra info set by the authenticator recorded by getClientInfo, which tell where
+// a service account token bound to a Pod is used from.
+var clientInfoKeys = map[string]bool{
+	"authentication.kubernetes.io/node-name": true,
+	"authentication.kubernetes.io/pod-name":  true,
+}
+
+// getClientInfo returns the available metadata of the client sending an admission request. The API server does not
+// forward the client's User-Agent to admission webhooks, so this is limited to the extra info set by the
+// authenticator in clientInfoKeys. The user's UID and any other extra info (e.g. the credential ID or the scopes of
+// a token) are left out, as the metadata is annotated to the Pod for anyone reading it to see. It returns nil if none
+// is available.
+func getClientInfo(userInfo authenticationv1.UserInfo) map[string]string {
+	var clientInfo map[string]string
+	for key, values := range userInfo.Extra {
+		if !clientInfoKeys[key] || len(values) == 0 {
+			continue
+		}
+		if clientInfo == nil {
+			clientInfo = map[string]string{}
+		}
+		clientInfo[key] = strings.Join(values, ",")
+	}
+
+	return clientInfo
+}
+
 // handleLiveness responds to a Kubernetes Liveness probe.
 func handleLiveness(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
@@ -301,9 +938,14 @@ func handleLiveness(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// handleReadiness responds to a Kubernetes Readiness probe.
-func handleReadiness(w http.ResponseWriter, r *http.Request) {
+// HandleReadiness responds to a Kubernetes Readiness probe. It fails until the controller is ready.
+func (s *Server) HandleReadiness(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
+	if s.Ready != nil && !s.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
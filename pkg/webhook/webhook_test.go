@@ -2,26 +2,44 @@ package webhook_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
 	admissionv1 "k8s.io/api/admission/v1"
 	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/box/kube-exec-controller/pkg/controller"
+	"github.com/box/kube-exec-controller/pkg/metrics"
 	"github.com/box/kube-exec-controller/pkg/webhook"
 )
 
@@ -76,6 +94,7 @@ func TestAdmitPodInteraction(t *testing.T) {
 				Username:      "test-user-exec",
 				ContainerName: "test-container-exec",
 				Commands:      []string{"test-command-exec"},
+				Verb:          controller.InteractionVerbExec,
 			},
 		},
 		{
@@ -102,6 +121,35 @@ func TestAdmitPodInteraction(t *testing.T) {
 				Username:      "test-user-attach",
 				ContainerName: "test-container-attach",
 				Commands:      []string{"test-command-attach"},
+				Verb:          controller.InteractionVerbAttach,
+			},
+		},
+		{
+			name: "Test-4 admit pod interaction from 'kubectl attach' with no command (an interactive session)",
+			admissionReview: admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					UID:       "test-uid-attach-interactive",
+					Namespace: testNamespaceRegular,
+					Name:      "test-pod-attach-interactive",
+					UserInfo: authenticationv1.UserInfo{
+						Username: "test-user-attach",
+					},
+					Object: runtime.RawExtension{
+						Raw: []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container-attach", "stdin": true, "tty": true}`, webhook.PodAttachAdmissionRequestKind))},
+				},
+			},
+			expectedAdmissionResponse: admissionv1.AdmissionResponse{
+				UID:     "test-uid-attach-interactive",
+				Allowed: true,
+			},
+			expectedPodInteraction: controller.PodInteraction{
+				PodNamespace:  testNamespaceRegular,
+				PodName:       "test-pod-attach-interactive",
+				Username:      "test-user-attach",
+				ContainerName: "test-container-attach",
+				Commands:      []string{},
+				Verb:          controller.InteractionVerbAttach,
+				Interactive:   true,
 			},
 		},
 	}
@@ -121,7 +169,10 @@ func TestAdmitPodInteraction(t *testing.T) {
 			responseRecorder := httptest.NewRecorder()
 			handler := http.HandlerFunc(testServer.AdmitPodInteraction)
 			// use a goroutine as the AdmitPodInteraction func could send values to channel
+			served := make(chan struct{})
 			go func() {
+				defer close(served)
+
 				handler.ServeHTTP(responseRecorder, request)
 				// manually insert an empty value in channel to unblock the loop
 				if reflect.DeepEqual(testCase.expectedPodInteraction, controller.PodInteraction{}) {
@@ -132,6 +183,8 @@ func TestAdmitPodInteraction(t *testing.T) {
 			// check received PodInteraction struct and the admission review response
 			receivedPodInteraction = <-controller.PodInteractionCh
 			checkPodIntearactionObj(t, receivedPodInteraction, testCase.expectedPodInteraction)
+			// the response is written after sending to the channel
+			<-served
 			checkAdmissionReviewResponse(t, responseRecorder.Body, testCase.expectedAdmissionResponse)
 		})
 	}
@@ -373,7 +426,10 @@ func TestAdmitPodUpdate(t *testing.T) {
 			responseRecorder := httptest.NewRecorder()
 			handler := http.HandlerFunc(testServer.AdmitPodUpdate)
 			// use a goroutine as the AdmitPodUpdate func could send values to channel
+			served := make(chan struct{})
 			go func() {
+				defer close(served)
+
 				handler.ServeHTTP(responseRecorder, request)
 				// manually insert an empty value in channel to unblock the loop
 				if reflect.DeepEqual(testCase.expectedPodExtensionUpdate, controller.PodExtensionUpdate{}) {
@@ -385,6 +441,8 @@ func TestAdmitPodUpdate(t *testing.T) {
 			receivedPodExtensionUpdate = <-controller.PodExtensionUpdateCh
 			checkPodExtensionUpdateObj(t, receivedPodExtensionUpdate, testCase.expectedPodExtensionUpdate)
 			// checkPodPodExtensionUpdateObj(t, receivedPodExtensionUpdate, testCase.expectedPodExtensionUpdate)
+			// the response is written after sending to the channel
+			<-served
 			checkAdmissionReviewResponse(t, responseRecorder.Body, testCase.expectedAdmissionResponse)
 		})
 	}
@@ -392,49 +450,1915 @@ func TestAdmitPodUpdate(t *testing.T) {
 	close(controller.PodExtensionUpdateCh)
 }
 
-// setupZapLogging gives better visibility when running a test
-func setupZapLogging(t *testing.T) {
-	logger := zaptest.NewLogger(t)
-	zap.ReplaceGlobals(logger)
-}
+// TestDecidePodInteractionTruncatedCommands tests truncating an oversized command list of a pod interaction
+func TestDecidePodInteractionTruncatedCommands(t *testing.T) {
+	setupZapLogging(t)
 
-// getPodObjectRaw constructs a new pod with the given labels and annotations and returns the encoded result
-func getPodObjectRaw(labels, annotations map[string]string) []byte {
-	pod := corev1.Pod{}
-	pod.SetLabels(labels)
-	pod.SetAnnotations(annotations)
+	testNamespace := "test-namespace-truncated"
+	longCommands := make([]string, 1000)
+	for i := range longCommands {
+		longCommands[i] = fmt.Sprintf("arg-%d", i)
+	}
+	commandsRaw, _ := json.Marshal(longCommands)
+	admissionRequest := &admissionv1.AdmissionRequest{
+		UID:       "test-uid-truncated",
+		Namespace: testNamespace,
+		Name:      "test-pod-truncated",
+		Object: runtime.RawExtension{
+			Raw: []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container", "command":%s}`, webhook.PodExecAdmissionRequestKind, commandsRaw)),
+		},
+	}
 
-	scheme := runtime.NewScheme()
-	corev1.AddToScheme(scheme)
-	codec := serializer.NewCodecFactory(scheme).LegacyCodec(corev1.SchemeGroupVersion)
-	output, _ := runtime.Encode(codec, pod.DeepCopyObject())
-	return output
+	// verify the command list is truncated by the max number of args
+	testServer := webhook.Server{MaxCommandArgs: 3}
+	decision := testServer.DecidePodInteraction(admissionRequest)
+	if decision.PodInteraction == nil {
+		t.Fatal("expected a pod interaction to be tracked, got nil")
+	}
+	expectedCommands := []string{"arg-0", "arg-1", "arg-2", webhook.CommandTruncatedMarker}
+	if !reflect.DeepEqual(expectedCommands, decision.PodInteraction.Commands) {
+		t.Errorf("expected commands: %v, got: %v", expectedCommands, decision.PodInteraction.Commands)
+	}
+
+	// verify the command list is truncated by the max number of characters
+	testServer = webhook.Server{MaxCommandLength: 12}
+	decision = testServer.DecidePodInteraction(admissionRequest)
+	expectedCommands = []string{"arg-0", "arg-1", "ar", webhook.CommandTruncatedMarker}
+	if !reflect.DeepEqual(expectedCommands, decision.PodInteraction.Commands) {
+		t.Errorf("expected commands: %v, got: %v", expectedCommands, decision.PodInteraction.Commands)
+	}
+
+	// verify a multi-byte character is never split by the max number of characters
+	multiByteCommandsRaw, _ := json.Marshal([]string{"echo", "héllo"})
+	multiByteRequest := admissionRequest.DeepCopy()
+	multiByteRequest.Object.Raw = []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container", "command":%s}`, webhook.PodExecAdmissionRequestKind, multiByteCommandsRaw))
+	testServer = webhook.Server{MaxCommandLength: 6}
+	decision = testServer.DecidePodInteraction(multiByteRequest)
+	expectedCommands = []string{"echo", "hé", webhook.CommandTruncatedMarker}
+	if !reflect.DeepEqual(expectedCommands, decision.PodInteraction.Commands) {
+		t.Errorf("expected commands: %v, got: %v", expectedCommands, decision.PodInteraction.Commands)
+	}
+
+	// verify the command list is kept as is without limits
+	testServer = webhook.Server{}
+	decision = testServer.DecidePodInteraction(admissionRequest)
+	if !reflect.DeepEqual(longCommands, decision.PodInteraction.Commands) {
+		t.Errorf("expected %d commands kept, got: %d", len(longCommands), len(decision.PodInteraction.Commands))
+	}
+
+	if count := testutil.ToFloat64(metrics.CommandsTruncatedTotal.WithLabelValues(testNamespace)); count != 3 {
+		t.Errorf("expected 3 truncations recorded in metrics, got: %v", count)
+	}
 }
 
-// checkAdmissionReviewResponse parses the given responseBody to AdmissionReview and compares it with the given AdmissionResponse
-func checkAdmissionReviewResponse(t *testing.T, responseBody *bytes.Buffer, expectedResponse admissionv1.AdmissionResponse) {
-	var reviewOut admissionv1.AdmissionReview
-	bytesOut, err := ioutil.ReadAll(responseBody)
+// TestDecidePodInteractionRedactedCommands tests redacting passwords in the command list of a pod interaction
+func TestDecidePodInteractionRedactedCommands(t *testing.T) {
+	setupZapLogging(t)
+
+	commandsRaw, _ := json.Marshal([]string{"mysql", "--password=secret", "test-db"})
+	admissionRequest := &admissionv1.AdmissionRequest{
+		UID:       "test-uid-redacted",
+		Namespace: "test-namespace-redacted",
+		Name:      "test-pod-redacted",
+		Object: runtime.RawExtension{
+			Raw: []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container", "command":%s}`, webhook.PodExecAdmissionRequestKind, commandsRaw)),
+		},
+	}
+
+	// verify the password is redacted before the command list gets truncated
+	redactions, err := controller.ParseRedactPatterns(controller.DefaultRedactPatterns)
 	if err != nil {
-		t.Error("error reading admission review respsone:", err)
-		return
+		t.Fatal(err)
 	}
-	err = json.Unmarshal(bytesOut, &reviewOut)
+	testServer := webhook.Server{CommandRedactions: redactions, MaxCommandLength: 20}
+	decision := testServer.DecidePodInteraction(admissionRequest)
+	if decision.PodInteraction == nil {
+		t.Fatal("expected a pod interaction to be tracked, got nil")
+	}
+	expectedCommands := []string{"mysql", "--password=***", "t", webhook.CommandTruncatedMarker}
+	if !reflect.DeepEqual(expectedCommands, decision.PodInteraction.Commands) {
+		t.Errorf("expected commands: %v, got: %v", expectedCommands, decision.PodInteraction.Commands)
+	}
+}
+
+// TestAdmitPodUpdateDeniedEvent tests webhook server submitting an event to a pod of a denied update changing its
+// immutable labels, besides denying it
+func TestAdmitPodUpdateDeniedEvent(t *testing.T) {
+	setupZapLogging(t)
+
+	fakeRecorder := record.NewFakeRecorder(10)
+	testServer := webhook.Server{Recorder: fakeRecorder}
+	updateReview := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "test-uid-denied-update",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod-denied-update",
+			UserInfo:  authenticationv1.UserInfo{Username: "test-user"},
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(map[string]string{controller.PodTTLDurationLabel: "10h"}, nil),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(map[string]string{
+					controller.PodInteractionTimestampLabel: time.Now().String(),
+					controller.PodTTLDurationLabel:          "1h",
+				}, nil),
+			},
+		},
+	}
+	bytesIn, _ := json.Marshal(updateReview)
+	responseRecorder := httptest.NewRecorder()
+	testServer.AdmitPodUpdate(responseRecorder, httptest.NewRequest(http.MethodPost, "/admit-pod-update", bytes.NewBuffer(bytesIn)))
+
+	// verify the update is denied as before
+	checkAdmissionReviewResponse(t, responseRecorder.Body, admissionv1.AdmissionResponse{
+		UID:     "test-uid-denied-update",
+		Allowed: false,
+		Result: &metav1.Status{
+			Code:    http.StatusForbidden,
+			Message: webhook.ImmutableLabelsDisallowMsg,
+		},
+	})
+
+	// verify an event describing the denial and the requesting user is submitted
+	select {
+	case event := <-fakeRecorder.Events:
+		expectedPrefix := fmt.Sprintf("Warning PodUpdateDenied Pod update by user 'test-user' is denied: %s",
+			webhook.ImmutableLabelsDisallowMsg)
+		if !strings.HasPrefix(event, expectedPrefix) {
+			t.Errorf("expected an event starting with %q, got: %q", expectedPrefix, event)
+		}
+	default:
+		t.Error("expected an event submitted for the denied update, got none")
+	}
+}
+
+// TestDecidePodUpdateFromController tests allowing the controller itself to clear interaction labels of a pod
+func TestDecidePodUpdateFromController(t *testing.T) {
+	setupZapLogging(t)
+
+	controllerUsername := "system:serviceaccount:test-namespace:test-controller"
+	admissionRequest := &admissionv1.AdmissionRequest{
+		UID:       "test-uid-clear-interacted-labels",
+		Namespace: "test-namespace-regular",
+		Name:      "test-pod-clear-interacted-labels",
+		UserInfo:  authenticationv1.UserInfo{Username: controllerUsername},
+		Object: runtime.RawExtension{
+			Raw: getPodObjectRaw(nil, nil),
+		},
+		OldObject: runtime.RawExtension{
+			Raw: getPodObjectRaw(
+				map[string]string{
+					controller.PodInteractionTimestampLabel: time.Now().String(),
+				},
+				nil,
+			),
+		},
+	}
+
+	// verify the update is disallowed if the controller username is not configured
+	testServer := webhook.Server{}
+	decision := testServer.DecidePodUpdate(admissionRequest)
+	if decision.Allowed || !strings.HasPrefix(decision.Message, webhook.ImmutableLabelsDisallowMsg) {
+		t.Errorf("expected the update disallowed with message %q, got: %+v", webhook.ImmutableLabelsDisallowMsg, decision)
+	}
+
+	// verify the update is allowed if it is sent from the controller
+	testServer = webhook.Server{ControllerUsername: controllerUsername}
+	decision = testServer.DecidePodUpdate(admissionRequest)
+	if !decision.Allowed {
+		t.Errorf("expected the update from the controller allowed, got: %+v", decision)
+	}
+}
+
+// TestDecidePodUpdateFromControllerFieldManager tests identifying the controller's own updates by their user, rather than
+// by their field manager which can be set by anyone
+func TestDecidePodUpdateFromControllerFieldManager(t *testing.T) {
+	setupZapLogging(t)
+
+	controllerUsername := "system:serviceaccount:test-namespace:test-controller"
+	getAdmissionRequest := func(username, fieldManager string, newLabels map[string]string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid-field-manager",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod-field-manager",
+			UserInfo:  authenticationv1.UserInfo{Username: username},
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(newLabels, map[string]string{controller.PodExtendDurationAnnotate: "1h"}),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(map[string]string{controller.PodInteractionTimestampLabel: "1634408037"}, nil),
+			},
+			Options: runtime.RawExtension{
+				Raw: []byte(fmt.Sprintf(`{"kind":"PatchOptions","apiVersion":"meta.k8s.io/v1","fieldManager":"%s"}`, fieldManager)),
+			},
+		}
+	}
+	testServer := webhook.Server{ControllerUsername: controllerUsername}
+	interactedLabels := map[string]string{controller.PodInteractionTimestampLabel: "1634408037"}
+
+	// verify an update by another user is sent to the controller
+	decision := testServer.DecidePodUpdate(getAdmissionRequest("test-user", "kubectl-annotate", interactedLabels))
+	if !decision.Allowed || decision.PodExtensionUpdate == nil {
+		t.Errorf("expected the update allowed and sent to the controller, got: %+v", decision)
+	}
+
+	// verify an update by another user setting the controller's field manager is still sent to the controller
+	decision = testServer.DecidePodUpdate(getAdmissionRequest("test-user", controller.FieldManager, interactedLabels))
+	if !decision.Allowed || decision.PodExtensionUpdate == nil {
+		t.Errorf("expected the update allowed and sent to the controller, got: %+v", decision)
+	}
+
+	// verify an update by another user setting the controller's field manager is still validated
+	decision = testServer.DecidePodUpdate(getAdmissionRequest("test-user", controller.FieldManager, nil))
+	if decision.Allowed || !strings.HasPrefix(decision.Message, webhook.ImmutableLabelsDisallowMsg) {
+		t.Errorf("expected the update disallowed with message %q, got: %+v", webhook.ImmutableLabelsDisallowMsg, decision)
+	}
+
+	// verify an update by the controller is allowed without being sent to the controller
+	decision = testServer.DecidePodUpdate(getAdmissionRequest(controllerUsername, controller.FieldManager, interactedLabels))
+	if !decision.Allowed || decision.PodExtensionUpdate != nil {
+		t.Errorf("expected the controller's update allowed and not sent to the controller, got: %+v", decision)
+	}
+}
+
+// TestDecidePodUpdateMaxExtension tests webhook server denying an extension exceeding the max extension
+func TestDecidePodUpdateMaxExtension(t *testing.T) {
+	setupZapLogging(t)
+
+	interactedLabels := map[string]string{
+		controller.PodInteractionTimestampLabel: "1634408037",
+		controller.PodTTLDurationLabel:          "2m0s",
+	}
+	getExtensionRequest := func(extension string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid-max-extension",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod-max-extension",
+			UserInfo:  authenticationv1.UserInfo{Username: "test-user"},
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(interactedLabels, map[string]string{
+					controller.PodExtendDurationAnnotate: extension,
+				}),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(interactedLabels, nil),
+			},
+		}
+	}
+	testServer := webhook.Server{MaxExtension: 30 * time.Minute}
+
+	// verify an extension exceeding the max extension is denied without being sent to the controller
+	decision := testServer.DecidePodUpdate(getExtensionRequest("1h"))
+	if decision.Allowed || !strings.HasPrefix(decision.Message, webhook.ExceededMaxExtensionMsg) ||
+		decision.PodExtensionUpdate != nil {
+		t.Errorf("expected the extension denied with message %q, got: %+v", webhook.ExceededMaxExtensionMsg, decision)
+	}
+
+	// verify an extension within the max extension is allowed
+	decision = testServer.DecidePodUpdate(getExtensionRequest("30m"))
+	if !decision.Allowed || decision.PodExtensionUpdate == nil {
+		t.Errorf("expected the extension allowed and sent to the controller, got: %+v", decision)
+	}
+}
+
+// TestDecidePodUpdateInUse tests webhook server forwarding a pod held in use by its interactor or released to the
+// controller
+func TestDecidePodUpdateInUse(t *testing.T) {
+	setupZapLogging(t)
+
+	interactedLabels := map[string]string{
+		controller.PodInteractionTimestampLabel: "1634408037",
+		controller.PodTTLDurationLabel:          "2m0s",
+		controller.PodInteractorLabel:           "test-user",
+	}
+	getInUseRequestFrom := func(username string, oldAnnotations, annotations map[string]string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid-in-use",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod-in-use",
+			UserInfo:  authenticationv1.UserInfo{Username: username},
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(interactedLabels, annotations),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(interactedLabels, oldAnnotations),
+			},
+		}
+	}
+	getInUseRequest := func(oldAnnotations, annotations map[string]string) *admissionv1.AdmissionRequest {
+		return getInUseRequestFrom("test-user", oldAnnotations, annotations)
+	}
+	heldAnnotations := map[string]string{controller.PodInUseAnnotate: "true"}
+	testServer := webhook.Server{}
+
+	// verify holding and releasing the pod are allowed and sent to the controller as toggles
+	for _, request := range []*admissionv1.AdmissionRequest{
+		getInUseRequest(nil, heldAnnotations),
+		getInUseRequest(heldAnnotations, nil),
+	} {
+		decision := testServer.DecidePodUpdate(request)
+		if !decision.Allowed || decision.PodExtensionUpdate == nil || !decision.PodExtensionUpdate.InUseToggled {
+			t.Errorf("expected the in-use toggle allowed and sent to the controller, got: %+v", decision)
+		}
+	}
+
+	// verify an invalid value is denied
+	decision := testServer.DecidePodUpdate(getInUseRequest(nil, map[string]string{controller.PodInUseAnnotate: "yes"}))
+	if decision.Allowed || !strings.HasPrefix(decision.Message, webhook.InvalidAnnotationsValueMsg) {
+		t.Errorf("expected the in-use toggle denied with message %q, got: %+v", webhook.InvalidAnnotationsValueMsg, decision)
+	}
+
+	// verify holding the pod by another user is denied, while releasing it is allowed
+	decision = testServer.DecidePodUpdate(getInUseRequestFrom("test-user-other", nil, heldAnnotations))
+	if decision.Allowed || !strings.HasPrefix(decision.Message, webhook.InteractorOnlyDisallowMsg) {
+		t.Errorf("expected the in-use hold denied with message %q, got: %+v", webhook.InteractorOnlyDisallowMsg, decision)
+	}
+	decision = testServer.DecidePodUpdate(getInUseRequestFrom("test-user-other", heldAnnotations, nil))
+	if !decision.Allowed || decision.PodExtensionUpdate == nil || !decision.PodExtensionUpdate.InUseToggled {
+		t.Errorf("expected the in-use release allowed and sent to the controller, got: %+v", decision)
+	}
+}
+
+// TestDecidePodUpdateLastInteraction tests webhook server denying anyone but the controller changing the latest
+// interaction of a pod, which would postpone its eviction
+func TestDecidePodUpdateLastInteraction(t *testing.T) {
+	setupZapLogging(t)
+
+	interactedLabels := map[string]string{
+		controller.PodInteractionTimestampLabel: "1634408037",
+		controller.PodTTLDurationLabel:          "2m0s",
+	}
+	getLastInteractionRequest := func(username string, oldAnnotations, annotations map[string]string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid-last-interaction",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod-last-interaction",
+			UserInfo:  authenticationv1.UserInfo{Username: username},
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(interactedLabels, annotations),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(interactedLabels, oldAnnotations),
+			},
+		}
+	}
+	testServer := webhook.Server{ControllerUsername: "test-controller"}
+	recorded := map[string]string{
+		controller.PodLastInteractionTimestampAnnotate: strconv.FormatInt(time.Now().Unix(), 10),
+	}
+
+	// verify the controller recording the latest interaction is allowed
+	decision := testServer.DecidePodUpdate(getLastInteractionRequest("test-controller", nil, recorded))
+	if !decision.Allowed {
+		t.Errorf("expected the latest interaction recorded by the controller allowed, got: %+v", decision)
+	}
+
+	// verify a user setting, changing or removing the latest interaction is denied, even to a past time
+	changed := map[string]string{
+		controller.PodLastInteractionTimestampAnnotate: strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10),
+	}
+	for _, annotations := range [][2]map[string]string{{nil, recorded}, {recorded, changed}, {recorded, nil}} {
+		decision = testServer.DecidePodUpdate(getLastInteractionRequest("test-user", annotations[0], annotations[1]))
+		if decision.Allowed || !strings.HasPrefix(decision.Message, webhook.ControllerOnlyDisallowMsg) {
+			t.Errorf("expected the latest interaction %v changed to %v denied with message %q, got: %+v",
+				annotations[0], annotations[1], webhook.ControllerOnlyDisallowMsg, decision)
+		}
+	}
+}
+
+// TestDecidePodUpdateExtensionHistory tests webhook server denying anyone but the controller changing the extension
+// history of a pod
+func TestDecidePodUpdateExtensionHistory(t *testing.T) {
+	setupZapLogging(t)
+
+	interactedLabels := map[string]string{
+		controller.PodInteractionTimestampLabel: "1634408037",
+		controller.PodTTLDurationLabel:          "2m0s",
+	}
+	getHistoryRequest := func(username string, oldAnnotations, annotations map[string]string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid-extension-history",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod-extension-history",
+			UserInfo:  authenticationv1.UserInfo{Username: username},
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(interactedLabels, annotations),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(interactedLabels, oldAnnotations),
+			},
+		}
+	}
+	testServer := webhook.Server{ControllerUsername: "test-controller"}
+	recorded := map[string]string{
+		controller.PodExtensionHistoryAnnotate: `[{"requester":"test-user","duration":"1h","time":"2021-10-16T18:13:57Z"}]`,
+	}
+
+	// verify the controller recording an extension is allowed
+	decision := testServer.DecidePodUpdate(getHistoryRequest("test-controller", nil, recorded))
+	if !decision.Allowed {
+		t.Errorf("expected the extension history recorded by the controller allowed, got: %+v", decision)
+	}
+
+	// verify a user setting, changing or removing the extension history is denied
+	changed := map[string]string{
+		controller.PodExtensionHistoryAnnotate: `[{"requester":"another-user","duration":"1h","time":"2021-10-16T18:13:57Z"}]`,
+	}
+	for _, annotations := range [][2]map[string]string{{nil, recorded}, {recorded, changed}, {recorded, nil}} {
+		decision = testServer.DecidePodUpdate(getHistoryRequest("test-user", annotations[0], annotations[1]))
+		if decision.Allowed || !strings.HasPrefix(decision.Message, webhook.ControllerOnlyDisallowMsg) {
+			t.Errorf("expected the extension history %v changed to %v denied with message %q, got: %+v",
+				annotations[0], annotations[1], webhook.ControllerOnlyDisallowMsg, decision)
+		}
+	}
+}
+
+// TestDecidePodUpdateDisableEviction tests webhook server only allowing the allowlisted users and the controller to
+// pin or unpin a pod, interacted or not
+func TestDecidePodUpdateDisableEviction(t *testing.T) {
+	setupZapLogging(t)
+
+	pinned := map[string]string{controller.PodDisableEvictionAnnotate: "true"}
+	getPinRequest := func(username string, oldAnnotations, annotations map[string]string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid-disable-eviction",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod-disable-eviction",
+			UserInfo:  authenticationv1.UserInfo{Username: username},
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(nil, annotations),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(nil, oldAnnotations),
+			},
+		}
+	}
+	testServer := webhook.Server{
+		AllowedUsers:       map[string]bool{"test-oncall": true},
+		ControllerUsername: "test-controller",
+	}
+
+	// verify a user pinning or unpinning a pod before interacting it is denied
+	for _, annotations := range [][2]map[string]string{{nil, pinned}, {pinned, nil}} {
+		decision := testServer.DecidePodUpdate(getPinRequest("test-user", annotations[0], annotations[1]))
+		if decision.Allowed || !strings.HasPrefix(decision.Message, webhook.PrivilegedOnlyDisallowMsg) {
+			t.Errorf("expected pinning %v to %v denied with message %q, got: %+v",
+				annotations[0], annotations[1], webhook.PrivilegedOnlyDisallowMsg, decision)
+		}
+	}
+
+	// verify an allowlisted user or the controller pinning a pod is allowed, as well as a user keeping it pinned
+	for _, username := range []string{"test-oncall", "test-controller"} {
+		if decision := testServer.DecidePodUpdate(getPinRequest(username, nil, pinned)); !decision.Allowed {
+			t.Errorf("expected pinning a pod by %s allowed, got: %+v", username, decision)
+		}
+	}
+	if decision := testServer.DecidePodUpdate(getPinRequest("test-user", pinned, pinned)); !decision.Allowed {
+		t.Errorf("expected updating a pinned pod allowed, got: %+v", decision)
+	}
+}
+
+// TestDecidePodUpdateDebugJob tests webhook server only allowing the allowlisted users and the controller to label a
+// pod as a debug Job or unlabel it, interacted or not
+func TestDecidePodUpdateDebugJob(t *testing.T) {
+	setupZapLogging(t)
+
+	debugJob := map[string]string{controller.DebugJobLabel: "true"}
+	getLabelRequest := func(username string, oldLabels, labels map[string]string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid-debug-job",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod-debug-job",
+			UserInfo:  authenticationv1.UserInfo{Username: username},
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(labels, nil),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(oldLabels, nil),
+			},
+		}
+	}
+	testServer := webhook.Server{
+		AllowedUsers:       map[string]bool{"test-oncall": true},
+		ControllerUsername: "test-controller",
+	}
+
+	// verify a user labeling or unlabeling a pod as a debug Job is denied
+	for _, labels := range [][2]map[string]string{{nil, debugJob}, {debugJob, nil}} {
+		decision := testServer.DecidePodUpdate(getLabelRequest("test-user", labels[0], labels[1]))
+		if decision.Allowed || !strings.HasPrefix(decision.Message, webhook.PrivilegedOnlyDisallowMsg) {
+			t.Errorf("expected labeling %v to %v denied with message %q, got: %+v",
+				labels[0], labels[1], webhook.PrivilegedOnlyDisallowMsg, decision)
+		}
+	}
+
+	// verify an allowlisted user or the controller labeling a pod is allowed, as well as a user keeping it labeled
+	for _, username := range []string{"test-oncall", "test-controller"} {
+		if decision := testServer.DecidePodUpdate(getLabelRequest(username, nil, debugJob)); !decision.Allowed {
+			t.Errorf("expected labeling a pod as a debug Job by %s allowed, got: %+v", username, decision)
+		}
+	}
+	if decision := testServer.DecidePodUpdate(getLabelRequest("test-user", debugJob, debugJob)); !decision.Allowed {
+		t.Errorf("expected updating a debug Job pod allowed, got: %+v", decision)
+	}
+}
+
+// TestDecidePodUpdateExemptSelector tests webhook server only allowing the allowlisted users and the controller to
+// change the labels selected by the exempt selector, interacted or not
+func TestDecidePodUpdateExemptSelector(t *testing.T) {
+	setupZapLogging(t)
+
+	selector, err := controller.ParsePodExemptSelector("app in (ci-runner, sandbox)")
 	if err != nil {
-		t.Error("error un-marshaling admission review respsone:", err, bytesOut)
-		return
+		t.Fatal(err)
+	}
+	exempt := map[string]string{"app": "sandbox", "team": "test"}
+	getLabelRequest := func(username string, oldLabels, labels map[string]string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid-exempt-selector",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod-exempt-selector",
+			UserInfo:  authenticationv1.UserInfo{Username: username},
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(labels, nil),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(oldLabels, nil),
+			},
+		}
+	}
+	testServer := webhook.Server{
+		AllowedUsers:       map[string]bool{"test-oncall": true},
+		ControllerUsername: "test-controller",
+		PodExemptSelector:  selector,
 	}
 
-	actualResponse := reviewOut.Response
-	if actualResponse == nil {
-		t.Error("expecting response from outgoing review, got nil")
-		return
+	// verify a user setting, changing or removing a selected label is denied
+	for _, labels := range [][2]map[string]string{{nil, exempt}, {exempt, nil}, {{"app": "web"}, exempt}} {
+		decision := testServer.DecidePodUpdate(getLabelRequest("test-user", labels[0], labels[1]))
+		if decision.Allowed || decision.Message != fmt.Sprintln(webhook.PrivilegedOnlyDisallowMsg, "app") {
+			t.Errorf("expected labeling %v to %v denied with message %q, got: %+v",
+				labels[0], labels[1], webhook.PrivilegedOnlyDisallowMsg, decision)
+		}
 	}
-	if actualResponse.Allowed != expectedResponse.Allowed {
-		t.Errorf("expected response Allowed: %t, got: %t", expectedResponse.Allowed, actualResponse.Allowed)
+
+	// verify an allowlisted user or the controller changing a selected label is allowed, as well as a user changing
+	// the other labels
+	for _, username := range []string{"test-oncall", "test-controller"} {
+		if decision := testServer.DecidePodUpdate(getLabelRequest(username, nil, exempt)); !decision.Allowed {
+			t.Errorf("expected labeling a pod exempt by %s allowed, got: %+v", username, decision)
+		}
 	}
-	if actualResponse.UID != expectedResponse.UID {
-		t.Errorf("expected response UID: %s, got: %s", expectedResponse.UID, actualResponse.UID)
+	if decision := testServer.DecidePodUpdate(getLabelRequest("test-user", map[string]string{"app": "sandbox"}, exempt)); !decision.Allowed {
+		t.Errorf("expected changing an unselected label allowed, got: %+v", decision)
+	}
+}
+
+// TestDecidePodUpdateJustificationTime tests webhook server denying a pod justified at an invalid or future time
+func TestDecidePodUpdateJustificationTime(t *testing.T) {
+	setupZapLogging(t)
+
+	getJustifyRequest := func(justifiedTimestamp string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid-justification",
+			Namespace: "test-namespace-sensitive",
+			Name:      "test-pod-justification",
+			UserInfo:  authenticationv1.UserInfo{Username: "test-user"},
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(nil, map[string]string{
+					controller.PodExecJustificationAnnotate:     "debugging an incident",
+					controller.PodExecJustificationTimeAnnotate: justifiedTimestamp,
+				}),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(nil, nil),
+			},
+		}
+	}
+	testServer := webhook.Server{}
+
+	// verify justifying a pod now, or slightly in the future by a skewed clock, is allowed
+	for _, justifiedTime := range []time.Time{time.Now(), time.Now().Add(30 * time.Second)} {
+		decision := testServer.DecidePodUpdate(getJustifyRequest(strconv.FormatInt(justifiedTime.Unix(), 10)))
+		if !decision.Allowed {
+			t.Errorf("expected justifying a pod at %s allowed, got: %+v", justifiedTime, decision)
+		}
+	}
+
+	// verify justifying a pod at an invalid or future time is denied
+	future := strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10)
+	for _, justifiedTimestamp := range []string{future, "tomorrow"} {
+		decision := testServer.DecidePodUpdate(getJustifyRequest(justifiedTimestamp))
+		if decision.Allowed || !strings.HasPrefix(decision.Message, webhook.InvalidAnnotationsValueMsg) {
+			t.Errorf("expected justifying a pod at %q denied with message %q, got: %+v",
+				justifiedTimestamp, webhook.InvalidAnnotationsValueMsg, decision)
+		}
+	}
+}
+
+// TestDecidePodUpdateAnnotationMetadata tests webhook server denying changes to interaction metadata stored in annotations
+func TestDecidePodUpdateAnnotationMetadata(t *testing.T) {
+	setupZapLogging(t)
+
+	interactedAnnotations := map[string]string{
+		controller.PodInteractionTimestampLabel: "1634408037",
+		controller.PodTTLDurationLabel:          "2m0s",
+	}
+	getUpdateRequest := func(annotations map[string]string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid-annotation-metadata",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod-annotation-metadata",
+			UserInfo:  authenticationv1.UserInfo{Username: "test-user"},
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(nil, annotations),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(nil, interactedAnnotations),
+			},
+		}
+	}
+	testServer := webhook.Server{}
+
+	// verify changing the TTL annotation is denied as it would be if stored as a label
+	decision := testServer.DecidePodUpdate(getUpdateRequest(map[string]string{
+		controller.PodInteractionTimestampLabel: "1634408037",
+		controller.PodTTLDurationLabel:          "24h0m0s",
+	}))
+	if decision.Allowed || !strings.HasPrefix(decision.Message, webhook.ImmutableLabelsDisallowMsg) {
+		t.Errorf("expected the TTL change denied with message %q, got: %+v", webhook.ImmutableLabelsDisallowMsg, decision)
+	}
+
+	// verify an extension of the pod is still allowed and sent to the controller
+	decision = testServer.DecidePodUpdate(getUpdateRequest(map[string]string{
+		controller.PodInteractionTimestampLabel: "1634408037",
+		controller.PodTTLDurationLabel:          "2m0s",
+		controller.PodExtendDurationAnnotate:    "1h",
+	}))
+	if !decision.Allowed || decision.PodExtensionUpdate == nil {
+		t.Errorf("expected the extension allowed and sent to the controller, got: %+v", decision)
+	}
+}
+
+// TestNamespaceAllowlistValidation tests warning about invalid or nonexistent namespaces in the allowlist
+func TestNamespaceAllowlistValidation(t *testing.T) {
+	observedCore, observedLogs := observer.New(zap.WarnLevel)
+	zap.ReplaceGlobals(zap.New(observedCore))
+
+	// verify invalid entries are skipped with warnings and valid ones are normalized
+	testServer := webhook.NewOfflineServer(" kube-system, Kube-Public,,kube_system ,kube-system")
+	expectedNamespaces := map[string]bool{"kube-system": true}
+	if !reflect.DeepEqual(expectedNamespaces, testServer.AllowedNamespaces) {
+		t.Errorf("expected allowed namespaces: %v, got: %v", expectedNamespaces, testServer.AllowedNamespaces)
+	}
+	invalidLogs := observedLogs.FilterMessageSnippet("invalid namespace name").AllUntimed()
+	if len(invalidLogs) != 2 {
+		t.Fatalf("expected 2 warnings of invalid namespaces, got: %v", invalidLogs)
+	}
+	for i, expectedNamespace := range []string{"Kube-Public", "kube_system"} {
+		if ns := invalidLogs[i].ContextMap()["namespace"]; ns != expectedNamespace {
+			t.Errorf("expected a warning of invalid namespace %s, got: %v", expectedNamespace, ns)
+		}
+	}
+
+	// verify nonexistent namespaces are warned
+	testServer = webhook.NewOfflineServer("kube-system,kube-sytem")
+	fakeClient := fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}})
+	if err := testServer.WarnMissingNamespaces(fakeClient); err != nil {
+		t.Fatal(err)
+	}
+	missingLogs := observedLogs.FilterMessageSnippet("does not exist").AllUntimed()
+	if len(missingLogs) != 1 || missingLogs[0].ContextMap()["namespace"] != "kube-sytem" {
+		t.Errorf("expected a warning of nonexistent namespace kube-sytem, got: %v", missingLogs)
+	}
+}
+
+// TestNamespaceAllowlistPatterns tests allowing the interactions in namespaces matching a glob or a regular expression
+// of the allowlist, besides the exact ones
+func TestNamespaceAllowlistPatterns(t *testing.T) {
+	setupZapLogging(t)
+
+	testServer := webhook.NewOfflineServer("kube-system,team-*-sandbox,regex:^ci-[0-9]+$,regex:[invalid")
+	if len(testServer.AllowedNamespacePatterns) != 2 {
+		t.Fatalf("expected 2 valid namespace patterns, got: %v", testServer.AllowedNamespacePatterns)
+	}
+
+	testCases := []struct {
+		namespace string
+		allowed   bool
+	}{
+		{"kube-system", true},
+		{"team-a-sandbox", true},
+		{"team-payments-sandbox", true},
+		{"ci-42", true},
+		{"team-a-sandbox-2", false},
+		{"my-team-a-sandbox", false},
+		{"ci-latest", false},
+		{"kube-public", false},
+	}
+	for _, testCase := range testCases {
+		admissionRequest := &admissionv1.AdmissionRequest{
+			UID:       "test-uid-namespace-pattern",
+			Namespace: testCase.namespace,
+			Name:      "test-pod",
+			Object: runtime.RawExtension{
+				Raw: []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container", "command":["sh"]}`, webhook.PodExecAdmissionRequestKind)),
+			},
+		}
+		decision := testServer.DecidePodInteraction(admissionRequest)
+		if !decision.Allowed || (decision.PodInteraction == nil) != testCase.allowed {
+			t.Errorf("expected the interaction in namespace %s exempt: %t, got: %+v",
+				testCase.namespace, testCase.allowed, decision)
+		}
+	}
+}
+
+// TestMutatePodCreation tests webhook server injecting the tracking label to a created pod by a JSON patch, unless the
+// pod is labeled already or in an allowed namespace
+func TestMutatePodCreation(t *testing.T) {
+	setupZapLogging(t)
+
+	testServer := webhook.Server{
+		AllowedNamespaces: map[string]bool{"test-namespace-allowed": true},
+	}
+	testCases := []struct {
+		name          string
+		namespace     string
+		labels        map[string]string
+		expectedPatch string
+	}{
+		{
+			name:          "Test-1 add the tracking label to a pod without labels",
+			namespace:     "test-namespace",
+			expectedPatch: `[{"op":"add","path":"/metadata/labels","value":{"box.com/execTracking":"true"}}]`,
+		},
+		{
+			name:          "Test-2 add the tracking label to a pod with other labels",
+			namespace:     "test-namespace",
+			labels:        map[string]string{"app": "test"},
+			expectedPatch: `[{"op":"add","path":"/metadata/labels/box.com~1execTracking","value":"true"}]`,
+		},
+		{
+			name:      "Test-3 skip a pod labeled already",
+			namespace: "test-namespace",
+			labels:    map[string]string{controller.PodTrackingLabel: "true"},
+		},
+		{
+			name:      "Test-4 skip a pod in an allowed namespace",
+			namespace: "test-namespace-allowed",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			creationReview := admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					UID:       "test-uid-mutate-pod",
+					Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+					Operation: admissionv1.Create,
+					Namespace: testCase.namespace,
+					Name:      "test-pod",
+					Object:    runtime.RawExtension{Raw: getPodObjectRaw(testCase.labels, nil)},
+				},
+			}
+			bytesIn, _ := json.Marshal(creationReview)
+			responseRecorder := httptest.NewRecorder()
+			testServer.MutatePodCreation(responseRecorder, httptest.NewRequest(http.MethodPost, "/mutate-pod", bytes.NewBuffer(bytesIn)))
+
+			var reviewOut admissionv1.AdmissionReview
+			if err := json.Unmarshal(responseRecorder.Body.Bytes(), &reviewOut); err != nil {
+				t.Fatal(err)
+			}
+			response := reviewOut.Response
+			if response == nil || !response.Allowed || response.UID != "test-uid-mutate-pod" {
+				t.Fatalf("expected the pod creation allowed, got: %+v", response)
+			}
+			if string(response.Patch) != testCase.expectedPatch {
+				t.Errorf("expected response Patch: %s, got: %s", testCase.expectedPatch, response.Patch)
+			}
+			if testCase.expectedPatch != "" && (response.PatchType == nil || *response.PatchType != admissionv1.PatchTypeJSONPatch) {
+				t.Errorf("expected response PatchType: %s, got: %v", admissionv1.PatchTypeJSONPatch, response.PatchType)
+			}
+		})
+	}
+}
+
+// TestDecidePodInteractionClientInfo tests recording the client metadata of a pod interaction if available
+func TestDecidePodInteractionClientInfo(t *testing.T) {
+	setupZapLogging(t)
+
+	admissionRequest := &admissionv1.AdmissionRequest{
+		UID:       "test-uid-client-info",
+		Namespace: "test-namespace-regular",
+		Name:      "test-pod-client-info",
+		UserInfo: authenticationv1.UserInfo{
+			Username: "test-user",
+			UID:      "test-user-uid",
+			Extra: map[string]authenticationv1.ExtraValue{
+				"authentication.kubernetes.io/credential-id": {"X509SHA256=abc"},
+				"authentication.kubernetes.io/node-name":     {"test-node"},
+				"authentication.kubernetes.io/pod-name":      {},
+				"scopes":                                     {"openid", "email"},
+			},
+		},
+		Object: runtime.RawExtension{
+			Raw: []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container", "command":["sh"]}`, webhook.PodExecAdmissionRequestKind)),
+		},
+	}
+
+	// verify only the available non-sensitive client metadata is recorded, leaving out the UID, credential ID and scopes
+	testServer := webhook.Server{}
+	decision := testServer.DecidePodInteraction(admissionRequest)
+	if decision.PodInteraction == nil {
+		t.Fatal("expected a pod interaction to be tracked, got nil")
+	}
+	expectedClientInfo := map[string]string{
+		"authentication.kubernetes.io/node-name": "test-node",
+	}
+	if !reflect.DeepEqual(expectedClientInfo, decision.PodInteraction.ClientInfo) {
+		t.Errorf("expected client info: %v, got: %v", expectedClientInfo, decision.PodInteraction.ClientInfo)
+	}
+
+	// verify nothing is recorded without any non-sensitive client metadata
+	delete(admissionRequest.UserInfo.Extra, "authentication.kubernetes.io/node-name")
+	decision = testServer.DecidePodInteraction(admissionRequest)
+	if decision.PodInteraction.ClientInfo != nil {
+		t.Errorf("expected no client info, got: %v", decision.PodInteraction.ClientInfo)
+	}
+}
+
+// TestDecidePodInteractionPortForward tests tracking a "kubectl port-forward" request with its forwarded ports
+func TestDecidePodInteractionPortForward(t *testing.T) {
+	setupZapLogging(t)
+
+	admissionRequest := &admissionv1.AdmissionRequest{
+		UID:         "test-uid-port-forward",
+		Namespace:   "test-namespace-regular",
+		Name:        "test-pod-port-forward",
+		SubResource: "portforward",
+		UserInfo:    authenticationv1.UserInfo{Username: "test-user"},
+		Object: runtime.RawExtension{
+			Raw: []byte(fmt.Sprintf(`{"kind":"%s", "apiVersion": "v1", "ports":[8080, 9090]}`, webhook.PodPortForwardAdmissionRequestKind)),
+		},
+	}
+
+	testServer := webhook.Server{}
+	decision := testServer.DecidePodInteraction(admissionRequest)
+	if !decision.Allowed || decision.PodInteraction == nil {
+		t.Fatalf("expected the port-forward allowed and tracked, got: %+v", decision)
+	}
+	interaction := decision.PodInteraction
+	if interaction.PodName != "test-pod-port-forward" || interaction.PodNamespace != "test-namespace-regular" ||
+		interaction.Username != "test-user" {
+		t.Errorf("expected the interaction of test-user with test-pod-port-forward, got: %+v", interaction)
+	}
+	expectedPorts := []int32{8080, 9090}
+	if !reflect.DeepEqual(expectedPorts, interaction.Ports) {
+		t.Errorf("expected ports: %v, got: %v", expectedPorts, interaction.Ports)
+	}
+	if len(interaction.Commands) != 0 || interaction.Interactive {
+		t.Errorf("expected no command and no interactive session, got: %+v", interaction)
+	}
+	if interaction.Verb != controller.InteractionVerbPortForward {
+		t.Errorf("expected the verb %q, got: %q", controller.InteractionVerbPortForward, interaction.Verb)
+	}
+}
+
+// TestDecidePodInteractionDebugContainer tests tracking a "kubectl debug" request attaching to its ephemeral container,
+// and an attach request with no container set
+func TestDecidePodInteractionDebugContainer(t *testing.T) {
+	setupZapLogging(t)
+
+	admissionRequest := &admissionv1.AdmissionRequest{
+		UID:         "test-uid-debug",
+		Namespace:   "test-namespace-regular",
+		Name:        "test-pod-distroless",
+		SubResource: "attach",
+		UserInfo:    authenticationv1.UserInfo{Username: "test-user"},
+		Object: runtime.RawExtension{
+			Raw: []byte(fmt.Sprintf(`{"kind":"%s", "apiVersion": "v1", "stdin":true, "stdout":true, "tty":true, "container":"debugger-x7k2p"}`,
+				webhook.PodAttachAdmissionRequestKind)),
+		},
+	}
+
+	testServer := webhook.Server{}
+	decision := testServer.DecidePodInteraction(admissionRequest)
+	if !decision.Allowed || decision.PodInteraction == nil {
+		t.Fatalf("expected the debug container attach allowed and tracked, got: %+v", decision)
+	}
+	interaction := decision.PodInteraction
+	if interaction.PodName != "test-pod-distroless" || interaction.ContainerName != "debugger-x7k2p" {
+		t.Errorf("expected the interaction with the debugger-x7k2p container of test-pod-distroless, got: %+v", interaction)
+	}
+	if len(interaction.Commands) != 0 || !interaction.Interactive {
+		t.Errorf("expected an interactive session with no command, got: %+v", interaction)
+	}
+
+	// verify an attach request without a container (to the Pod's default one) is tracked too
+	admissionRequest.Object.Raw = []byte(fmt.Sprintf(`{"kind":"%s", "apiVersion": "v1", "stdin":true, "tty":true}`,
+		webhook.PodAttachAdmissionRequestKind))
+	decision = testServer.DecidePodInteraction(admissionRequest)
+	if !decision.Allowed || decision.PodInteraction == nil {
+		t.Fatalf("expected the attach without a container allowed and tracked, got: %+v", decision)
+	}
+	if decision.PodInteraction.ContainerName != "" || !decision.PodInteraction.Interactive {
+		t.Errorf("expected an interactive session with no container, got: %+v", decision.PodInteraction)
+	}
+}
+
+// TestDecidePodInteractionMalformedRequest tests rejecting an interaction request with a field of an unexpected type
+// as a bad request, while its optional fields can be absent
+func TestDecidePodInteractionMalformedRequest(t *testing.T) {
+	setupZapLogging(t)
+
+	kind := webhook.PodExecAdmissionRequestKind
+	testCases := []struct {
+		name       string
+		raw        string
+		badRequest bool
+	}{
+		{"missing container", fmt.Sprintf(`{"kind":"%s", "command":["sh"]}`, kind), false},
+		{"missing command", fmt.Sprintf(`{"kind":"%s", "container":"test-container"}`, kind), false},
+		{"null container", fmt.Sprintf(`{"kind":"%s", "container":null, "command":["sh"]}`, kind), false},
+		{"missing kind", `{"container":"test-container", "command":["sh"]}`, true},
+		{"non-string container", fmt.Sprintf(`{"kind":"%s", "container":1, "command":["sh"]}`, kind), true},
+		{"non-list command", fmt.Sprintf(`{"kind":"%s", "container":"test-container", "command":"sh"}`, kind), true},
+		{"non-string command element", fmt.Sprintf(`{"kind":"%s", "container":"test-container", "command":["sh", 1]}`, kind), true},
+		{"non-number port", fmt.Sprintf(`{"kind":"%s", "ports":["8080"]}`, kind), true},
+	}
+
+	testServer := webhook.Server{}
+	for _, tc := range testCases {
+		admissionRequest := &admissionv1.AdmissionRequest{
+			UID:       "test-uid-malformed",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod",
+			UserInfo:  authenticationv1.UserInfo{Username: "test-user"},
+			Object: runtime.RawExtension{
+				Raw: []byte(tc.raw),
+			},
+		}
+
+		decision := testServer.DecidePodInteraction(admissionRequest)
+		if tc.badRequest {
+			if decision.StatusCode != http.StatusBadRequest || decision.PodInteraction != nil {
+				t.Errorf("expected the request with %s to be a bad request, got: %+v", tc.name, decision)
+			}
+		} else if decision.StatusCode != http.StatusOK || decision.PodInteraction == nil {
+			t.Errorf("expected the request with %s allowed and tracked, got: %+v", tc.name, decision)
+		}
+	}
+}
+
+// TestPluginWarning tests webhook server returning a warning advising "kubectl pi" on tracked interactions only
+func TestPluginWarning(t *testing.T) {
+	setupZapLogging(t)
+
+	testServer := webhook.Server{
+		AllowedNamespaces: map[string]bool{"test-namespace-allowed": true},
+		PluginWarning:     webhook.DefaultPluginWarning,
+	}
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+
+	// verify a tracked interaction gets the warning in its response
+	interactionReview := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "test-uid-plugin-warning",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod",
+			Object: runtime.RawExtension{
+				Raw: []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container", "command":["sh"]}`, webhook.PodExecAdmissionRequestKind)),
+			},
+		},
+	}
+	bytesIn, _ := json.Marshal(interactionReview)
+	responseRecorder := httptest.NewRecorder()
+	testServer.AdmitPodInteraction(responseRecorder, httptest.NewRequest(http.MethodPost, "/admit-pod-interaction", bytes.NewBuffer(bytesIn)))
+	checkAdmissionReviewResponse(t, responseRecorder.Body, admissionv1.AdmissionResponse{
+		UID:      "test-uid-plugin-warning",
+		Allowed:  true,
+		Warnings: []string{webhook.DefaultPluginWarning},
+	})
+	<-controller.PodInteractionCh
+
+	// verify an interaction not tracked gets no warning
+	interactionReview.Request.Namespace = "test-namespace-allowed"
+	decision := testServer.DecidePodInteraction(interactionReview.Request)
+	if decision.PodInteraction != nil || len(decision.Warnings) != 0 {
+		t.Errorf("expected the interaction not tracked and no warning, got: %+v", decision)
+	}
+
+	// verify no warning is returned once disabled
+	testServer.PluginWarning = ""
+	interactionReview.Request.Namespace = "test-namespace-regular"
+	decision = testServer.DecidePodInteraction(interactionReview.Request)
+	if decision.PodInteraction == nil || len(decision.Warnings) != 0 {
+		t.Errorf("expected the interaction tracked without a warning, got: %+v", decision)
+	}
+}
+
+// TestEvictionWarning tests webhook server returning a warning of when the Pod gets evicted on tracked interactions
+func TestEvictionWarning(t *testing.T) {
+	setupZapLogging(t)
+
+	testServer := webhook.Server{
+		AllowedNamespaces: map[string]bool{"test-namespace-allowed": true},
+		PluginWarning:     webhook.DefaultPluginWarning,
+		EvictionWarning:   true,
+	}
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+
+	// verify a tracked interaction gets the eviction warning before the plugin warning in its response
+	interactionReview := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "test-uid-eviction-warning",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod",
+			Object: runtime.RawExtension{
+				Raw: []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container", "command":["sh"]}`, webhook.PodExecAdmissionRequestKind)),
+			},
+		},
+	}
+	bytesIn, _ := json.Marshal(interactionReview)
+	responseRecorder := httptest.NewRecorder()
+	testServer.AdmitPodInteraction(responseRecorder, httptest.NewRequest(http.MethodPost, "/admit-pod-interaction", bytes.NewBuffer(bytesIn)))
+	checkAdmissionReviewResponse(t, responseRecorder.Body, admissionv1.AdmissionResponse{
+		UID:     "test-uid-eviction-warning",
+		Allowed: true,
+		Warnings: []string{
+			"This Pod will be evicted once its TTL expires, see 'kubectl pi get' for when or run 'kubectl pi extend' to keep it",
+			webhook.DefaultPluginWarning,
+		},
+	})
+	<-controller.PodInteractionCh
+
+	// verify an interaction not tracked gets no warning
+	interactionReview.Request.Namespace = "test-namespace-allowed"
+	decision := testServer.DecidePodInteraction(interactionReview.Request)
+	if decision.PodInteraction != nil || len(decision.Warnings) != 0 {
+		t.Errorf("expected the interaction not tracked and no warning, got: %+v", decision)
+	}
+}
+
+// TestHandleReadiness tests the readiness probe failing until the controller is ready
+func TestHandleReadiness(t *testing.T) {
+	ready := false
+	testServer := webhook.Server{Ready: func() bool { return ready }}
+
+	responseRecorder := httptest.NewRecorder()
+	testServer.HandleReadiness(responseRecorder, httptest.NewRequest(http.MethodGet, "/health/readiness", nil))
+	if responseRecorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status code %d before ready, got: %d", http.StatusServiceUnavailable, responseRecorder.Code)
+	}
+
+	ready = true
+	responseRecorder = httptest.NewRecorder()
+	testServer.HandleReadiness(responseRecorder, httptest.NewRequest(http.MethodGet, "/health/readiness", nil))
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("expected status code %d once ready, got: %d", http.StatusOK, responseRecorder.Code)
+	}
+}
+
+// TestHandleReadinessUntilStartupReconcile tests the readiness probe gated on the controller failing until the
+// previously interacted pods are checked at startup
+func TestHandleReadinessUntilStartupReconcile(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	zap.ReplaceGlobals(logger)
+
+	namespace := "test-namespace"
+	interactedPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "test-pod",
+		Namespace: namespace,
+		UID:       "test-pod",
+		Labels: map[string]string{
+			controller.PodInteractionTimestampLabel: strconv.FormatInt(time.Now().Unix(), 10),
+			controller.PodTTLDurationLabel:          time.Hour.String(),
+		},
+	}}
+	fakeClient := fake.NewSimpleClientset(interactedPod)
+	contr := controller.NewController(fakeClient, 3600)
+	testServer := webhook.Server{Ready: contr.Ready}
+
+	responseRecorder := httptest.NewRecorder()
+	testServer.HandleReadiness(responseRecorder, httptest.NewRequest(http.MethodGet, "/health/readiness", nil))
+	if responseRecorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status code %d before the startup reconcile, got: %d",
+			http.StatusServiceUnavailable, responseRecorder.Code)
+	}
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction)
+	close(controller.PodInteractionCh)
+	contr.CheckPodInteraction()
+
+	responseRecorder = httptest.NewRecorder()
+	testServer.HandleReadiness(responseRecorder, httptest.NewRequest(http.MethodGet, "/health/readiness", nil))
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("expected status code %d after the startup reconcile, got: %d", http.StatusOK, responseRecorder.Code)
+	}
+	pod, err := fakeClient.CoreV1().Pods(namespace).Get(context.TODO(), interactedPod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, present := pod.Annotations[controller.PodTerminationTimeAnnotate]; !present {
+		t.Error("expected the previously interacted pod checked once ready, but it has no termination time")
+	}
+}
+
+// TestExemptAll tests webhook server allowing all requests without tracking anything while exempting all
+func TestExemptAll(t *testing.T) {
+	setupZapLogging(t)
+
+	testServer := webhook.Server{ExemptAll: true}
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate, 1)
+
+	// verify an interaction is allowed without being sent to the controller
+	interactionReview := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "test-uid-exempt-all-interaction",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod",
+			Object: runtime.RawExtension{
+				Raw: []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container", "command":["sh"]}`, webhook.PodExecAdmissionRequestKind)),
+			},
+		},
+	}
+	bytesIn, _ := json.Marshal(interactionReview)
+	responseRecorder := httptest.NewRecorder()
+	testServer.AdmitPodInteraction(responseRecorder, httptest.NewRequest(http.MethodPost, "/admit-pod-interaction", bytes.NewBuffer(bytesIn)))
+	checkAdmissionReviewResponse(t, responseRecorder.Body, admissionv1.AdmissionResponse{
+		UID:     "test-uid-exempt-all-interaction",
+		Allowed: true,
+	})
+
+	// verify an update changing interacted labels is allowed without being sent to the controller
+	updateReview := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "test-uid-exempt-all-update",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod",
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(nil, map[string]string{controller.PodExtendDurationAnnotate: "1h"}),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(map[string]string{controller.PodInteractionTimestampLabel: time.Now().String()}, nil),
+			},
+		},
+	}
+	bytesIn, _ = json.Marshal(updateReview)
+	responseRecorder = httptest.NewRecorder()
+	testServer.AdmitPodUpdate(responseRecorder, httptest.NewRequest(http.MethodPost, "/admit-pod-update", bytes.NewBuffer(bytesIn)))
+	checkAdmissionReviewResponse(t, responseRecorder.Body, admissionv1.AdmissionResponse{
+		UID:     "test-uid-exempt-all-update",
+		Allowed: true,
+	})
+
+	if len(controller.PodInteractionCh) != 0 || len(controller.PodExtensionUpdateCh) != 0 {
+		t.Error("expected nothing sent to the controller while exempting all requests")
+	}
+}
+
+// TestUserGroupAllowlist tests webhook server allowing interactions from allowlisted users or groups without tracking
+// them, while still validating and handling their Pod updates
+func TestUserGroupAllowlist(t *testing.T) {
+	setupZapLogging(t)
+
+	testServer := webhook.Server{
+		AllowedUsers:  webhook.ParseAllowlist(" test-oncall-user , ,test-admin"),
+		AllowedGroups: webhook.ParseAllowlist("test-sre"),
+	}
+	testCases := []struct {
+		name     string
+		userInfo authenticationv1.UserInfo
+		tracked  bool
+	}{
+		{"user match", authenticationv1.UserInfo{Username: "test-oncall-user", Groups: []string{"test-dev"}}, false},
+		{"group match", authenticationv1.UserInfo{Username: "test-user", Groups: []string{"test-dev", "test-sre"}}, false},
+		{"no match", authenticationv1.UserInfo{Username: "test-user", Groups: []string{"test-dev"}}, true},
+	}
+
+	for _, tc := range testCases {
+		controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+		controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate, 1)
+
+		// verify an interaction is allowed and only sent to the controller if not allowlisted
+		interactionReview := admissionv1.AdmissionReview{
+			Request: &admissionv1.AdmissionRequest{
+				UID:       "test-uid-allowlist-interaction",
+				Namespace: "test-namespace-regular",
+				Name:      "test-pod",
+				UserInfo:  tc.userInfo,
+				Object: runtime.RawExtension{
+					Raw: []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container", "command":["sh"]}`, webhook.PodExecAdmissionRequestKind)),
+				},
+			},
+		}
+		bytesIn, _ := json.Marshal(interactionReview)
+		responseRecorder := httptest.NewRecorder()
+		testServer.AdmitPodInteraction(responseRecorder, httptest.NewRequest(http.MethodPost, "/admit-pod-interaction", bytes.NewBuffer(bytesIn)))
+		checkAdmissionReviewResponse(t, responseRecorder.Body, admissionv1.AdmissionResponse{
+			UID:     "test-uid-allowlist-interaction",
+			Allowed: true,
+		})
+		if tracked := len(controller.PodInteractionCh) == 1; tracked != tc.tracked {
+			t.Errorf("%s: expected the interaction tracked to be %t, got: %t", tc.name, tc.tracked, tracked)
+		}
+
+		// verify an extension is allowed and sent to the controller regardless of the allowlist
+		updateReview := admissionv1.AdmissionReview{
+			Request: &admissionv1.AdmissionRequest{
+				UID:       "test-uid-allowlist-update",
+				Namespace: "test-namespace-regular",
+				Name:      "test-pod",
+				UserInfo:  tc.userInfo,
+				Object: runtime.RawExtension{
+					Raw: getPodObjectRaw(
+						map[string]string{controller.PodInteractionTimestampLabel: "1634408037"},
+						map[string]string{controller.PodExtendDurationAnnotate: "1h"},
+					),
+				},
+				OldObject: runtime.RawExtension{
+					Raw: getPodObjectRaw(map[string]string{controller.PodInteractionTimestampLabel: "1634408037"}, nil),
+				},
+			},
+		}
+		bytesIn, _ = json.Marshal(updateReview)
+		responseRecorder = httptest.NewRecorder()
+		testServer.AdmitPodUpdate(responseRecorder, httptest.NewRequest(http.MethodPost, "/admit-pod-update", bytes.NewBuffer(bytesIn)))
+		checkAdmissionReviewResponse(t, responseRecorder.Body, admissionv1.AdmissionResponse{
+			UID:     "test-uid-allowlist-update",
+			Allowed: true,
+		})
+		if tracked := len(controller.PodExtensionUpdateCh) == 1; !tracked {
+			t.Errorf("%s: expected the extension tracked, got none", tc.name)
+		}
+
+		// verify changing the immutable labels of an interacted pod is denied regardless of the allowlist
+		decision := testServer.DecidePodUpdate(&admissionv1.AdmissionRequest{
+			UID:       "test-uid-allowlist-immutable",
+			Namespace: "test-namespace-regular",
+			Name:      "test-pod",
+			UserInfo:  tc.userInfo,
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(map[string]string{controller.PodInteractionTimestampLabel: "1634408038"}, nil),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(map[string]string{controller.PodInteractionTimestampLabel: "1634408037"}, nil),
+			},
+		})
+		if decision.Allowed || !strings.HasPrefix(decision.Message, webhook.ImmutableLabelsDisallowMsg) {
+			t.Errorf("%s: expected the update denied with message %q, got: %+v", tc.name, webhook.ImmutableLabelsDisallowMsg, decision)
+		}
+	}
+}
+
+// TestCommandAllowlist tests webhook server not tracking interactions running an allowlisted read-only command
+func TestCommandAllowlist(t *testing.T) {
+	setupZapLogging(t)
+
+	testServer := webhook.Server{AllowedCommands: webhook.ParseAllowlist("cat,ls,ps,/opt/tools/status")}
+	testCases := []struct {
+		commands []string
+		tracked  bool
+	}{
+		{[]string{"cat", "/etc/config"}, false},
+		{[]string{"/bin/ls", "-la"}, false},
+		{[]string{"/usr/bin/../bin/ps"}, false},
+		{[]string{"/tmp/ls"}, true},
+		{[]string{"./cat", "/etc/config"}, true},
+		{[]string{"/bin/../tmp/ls"}, true},
+		{[]string{"/opt/tools/status"}, false},
+		{[]string{"/tmp/status"}, true},
+		{[]string{"sh"}, true},
+		{[]string{"sh", "-c", "cat /etc/config"}, true},
+		{[]string{"catalog"}, true},
+		{nil, true},
+	}
+
+	for _, tc := range testCases {
+		controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+		commands, _ := json.Marshal(tc.commands)
+		review := admissionv1.AdmissionReview{
+			Request: &admissionv1.AdmissionRequest{
+				UID:       "test-uid-command-allowlist",
+				Namespace: "test-namespace-regular",
+				Name:      "test-pod",
+				Object: runtime.RawExtension{
+					Raw: []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container", "command":%s}`,
+						webhook.PodExecAdmissionRequestKind, commands)),
+				},
+			},
+		}
+		bytesIn, _ := json.Marshal(review)
+		responseRecorder := httptest.NewRecorder()
+		testServer.AdmitPodInteraction(responseRecorder, httptest.NewRequest(http.MethodPost, "/admit-pod-interaction", bytes.NewBuffer(bytesIn)))
+		checkAdmissionReviewResponse(t, responseRecorder.Body, admissionv1.AdmissionResponse{
+			UID:     "test-uid-command-allowlist",
+			Allowed: true,
+		})
+		if tracked := len(controller.PodInteractionCh) == 1; tracked != tc.tracked {
+			t.Errorf("expected the interaction running %v tracked to be %t, got: %t", tc.commands, tc.tracked, tracked)
+		}
+	}
+}
+
+// TestMetricsHandler tests exposing metrics of admitted interactions and denied updates in the Prometheus format
+func TestMetricsHandler(t *testing.T) {
+	setupZapLogging(t)
+
+	testNamespace := "test-namespace-metrics"
+	testServer := webhook.Server{}
+
+	// admit a pod interaction
+	interactionReview := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:         "test-uid-exec",
+			Namespace:   testNamespace,
+			Name:        "test-pod",
+			SubResource: "exec",
+			UserInfo:    authenticationv1.UserInfo{Username: "test-user"},
+			Object: runtime.RawExtension{
+				Raw: []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container", "command":["sh"]}`, webhook.PodExecAdmissionRequestKind)),
+			},
+		},
+	}
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+	bytesIn, _ := json.Marshal(interactionReview)
+	testServer.AdmitPodInteraction(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/admit-pod-interaction", bytes.NewBuffer(bytesIn)))
+
+	// deny a pod update removing its interaction labels
+	updateReview := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "test-uid-update",
+			Namespace: testNamespace,
+			Name:      "test-pod",
+			UserInfo:  authenticationv1.UserInfo{Username: "test-user"},
+			Object: runtime.RawExtension{
+				Raw: getPodObjectRaw(nil, nil),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: getPodObjectRaw(map[string]string{controller.PodInteractionTimestampLabel: "1"}, nil),
+			},
+		},
+	}
+	bytesIn, _ = json.Marshal(updateReview)
+	testServer.AdmitPodUpdate(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/admit-pod-update", bytes.NewBuffer(bytesIn)))
+
+	// verify the exposed metrics
+	responseRecorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got: %d", http.StatusOK, responseRecorder.Code)
+	}
+
+	expectedMetrics := []string{
+		fmt.Sprintf(`kube_exec_interactions_total{namespace="%s",verb="exec"} 1`, testNamespace),
+		fmt.Sprintf(`kube_exec_denied_updates_total{namespace="%s"} 1`, testNamespace),
+		"# TYPE kube_exec_termination_timers gauge",
+		"# TYPE kube_exec_eviction_disabled gauge",
+	}
+	for _, expectedMetric := range expectedMetrics {
+		if !strings.Contains(responseRecorder.Body.String(), expectedMetric) {
+			t.Errorf("expected the exposed metrics to contain %q, got:\n%s", expectedMetric, responseRecorder.Body.String())
+		}
+	}
+}
+
+// TestAuditLog tests webhook server appending an audit log record of every admitted interaction, tracked or exempted
+func TestAuditLog(t *testing.T) {
+	setupZapLogging(t)
+
+	auditLogPath := filepath.Join(t.TempDir(), "audit.log")
+	auditLogger, err := webhook.NewFileAuditLogger(auditLogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLogger.Close()
+	redactions, err := controller.ParseRedactPatterns(controller.DefaultRedactPatterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testServer := webhook.Server{
+		AllowedNamespaces: map[string]bool{"test-namespace-allowed": true},
+		CommandRedactions: redactions,
+		AuditLogger:       auditLogger,
+	}
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 1)
+
+	// mock an exec in a regular namespace and another in an allowlisted one
+	for _, namespace := range []string{"test-namespace-regular", "test-namespace-allowed"} {
+		interactionReview := admissionv1.AdmissionReview{
+			Request: &admissionv1.AdmissionRequest{
+				UID:         types.UID("test-uid-" + namespace),
+				Namespace:   namespace,
+				Name:        "test-pod",
+				SubResource: "exec",
+				UserInfo:    authenticationv1.UserInfo{Username: "test-user"},
+				Object: runtime.RawExtension{
+					Raw: []byte(fmt.Sprintf(`{"kind":"%s", "container": "test-container", "command":["mysql","-psecret"]}`,
+						webhook.PodExecAdmissionRequestKind)),
+				},
+			},
+		}
+		bytesIn, _ := json.Marshal(interactionReview)
+		responseRecorder := httptest.NewRecorder()
+		testServer.AdmitPodInteraction(responseRecorder, httptest.NewRequest(http.MethodPost, "/admit-pod-interaction", bytes.NewBuffer(bytesIn)))
+	}
+	<-controller.PodInteractionCh
+
+	// verify a record is appended for both with the password redacted, the exempted one not tracked
+	content, err := ioutil.ReadFile(auditLogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 audit log records, got: %q", content)
+	}
+	for i, namespace := range []string{"test-namespace-regular", "test-namespace-allowed"} {
+		var record webhook.AuditLogRecord
+		if err := json.Unmarshal([]byte(lines[i]), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Timestamp.IsZero() {
+			t.Errorf("expected the audit log record timestamped, got: %+v", record)
+		}
+		record.Timestamp = time.Time{}
+		expected := webhook.AuditLogRecord{
+			Username:      "test-user",
+			PodNamespace:  namespace,
+			PodName:       "test-pod",
+			SubResource:   "exec",
+			ContainerName: "test-container",
+			Commands:      []string{"mysql", "-p***"},
+			Tracked:       namespace == "test-namespace-regular",
+		}
+		if !reflect.DeepEqual(expected, record) {
+			t.Errorf("expected audit log record: %+v, got: %+v", expected, record)
+		}
+	}
+}
+
+// TestReplayAuditLog tests replaying successful Pod interactions from an audit log
+func TestReplayAuditLog(t *testing.T) {
+	setupZapLogging(t)
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction, 10)
+	testServer := webhook.NewOfflineServer("kube-system")
+
+	replayed, err := testServer.ReplayAuditLog("testdata/audit.log", make(chan struct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(controller.PodInteractionCh)
+
+	// only the exec and attach requests responded successfully outside the allowlist are replayed, each once
+	expected := []controller.PodInteraction{
+		{
+			PodName:       "test-pod",
+			PodNamespace:  "test-namespace",
+			ContainerName: "test-container",
+			Username:      "test-user",
+			Commands:      []string{"/bin/sh", "-c", "ls"},
+			InitTime:      time.Date(2021, 10, 16, 18, 13, 57, 123456000, time.UTC),
+			Verb:          controller.InteractionVerbExec,
+			Replayed:      true,
+		},
+		{
+			PodName:       "test-pod-2",
+			PodNamespace:  "test-namespace",
+			ContainerName: "test-container",
+			Username:      "impersonated-user",
+			Commands:      []string{},
+			InitTime:      time.Date(2021, 10, 16, 18, 15, 0, 0, time.UTC),
+			Verb:          controller.InteractionVerbAttach,
+			Interactive:   true,
+			Replayed:      true,
+		},
+	}
+	if replayed != len(expected) {
+		t.Errorf("expected replayed interactions: %d, got: %d", len(expected), replayed)
+	}
+
+	var received []controller.PodInteraction
+	for podInteraction := range controller.PodInteractionCh {
+		received = append(received, podInteraction)
+	}
+	if len(received) != len(expected) {
+		t.Fatalf("expected pod interactions: %v, got: %v", expected, received)
+	}
+	for i := range expected {
+		if !received[i].InitTime.Equal(expected[i].InitTime) {
+			t.Errorf("expected interacted time: %s, got: %s", expected[i].InitTime, received[i].InitTime)
+		}
+		checkPodIntearactionObj(t, received[i], expected[i])
+	}
+
+	if _, err := testServer.ReplayAuditLog("testdata/missing.log", make(chan struct{})); err == nil {
+		t.Error("expected an error replaying a missing audit log, got nil")
+	}
+
+	// verify nothing is replayed once stopped
+	stopCh := make(chan struct{})
+	close(stopCh)
+	if replayed, err := testServer.ReplayAuditLog("testdata/audit.log", stopCh); err != nil || replayed != 0 {
+		t.Errorf("expected nothing replayed once stopped, got: %d, %v", replayed, err)
+	}
+}
+
+// TestCloseControllerChannels tests webhook server closing the controller's channels only once the handlers sending
+// to them have returned, giving up the sends blocked past the timeout
+func TestCloseControllerChannels(t *testing.T) {
+	setupZapLogging(t)
+
+	controller.PodInteractionCh = make(chan controller.PodInteraction)
+	controller.PodExtensionUpdateCh = make(chan controller.PodExtensionUpdate)
+	testServer := webhook.NewOfflineServer("kube-system")
+	reviewBytes, err := ioutil.ReadFile("testdata/exec-review.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admit := func() {
+		request := httptest.NewRequest(http.MethodPost, "/admit-pod-interaction", bytes.NewReader(reviewBytes))
+		request.Header.Set("Content-Type", "application/json")
+		testServer.AdmitPodInteraction(httptest.NewRecorder(), request)
+	}
+
+	// send an interaction the controller never receives, blocking its handler
+	interactions := metrics.InteractionsTotal.WithLabelValues("test-namespace", "exec")
+	admittedBefore := testutil.ToFloat64(interactions)
+	admitted := make(chan struct{})
+	go func() {
+		defer close(admitted)
+		admit()
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(interactions) == admittedBefore {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the interaction request to be in progress")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// verify the blocked handler returns and the channels are closed without panicking, as well as nothing is sent
+	// to them afterwards
+	testServer.CloseControllerChannels(50 * time.Millisecond)
+	<-admitted
+	if _, open := <-controller.PodInteractionCh; open {
+		t.Error("expected the interaction channel closed, but it is open")
+	}
+	admit()
+	if replayed, err := testServer.ReplayAuditLog("testdata/audit.log", make(chan struct{})); err != nil || replayed != 0 {
+		t.Errorf("expected nothing replayed once the channels are closed, got: %d, %v", replayed, err)
+	}
+}
+
+// TestRunGracefulShutdown tests webhook server completing the request in progress before shutting down
+func TestRunGracefulShutdown(t *testing.T) {
+	setupZapLogging(t)
+
+	certPath, keyPath := writeTestKeyPair(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	testServer, err := webhook.NewServer(port, certPath, keyPath, "kube-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller.PodInteractionCh = make(chan controller.PodInteraction)
+	stopCh := make(chan struct{})
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- testServer.Run(stopCh)
+	}()
+
+	// wait for the server to start listening
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	serverURL := fmt.Sprintf("https://127.0.0.1:%d", port)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		response, err := client.Get(serverURL + "/health/liveness")
+		if err == nil {
+			response.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the server to start listening, got:", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// send an interaction request, whose handler blocks on sending the interaction to the controller
+	reviewBytes, err := ioutil.ReadFile("testdata/exec-review.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	interactions := metrics.InteractionsTotal.WithLabelValues("test-namespace", "exec")
+	admittedBefore := testutil.ToFloat64(interactions)
+	responseBody := &bytes.Buffer{}
+	served := make(chan error, 1)
+	go func() {
+		response, err := client.Post(serverURL+"/admit-pod-interaction", "application/json", bytes.NewReader(reviewBytes))
+		if err == nil {
+			defer response.Body.Close()
+			_, err = responseBody.ReadFrom(response.Body)
+		}
+		served <- err
+	}()
+	for testutil.ToFloat64(interactions) == admittedBefore {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the interaction request to be in progress")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// trigger the shutdown while the request is in progress, then let the request complete
+	close(stopCh)
+	time.Sleep(100 * time.Millisecond)
+	receivedPodInteraction := <-controller.PodInteractionCh
+	if receivedPodInteraction.PodName != "test-pod" {
+		t.Errorf("expected the interaction of test-pod sent to the controller, got: %+v", receivedPodInteraction)
+	}
+
+	// verify the response is completed and the server is shut down without error
+	if err := <-served; err != nil {
+		t.Fatal("expected the request in progress to complete, got:", err)
+	}
+	checkAdmissionReviewResponse(t, responseBody, admissionv1.AdmissionResponse{
+		UID:     "3f5c8b4e-1b7a-4f0e-9a47-2f6d0a7b9c01",
+		Allowed: true,
+	})
+	if err := <-runErr; err != nil {
+		t.Fatal("expected the server to shut down without error, got:", err)
+	}
+}
+
+// TestRunHealthPort tests webhook server serving the health checks over plain HTTP on the health port, without
+// serving any admission request on it
+func TestRunHealthPort(t *testing.T) {
+	setupZapLogging(t)
+
+	certPath, keyPath := writeTestKeyPair(t)
+	var ports []int
+	for i := 0; i < 2; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ports = append(ports, listener.Addr().(*net.TCPAddr).Port)
+		listener.Close()
+	}
+
+	testServer, err := webhook.NewServer(ports[0], certPath, keyPath, "kube-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testServer.HealthPort = ports[1]
+	stopCh := make(chan struct{})
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- testServer.Run(stopCh)
+	}()
+
+	// wait for the server to start listening on the health port
+	healthURL := fmt.Sprintf("http://127.0.0.1:%d", ports[1])
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		response, err := http.Get(healthURL + "/health/liveness")
+		if err == nil {
+			response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("expected liveness status: %d, got: %d", http.StatusOK, response.StatusCode)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the server to start listening on the health port, got:", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	response, err := http.Get(healthURL + "/health/readiness")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("expected readiness status: %d, got: %d", http.StatusOK, response.StatusCode)
+	}
+
+	response, err = http.Post(healthURL+"/admit-pod-interaction", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected no admission request served on the health port, got status: %d", response.StatusCode)
+	}
+
+	// the health checks are still served on the TLS port
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	response, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/health/liveness", ports[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("expected liveness status on the TLS port: %d, got: %d", http.StatusOK, response.StatusCode)
+	}
+
+	close(stopCh)
+	if err := <-runErr; err != nil {
+		t.Fatal("expected the server to shut down without error, got:", err)
+	}
+}
+
+// TestReviewFile tests admitting recorded AdmissionReviews offline without sending anything to the controller
+func TestReviewFile(t *testing.T) {
+	setupZapLogging(t)
+
+	testServer := webhook.NewOfflineServer("kube-system")
+
+	testCases := []struct {
+		name             string
+		path             string
+		expectedUID      string
+		expectedDecision webhook.Decision
+	}{
+		{
+			name:        "Test-1 review a recorded 'kubectl exec' request to be tracked",
+			path:        "testdata/exec-review.json",
+			expectedUID: "3f5c8b4e-1b7a-4f0e-9a47-2f6d0a7b9c01",
+			expectedDecision: webhook.Decision{
+				StatusCode: http.StatusOK,
+				Allowed:    true,
+				PodInteraction: &controller.PodInteraction{
+					PodName:       "test-pod",
+					PodNamespace:  "test-namespace",
+					ContainerName: "test-container",
+					Username:      "test-user",
+					Commands:      []string{"/bin/sh"},
+					Verb:          controller.InteractionVerbExec,
+				},
+			},
+		},
+		{
+			name:        "Test-2 review a recorded 'kubectl attach' request under an allowed (exempt) namespace",
+			path:        "testdata/attach-review.json",
+			expectedUID: "7a0e2d51-4c3b-4e8f-b1d6-5c9e8f7a6b02",
+			expectedDecision: webhook.Decision{
+				StatusCode: http.StatusOK,
+				Allowed:    true,
+			},
+		},
+		{
+			name:        "Test-3 review a recorded pod update requesting an extension",
+			path:        "testdata/update-review.json",
+			expectedUID: "c2b1a0f9-8e7d-4c6b-a5f4-e3d2c1b0a903",
+			expectedDecision: webhook.Decision{
+				StatusCode: http.StatusOK,
+				Allowed:    true,
+				PodExtensionUpdate: &controller.PodExtensionUpdate{
+					Pod: corev1.Pod{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								controller.PodInteractionTimestampLabel: "1634408037",
+								controller.PodInteractorLabel:           "test-user",
+								controller.PodTTLDurationLabel:          "2m0s",
+							},
+							Annotations: map[string]string{
+								controller.PodExtendDurationAnnotate:  "1h",
+								controller.PodTerminationTimeAnnotate: "2021-10-16 18:15:57 +0000 UTC",
+							},
+						},
+					},
+					Username: "test-user",
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := testServer.ReviewFile(testCase.path, &out); err != nil {
+				t.Fatal(err)
+			}
+
+			var result webhook.ReviewResult
+			if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+				t.Fatal("error un-marshaling review result:", err, out.String())
+			}
+
+			decision := result.Decision
+			expected := testCase.expectedDecision
+			if decision.StatusCode != expected.StatusCode || decision.Allowed != expected.Allowed {
+				t.Errorf("expected status code: %d and allowed: %t, got: %d and %t",
+					expected.StatusCode, expected.Allowed, decision.StatusCode, decision.Allowed)
+			}
+			if (decision.PodInteraction == nil) != (expected.PodInteraction == nil) {
+				t.Fatalf("expected pod interaction: %v, got: %v", expected.PodInteraction, decision.PodInteraction)
+			}
+			if expected.PodInteraction != nil {
+				checkPodIntearactionObj(t, *decision.PodInteraction, *expected.PodInteraction)
+			}
+			if (decision.PodExtensionUpdate == nil) != (expected.PodExtensionUpdate == nil) {
+				t.Fatalf("expected pod extension update: %v, got: %v", expected.PodExtensionUpdate, decision.PodExtensionUpdate)
+			}
+			if expected.PodExtensionUpdate != nil {
+				checkPodExtensionUpdateObj(t, *decision.PodExtensionUpdate, *expected.PodExtensionUpdate)
+			}
+
+			if result.Response.Response == nil || string(result.Response.Response.UID) != testCase.expectedUID {
+				t.Errorf("expected response UID: %s, got: %v", testCase.expectedUID, result.Response.Response)
+			}
+		})
+	}
+}
+
+// setupZapLogging gives better visibility when running a test
+func setupZapLogging(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	zap.ReplaceGlobals(logger)
+}
+
+// writeTestKeyPair writes a self-signed certificate and its key for "127.0.0.1" to a temp dir and returns their paths
+func writeTestKeyPair(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kube-exec-controller-test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err := ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return certPath, keyPath
+}
+
+// getPodObjectRaw constructs a new pod with the given labels and annotations and returns the encoded result
+func getPodObjectRaw(labels, annotations map[string]string) []byte {
+	pod := corev1.Pod{}
+	pod.SetLabels(labels)
+	pod.SetAnnotations(annotations)
+
+	scheme := runtime.NewScheme()
+	corev1.AddToScheme(scheme)
+	codec := serializer.NewCodecFactory(scheme).LegacyCodec(corev1.SchemeGroupVersion)
+	output, _ := runtime.Encode(codec, pod.DeepCopyObject())
+	return output
+}
+
+// checkAdmissionReviewResponse parses the given responseBody to AdmissionReview and compares it with the given AdmissionResponse
+func checkAdmissionReviewResponse(t *testing.T, responseBody *bytes.Buffer, expectedResponse admissionv1.AdmissionResponse) {
+	var reviewOut admissionv1.AdmissionReview
+	bytesOut, err := ioutil.ReadAll(responseBody)
+	if err != nil {
+		t.Error("error reading admission review respsone:", err)
+		return
+	}
+	err = json.Unmarshal(bytesOut, &reviewOut)
+	if err != nil {
+		t.Error("error un-marshaling admission review respsone:", err, bytesOut)
+		return
+	}
+
+	actualResponse := reviewOut.Response
+	if actualResponse == nil {
+		t.Error("expecting response from outgoing review, got nil")
+		return
+	}
+	if actualResponse.Allowed != expectedResponse.Allowed {
+		t.Errorf("expected response Allowed: %t, got: %t", expectedResponse.Allowed, actualResponse.Allowed)
+	}
+	if actualResponse.UID != expectedResponse.UID {
+		t.Errorf("expected response UID: %s, got: %s", expectedResponse.UID, actualResponse.UID)
+	}
+	if !reflect.DeepEqual(expectedResponse.Warnings, actualResponse.Warnings) {
+		t.Errorf("expected response Warnings: %v, got: %v", expectedResponse.Warnings, actualResponse.Warnings)
 	}
 	// check AdmissionResponse.Result if expected
 	if expectedResponse.Result != nil {